@@ -0,0 +1,87 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package admin
+
+import (
+	"context"
+	"github.com/Loopring/relay/config"
+	"github.com/Loopring/relay/log"
+	"github.com/ethereum/go-ethereum/rpc"
+	"net"
+	"net/http"
+)
+
+// AdminServiceImpl serves operator only apis, it should listen on a loopback or private interface.
+// jsonrpc methods are served on "/", namespaces are registered by the services who own them.
+type AdminServiceImpl struct {
+	options config.AdminOptions
+	rpc     *rpc.Server
+	mux     *http.ServeMux
+	server  *http.Server
+}
+
+func NewAdminService(options config.AdminOptions) *AdminServiceImpl {
+	a := &AdminServiceImpl{}
+	a.options = options
+	a.rpc = rpc.NewServer()
+	a.mux = http.NewServeMux()
+	a.mux.Handle("/", a.rpc)
+	return a
+}
+
+func (a *AdminServiceImpl) RegisterName(namespace string, service interface{}) error {
+	return a.rpc.RegisterName(namespace, service)
+}
+
+func (a *AdminServiceImpl) Handle(pattern string, handler http.Handler) {
+	a.mux.Handle(pattern, handler)
+}
+
+func (a *AdminServiceImpl) Start() {
+	if "" == a.options.Port {
+		log.Info("admin port not configured, admin api disabled")
+		return
+	}
+
+	var (
+		listener net.Listener
+		err      error
+	)
+	if listener, err = net.Listen("tcp", a.options.Host+":"+a.options.Port); err != nil {
+		log.Errorf("admin listen err:%s", err.Error())
+		return
+	}
+	a.server = &http.Server{Handler: a.mux}
+	go a.server.Serve(listener)
+	log.Info("admin endpoint opened on " + listener.Addr().String())
+}
+
+func (a *AdminServiceImpl) Stop() {
+	if nil != a.server {
+		a.server.Close()
+	}
+}
+
+// Shutdown stops accepting connections and waits the running requests until ctx is done
+func (a *AdminServiceImpl) Shutdown(ctx context.Context) error {
+	if nil != a.server {
+		return a.server.Shutdown(ctx)
+	}
+	return nil
+}
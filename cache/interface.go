@@ -61,6 +61,11 @@ type Cache interface {
 
 	ZRange(key string, start, stop int64, withScores bool) ([][]byte, error)
 	ZRemRangeByScore(key string, start, stop int64) (int64, error)
+	ZRevRangeByScore(key string, max, min, offset, count int64) ([][]byte, error)
+
+	Ping() error
+
+	Close() error
 }
 
 func NewCache(cfg interface{}) {
@@ -69,12 +74,19 @@ func NewCache(cfg interface{}) {
 	cache = redisCache
 }
 
+// SetCache replaces the redis cache, it's used by tests to run without redis
+func SetCache(c Cache) {
+	cache = c
+}
+
 func Set(key string, value []byte, ttl int64) error { return cache.Set(key, value, ttl) }
 func Get(key string) ([]byte, error)                { return cache.Get(key) }
 func Del(key string) error                          { return cache.Del(key) }
 func Dels(keys []string) error                      { return cache.Dels(keys) }
 func Exists(key string) (bool, error)               { return cache.Exists(key) }
 func Keys(keyFormat string) ([][]byte, error)       { return cache.Keys(keyFormat) }
+func Ping() error                                   { return cache.Ping() }
+func Close() error                                  { return cache.Close() }
 
 func HMSet(key string, ttl int64, args ...[]byte) error {
 	return cache.HMSet(key, ttl, args...)
@@ -128,3 +140,6 @@ func ZRange(key string, start, stop int64, withScores bool) ([][]byte, error) {
 func ZRemRangeByScore(key string, start, stop int64) (int64, error) {
 	return cache.ZRemRangeByScore(key, start, stop)
 }
+func ZRevRangeByScore(key string, max, min, offset, count int64) ([][]byte, error) {
+	return cache.ZRevRangeByScore(key, max, min, offset, count)
+}
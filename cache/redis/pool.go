@@ -23,7 +23,9 @@ import (
 	"fmt"
 	"github.com/Loopring/relay/config"
 	"github.com/Loopring/relay/log"
+	"github.com/Loopring/relay/metrics"
 	"github.com/garyburd/redigo/redis"
+	"strings"
 	"time"
 )
 
@@ -54,7 +56,7 @@ func (impl *RedisCacheImpl) Initialize(cfg interface{}) {
 			}
 
 			if err != nil {
-				log.Fatal(err.Error())
+				log.Errorf("redis dial err:%s", err.Error())
 				return nil, err
 			}
 
@@ -63,10 +65,29 @@ func (impl *RedisCacheImpl) Initialize(cfg interface{}) {
 	}
 }
 
+// timedConn records the latency and errors of each command
+type timedConn struct {
+	redis.Conn
+}
+
+func (c timedConn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	start := time.Now()
+	reply, err := c.Conn.Do(commandName, args...)
+	metrics.Timer("relay_redis_command_duration_seconds", "command", strings.ToLower(commandName)).UpdateSince(start)
+	if nil != err && redis.ErrNil != err {
+		metrics.Counter("relay_redis_command_errors_total", "command", strings.ToLower(commandName)).Inc(1)
+	}
+	return reply, err
+}
+
+func (impl *RedisCacheImpl) conn() redis.Conn {
+	return timedConn{impl.pool.Get()}
+}
+
 func (impl *RedisCacheImpl) Get(key string) ([]byte, error) {
 	//log.Info("[REDIS-GET] key : " + key)
 
-	conn := impl.pool.Get()
+	conn := impl.conn()
 	defer conn.Close()
 
 	reply, err := conn.Do("get", key)
@@ -88,7 +109,7 @@ func (impl *RedisCacheImpl) Exists(key string) (bool, error) {
 
 	//log.Info("[REDIS-Exists] key : " + key)
 
-	conn := impl.pool.Get()
+	conn := impl.conn()
 	defer conn.Close()
 
 	reply, err := conn.Do("exists", key)
@@ -110,7 +131,7 @@ func (impl *RedisCacheImpl) Set(key string, value []byte, ttl int64) error {
 
 	//log.Info("[REDIS-SET] key : " + key)
 
-	conn := impl.pool.Get()
+	conn := impl.conn()
 	defer conn.Close()
 
 	if _, err := conn.Do("set", key, value); err != nil {
@@ -131,7 +152,7 @@ func (impl *RedisCacheImpl) Del(key string) error {
 
 	//log.Info("[REDIS-Del] key : " + key)
 
-	conn := impl.pool.Get()
+	conn := impl.conn()
 	defer conn.Close()
 
 	_, err := conn.Do("del", key)
@@ -142,7 +163,7 @@ func (impl *RedisCacheImpl) Del(key string) error {
 }
 
 func (impl *RedisCacheImpl) Dels(keys []string) error {
-	conn := impl.pool.Get()
+	conn := impl.conn()
 	defer conn.Close()
 
 	var list []interface{}
@@ -165,7 +186,7 @@ func (impl *RedisCacheImpl) Keys(keyFormat string) ([][]byte, error) {
 
 	//log.Info("[REDIS-HMGET] key : " + key)
 
-	conn := impl.pool.Get()
+	conn := impl.conn()
 	defer conn.Close()
 
 	vs := []interface{}{}
@@ -191,7 +212,7 @@ func (impl *RedisCacheImpl) HMSet(key string, ttl int64, args ...[]byte) error {
 
 	//log.Info("[REDIS-HMSET] key : " + key)
 
-	conn := impl.pool.Get()
+	conn := impl.conn()
 	defer conn.Close()
 
 	if len(args)%2 != 0 {
@@ -219,7 +240,7 @@ func (impl *RedisCacheImpl) ZAdd(key string, ttl int64, args ...[]byte) error {
 
 	//log.Info("[REDIS-ZAdd] key : " + key)
 
-	conn := impl.pool.Get()
+	conn := impl.conn()
 	defer conn.Close()
 
 	if len(args)%2 != 0 {
@@ -247,7 +268,7 @@ func (impl *RedisCacheImpl) HMGet(key string, fields ...[]byte) ([][]byte, error
 
 	//log.Info("[REDIS-HMGET] key : " + key)
 
-	conn := impl.pool.Get()
+	conn := impl.conn()
 	defer conn.Close()
 
 	vs := []interface{}{}
@@ -277,7 +298,7 @@ func (impl *RedisCacheImpl) ZRange(key string, start, stop int64, withScores boo
 
 	//log.Info("[REDIS-ZRANGE] key : " + key)
 
-	conn := impl.pool.Get()
+	conn := impl.conn()
 	defer conn.Close()
 
 	vs := []interface{}{}
@@ -307,7 +328,7 @@ func (impl *RedisCacheImpl) HDel(key string, fields ...[]byte) (int64, error) {
 
 	//log.Info("[REDIS-HDEL] key : " + key)
 
-	conn := impl.pool.Get()
+	conn := impl.conn()
 	defer conn.Close()
 
 	vs := []interface{}{}
@@ -329,7 +350,7 @@ func (impl *RedisCacheImpl) SCard(key string) (int64, error) {
 
 	//log.Info("[REDIS-SCARD] key : " + key)
 
-	conn := impl.pool.Get()
+	conn := impl.conn()
 	defer conn.Close()
 
 	vs := []interface{}{}
@@ -349,7 +370,7 @@ func (impl *RedisCacheImpl) ZRemRangeByScore(key string, start, stop int64) (int
 
 	//log.Info("[REDIS-ZRemRangeByScore] key : " + key)
 
-	conn := impl.pool.Get()
+	conn := impl.conn()
 	defer conn.Close()
 
 	vs := []interface{}{}
@@ -366,11 +387,36 @@ func (impl *RedisCacheImpl) ZRemRangeByScore(key string, start, stop int64) (int
 	}
 }
 
+func (impl *RedisCacheImpl) ZRevRangeByScore(key string, max, min, offset, count int64) ([][]byte, error) {
+
+	conn := impl.conn()
+	defer conn.Close()
+
+	vs := []interface{}{}
+	vs = append(vs, key, max, min, []byte("LIMIT"), offset, count)
+	reply, err := conn.Do("ZREVRANGEBYSCORE", vs...)
+
+	res := [][]byte{}
+	if nil != err {
+		log.Errorf(" key:%s, err:%s", key, err.Error())
+	} else if nil == err && nil != reply {
+		rs := reply.([]interface{})
+		for _, r := range rs {
+			if nil == r {
+				res = append(res, []byte{})
+			} else {
+				res = append(res, r.([]byte))
+			}
+		}
+	}
+	return res, err
+}
+
 func (impl *RedisCacheImpl) SRem(key string, members ...[]byte) (int64, error) {
 
 	//log.Info("[REDIS-SRem] key : " + key)
 
-	conn := impl.pool.Get()
+	conn := impl.conn()
 	defer conn.Close()
 
 	vs := []interface{}{}
@@ -390,7 +436,7 @@ func (impl *RedisCacheImpl) SRem(key string, members ...[]byte) (int64, error) {
 }
 
 func (impl *RedisCacheImpl) SIsMember(key string, member []byte) (bool, error) {
-	conn := impl.pool.Get()
+	conn := impl.conn()
 	defer conn.Close()
 
 	reply, err := conn.Do("sismember", key, member)
@@ -406,7 +452,7 @@ func (impl *RedisCacheImpl) HGetAll(key string) ([][]byte, error) {
 
 	//log.Info("[REDIS-HGetAll] key : " + key)
 
-	conn := impl.pool.Get()
+	conn := impl.conn()
 	defer conn.Close()
 
 	reply, err := conn.Do("hgetall", key)
@@ -426,7 +472,7 @@ func (impl *RedisCacheImpl) HVals(key string) ([][]byte, error) {
 
 	//log.Info("[REDIS-HVals] key : " + key)
 
-	conn := impl.pool.Get()
+	conn := impl.conn()
 	defer conn.Close()
 
 	//todo:test nil result
@@ -448,7 +494,7 @@ func (impl *RedisCacheImpl) HExists(key string, field []byte) (bool, error) {
 
 	//log.Info("[REDIS-HExists] key : " + key)
 
-	conn := impl.pool.Get()
+	conn := impl.conn()
 	defer conn.Close()
 
 	reply, err := conn.Do("hexists", key, field)
@@ -466,7 +512,7 @@ func (impl *RedisCacheImpl) SAdd(key string, ttl int64, members ...[]byte) error
 
 	//log.Info("[REDIS-SAdd] key : " + key)
 
-	conn := impl.pool.Get()
+	conn := impl.conn()
 	defer conn.Close()
 
 	vs := []interface{}{}
@@ -491,7 +537,7 @@ func (impl *RedisCacheImpl) SMembers(key string) ([][]byte, error) {
 
 	//log.Info("[REDIS-SMembers] key : " + key)
 
-	conn := impl.pool.Get()
+	conn := impl.conn()
 	defer conn.Close()
 
 	reply, err := conn.Do("smembers", key)
@@ -507,3 +553,15 @@ func (impl *RedisCacheImpl) SMembers(key string) ([][]byte, error) {
 	}
 	return res, err
 }
+
+func (impl *RedisCacheImpl) Ping() error {
+	conn := impl.conn()
+	defer conn.Close()
+
+	_, err := conn.Do("ping")
+	return err
+}
+
+func (impl *RedisCacheImpl) Close() error {
+	return impl.pool.Close()
+}
@@ -0,0 +1,103 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/Loopring/relay/cmd/utils"
+	"github.com/Loopring/relay/console"
+	"github.com/Loopring/relay/crypto/passphrase"
+	"gopkg.in/urfave/cli.v1"
+)
+
+func attachCommand() cli.Command {
+	c := cli.Command{
+		Name:      "attach",
+		Usage:     "start an interactive console attached to a running relay",
+		ArgsUsage: "[endpoint]",
+		Category:  "client commands:",
+		Description: `
+The console calls the jsonrpc methods of relay, such as "loopring_getDepth {\"market\":\"LRC-WETH\"}",
+the endpoint is http, ws or ipc, it's the jsonrpc endpoint of relay by default, the admin endpoint
+can be attached to call miner_*, account_*, whitelist_* and node_* methods. Tab completes the methods
+and "help" lists the helpers to create, sign and submit orders.`,
+		Action: attach,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "exec",
+				Usage: "execute the statements separated by newline and exit",
+			},
+			cli.StringFlag{
+				Name:  "datadir",
+				Usage: "keystore to sign orders, the passphrase is read from the credentials dir, env or terminal",
+			},
+			cli.StringFlag{
+				Name:  "history",
+				Usage: "file to keep the history of console",
+				Value: defaultHistoryFile(),
+			},
+		},
+	}
+	return c
+}
+
+func attach(ctx *cli.Context) {
+	endpoint := endpointFlag.Value
+	if ctx.NArg() > 0 {
+		endpoint = ctx.Args().First()
+	}
+
+	sources := passphrase.Sources{}
+	if dir := passphrase.DefaultCredentialsDir(); "" != dir {
+		if source, err := passphrase.NewDirSource(dir); nil == err {
+			sources = append(sources, source)
+		}
+	}
+	sources = append(sources, passphrase.NewEnvSource())
+
+	c, err := console.New(console.Config{
+		Endpoint:    endpoint,
+		Datadir:     ctx.String("datadir"),
+		Sources:     sources,
+		HistoryFile: ctx.String("history"),
+		Out:         ctx.App.Writer,
+	})
+	if nil != err {
+		utils.ExitWithErr(ctx.App.Writer, err)
+	}
+	defer c.Stop()
+
+	if ctx.IsSet("exec") {
+		err = c.Execute(ctx.String("exec"))
+	} else {
+		err = c.Interactive()
+	}
+	if nil != err {
+		utils.ExitWithErr(ctx.App.Writer, err)
+	}
+}
+
+func defaultHistoryFile() string {
+	if home := os.Getenv("HOME"); "" != home {
+		return filepath.Join(home, ".relay_history")
+	}
+	return ""
+}
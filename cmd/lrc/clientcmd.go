@@ -0,0 +1,185 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Loopring/relay/cmd/utils"
+	"github.com/Loopring/relay/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// the commands talk to a running relay, the public methods are called on the jsonrpc endpoint
+// and the operator only methods on the admin endpoint
+var (
+	endpointFlag = cli.StringFlag{
+		Name:  "endpoint",
+		Usage: "jsonrpc endpoint of relay",
+		Value: "http://127.0.0.1:8083",
+	}
+	adminEndpointFlag = cli.StringFlag{
+		Name:  "endpoint",
+		Usage: "admin endpoint of relay",
+		Value: "http://127.0.0.1:8089",
+	}
+	formatFlag = cli.StringFlag{
+		Name:  "format",
+		Usage: "table or json",
+		Value: "table",
+	}
+)
+
+func clientFlags(flags ...cli.Flag) []cli.Flag {
+	return append([]cli.Flag{endpointFlag, formatFlag}, flags...)
+}
+
+func adminFlags(flags ...cli.Flag) []cli.Flag {
+	return append([]cli.Flag{adminEndpointFlag, formatFlag}, flags...)
+}
+
+func callRelay(ctx *cli.Context, result interface{}, method string, args ...interface{}) {
+	client, err := rpc.DialHTTP(ctx.String("endpoint"))
+	if nil != err {
+		utils.ExitWithErr(ctx.App.Writer, err)
+	}
+	defer client.Close()
+
+	if err := client.Call(result, method, args...); nil != err {
+		utils.ExitWithErr(ctx.App.Writer, fmt.Errorf("%s failed, err:%s", method, err.Error()))
+	}
+}
+
+func isJsonFormat(ctx *cli.Context) bool {
+	return "json" == ctx.String("format")
+}
+
+func printJson(ctx *cli.Context, v interface{}) {
+	encoder := json.NewEncoder(ctx.App.Writer)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); nil != err {
+		utils.ExitWithErr(ctx.App.Writer, err)
+	}
+}
+
+func newTable(ctx *cli.Context, header ...string) *tabwriter.Writer {
+	w := tabwriter.NewWriter(ctx.App.Writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	return w
+}
+
+func printRow(w *tabwriter.Writer, columns ...interface{}) {
+	values := make([]string, len(columns))
+	for idx, c := range columns {
+		values[idx] = fmt.Sprint(c)
+	}
+	fmt.Fprintln(w, strings.Join(values, "\t"))
+}
+
+func requireAddress(ctx *cli.Context, name string) common.Address {
+	v := ctx.String(name)
+	if !common.IsHexAddress(v) {
+		utils.ExitWithErr(ctx.App.Writer, fmt.Errorf("%s:%s is not a HexAddress", name, v))
+	}
+	return common.HexToAddress(v)
+}
+
+func requireString(ctx *cli.Context, name string) string {
+	v := ctx.String(name)
+	if "" == v {
+		utils.ExitWithErr(ctx.App.Writer, fmt.Errorf("%s can't be empty", name))
+	}
+	return v
+}
+
+// relayTokens returns the tokens supported by relay by upper case symbol
+func relayTokens(ctx *cli.Context) map[string]types.Token {
+	var list []types.Token
+	callRelay(ctx, &list, "loopring_getSupportedTokens")
+	tokens := make(map[string]types.Token)
+	for _, token := range list {
+		tokens[strings.ToUpper(token.Symbol)] = token
+	}
+	return tokens
+}
+
+// defaultDelegate returns the delegate given by --delegate, or the first one of relay
+func defaultDelegate(ctx *cli.Context) (delegate, protocol common.Address) {
+	var contracts map[string][]string
+	callRelay(ctx, &contracts, "loopring_getContracts")
+	if v := ctx.String("delegate"); "" != v {
+		delegate = common.HexToAddress(v)
+		for d, protocols := range contracts {
+			if common.HexToAddress(d) == delegate && len(protocols) > 0 {
+				return delegate, common.HexToAddress(protocols[0])
+			}
+		}
+		utils.ExitWithErr(ctx.App.Writer, fmt.Errorf("delegate:%s isn't supported by relay", v))
+	}
+	delegates := []string{}
+	for d := range contracts {
+		delegates = append(delegates, d)
+	}
+	sort.Strings(delegates)
+	for _, d := range delegates {
+		if protocols := contracts[d]; len(protocols) > 0 {
+			return common.HexToAddress(d), common.HexToAddress(protocols[0])
+		}
+	}
+	utils.ExitWithErr(ctx.App.Writer, errors.New("there isn't any contract in relay"))
+	return
+}
+
+// parseAmount converts the amount in token unit like 1.5 to the amount in the smallest unit
+func parseAmount(v string, decimals *big.Int) (*big.Int, error) {
+	amount, ok := new(big.Rat).SetString(v)
+	if !ok || amount.Sign() < 0 {
+		return nil, fmt.Errorf("invalid amount:%s", v)
+	}
+	amount.Mul(amount, new(big.Rat).SetInt(decimals))
+	if !amount.IsInt() {
+		return nil, fmt.Errorf("amount:%s has too many decimals", v)
+	}
+	return amount.Num(), nil
+}
+
+// formatAmount converts the hex or decimal amount in the smallest unit to the token unit
+func formatAmount(v string, token types.Token) string {
+	amount, ok := new(big.Int), false
+	if strings.HasPrefix(v, "0x") {
+		amount, ok = amount.SetString(strings.TrimPrefix(v, "0x"), 16)
+	} else {
+		amount, ok = amount.SetString(v, 10)
+	}
+	if !ok {
+		return v
+	}
+	if nil == token.Decimals || token.Decimals.Sign() <= 0 {
+		return amount.String()
+	}
+	return strings.TrimRight(strings.TrimRight(new(big.Rat).SetFrac(amount, token.Decimals).FloatString(8), "0"), ".")
+}
@@ -0,0 +1,79 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/Loopring/relay/cmd/utils"
+	"github.com/Loopring/relay/config"
+	"gopkg.in/urfave/cli.v1"
+)
+
+func configCommands() cli.Command {
+	c := cli.Command{
+		Name:     "config",
+		Usage:    "inspect the config",
+		Category: "config commands:",
+		Subcommands: []cli.Command{
+			cli.Command{
+				Name:   "print",
+				Usage:  "print the effective config merged from the file, includes, RELAY_* env and --set, secrets are masked",
+				Action: printConfig,
+				Flags:  []cli.Flag{utils.ConfigFlag, utils.SetFlag, utils.ModeFlag},
+			},
+			cli.Command{
+				Name:   "validate",
+				Usage:  "check the unknown keys, ranges, addresses, urls and consistency of the config, all errors are reported",
+				Action: validateConfig,
+				Flags:  []cli.Flag{utils.ConfigFlag, utils.SetFlag, utils.ModeFlag},
+			},
+		},
+	}
+	return c
+}
+
+func printConfig(ctx *cli.Context) {
+	globalConfig, err := utils.LoadGlobalConfig(ctx)
+	if nil != err {
+		utils.ExitWithErr(ctx.App.Writer, err)
+	}
+	for _, setting := range config.Settings(globalConfig, true) {
+		fmt.Fprintln(ctx.App.Writer, setting.String())
+	}
+}
+
+func validateConfig(ctx *cli.Context) {
+	globalConfig, err := utils.LoadGlobalConfig(ctx)
+	if nil == err {
+		err = config.Validate(globalConfig)
+	}
+	if nil == err {
+		fmt.Fprintln(ctx.App.Writer, "config is valid")
+		return
+	}
+	errs, ok := err.(config.ValidationErrors)
+	if !ok {
+		utils.ExitWithErr(ctx.App.Writer, err)
+	}
+	for _, e := range errs {
+		fmt.Fprintln(ctx.App.Writer, e.Error())
+	}
+	utils.ExitWithErr(ctx.App.Writer, fmt.Errorf("%d errors found in config", len(errs)))
+}
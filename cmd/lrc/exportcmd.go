@@ -0,0 +1,138 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Loopring/relay/cmd/utils"
+	"github.com/Loopring/relay/gateway"
+	"gopkg.in/urfave/cli.v1"
+)
+
+func exportCommand() cli.Command {
+	c := cli.Command{
+		Name:     "export",
+		Usage:    "export the statement of an owner with fiat value and cost basis",
+		Category: "account commands:",
+		Action:   exportStatement,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "endpoint",
+				Usage: "rest endpoint of relay",
+				Value: "http://127.0.0.1:8084",
+			},
+			cli.StringFlag{
+				Name:  "owner",
+				Usage: "address of owner",
+			},
+			cli.StringFlag{
+				Name:  "start",
+				Usage: "start of statement, unix timestamp or date like 2018-01-02",
+			},
+			cli.StringFlag{
+				Name:  "end",
+				Usage: "end of statement, unix timestamp or date like 2018-01-02, default is now",
+			},
+			cli.StringFlag{
+				Name:  "format",
+				Usage: "csv or ndjson",
+				Value: "csv",
+			},
+			cli.StringFlag{
+				Name:  "currency",
+				Usage: "USD, CNY or BTC",
+				Value: "USD",
+			},
+			cli.StringFlag{
+				Name:  "output,o",
+				Usage: "output file, default is stdout",
+			},
+		},
+	}
+	return c
+}
+
+func exportStatement(ctx *cli.Context) {
+	owner := ctx.String("owner")
+	if "" == owner {
+		utils.ExitWithErr(ctx.App.Writer, errors.New("owner can't be empty"))
+	}
+
+	params := url.Values{}
+	params.Set("format", ctx.String("format"))
+	params.Set("currency", ctx.String("currency"))
+	for _, name := range []string{"start", "end"} {
+		if v := ctx.String(name); "" != v {
+			timestamp, err := parseExportTime(v)
+			if nil != err {
+				utils.ExitWithErr(ctx.App.Writer, err)
+			}
+			params.Set(name, strconv.FormatInt(timestamp, 10))
+		}
+	}
+
+	u := strings.TrimSuffix(ctx.String("endpoint"), "/") + gateway.RestPathPrefix + "/owners/" + owner + "/export?" + params.Encode()
+	res, err := http.Get(u)
+	if nil != err {
+		utils.ExitWithErr(ctx.App.Writer, err)
+	}
+	defer res.Body.Close()
+
+	if http.StatusOK != res.StatusCode {
+		body := &gateway.RestErrorBody{}
+		if err := json.NewDecoder(res.Body).Decode(body); nil != err {
+			utils.ExitWithErr(ctx.App.Writer, fmt.Errorf("export failed, http status:%d", res.StatusCode))
+		}
+		utils.ExitWithErr(ctx.App.Writer, errors.New(body.Error.Message))
+	}
+
+	var output io.Writer = ctx.App.Writer
+	if file := ctx.String("output"); "" != file {
+		f, err := os.Create(file)
+		if nil != err {
+			utils.ExitWithErr(ctx.App.Writer, err)
+		}
+		defer f.Close()
+		output = f
+	}
+	if _, err := io.Copy(output, res.Body); nil != err {
+		utils.ExitWithErr(ctx.App.Writer, err)
+	}
+}
+
+func parseExportTime(v string) (int64, error) {
+	if timestamp, err := strconv.ParseInt(v, 10, 64); nil == err {
+		return timestamp, nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if nil != err {
+		return 0, fmt.Errorf("invalid time:%s, should be unix timestamp or date like 2018-01-02", v)
+	}
+	return t.Unix(), nil
+}
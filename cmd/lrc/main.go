@@ -41,6 +41,15 @@ func main() {
 
 	app.Commands = []cli.Command{
 		accountCommands(),
+		attachCommand(),
+		configCommands(),
+		exportCommand(),
+		minerCommands(),
+		orderCommands(),
+		marketCommands(),
+		ringCommands(),
+		whitelistCommands(),
+		nodeCommands(),
 	}
 
 	sort.Sort(cli.CommandsByName(app.Commands))
@@ -0,0 +1,139 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Loopring/relay/gateway"
+	"github.com/Loopring/relay/market"
+	"gopkg.in/urfave/cli.v1"
+)
+
+func marketCommands() cli.Command {
+	c := cli.Command{
+		Name:     "market",
+		Usage:    "query depth, tickers and trades of markets",
+		Category: "client commands:",
+		Subcommands: []cli.Command{
+			cli.Command{
+				Name:   "depth",
+				Usage:  "show the depth of a market",
+				Action: showDepth,
+				Flags: clientFlags(
+					cli.StringFlag{Name: "market", Usage: "market like LRC-WETH"},
+					cli.StringFlag{Name: "delegate", Usage: "delegate address, default is the first one of relay"},
+					cli.IntFlag{Name: "limit", Usage: "the max count of price levels each side", Value: 20},
+				),
+			},
+			cli.Command{
+				Name:   "ticker",
+				Usage:  "show the 24 hours tickers",
+				Action: showTickers,
+				Flags: clientFlags(
+					cli.StringFlag{Name: "market", Usage: "market like LRC-WETH, default is all"},
+				),
+			},
+			cli.Command{
+				Name:   "trades",
+				Usage:  "show the latest trades of a market",
+				Action: showTrades,
+				Flags: clientFlags(
+					cli.StringFlag{Name: "market", Usage: "market like LRC-WETH"},
+					cli.StringFlag{Name: "delegate", Usage: "delegate address, default is the first one of relay"},
+				),
+			},
+		},
+	}
+	return c
+}
+
+func showDepth(ctx *cli.Context) {
+	delegate, _ := defaultDelegate(ctx)
+	var depth gateway.Depth
+	callRelay(ctx, &depth, "loopring_getDepth", gateway.DepthQuery{DelegateAddress: delegate.Hex(), Market: requireString(ctx, "market")})
+	limit := ctx.Int("limit")
+	if limit > 0 {
+		if len(depth.Depth.Sell) > limit {
+			depth.Depth.Sell = depth.Depth.Sell[len(depth.Depth.Sell)-limit:]
+		}
+		if len(depth.Depth.Buy) > limit {
+			depth.Depth.Buy = depth.Depth.Buy[:limit]
+		}
+	}
+	if isJsonFormat(ctx) {
+		printJson(ctx, depth)
+		return
+	}
+	w := newTable(ctx, "SIDE", "PRICE", "AMOUNT", "SIZE")
+	for _, level := range depth.Depth.Sell {
+		printRow(w, append([]interface{}{"sell"}, depthLevel(level)...)...)
+	}
+	for _, level := range depth.Depth.Buy {
+		printRow(w, append([]interface{}{"buy"}, depthLevel(level)...)...)
+	}
+	w.Flush()
+}
+
+func depthLevel(level []string) []interface{} {
+	columns := []interface{}{"", "", ""}
+	for idx := 0; idx < len(level) && idx < len(columns); idx++ {
+		columns[idx] = level[idx]
+	}
+	return columns
+}
+
+func showTickers(ctx *cli.Context) {
+	var tickers []market.Ticker
+	callRelay(ctx, &tickers, "loopring_getTicker")
+	if mkt := ctx.String("market"); "" != mkt {
+		filtered := []market.Ticker{}
+		for _, ticker := range tickers {
+			if strings.ToUpper(mkt) == strings.ToUpper(ticker.Market) {
+				filtered = append(filtered, ticker)
+			}
+		}
+		tickers = filtered
+	}
+	if isJsonFormat(ctx) {
+		printJson(ctx, tickers)
+		return
+	}
+	w := newTable(ctx, "MARKET", "LAST", "BUY", "SELL", "HIGH", "LOW", "VOL", "AMOUNT", "CHANGE", "TRADES")
+	for _, t := range tickers {
+		printRow(w, t.Market, t.Last, t.Buy, t.Sell, t.High, t.Low, t.Vol, t.Amount, t.Change, t.TradeCount)
+	}
+	w.Flush()
+}
+
+func showTrades(ctx *cli.Context) {
+	delegate, _ := defaultDelegate(ctx)
+	var fills []gateway.LatestFill
+	callRelay(ctx, &fills, "loopring_getLatestFills", gateway.FillQuery{DelegateAddress: delegate.Hex(), Market: requireString(ctx, "market")})
+	if isJsonFormat(ctx) {
+		printJson(ctx, fills)
+		return
+	}
+	w := newTable(ctx, "TIME", "SIDE", "PRICE", "AMOUNT", "LRC_FEE", "RING_HASH")
+	for _, f := range fills {
+		printRow(w, time.Unix(f.CreateTime, 0).Format("2006-01-02 15:04:05"), f.Side, f.Price, f.Amount, f.LrcFee, f.RingHash)
+	}
+	w.Flush()
+}
@@ -18,7 +18,13 @@
 
 package main
 
-import "gopkg.in/urfave/cli.v1"
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/Loopring/relay/miner"
+	"gopkg.in/urfave/cli.v1"
+)
 
 func minerCommands() cli.Command {
 	minerCommand := cli.Command{
@@ -26,6 +32,59 @@ func minerCommands() cli.Command {
 		Usage:    "miner ",
 		Category: "miner commands",
 		Action:   nil,
+		Subcommands: []cli.Command{
+			cli.Command{
+				Name:   "pnl",
+				Usage:  "report the daily profit and loss of the submitted rings",
+				Action: reportProfits,
+				Flags: adminFlags(
+					cli.IntFlag{
+						Name:  "days",
+						Usage: "the last days to report",
+						Value: 7,
+					},
+					cli.StringFlag{
+						Name:  "miner",
+						Usage: "sender address of rings, default is all",
+					},
+				),
+			},
+		},
 	}
 	return minerCommand
 }
+
+func reportProfits(ctx *cli.Context) {
+	var profits []miner.DailyProfit
+	callRelay(ctx, &profits, "miner_dailyProfits", ctx.Int("days"), ctx.String("miner"))
+
+	if isJsonFormat(ctx) {
+		printJson(ctx, profits)
+		return
+	}
+
+	w := newTable(ctx, "DATE", "MINER", "RINGS", "MINED", "FAILED", "PENDING", "EST_FEE", "EST_COST", "EST_RECEIVED", "FEE", "LRC_REWARD", "GAS_COST", "PROFIT")
+	var total miner.DailyProfit
+	for _, p := range profits {
+		printProfit(w, p.Date, p.Miner, p)
+		total.Rings += p.Rings
+		total.Mined += p.Mined
+		total.Failed += p.Failed
+		total.Pending += p.Pending
+		total.EstLegalFee += p.EstLegalFee
+		total.EstLegalCost += p.EstLegalCost
+		total.EstReceived += p.EstReceived
+		total.LegalFee += p.LegalFee
+		total.LegalReward += p.LegalReward
+		total.LegalGasCost += p.LegalGasCost
+		total.LegalProfit += p.LegalProfit
+	}
+	printProfit(w, "TOTAL", "", total)
+	w.Flush()
+}
+
+func printProfit(w *tabwriter.Writer, date, minerAddress string, p miner.DailyProfit) {
+	fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\n",
+		date, minerAddress, p.Rings, p.Mined, p.Failed, p.Pending,
+		p.EstLegalFee, p.EstLegalCost, p.EstReceived, p.LegalFee, p.LegalReward, p.LegalGasCost, p.LegalProfit)
+}
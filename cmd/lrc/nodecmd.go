@@ -19,15 +19,19 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/Loopring/relay/cmd/utils"
 	"github.com/Loopring/relay/config"
 	"github.com/Loopring/relay/crypto"
+	"github.com/Loopring/relay/crypto/passphrase"
 	"github.com/Loopring/relay/log"
 	"github.com/Loopring/relay/node"
 	"github.com/ethereum/go-ethereum/accounts"
@@ -47,36 +51,61 @@ func startNode(ctx *cli.Context) error {
 		}
 	}()
 
-	var n *node.Node
-	signalChan := make(chan os.Signal, 1)
-	signal.Notify(signalChan, os.Interrupt)
-	signal.Notify(signalChan, os.Kill)
+	n := node.NewNode(logger, globalConfig)
+
+	if sources := unlockAccount(ctx, globalConfig); nil != sources {
+		n.RegisterAccountAdmin(sources)
+	}
+
+	n.SetConfigLoader(func() (*config.GlobalConfig, error) {
+		return utils.LoadGlobalConfig(ctx)
+	})
+	reloadChan := make(chan os.Signal, 1)
+	signal.Notify(reloadChan, syscall.SIGHUP)
 	go func() {
-		for {
-			select {
-			case sig := <-signalChan:
-				log.Infof("captured %s, exiting...\n", sig.String())
-				if nil != n {
-					n.Stop()
-				}
-				os.Exit(1)
+		for range reloadChan {
+			log.Info("captured hangup, reloading config...")
+			if _, err := n.Reload(); nil != err {
+				log.Errorf("failed to reload config, err:%s", err.Error())
 			}
 		}
 	}()
 
-	n = node.NewNode(logger, globalConfig)
-
-	unlockAccount(ctx, globalConfig)
-
 	n.Start()
 
 	log.Info("started")
 
-	n.Wait()
+	// the first signal shuts the node down gracefully, the second one exits at once
+	signalChan := make(chan os.Signal, 2)
+	signal.Notify(signalChan, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signalChan
+		timeout := time.Duration(globalConfig.ShutdownTimeout) * time.Second
+		log.Infof("captured %s, shutting down in %s...", sig.String(), timeout.String())
+		go func() {
+			sig := <-signalChan
+			log.Errorf("captured %s again, exiting...", sig.String())
+			os.Exit(1)
+		}()
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		n.Shutdown(ctx)
+	}()
+
+	if err := n.Wait(); nil != err {
+		log.Errorf("shutdown, err:%s", err.Error())
+		return err
+	}
+	log.Info("stopped")
 	return nil
 }
 
-func unlockAccount(ctx *cli.Context, globalConfig *config.GlobalConfig) {
+// it returns the passphrase sources used by admin api to unlock accounts at runtime
+func unlockAccount(ctx *cli.Context, globalConfig *config.GlobalConfig) passphrase.Sources {
+	if ("full" == globalConfig.Mode || "miner" == globalConfig.Mode) && crypto.SIGNER_REMOTE == globalConfig.Signer.Type {
+		checkRemoteAccounts(ctx, globalConfig)
+		return nil
+	}
 	if "full" == globalConfig.Mode || "miner" == globalConfig.Mode {
 		unlockAccs := []accounts.Account{}
 		minerAccs := []string{}
@@ -112,28 +141,98 @@ func unlockAccount(ctx *cli.Context, globalConfig *config.GlobalConfig) {
 			}
 		}
 
-		var passwords []string
-		if ctx.IsSet(utils.PasswordsFlag.Name) {
-			passwords = strings.Split(ctx.String(utils.PasswordsFlag.Name), ",")
-			if len(passwords) != len(unlockAccs) {
-				utils.ExitWithErr(ctx.App.Writer, errors.New("the count of passwords and unlocks not match "))
-			}
-		}
-		for idx, acc := range unlockAccs {
-			var passphrase string
-			if ctx.IsSet(utils.PasswordsFlag.Name) {
-				passphrase = passwords[idx]
-				if err := crypto.UnlockKSAccount(acc, passphrase); nil != err {
+		sources := passphraseSources(ctx, unlockAccs)
+		for _, acc := range unlockAccs {
+			if pass, err := sources.Passphrase(acc.Address); nil == err {
+				if err := crypto.UnlockKSAccount(acc, pass); nil != err {
 					if keystore.ErrNoMatch == err {
 						log.Fatalf("err:", err.Error())
 					} else {
 						utils.ExitWithErr(ctx.App.Writer, errors.New("failed to unlock address:"+acc.Address.Hex()))
 					}
 				}
-			} else {
+				log.Infof("Unlocked address:%s", acc.Address.Hex())
+			} else if passphrase.ErrNotFound == err {
 				unlockAccountFromTerminal(acc, ctx)
+			} else {
+				utils.ExitWithErr(ctx.App.Writer, fmt.Errorf("failed to get passphrase of address:%s, err:%s", acc.Address.Hex(), err.Error()))
+			}
+		}
+		return sources
+	}
+	return nil
+}
+
+// the passphrase is searched in password-file, passwords, credentials-dir and env in order,
+// it's read from terminal if none of them has it
+func passphraseSources(ctx *cli.Context, unlockAccs []accounts.Account) passphrase.Sources {
+	sources := passphrase.Sources{}
+	addresses := []common.Address{}
+	for _, acc := range unlockAccs {
+		addresses = append(addresses, acc.Address)
+	}
+	if ctx.IsSet(utils.PasswordFileFlag.Name) {
+		if source, err := passphrase.NewFileSource(ctx.String(utils.PasswordFileFlag.Name), addresses); nil != err {
+			utils.ExitWithErr(ctx.App.Writer, err)
+		} else {
+			sources = append(sources, source)
+		}
+	}
+	if ctx.IsSet(utils.PasswordsFlag.Name) {
+		log.Warnf("--%s is deprecated, the passwords are visible in the process list, use --%s, --%s or env instead", utils.PasswordsFlag.Name, utils.PasswordFileFlag.Name, utils.CredentialsDirFlag.Name)
+		passwords := strings.Split(ctx.String(utils.PasswordsFlag.Name), ",")
+		if len(passwords) != len(unlockAccs) {
+			utils.ExitWithErr(ctx.App.Writer, errors.New("the count of passwords and unlocks not match "))
+		}
+		source := passphrase.StaticSource{}
+		for idx, addr := range addresses {
+			source[addr] = passwords[idx]
+		}
+		sources = append(sources, source)
+	}
+	dir := passphrase.DefaultCredentialsDir()
+	if ctx.IsSet(utils.CredentialsDirFlag.Name) {
+		dir = ctx.String(utils.CredentialsDirFlag.Name)
+	}
+	if "" != dir {
+		if source, err := passphrase.NewDirSource(dir); nil != err {
+			utils.ExitWithErr(ctx.App.Writer, err)
+		} else {
+			sources = append(sources, source)
+		}
+	}
+	return append(sources, passphrase.NewEnvSource())
+}
+
+// the keys are kept by remote signer, the miners only need to be managed by it
+func checkRemoteAccounts(ctx *cli.Context, globalConfig *config.GlobalConfig) {
+	if ctx.IsSet(utils.UnlockFlag.Name) || ctx.IsSet(utils.PasswordsFlag.Name) || ctx.IsSet(utils.PasswordFileFlag.Name) || ctx.IsSet(utils.CredentialsDirFlag.Name) {
+		utils.ExitWithErr(ctx.App.Writer, errors.New("unlocks and passwords should be set to the remote signer"))
+	}
+	remoteAccs, err := crypto.RemoteAccounts()
+	if nil != err {
+		utils.ExitWithErr(ctx.App.Writer, fmt.Errorf("failed to list accounts of remote signer, err:%s", err.Error()))
+	}
+	minerAccs := []string{}
+	for _, addr := range globalConfig.Miner.NormalMiners {
+		minerAccs = append(minerAccs, addr.Address)
+	}
+	for _, addr := range globalConfig.Miner.PercentMiners {
+		minerAccs = append(minerAccs, addr.Address)
+	}
+	if len(minerAccs) <= 0 {
+		utils.ExitWithErr(ctx.App.Writer, fmt.Errorf("require a address as miner to sign and submit ring when running as miner"))
+	}
+	for _, addr := range minerAccs {
+		managed := false
+		for _, remoteAcc := range remoteAccs {
+			if common.HexToAddress(addr) == remoteAcc {
+				managed = true
 			}
 		}
+		if !managed {
+			utils.ExitWithErr(ctx.App.Writer, fmt.Errorf("the address:%s used to mine ring isn't managed by the remote signer", addr))
+		}
 	}
 }
 
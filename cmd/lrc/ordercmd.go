@@ -0,0 +1,335 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/Loopring/relay/cmd/utils"
+	"github.com/Loopring/relay/crypto"
+	"github.com/Loopring/relay/crypto/passphrase"
+	"github.com/Loopring/relay/gateway"
+	"github.com/Loopring/relay/types"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var signerFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "datadir",
+		Usage: "keystore of the owner",
+	},
+	cli.StringFlag{
+		Name:  "password-file",
+		Usage: "the file contains the password of owner, it must be only accessible by the user, otherwise it's read from credentials dir, env or terminal",
+	},
+}
+
+func orderCommands() cli.Command {
+	c := cli.Command{
+		Name:     "order",
+		Usage:    "submit, query and cancel orders",
+		Category: "client commands:",
+		Subcommands: []cli.Command{
+			cli.Command{
+				Name:   "submit",
+				Usage:  "sign an order with a keystore account and submit it",
+				Action: submitOrder,
+				Flags: clientFlags(append(signerFlags,
+					cli.StringFlag{Name: "owner", Usage: "address of owner"},
+					cli.StringFlag{Name: "token-s", Usage: "symbol or address of the token to sell"},
+					cli.StringFlag{Name: "token-b", Usage: "symbol or address of the token to buy"},
+					cli.StringFlag{Name: "amount-s", Usage: "amount to sell in token unit, such as 1.5"},
+					cli.StringFlag{Name: "amount-b", Usage: "amount to buy in token unit"},
+					cli.StringFlag{Name: "lrc-fee", Usage: "lrc fee in token unit", Value: "0"},
+					cli.IntFlag{Name: "margin-split", Usage: "margin split percentage, 0 means 100", Value: 50},
+					cli.BoolFlag{Name: "buy-no-more-than-b", Usage: "don't buy more than amount-b"},
+					cli.Int64Flag{Name: "valid-since", Usage: "unix timestamp, default is now"},
+					cli.Int64Flag{Name: "ttl", Usage: "seconds the order is valid for", Value: 86400},
+					cli.StringFlag{Name: "wallet", Usage: "wallet address to share the margin split"},
+					cli.StringFlag{Name: "delegate", Usage: "delegate address, default is the first one of relay"},
+					cli.StringFlag{Name: "pow-difficulty", Usage: "pow difficulty in hex, default is the one of relay"},
+					cli.DurationFlag{Name: "pow-timeout", Usage: "give up searching the pow nonce after it", Value: 5 * time.Minute},
+				)...),
+			},
+			cli.Command{
+				Name:   "get",
+				Usage:  "show an order",
+				Action: getOrder,
+				Flags: clientFlags(
+					cli.StringFlag{Name: "hash", Usage: "hash of order"},
+				),
+			},
+			cli.Command{
+				Name:   "list",
+				Usage:  "list orders",
+				Action: listOrders,
+				Flags: clientFlags(
+					cli.StringFlag{Name: "owner", Usage: "address of owner"},
+					cli.StringFlag{Name: "market", Usage: "market like LRC-WETH"},
+					cli.StringFlag{Name: "status", Usage: "ORDER_OPENED, ORDER_FINISHED, ORDER_CANCELLED or ORDER_EXPIRE"},
+					cli.StringFlag{Name: "side", Usage: "buy or sell"},
+					cli.IntFlag{Name: "page", Usage: "page index", Value: 1},
+					cli.IntFlag{Name: "size", Usage: "page size", Value: 20},
+				),
+			},
+			cli.Command{
+				Name:   "cancel",
+				Usage:  "cancel an order in relay with the signature of owner, it's still valid on chain",
+				Action: cancelOrder,
+				Flags: clientFlags(append(signerFlags,
+					cli.StringFlag{Name: "hash", Usage: "hash of order"},
+				)...),
+			},
+		},
+	}
+	return c
+}
+
+func submitOrder(ctx *cli.Context) {
+	owner := requireAddress(ctx, "owner")
+	tokens := relayTokens(ctx)
+	tokenS := requireToken(ctx, tokens, "token-s")
+	tokenB := requireToken(ctx, tokens, "token-b")
+	amountS, err := parseAmount(requireString(ctx, "amount-s"), tokenS.Decimals)
+	if nil != err {
+		utils.ExitWithErr(ctx.App.Writer, err)
+	}
+	amountB, err := parseAmount(requireString(ctx, "amount-b"), tokenB.Decimals)
+	if nil != err {
+		utils.ExitWithErr(ctx.App.Writer, err)
+	}
+	lrcFee := big.NewInt(0)
+	if lrc, ok := tokens["LRC"]; ok {
+		if lrcFee, err = parseAmount(ctx.String("lrc-fee"), lrc.Decimals); nil != err {
+			utils.ExitWithErr(ctx.App.Writer, err)
+		}
+	} else if "0" != ctx.String("lrc-fee") {
+		utils.ExitWithErr(ctx.App.Writer, errors.New("LRC isn't supported by relay"))
+	}
+	marginSplit := ctx.Int("margin-split")
+	if marginSplit < 0 || marginSplit > 100 {
+		utils.ExitWithErr(ctx.App.Writer, errors.New("margin-split should be in [0, 100]"))
+	}
+	validSince := time.Now().Unix()
+	if ctx.IsSet("valid-since") {
+		validSince = ctx.Int64("valid-since")
+	}
+	var wallet common.Address
+	if ctx.IsSet("wallet") {
+		wallet = requireAddress(ctx, "wallet")
+	}
+	delegate, protocol := defaultDelegate(ctx)
+
+	// the auth key is only used to sign the ring including this order by the wallet
+	authKey, err := ethCrypto.GenerateKey()
+	if nil != err {
+		utils.ExitWithErr(ctx.App.Writer, err)
+	}
+	authPrivateKey, err := crypto.NewPrivateKeyCrypto(true, common.ToHex(ethCrypto.FromECDSA(authKey)))
+	if nil != err {
+		utils.ExitWithErr(ctx.App.Writer, err)
+	}
+
+	order := &types.Order{
+		Protocol:              protocol,
+		DelegateAddress:       delegate,
+		AuthAddr:              authPrivateKey.Address(),
+		AuthPrivateKey:        authPrivateKey,
+		WalletAddress:         wallet,
+		TokenS:                tokenS.Protocol,
+		TokenB:                tokenB.Protocol,
+		AmountS:               amountS,
+		AmountB:               amountB,
+		ValidSince:            big.NewInt(validSince),
+		ValidUntil:            big.NewInt(validSince + ctx.Int64("ttl")),
+		LrcFee:                lrcFee,
+		BuyNoMoreThanAmountB:  ctx.Bool("buy-no-more-than-b"),
+		MarginSplitPercentage: uint8(marginSplit),
+		Owner:                 owner,
+		OrderType:             types.ORDER_TYPE_MARKET,
+	}
+	order.Hash = order.GenerateHash()
+	unlockOwner(ctx, owner)
+	if err := order.GenerateAndSetSignature(owner); nil != err {
+		utils.ExitWithErr(ctx.App.Writer, err)
+	}
+	difficulty := ctx.String("pow-difficulty")
+	if "" == difficulty {
+		callRelay(ctx, &difficulty, "loopring_getPowDifficulty")
+	}
+	searchCtx, cancel := context.WithTimeout(context.Background(), ctx.Duration("pow-timeout"))
+	defer cancel()
+	powNonce, err := gateway.SearchPowNonce(searchCtx, order.V, order.R, order.S, types.HexToBigint(difficulty))
+	if nil != err {
+		utils.ExitWithErr(ctx.App.Writer, err)
+	}
+	order.PowNonce = powNonce
+
+	var orderHash string
+	callRelay(ctx, &orderHash, "loopring_submitOrder", types.ToOrderJsonRequest(order))
+	if isJsonFormat(ctx) {
+		printJson(ctx, map[string]string{"orderHash": orderHash})
+	} else {
+		fmt.Fprintln(ctx.App.Writer, orderHash)
+	}
+}
+
+func getOrder(ctx *cli.Context) {
+	var order gateway.OrderJsonResult
+	callRelay(ctx, &order, "loopring_getOrderByHash", gateway.OrderQuery{OrderHash: requireString(ctx, "hash")})
+	if isJsonFormat(ctx) {
+		printJson(ctx, order)
+		return
+	}
+	printOrders(ctx, []gateway.OrderJsonResult{order})
+}
+
+type orderPage struct {
+	Data      []gateway.OrderJsonResult `json:"data"`
+	PageIndex int                       `json:"pageIndex"`
+	PageSize  int                       `json:"pageSize"`
+	Total     int                       `json:"total"`
+}
+
+func listOrders(ctx *cli.Context) {
+	query := &gateway.OrderQuery{
+		Owner:     ctx.String("owner"),
+		Market:    ctx.String("market"),
+		Status:    ctx.String("status"),
+		Side:      ctx.String("side"),
+		PageIndex: ctx.Int("page"),
+		PageSize:  ctx.Int("size"),
+	}
+	var page orderPage
+	callRelay(ctx, &page, "loopring_getOrders", query)
+	if isJsonFormat(ctx) {
+		printJson(ctx, page)
+		return
+	}
+	printOrders(ctx, page.Data)
+	fmt.Fprintf(ctx.App.Writer, "page %d, %d of %d orders\n", page.PageIndex, len(page.Data), page.Total)
+}
+
+func printOrders(ctx *cli.Context, orders []gateway.OrderJsonResult) {
+	tokens := relayTokens(ctx)
+	w := newTable(ctx, "HASH", "OWNER", "MARKET", "SIDE", "STATUS", "AMOUNT_S", "AMOUNT_B", "DEALT_S", "DEALT_B", "LRC_FEE", "VALID_UNTIL")
+	for _, o := range orders {
+		tokenS := tokens[strings.ToUpper(o.RawOrder.TokenS)]
+		tokenB := tokens[strings.ToUpper(o.RawOrder.TokenB)]
+		printRow(w, o.RawOrder.Hash, o.RawOrder.Owner, o.RawOrder.Market, o.RawOrder.Side, o.Status,
+			formatAmount(o.RawOrder.AmountS, tokenS)+" "+o.RawOrder.TokenS,
+			formatAmount(o.RawOrder.AmountB, tokenB)+" "+o.RawOrder.TokenB,
+			formatAmount(o.DealtAmountS, tokenS), formatAmount(o.DealtAmountB, tokenB),
+			formatAmount(o.RawOrder.LrcFee, tokens["LRC"]), formatTimestamp(o.RawOrder.ValidUntil))
+	}
+	w.Flush()
+}
+
+func cancelOrder(ctx *cli.Context) {
+	var order gateway.OrderJsonResult
+	callRelay(ctx, &order, "loopring_getOrderByHash", gateway.OrderQuery{OrderHash: requireString(ctx, "hash")})
+	owner := common.HexToAddress(order.RawOrder.Owner)
+	orderHash := common.HexToHash(order.RawOrder.Hash)
+
+	unlockOwner(ctx, owner)
+	query := gateway.CancelOrderQuery{OrderHash: orderHash.Hex(), Timestamp: time.Now().Unix()}
+	sig, err := crypto.Sign(types.CancelOrderHash(orderHash, query.Timestamp), owner)
+	if nil != err {
+		utils.ExitWithErr(ctx.App.Writer, err)
+	}
+	v, r, s := crypto.SigToVRS(sig)
+	query.V, query.R, query.S = uint8(v), types.BytesToBytes32(r), types.BytesToBytes32(s)
+
+	var res string
+	callRelay(ctx, &res, "loopring_cancelOrder", query)
+	if isJsonFormat(ctx) {
+		printJson(ctx, map[string]string{"orderHash": res})
+	} else {
+		fmt.Fprintln(ctx.App.Writer, "cancelled", res)
+	}
+}
+
+func requireToken(ctx *cli.Context, tokens map[string]types.Token, name string) types.Token {
+	v := requireString(ctx, name)
+	if common.IsHexAddress(v) {
+		for _, token := range tokens {
+			if token.Protocol == common.HexToAddress(v) {
+				return token
+			}
+		}
+	} else if token, ok := tokens[strings.ToUpper(v)]; ok {
+		return token
+	}
+	utils.ExitWithErr(ctx.App.Writer, fmt.Errorf("%s:%s isn't supported by relay", name, v))
+	return types.Token{}
+}
+
+// unlockOwner unlocks the owner in keystore to sign, the passphrase is read from
+// --password-file, the credentials directory, env or terminal in order
+func unlockOwner(ctx *cli.Context, owner common.Address) {
+	ks := keystore.NewKeyStore(requireString(ctx, "datadir"), keystore.StandardScryptN, keystore.StandardScryptP)
+	crypto.Initialize(crypto.NewKSCrypto(true, ks))
+	acc := accounts.Account{Address: owner}
+
+	sources := passphrase.Sources{}
+	if file := ctx.String("password-file"); "" != file {
+		source, err := passphrase.NewFileSource(file, []common.Address{owner})
+		if nil != err {
+			utils.ExitWithErr(ctx.App.Writer, err)
+		}
+		sources = append(sources, source)
+	}
+	if dir := passphrase.DefaultCredentialsDir(); "" != dir {
+		if source, err := passphrase.NewDirSource(dir); nil == err {
+			sources = append(sources, source)
+		}
+	}
+	sources = append(sources, passphrase.NewEnvSource())
+
+	pass, err := sources.Passphrase(owner)
+	if passphrase.ErrNotFound == err {
+		pass, err = getPassphraseFromTeminal(false, ctx.App.Writer)
+	}
+	if nil != err {
+		utils.ExitWithErr(ctx.App.Writer, err)
+	}
+	if err := crypto.UnlockKSAccount(acc, pass); nil != err {
+		utils.ExitWithErr(ctx.App.Writer, fmt.Errorf("failed to unlock address:%s, err:%s", owner.Hex(), err.Error()))
+	}
+}
+
+func formatTimestamp(v string) string {
+	timestamp := types.HexToBigint(v)
+	if !strings.HasPrefix(v, "0x") {
+		timestamp, _ = new(big.Int).SetString(v, 10)
+	}
+	if nil == timestamp || timestamp.Sign() <= 0 {
+		return v
+	}
+	return time.Unix(timestamp.Int64(), 0).Format("2006-01-02 15:04:05")
+}
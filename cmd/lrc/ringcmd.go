@@ -0,0 +1,118 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/Loopring/relay/cmd/utils"
+	"github.com/Loopring/relay/dao"
+	"github.com/Loopring/relay/gateway"
+	"github.com/Loopring/relay/types"
+	"gopkg.in/urfave/cli.v1"
+)
+
+func ringCommands() cli.Command {
+	c := cli.Command{
+		Name:     "ring",
+		Usage:    "query the mined rings",
+		Category: "client commands:",
+		Subcommands: []cli.Command{
+			cli.Command{
+				Name:   "list",
+				Usage:  "list the mined rings",
+				Action: listRings,
+				Flags: clientFlags(
+					cli.StringFlag{Name: "delegate", Usage: "delegate address, default is all"},
+					cli.IntFlag{Name: "page", Usage: "page index", Value: 1},
+					cli.IntFlag{Name: "size", Usage: "page size, at most 20", Value: 20},
+				),
+			},
+			cli.Command{
+				Name:   "show",
+				Usage:  "show a mined ring and its fills",
+				Action: showRing,
+				Flags: clientFlags(
+					cli.StringFlag{Name: "index", Usage: "ring index, decimal or hex"},
+					cli.StringFlag{Name: "delegate", Usage: "delegate address, default is all"},
+				),
+			},
+		},
+	}
+	return c
+}
+
+type ringPage struct {
+	Data      []dao.RingMinedEvent `json:"data"`
+	PageIndex int                  `json:"pageIndex"`
+	PageSize  int                  `json:"pageSize"`
+	Total     int                  `json:"total"`
+}
+
+func listRings(ctx *cli.Context) {
+	query := gateway.RingMinedQuery{DelegateAddress: ctx.String("delegate"), PageIndex: ctx.Int("page"), PageSize: ctx.Int("size")}
+	var page ringPage
+	callRelay(ctx, &page, "loopring_getRingMined", query)
+	if isJsonFormat(ctx) {
+		printJson(ctx, page)
+		return
+	}
+	lrc := relayTokens(ctx)["LRC"]
+	w := newTable(ctx, "RING_INDEX", "RING_HASH", "MINER", "TX_HASH", "BLOCK", "TRADES", "LRC_FEE", "TIME")
+	for _, r := range page.Data {
+		printRow(w, r.RingIndex, r.RingHash, r.Miner, r.TxHash, r.BlockNumber, r.TradeAmount, formatAmount(r.TotalLrcFee, lrc), time.Unix(r.Time, 0).Format("2006-01-02 15:04:05"))
+	}
+	w.Flush()
+	fmt.Fprintf(ctx.App.Writer, "page %d, %d of %d rings\n", page.PageIndex, len(page.Data), page.Total)
+}
+
+func showRing(ctx *cli.Context) {
+	index := requireString(ctx, "index")
+	ringIndex, ok := new(big.Int).SetString(index, 10)
+	if strings.HasPrefix(index, "0x") {
+		ringIndex, ok = new(big.Int).SetString(strings.TrimPrefix(index, "0x"), 16)
+	}
+	if !ok {
+		utils.ExitWithErr(ctx.App.Writer, fmt.Errorf("invalid ring index:%s", index))
+	}
+	var detail gateway.RingMinedDetail
+	callRelay(ctx, &detail, "loopring_getRingMinedDetail", gateway.RingMinedQuery{DelegateAddress: ctx.String("delegate"), RingIndex: types.BigintToHex(ringIndex)})
+	if isJsonFormat(ctx) {
+		printJson(ctx, detail)
+		return
+	}
+	tokens := relayTokens(ctx)
+	info := detail.RingInfo
+	w := newTable(ctx, "RING_INDEX", "RING_HASH", "MINER", "FEE_RECIPIENT", "TX_HASH", "BLOCK", "LRC_FEE", "TIME")
+	printRow(w, info.RingIndex, info.RingHash, info.Miner, info.FeeRecipient, info.TxHash, info.BlockNumber, formatAmount(info.TotalLrcFee, tokens["LRC"]), time.Unix(info.Time, 0).Format("2006-01-02 15:04:05"))
+	w.Flush()
+	fmt.Fprintln(ctx.App.Writer)
+	w = newTable(ctx, "FILL", "ORDER_HASH", "OWNER", "SIDE", "AMOUNT_S", "AMOUNT_B", "LRC_FEE", "SPLIT_S", "SPLIT_B")
+	for _, f := range detail.Fills {
+		tokenS := tokens[strings.ToUpper(f.TokenS)]
+		tokenB := tokens[strings.ToUpper(f.TokenB)]
+		printRow(w, f.FillIndex, f.OrderHash, f.Owner, f.Side,
+			formatAmount(f.AmountS, tokenS)+" "+f.TokenS, formatAmount(f.AmountB, tokenB)+" "+f.TokenB,
+			formatAmount(f.LrcFee, tokens["LRC"]), formatAmount(f.SplitS, tokenS), formatAmount(f.SplitB, tokenB))
+	}
+	w.Flush()
+}
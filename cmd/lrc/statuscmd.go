@@ -0,0 +1,86 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Loopring/relay/node"
+	"gopkg.in/urfave/cli.v1"
+)
+
+func nodeCommands() cli.Command {
+	c := cli.Command{
+		Name:     "node",
+		Usage:    "inspect the running relay",
+		Category: "admin commands:",
+		Subcommands: []cli.Command{
+			cli.Command{
+				Name:   "status",
+				Usage:  "show version, mode, uptime and miners of relay",
+				Action: showNodeStatus,
+				Flags:  adminFlags(),
+			},
+			cli.Command{
+				Name:   "reload",
+				Usage:  "reload the config of relay, the same as sending SIGHUP to it",
+				Action: reloadNode,
+				Flags:  adminFlags(),
+			},
+		},
+	}
+	return c
+}
+
+func showNodeStatus(ctx *cli.Context) {
+	var status node.NodeStatus
+	callRelay(ctx, &status, "node_status")
+	if isJsonFormat(ctx) {
+		printJson(ctx, status)
+		return
+	}
+	fmt.Fprintf(ctx.App.Writer, "version:  %s\nmode:     %s\nuptime:   %s\nmarkets:  %d\n", status.Version, status.Mode, time.Duration(status.Uptime)*time.Second, len(status.Markets))
+	if len(status.Miners) > 0 {
+		fmt.Fprintln(ctx.App.Writer)
+		w := newTable(ctx, "MINER", "ROLE", "CAN_SIGN")
+		for _, m := range status.Miners {
+			printRow(w, m.Address.Hex(), m.Role, m.CanSign)
+		}
+		w.Flush()
+	}
+}
+
+func reloadNode(ctx *cli.Context) {
+	var result node.ReloadResult
+	callRelay(ctx, &result, "node_reload")
+	if isJsonFormat(ctx) {
+		printJson(ctx, result)
+		return
+	}
+	for _, key := range result.Applied {
+		fmt.Fprintf(ctx.App.Writer, "applied:           %s\n", key)
+	}
+	for _, key := range result.RestartRequired {
+		fmt.Fprintf(ctx.App.Writer, "restart required:  %s\n", key)
+	}
+	if 0 == len(result.Applied)+len(result.RestartRequired) {
+		fmt.Fprintln(ctx.App.Writer, "no option changed, token list reloaded")
+	}
+}
@@ -0,0 +1,84 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Loopring/relay/types"
+	"gopkg.in/urfave/cli.v1"
+)
+
+func whitelistCommands() cli.Command {
+	c := cli.Command{
+		Name:     "whitelist",
+		Usage:    "manage the owners allowed to submit orders when the white list is open",
+		Category: "admin commands:",
+		Subcommands: []cli.Command{
+			cli.Command{
+				Name:   "add",
+				Usage:  "add an owner to white list",
+				Action: addWhiteListUser,
+				Flags:  adminFlags(cli.StringFlag{Name: "owner", Usage: "address of owner"}),
+			},
+			cli.Command{
+				Name:   "remove",
+				Usage:  "remove an owner from white list",
+				Action: removeWhiteListUser,
+				Flags:  adminFlags(cli.StringFlag{Name: "owner", Usage: "address of owner"}),
+			},
+			cli.Command{
+				Name:   "list",
+				Usage:  "list the owners in white list",
+				Action: listWhiteListUsers,
+				Flags:  adminFlags(),
+			},
+		},
+	}
+	return c
+}
+
+func addWhiteListUser(ctx *cli.Context) {
+	owner := requireAddress(ctx, "owner")
+	var res interface{}
+	callRelay(ctx, &res, "whitelist_add", owner)
+	fmt.Fprintln(ctx.App.Writer, "added", owner.Hex())
+}
+
+func removeWhiteListUser(ctx *cli.Context) {
+	owner := requireAddress(ctx, "owner")
+	var res interface{}
+	callRelay(ctx, &res, "whitelist_remove", owner)
+	fmt.Fprintln(ctx.App.Writer, "removed", owner.Hex())
+}
+
+func listWhiteListUsers(ctx *cli.Context) {
+	var users []types.WhiteListUser
+	callRelay(ctx, &users, "whitelist_list")
+	if isJsonFormat(ctx) {
+		printJson(ctx, users)
+		return
+	}
+	w := newTable(ctx, "OWNER", "CREATE_TIME")
+	for _, u := range users {
+		printRow(w, u.Owner.Hex(), time.Unix(u.CreateTime, 0).Format("2006-01-02 15:04:05"))
+	}
+	w.Flush()
+}
@@ -0,0 +1,169 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/Loopring/relay/cmd/utils"
+	"github.com/Loopring/relay/log"
+	"github.com/Loopring/relay/signer"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	"golang.org/x/crypto/ssh/terminal"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// signer is a reference remote signer of relay, it keeps the keys of miners out of relay.
+// It serves account_list, account_signData and account_signTransaction on a unix socket or http,
+// and only signs what its policy allows.
+func main() {
+	app := utils.NewApp()
+	app.Usage = "the remote signer of Loopring/relay"
+	app.Action = startSigner
+	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "config,c",
+			Usage: "config file",
+		},
+		cli.StringFlag{
+			Name:  "password-file",
+			Usage: "the file contains passwords to unlock accounts, one per line, prompt from terminal if not set",
+		},
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func startSigner(ctx *cli.Context) error {
+	c, err := signer.LoadConfig(ctx.String("config"))
+	if nil != err {
+		utils.ExitWithErr(ctx.App.Writer, err)
+	}
+	logger := log.Initialize(c.Log)
+	defer logger.Sync()
+
+	policy, err := signer.NewPolicy(c.Policy)
+	if nil != err {
+		utils.ExitWithErr(ctx.App.Writer, err)
+	}
+
+	ks := keystore.NewKeyStore(c.Keydir, keystore.StandardScryptN, keystore.StandardScryptP)
+	unlockAccounts(ctx, ks, c.Unlocks)
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("account", signer.NewSignerApi(ks, policy)); nil != err {
+		utils.ExitWithErr(ctx.App.Writer, err)
+	}
+
+	if "" == c.Ipc && "" == c.HttpPort {
+		utils.ExitWithErr(ctx.App.Writer, errors.New("neither ipc nor http_port is configured"))
+	}
+	if "" != c.Ipc {
+		listener, err := rpc.CreateIPCListener(c.Ipc)
+		if nil != err {
+			utils.ExitWithErr(ctx.App.Writer, err)
+		}
+		go server.ServeListener(listener)
+		log.Infof("signer endpoint opened on %s", c.Ipc)
+	}
+	if "" != c.HttpPort {
+		listener, err := net.Listen("tcp", c.HttpHost+":"+c.HttpPort)
+		if nil != err {
+			utils.ExitWithErr(ctx.App.Writer, err)
+		}
+		go rpc.NewHTTPServer([]string{}, server).Serve(listener)
+		log.Infof("signer endpoint opened on http://%s", listener.Addr().String())
+	}
+
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, os.Interrupt, syscall.SIGTERM)
+	sig := <-signalChan
+	log.Infof("captured %s, exiting...", sig.String())
+	server.Stop()
+	if "" != c.Ipc {
+		os.Remove(c.Ipc)
+	}
+	return nil
+}
+
+func unlockAccounts(ctx *cli.Context, ks *keystore.KeyStore, unlocks []string) {
+	var passwords []string
+	if file := ctx.String("password-file"); "" != file {
+		var err error
+		if passwords, err = readPasswords(file); nil != err {
+			utils.ExitWithErr(ctx.App.Writer, err)
+		}
+		if len(passwords) != len(unlocks) {
+			utils.ExitWithErr(ctx.App.Writer, errors.New("the count of passwords and unlocks not match"))
+		}
+	}
+
+	for idx, addr := range unlocks {
+		if !common.IsHexAddress(addr) {
+			utils.ExitWithErr(ctx.App.Writer, errors.New(addr+" is not a HexAddress"))
+		}
+		acc := accounts.Account{Address: common.HexToAddress(addr)}
+		var passphrase string
+		if len(passwords) > 0 {
+			passphrase = passwords[idx]
+		} else {
+			fmt.Fprintf(ctx.App.Writer, "Unlocking account %s, enter passphrase:", acc.Address.Hex())
+			input, err := terminal.ReadPassword(int(syscall.Stdin))
+			fmt.Fprint(ctx.App.Writer, "\n")
+			if nil != err {
+				utils.ExitWithErr(ctx.App.Writer, err)
+			}
+			passphrase = string(input)
+		}
+		if err := ks.Unlock(acc, passphrase); nil != err {
+			utils.ExitWithErr(ctx.App.Writer, fmt.Errorf("failed to unlock address:%s, err:%s", acc.Address.Hex(), err.Error()))
+		}
+		log.Infof("unlocked address:%s", acc.Address.Hex())
+	}
+}
+
+func readPasswords(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if nil != err {
+		return nil, err
+	}
+	defer f.Close()
+
+	passwords := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimRight(scanner.Text(), "\r"); "" != line {
+			passwords = append(passwords, line)
+		}
+	}
+	return passwords, scanner.Err()
+}
@@ -19,13 +19,21 @@
 package utils
 
 import (
-	"reflect"
+	"os"
 
 	"github.com/Loopring/relay/config"
 	"gopkg.in/urfave/cli.v1"
 )
 
 var (
+	ConfigFlag = cli.StringFlag{
+		Name:  "config,c",
+		Usage: "config file",
+	}
+	SetFlag = cli.StringSliceFlag{
+		Name:  "set",
+		Usage: "override an option of config like miner.timing_matcher.duration=10000, it can be repeated and overrides RELAY_* env",
+	}
 	ModeFlag = cli.StringFlag{
 		Name:  "mode",
 		Usage: "the mode that will be run, it can be set by relay, miner or full",
@@ -36,19 +44,27 @@ var (
 	}
 	PasswordsFlag = cli.StringFlag{
 		Name:  "passwords",
-		Usage: "the file contains passwords used to unlock accounts ",
+		Usage: "deprecated, the comma separated passwords used to unlock accounts, they are visible in the process list",
+	}
+	PasswordFileFlag = cli.StringFlag{
+		Name:  "password-file",
+		Usage: "the file contains passwords used to unlock accounts, one per line in the order of unlocks, it must be only accessible by the owner",
+	}
+	CredentialsDirFlag = cli.StringFlag{
+		Name:  "credentials-dir",
+		Usage: "the directory contains a file named by each address with its password, default is $CREDENTIALS_DIRECTORY or /run/secrets",
 	}
 )
 
 func GlobalFlags() []cli.Flag {
 	return []cli.Flag{
-		cli.StringFlag{
-			Name:  "config,c",
-			Usage: "config file",
-		},
+		ConfigFlag,
+		SetFlag,
 		ModeFlag,
 		UnlockFlag,
 		PasswordsFlag,
+		PasswordFileFlag,
+		CredentialsDirFlag,
 	}
 }
 
@@ -73,30 +89,36 @@ func mergeMinerConfig(ctx *cli.Context, minerOpts *config.MinerOptions) {
 	if ctx.IsSet("ringMaxLength") {
 		minerOpts.RingMaxLength = ctx.Int("ringMaxLength")
 	}
-	minerOpts.WalletSplit = float64(0.8)
 }
 
 func mergeModeConfig(ctx *cli.Context, globalConfig *config.GlobalConfig) {
 	if ctx.IsSet(ModeFlag.Name) {
 		globalConfig.Mode = ctx.String(ModeFlag.Name)
-	} else {
-		globalConfig.Mode = "full"
 	}
 }
 
+// SetGlobalConfig loads the config by layers: the defaults, the file, its includes, RELAY_* env,
+// --set and at last the flags of options like --mode, it exits if the config is invalid
 func SetGlobalConfig(ctx *cli.Context) *config.GlobalConfig {
-	file := ""
-	if ctx.IsSet("config") {
-		file = ctx.String("config")
+	globalConfig, err := LoadGlobalConfig(ctx)
+	if nil == err {
+		err = config.Validate(globalConfig)
+	}
+	if nil != err {
+		ExitWithErr(ctx.App.Writer, err)
+	}
+	return globalConfig
+}
+
+// LoadGlobalConfig loads the config as SetGlobalConfig without validating it
+func LoadGlobalConfig(ctx *cli.Context) (*config.GlobalConfig, error) {
+	globalConfig, err := config.Load(ctx.String("config"), os.Environ(), ctx.StringSlice(SetFlag.Name))
+	if nil != err {
+		return nil, err
 	}
-	globalConfig := config.LoadConfig(file)
 	mergeMinerConfig(ctx, &globalConfig.Miner)
 
 	mergeModeConfig(ctx, globalConfig)
 
-	if _, err := config.Validator(reflect.ValueOf(globalConfig).Elem()); nil != err {
-		panic(err)
-	}
-
-	return globalConfig
+	return globalConfig, nil
 }
@@ -19,61 +19,37 @@
 package config
 
 import (
-	"errors"
 	"math/big"
-	"os"
 	"reflect"
 	"strconv"
 	"strings"
 
-	"github.com/naoina/toml"
 	"go.uber.org/zap"
 )
 
+// LoadConfig loads the file and its includes, it panics on any error
 func LoadConfig(file string) *GlobalConfig {
-	if "" == file {
-		dir, _ := os.Getwd()
-		file = dir + "/config/relay.toml"
-	}
-
-	io, err := os.Open(file)
-	if err != nil {
-		panic(err)
-	}
-	defer io.Close()
-
-	c := &GlobalConfig{}
-	c.defaultConfig()
-	if err := toml.NewDecoder(io).Decode(c); err != nil {
+	c, err := Load(file, nil, nil)
+	if nil != err {
 		panic(err)
 	}
-
-	//if c.Common.Develop {
-	//	basedir := strings.TrimSuffix(os.Getenv("GOPATH"), "/") + "/src/github.com/Loopring/relay/"
-	//	c.Keystore.Keydir = basedir + c.Keystore.Keydir
-	//
-	//	for idx, path := range c.Log.ZapOpts.OutputPaths {
-	//		if !strings.HasPrefix(path, "std") {
-	//			c.Log.ZapOpts.OutputPaths[idx] = basedir + path
-	//		}
-	//	}
-	//}
-
-	// extractor.IsDevNet default false
-
 	return c
 }
 
 type GlobalConfig struct {
-	Title string `required:"true"`
-	Mode  string `required:"true"`
-	Owner struct {
+	Title           string   `required:"true"`
+	Mode            string   `required:"true"`
+	Include         []string //files override the options of this one, relative to its dir
+	ShutdownTimeout int64    //seconds to drain the subsystems after SIGINT or SIGTERM, default:30
+	Owner           struct {
 		Name string
 	}
 	Mysql          MysqlOptions
 	Redis          RedisOptions
 	Ipfs           IpfsOptions
 	Jsonrpc        JsonrpcOptions
+	Rest           RestOptions
+	Admin          AdminOptions
 	Websocket      WebsocketOptions
 	GatewayFilters GatewayFiltersOptions
 	OrderManager   OrderManagerOptions
@@ -84,6 +60,7 @@ type GlobalConfig struct {
 	Miner          MinerOptions
 	Log            LogOptions
 	Keystore       KeyStoreOptions
+	Signer         SignerOptions
 	Market         MarketOptions
 	MarketCap      MarketCapOptions
 	UserManager    UserManagerOptions
@@ -98,18 +75,34 @@ type JsonrpcOptions struct {
 	Port string
 }
 
+type RestOptions struct {
+	Port        string
+	CacheMaxAge int
+}
+
+type AdminOptions struct {
+	Host string
+	Port string
+}
+
 type WebsocketOptions struct {
 	Port string
 }
 
 func (c *GlobalConfig) defaultConfig() {
-
+	c.Mode = "full"
+	c.Miner.WalletSplit = 0.8
+	c.Extractor.MaxSyncLag = 10
+	c.Extractor.MaxStallTime = 300
+	c.ShutdownTimeout = 30
 }
 
 type OrderManagerOptions struct {
 	CutoffCacheExpireTime int64
 	CutoffCacheCleanTime  int64
 	DustOrderValue        int64
+	MinerOrderPriority    string //price(default), lrc_fee, margin_split or age
+	MinerOrderPoolSize    int    //orders loaded for ranking by the priority, default:4 times of round_orders_count
 }
 
 type IpfsOptions struct {
@@ -128,7 +121,7 @@ func (opts IpfsOptions) Url() string {
 }
 
 type AccessorOptions struct {
-	RawUrls           []string `required:"true"`
+	RawUrls           []string `required:"true" secret:"url"`
 	FetchTxRetryCount int
 }
 
@@ -139,12 +132,22 @@ type ExtractorOptions struct {
 	ForkWaitingTime    int64
 	Debug              bool
 	Open               bool
+	MaxSyncLag         uint64 //the relay is ready when the extractor is within max_sync_lag blocks of the head, default:10
+	MaxStallTime       int64  //seconds, the extractor is down if it's behind the head and no block is got in max_stall_time, default:300
+}
+
+// miner keys are kept by an external signer when type is "remote",
+// endpoint is the ipc path or http url of it
+type SignerOptions struct {
+	Type     string //keystore(default) or remote
+	Endpoint string
 }
 
 type KeyStoreOptions struct {
-	Keydir  string
-	ScryptN int
-	ScryptP int
+	Keydir        string
+	ScryptN       int
+	ScryptP       int
+	RelockTimeout int64 // seconds, the default timeout of accounts unlocked by admin api, 0 means never
 }
 
 type ProtocolOptions struct {
@@ -173,6 +176,19 @@ type TimingMatcher struct {
 	DelayedNumber                int64
 	MaxCacheRoundsLength         int
 	LagForCleanSubmitCacheBlocks int64
+	RingSelector                 string            //greedy(default), max_total or fairness
+	MarketRingSelectors          map[string]string //selector of market, e.g. "LRC-WETH" = "max_total"
+	MatchingStatusTtl            int64             //seconds that the skip reason of an order is kept, default:3600
+	SelfTradePrevention          SelfTradePreventionOptions
+	MaxUnevaluatedRounds         int64   //every live order is evaluated at least once in the rounds, 0 means no guarantee
+	StarvedOrdersRatio           float64 //max ratio of round_orders_count taken by the orders not evaluated in max_unevaluated_rounds, default:0.5
+}
+
+// orders of the same owner are always prevented from matching each other since the contract can't settle them
+type SelfTradePreventionOptions struct {
+	Mode       string //skip(default) or cancel_newer
+	SameAuth   bool   //orders signed by the same auth address
+	OwnerGroup bool   //owners linked in the owner_group table
 }
 
 type PercentMinerAddress struct {
@@ -200,6 +216,40 @@ type MinerOptions struct {
 	MinGasLimit           int64
 	MaxGasLimit           int64
 	FeeReceipt            string
+	GasModel              GasModelOptions
+	Simulation            RingSimulationOptions
+	Batch                 BatchSubmitOptions
+	BalanceGuard          BalanceGuardOptions
+}
+
+type BalanceGuardOptions struct {
+	Interval           int     //seconds between two checks
+	MinerMinEth        float64 //in ether, of each normal and percent miner address, 0 means not checked
+	MinerMinLrc        float64 //in LRC, of each normal and percent miner address, 0 means not checked
+	FeeRecipientMinLrc float64 //in LRC, the margin split isn't selected while the fee recipient is lower
+}
+
+type BatchSubmitOptions struct {
+	Enabled       bool
+	HelperAddress string //the multicall helper contract that forwards each ring to the protocol
+	HelperAbi     string
+	Method        string //default:submitRings(address protocol, bytes data, uint256[] lengths)
+	MaxRings      int
+	MaxGas        int64
+}
+
+type RingSimulationOptions struct {
+	Enabled       bool  //eth_call each ring against the pending block before submitting
+	QuarantineTtl int64 //seconds that a ring failed in simulation won't be matched again
+}
+
+type GasModelOptions struct {
+	HistorySize     int     //samples kept for each ring length and fee selection
+	MinSamples      int     //the default gas is used until there are enough samples
+	StdDevFactor    float64 //estimate = mean + StdDevFactor*stddev
+	DefaultGas      int64
+	EstimatePending bool    //check each ring by eth_estimateGas against the pending state before submitting
+	EstimateMargin  float64 //gas limit of the submitted ring is at least estimateGas*EstimateMargin
 }
 
 type MarketOptions struct {
@@ -209,7 +259,7 @@ type MarketOptions struct {
 }
 
 type MarketCapOptions struct {
-	BaseUrl  string
+	BaseUrl  string `secret:"url"`
 	Currency string
 	Duration int
 	IsSync   bool
@@ -240,7 +290,7 @@ type MysqlOptions struct {
 	Hostname           string
 	Port               string
 	User               string
-	Password           string
+	Password           string `secret:"true"`
 	DbName             string
 	TablePrefix        string
 	MaxOpenConnections int
@@ -252,7 +302,7 @@ type MysqlOptions struct {
 type RedisOptions struct {
 	Host        string
 	Port        string
-	Password    string
+	Password    string `secret:"true"`
 	IdleTimeout int
 	MaxIdle     int
 	MaxActive   int
@@ -264,26 +314,6 @@ type UserManagerOptions struct {
 	WhiteListCacheCleanTime  int64
 }
 
-func Validator(cv reflect.Value) (bool, error) {
-	for i := 0; i < cv.NumField(); i++ {
-		cvt := cv.Type().Field(i)
-
-		if cv.Field(i).Type().Kind() == reflect.Struct {
-			if res, err := Validator(cv.Field(i)); nil != err {
-				return res, err
-			}
-		} else {
-			if "true" == cvt.Tag.Get("required") {
-				if !isSet(cv.Field(i)) {
-					return false, errors.New("The field " + cvt.Name + " in config must be setted")
-				}
-			}
-		}
-	}
-
-	return true, nil
-}
-
 func isSet(v reflect.Value) bool {
 	switch v.Type().Kind() {
 	case reflect.Invalid:
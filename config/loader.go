@@ -0,0 +1,301 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/naoina/toml"
+)
+
+// every option can be set by the environment variable named RELAY_ and the upper case key
+// with "." replaced by "_", such as RELAY_MYSQL_PASSWORD for mysql.password
+const ENV_PREFIX = "RELAY_"
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// lenientConfig skips the unknown keys, they are reported by unknownKeys
+var lenientConfig = toml.Config{
+	NormFieldName: toml.DefaultConfig.NormFieldName,
+	FieldToKey:    toml.DefaultConfig.FieldToKey,
+	MissingField: func(typ reflect.Type, key string) error {
+		return nil
+	},
+}
+
+// Load builds the config by layers, the later overrides the former: the defaults, the file,
+// files included by it, RELAY_* in environ and the overrides like section.key=value
+func Load(file string, environ []string, overrides []string) (*GlobalConfig, error) {
+	if "" == file {
+		dir, _ := os.Getwd()
+		file = dir + "/config/relay.toml"
+	}
+
+	c := &GlobalConfig{}
+	c.defaultConfig()
+	included := []string{}
+	unknown := ValidationErrors{}
+	if err := c.decodeFile(file, &included, &unknown); nil != err {
+		return nil, err
+	}
+	if len(unknown) > 0 {
+		return nil, unknown
+	}
+	c.Include = included
+
+	if err := c.setEnv(environ); nil != err {
+		return nil, err
+	}
+	for _, option := range overrides {
+		if err := c.Set(option); nil != err {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// decodeFile decodes the file and then its includes, the relative path of include is based on the dir of file.
+// The keys that aren't options are appended to unknown instead of failing at the first one.
+func (c *GlobalConfig) decodeFile(file string, included *[]string, unknown *ValidationErrors) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	table, err := toml.Parse(data)
+	if err != nil {
+		return fmt.Errorf("failed to decode config file:%s, err:%s", file, err.Error())
+	}
+	*unknown = append(*unknown, unknownKeys(file, table, reflect.TypeOf(c))...)
+
+	c.Include = nil
+	if err := lenientConfig.UnmarshalTable(table, c); err != nil {
+		return fmt.Errorf("failed to decode config file:%s, err:%s", file, err.Error())
+	}
+	for _, include := range c.Include {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(file), include)
+		}
+		for _, f := range *included {
+			if f == include {
+				return fmt.Errorf("config file:%s is included more than once", include)
+			}
+		}
+		*included = append(*included, include)
+		if err := c.decodeFile(include, included, unknown); nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+// Set overrides an option by key=value, such as miner.timing_matcher.duration=10000,
+// the value is in toml and strings can be unquoted, a key of map is the last part of key
+func (c *GlobalConfig) Set(option string) error {
+	idx := strings.Index(option, "=")
+	if idx <= 0 {
+		return fmt.Errorf("invalid option:%s, it should be like section.key=value", option)
+	}
+	key := strings.TrimSpace(option[:idx])
+	if err := setOption(reflect.ValueOf(c), strings.Split(key, "."), strings.TrimSpace(option[idx+1:])); nil != err {
+		return fmt.Errorf("failed to set %s, err:%s", key, err.Error())
+	}
+	return nil
+}
+
+func (c *GlobalConfig) setEnv(environ []string) error {
+	names := make(map[string]string)
+	for _, key := range OptionKeys(reflect.TypeOf(c)) {
+		names[EnvName(key)] = key
+	}
+	for _, env := range environ {
+		kv := strings.SplitN(env, "=", 2)
+		if len(kv) != 2 || !strings.HasPrefix(kv[0], ENV_PREFIX) {
+			continue
+		}
+		if key, ok := names[kv[0]]; ok {
+			if err := c.Set(key + "=" + kv[1]); nil != err {
+				return fmt.Errorf("invalid env %s, err:%s", kv[0], err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+func EnvName(key string) string {
+	return ENV_PREFIX + strings.ToUpper(strings.Replace(key, ".", "_", -1))
+}
+
+// OptionKeys returns the keys of all options in typ, the maps and slices are single options
+func OptionKeys(typ reflect.Type) []string {
+	return optionKeys(typ, "")
+}
+
+func optionKeys(typ reflect.Type, prefix string) []string {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	keys := []string{}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if "" != field.PkgPath {
+			continue
+		}
+		key := joinKey(prefix, keyName(field.Name))
+		if isTable(field.Type) {
+			keys = append(keys, optionKeys(field.Type, key)...)
+		} else if isValue(field.Type) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// keyName is the snake case of a field name, an acronym is kept as one word,
+// such as rate_ratio_cvs_threshold for RateRatioCVSThreshold
+func keyName(fieldName string) string {
+	runes := []rune(fieldName)
+	name := make([]rune, 0, len(runes)+4)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				name = append(name, '_')
+			}
+		}
+		name = append(name, unicode.ToLower(r))
+	}
+	return string(name)
+}
+
+func joinKey(prefix, key string) string {
+	if "" == prefix {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func isText(typ reflect.Type) bool {
+	return typ.Implements(textUnmarshalerType) || reflect.PtrTo(typ).Implements(textUnmarshalerType)
+}
+
+// isTable returns true if typ is a struct or a pointer of struct that isn't decoded from text
+func isTable(typ reflect.Type) bool {
+	for typ.Kind() == reflect.Ptr {
+		if isText(typ) {
+			return false
+		}
+		typ = typ.Elem()
+	}
+	return typ.Kind() == reflect.Struct && !isText(typ)
+}
+
+func isValue(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return isText(typ)
+	}
+	return true
+}
+
+// normKey is the same as the field name matching of toml decoder
+func normKey(key string) string {
+	return strings.Replace(strings.ToLower(key), "_", "", -1)
+}
+
+func setOption(v reflect.Value, path []string, raw string) error {
+	for idx, name := range path {
+		for v.Kind() == reflect.Ptr && isTable(v.Type()) {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		switch {
+		case v.Kind() == reflect.Struct && isTable(v.Type()):
+			field, ok := v.Type().FieldByNameFunc(func(fieldName string) bool {
+				return normKey(fieldName) == normKey(name)
+			})
+			if !ok || "" != field.PkgPath {
+				return fmt.Errorf("unknown option %s", strings.Join(path[:idx+1], "."))
+			}
+			v = v.FieldByIndex(field.Index)
+		case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String && idx == len(path)-1:
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := decodeValue(elem, raw); nil != err {
+				return err
+			}
+			if v.IsNil() {
+				v.Set(reflect.MakeMap(v.Type()))
+			}
+			v.SetMapIndex(reflect.ValueOf(name).Convert(v.Type().Key()), elem)
+			return nil
+		default:
+			return fmt.Errorf("unknown option %s", strings.Join(path[:idx+1], "."))
+		}
+	}
+	if isTable(v.Type()) || !isValue(v.Type()) {
+		return fmt.Errorf("%s isn't a value", strings.Join(path, "."))
+	}
+	return decodeValue(v, raw)
+}
+
+// decodeValue decodes raw as a toml value into v, a string can be unquoted, an integer is accepted
+// by float and a list of strings can be separated by comma
+func decodeValue(v reflect.Value, raw string) error {
+	candidates := []string{raw}
+	typ := v.Type()
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	switch {
+	case typ.Kind() == reflect.Float32 || typ.Kind() == reflect.Float64:
+		candidates = append(candidates, raw+".0")
+	case typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.String && !strings.HasPrefix(raw, "["):
+		items := []string{}
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); "" != item {
+				items = append(items, strconv.Quote(item))
+			}
+		}
+		candidates = append(candidates, "["+strings.Join(items, ", ")+"]")
+	}
+	candidates = append(candidates, strconv.Quote(raw))
+
+	holderType := reflect.StructOf([]reflect.StructField{{Name: "V", Type: v.Type()}})
+	var err error
+	for idx, candidate := range candidates {
+		holder := reflect.New(holderType)
+		if e := toml.Unmarshal([]byte("v = "+candidate), holder.Interface()); nil == e {
+			v.Set(holder.Elem().Field(0))
+			return nil
+		} else if idx == 0 {
+			err = e
+		}
+	}
+	return err
+}
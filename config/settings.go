@@ -0,0 +1,251 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const maskedSecret = "******"
+
+// Setting is an option and its value in toml, the key is like miner.timing_matcher.duration
+type Setting struct {
+	Key   string
+	Value string
+}
+
+func (s Setting) String() string {
+	return s.Key + " = " + s.Value
+}
+
+// Settings flattens the config to options, the ones tagged by secret are masked if maskSecrets,
+// only the userinfo, path and query are masked for the urls tagged by secret:"url", they may contain api keys
+func Settings(c *GlobalConfig, maskSecrets bool) []Setting {
+	settings := []Setting{}
+	collectSettings(reflect.ValueOf(c).Elem(), "", maskSecrets, &settings)
+	return settings
+}
+
+// Diff returns the keys of the options whose values differ between the configs, sorted
+func Diff(a, b *GlobalConfig) []string {
+	values := make(map[string]string)
+	for _, setting := range Settings(a, false) {
+		values[setting.Key] = setting.Value
+	}
+	keys := []string{}
+	for _, setting := range Settings(b, false) {
+		if value, exists := values[setting.Key]; !exists || value != setting.Value {
+			keys = append(keys, setting.Key)
+		}
+		delete(values, setting.Key)
+	}
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func collectSettings(v reflect.Value, prefix string, maskSecrets bool, settings *[]Setting) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if "" != field.PkgPath {
+			continue
+		}
+		key := joinKey(prefix, keyName(field.Name))
+		fv := v.Field(i)
+		if isTable(field.Type) {
+			for fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					break
+				}
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				collectSettings(fv, key, maskSecrets, settings)
+			}
+			continue
+		}
+		value, ok := formatValue(fv)
+		if !ok {
+			continue
+		}
+		if maskSecrets {
+			switch field.Tag.Get("secret") {
+			case "true":
+				if `""` != value {
+					value = strconv.Quote(maskedSecret)
+				}
+			case "url":
+				value, _ = formatValue(maskURLs(fv))
+			}
+		}
+		*settings = append(*settings, Setting{Key: key, Value: value})
+	}
+}
+
+// maskURLs masks the url or the slice of urls in v
+func maskURLs(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(maskURL(v.String()))
+	case reflect.Slice:
+		urls := make([]string, v.Len())
+		for i := range urls {
+			urls[i] = maskURL(v.Index(i).String())
+		}
+		return reflect.ValueOf(urls)
+	}
+	return v
+}
+
+// maskURL keeps the scheme and host of rawUrl, the whole is masked if it can't be parsed
+func maskURL(rawUrl string) string {
+	if "" == rawUrl {
+		return rawUrl
+	}
+	u, err := url.Parse(rawUrl)
+	if nil != err || "" == u.Host {
+		return maskedSecret
+	}
+	masked := u.Scheme + "://"
+	if nil != u.User {
+		masked += maskedSecret + "@"
+	}
+	masked += u.Host
+	if "" != u.Path && "/" != u.Path {
+		masked += "/" + maskedSecret
+	} else {
+		masked += u.Path
+	}
+	if "" != u.RawQuery {
+		masked += "?" + maskedSecret
+	}
+	return masked
+}
+
+// formatValue formats v as a toml value, it returns false if v is nil or can't be formatted
+func formatValue(v reflect.Value) (string, bool) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", false
+		}
+		if v.CanInterface() {
+			if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+				return formatText(m)
+			}
+		}
+		v = v.Elem()
+	}
+	if v.CanAddr() {
+		if m, ok := v.Addr().Interface().(encoding.TextMarshaler); ok {
+			return formatText(m)
+		}
+	}
+	if v.CanInterface() {
+		if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+			return formatText(m)
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return strconv.Quote(v.String()), true
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		s := strconv.FormatFloat(v.Float(), 'f', -1, 64)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+		return s, true
+	case reflect.Slice, reflect.Array:
+		items := []string{}
+		for i := 0; i < v.Len(); i++ {
+			if item, ok := formatValue(v.Index(i)); ok {
+				items = append(items, item)
+			}
+		}
+		return "[" + strings.Join(items, ", ") + "]", true
+	case reflect.Map:
+		keys := []string{}
+		values := make(map[string]string)
+		for _, k := range v.MapKeys() {
+			if value, ok := formatValue(v.MapIndex(k)); ok {
+				key := fmt.Sprint(k.Interface())
+				keys = append(keys, key)
+				values[key] = value
+			}
+		}
+		sort.Strings(keys)
+		items := []string{}
+		for _, key := range keys {
+			items = append(items, formatKey(key)+" = "+values[key])
+		}
+		return "{" + strings.Join(items, ", ") + "}", true
+	case reflect.Struct:
+		items := []string{}
+		for i := 0; i < v.NumField(); i++ {
+			if field := v.Type().Field(i); "" == field.PkgPath {
+				if value, ok := formatValue(v.Field(i)); ok {
+					items = append(items, keyName(field.Name)+" = "+value)
+				}
+			}
+		}
+		return "{" + strings.Join(items, ", ") + "}", true
+	}
+	return "", false
+}
+
+func formatText(m encoding.TextMarshaler) (value string, ok bool) {
+	// the zero value of some types like zap.AtomicLevel panics
+	defer func() {
+		if r := recover(); nil != r {
+			value, ok = "", false
+		}
+	}()
+	text, err := m.MarshalText()
+	if nil != err {
+		return "", false
+	}
+	return strconv.Quote(string(text)), true
+}
+
+// formatKey quotes the key of map unless it's a bare key of toml
+func formatKey(key string) string {
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return strconv.Quote(key)
+		}
+	}
+	if "" == key {
+		return `""`
+	}
+	return key
+}
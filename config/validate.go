@@ -0,0 +1,347 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/naoina/toml/ast"
+)
+
+// the names supported by the options, they are kept here for Validate since config can't import
+// ordermanager and timing_matcher, the tests of those packages check the lists are the same as theirs
+var (
+	MinerOrderPriorities = []string{"price", "lrc_fee", "margin_split", "age"}
+	RingSelectors        = []string{"greedy", "max_total", "fairness"}
+	SelfTradeModes       = []string{"skip", "cancel_newer"}
+)
+
+// ValidationErrors are all the errors found in the config, one per line
+type ValidationErrors []error
+
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+type keyError struct {
+	line int
+	err  error
+}
+
+// unknownKeys returns the keys in the file that aren't options of typ, sorted by line
+func unknownKeys(file string, t *ast.Table, typ reflect.Type) ValidationErrors {
+	keyErrs := []keyError{}
+	collectUnknownKeys(file, t, typ, "", &keyErrs)
+	sort.SliceStable(keyErrs, func(i, j int) bool { return keyErrs[i].line < keyErrs[j].line })
+	errs := ValidationErrors{}
+	for _, keyErr := range keyErrs {
+		errs = append(errs, keyErr.err)
+	}
+	return errs
+}
+
+func collectUnknownKeys(file string, t *ast.Table, typ reflect.Type, prefix string, keyErrs *[]keyError) {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct || isText(typ) {
+		return
+	}
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < typ.NumField(); i++ {
+		if field := typ.Field(i); "" == field.PkgPath {
+			fields[normKey(field.Name)] = field
+		}
+	}
+	for key, fieldAst := range t.Fields {
+		field, exists := fields[normKey(key)]
+		if !exists {
+			line := 0
+			switch av := fieldAst.(type) {
+			case *ast.KeyValue:
+				line = av.Line
+			case *ast.Table:
+				line = av.Line
+			case []*ast.Table:
+				line = av[0].Line
+			}
+			*keyErrs = append(*keyErrs, keyError{line, fmt.Errorf("%s:%d: unknown key %s", file, line, joinKey(prefix, key))})
+			continue
+		}
+		name := joinKey(prefix, keyName(field.Name))
+		switch av := fieldAst.(type) {
+		case *ast.Table:
+			collectUnknownKeys(file, av, field.Type, name, keyErrs)
+		case []*ast.Table:
+			if field.Type.Kind() == reflect.Slice {
+				for _, tbl := range av {
+					collectUnknownKeys(file, tbl, field.Type.Elem(), name, keyErrs)
+				}
+			}
+		}
+	}
+}
+
+// Validate checks the required options, the ranges, addresses and urls of options and the consistency
+// between them, such as the miners exist in the keystore and the listen ports don't collide.
+// All errors found are returned together as ValidationErrors.
+func Validate(c *GlobalConfig) error {
+	v := &validator{}
+	v.checkRequired(reflect.ValueOf(c).Elem(), "")
+
+	v.check(c.Mode == "full" || c.Mode == "relay" || c.Mode == "miner", "mode", "must be full, relay or miner, got %q", c.Mode)
+	v.checkPorts(c)
+	v.check(c.ShutdownTimeout > 0, "shutdown_timeout", "must be positive, got %d", c.ShutdownTimeout)
+
+	for i, rawUrl := range c.Accessor.RawUrls {
+		v.check(isURL(rawUrl, "http", "https"), fmt.Sprintf("accessor.raw_urls[%d]", i), "must be an http or https url, got %q", rawUrl)
+	}
+	v.check(len(c.Common.ProtocolImpl.Address) > 0, "common.protocol_impl.address", "at least one protocol address is required")
+	for version, address := range c.Common.ProtocolImpl.Address {
+		v.checkAddress("common.protocol_impl.address."+version, address)
+	}
+	if "" != c.Market.OldVersionWethAddress {
+		v.checkAddress("market.old_version_weth_address", c.Market.OldVersionWethAddress)
+	}
+	if "" != c.MarketCap.BaseUrl {
+		v.check(isURL(c.MarketCap.BaseUrl, "http", "https"), "market_cap.base_url", "must be an http or https url, got %q", c.MarketCap.BaseUrl)
+	}
+
+	filter := c.GatewayFilters.BaseFilter
+	v.checkFraction("gateway_filters.base_filter.min_split_percentage", filter.MinSplitPercentage)
+	v.checkFraction("gateway_filters.base_filter.max_split_percentage", filter.MaxSplitPercentage)
+	v.check(filter.MinSplitPercentage <= filter.MaxSplitPercentage, "gateway_filters.base_filter.min_split_percentage", "must not be greater than max_split_percentage")
+	v.check(filter.MinLrcFee >= 0, "gateway_filters.base_filter.min_lrc_fee", "must not be negative")
+	v.check(filter.MaxValidSinceInterval >= 0, "gateway_filters.base_filter.max_valid_since_interval", "must not be negative")
+	for symbol, amount := range filter.MinTokeSAmount {
+		_, ok := new(big.Int).SetString(amount, 10)
+		v.check(ok, "gateway_filters.base_filter.min_toke_s_amount."+symbol, "must be a decimal integer, got %q", amount)
+	}
+	if difficulty := c.GatewayFilters.PowFilter.Difficulty; "" != difficulty {
+		_, ok := new(big.Int).SetString(strings.TrimPrefix(difficulty, "0x"), 16)
+		v.check(ok, "gateway_filters.pow_filter.difficulty", "must be a hex integer, got %q", difficulty)
+	}
+
+	v.checkName("order_manager.miner_order_priority", c.OrderManager.MinerOrderPriority, MinerOrderPriorities)
+
+	if c.Extractor.Open {
+		v.check(c.Extractor.MaxStallTime > 0, "extractor.max_stall_time", "must be greater than 0, got %d", c.Extractor.MaxStallTime)
+	}
+
+	if "relay" != c.Mode {
+		v.checkMiner(c)
+	}
+
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	return nil
+}
+
+type validator struct {
+	errs ValidationErrors
+}
+
+func (v *validator) check(ok bool, key, format string, args ...interface{}) {
+	if !ok {
+		v.errs = append(v.errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+}
+
+func (v *validator) checkRequired(cv reflect.Value, prefix string) {
+	for i := 0; i < cv.NumField(); i++ {
+		field := cv.Type().Field(i)
+		if "" != field.PkgPath {
+			continue
+		}
+		key := joinKey(prefix, keyName(field.Name))
+		fv := cv.Field(i)
+		if "true" == field.Tag.Get("required") {
+			v.check(isSet(fv), key, "is required")
+		}
+		for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Struct && isTable(fv.Type()) {
+			v.checkRequired(fv, key)
+		}
+	}
+}
+
+func (v *validator) checkFraction(key string, value float64) {
+	v.check(value >= 0 && value <= 1, key, "must be between 0 and 1, got %v", value)
+}
+
+// checkName checks the value is one of the names, empty means the default one
+func (v *validator) checkName(key, value string, names []string) {
+	if "" == value {
+		return
+	}
+	for _, name := range names {
+		if value == name {
+			return
+		}
+	}
+	v.check(false, key, "must be one of %s, got %q", strings.Join(names, ", "), value)
+}
+
+func (v *validator) checkAddress(key, address string) {
+	v.check(common.IsHexAddress(address), key, "must be a hex address, got %q", address)
+}
+
+// checkPorts checks the listen ports are valid and different from each other
+func (v *validator) checkPorts(c *GlobalConfig) {
+	ports := []Setting{{"admin.port", c.Admin.Port}}
+	if "miner" != c.Mode {
+		ports = append(ports, Setting{"jsonrpc.port", c.Jsonrpc.Port}, Setting{"rest.port", c.Rest.Port}, Setting{"websocket.port", c.Websocket.Port})
+	}
+	used := make(map[string]string)
+	for _, port := range ports {
+		if "" == port.Value {
+			continue
+		}
+		if p, err := strconv.Atoi(port.Value); nil != err || p <= 0 || p > 65535 {
+			v.check(false, port.Key, "must be a port between 1 and 65535, got %q", port.Value)
+			continue
+		}
+		if key, exists := used[port.Value]; exists {
+			v.check(false, port.Key, "port %s is already used by %s", port.Value, key)
+			continue
+		}
+		used[port.Value] = port.Key
+	}
+}
+
+func (v *validator) checkMiner(c *GlobalConfig) {
+	miner := c.Miner
+	v.checkFraction("miner.subsidy", miner.Subsidy)
+	v.checkFraction("miner.wallet_split", miner.WalletSplit)
+	v.check(miner.RingMaxLength >= 2, "miner.ring_max_length", "must be at least 2, got %d", miner.RingMaxLength)
+	v.check(miner.MinGasLimit > 0, "miner.min_gas_limit", "must be greater than 0, got %d", miner.MinGasLimit)
+	v.check(miner.MaxGasLimit >= miner.MinGasLimit, "miner.max_gas_limit", "must not be less than min_gas_limit")
+	v.checkAddress("miner.fee_receipt", miner.FeeReceipt)
+	v.check(len(miner.NormalMiners)+len(miner.PercentMiners) > 0, "miner.normal_miners", "at least one normal or percent miner is required")
+
+	addresses := []string{}
+	for i, m := range miner.NormalMiners {
+		key := fmt.Sprintf("miner.normal_miners[%d]", i)
+		v.checkAddress(key+".address", m.Address)
+		v.check(m.GasPriceLimit >= 0, key+".gas_price_limit", "must not be negative")
+		addresses = append(addresses, m.Address)
+	}
+	for i, m := range miner.PercentMiners {
+		key := fmt.Sprintf("miner.percent_miners[%d]", i)
+		v.checkAddress(key+".address", m.Address)
+		v.check(m.FeePercent >= 0 && m.FeePercent <= 100, key+".fee_percent", "must be between 0 and 100, got %v", m.FeePercent)
+		addresses = append(addresses, m.Address)
+	}
+	if miner.Batch.Enabled {
+		v.checkAddress("miner.batch.helper_address", miner.Batch.HelperAddress)
+	}
+	v.check(miner.BalanceGuard.Interval >= 0, "miner.balance_guard.interval", "must not be negative")
+
+	if matcher := miner.TimingMatcher; nil == matcher {
+		v.check(false, "miner.timing_matcher", "is required")
+	} else {
+		v.check(matcher.Duration > 0, "miner.timing_matcher.duration", "must be greater than 0, got %d", matcher.Duration)
+		v.check(matcher.RoundOrdersCount > 0, "miner.timing_matcher.round_orders_count", "must be greater than 0, got %d", matcher.RoundOrdersCount)
+		v.check(matcher.DelayedNumber >= 0, "miner.timing_matcher.delayed_number", "must not be negative")
+		v.check(matcher.MatchingStatusTtl >= 0, "miner.timing_matcher.matching_status_ttl", "must not be negative")
+		v.check(matcher.MaxUnevaluatedRounds >= 0, "miner.timing_matcher.max_unevaluated_rounds", "must not be negative")
+		v.check(matcher.StarvedOrdersRatio >= 0 && matcher.StarvedOrdersRatio <= 1, "miner.timing_matcher.starved_orders_ratio", "must be between 0 and 1, got %v", matcher.StarvedOrdersRatio)
+		v.checkName("miner.timing_matcher.ring_selector", matcher.RingSelector, RingSelectors)
+		markets := []string{}
+		for market := range matcher.MarketRingSelectors {
+			markets = append(markets, market)
+		}
+		sort.Strings(markets)
+		for _, market := range markets {
+			v.checkName("miner.timing_matcher.market_ring_selectors."+market, matcher.MarketRingSelectors[market], RingSelectors)
+		}
+		v.checkName("miner.timing_matcher.self_trade_prevention.mode", matcher.SelfTradePrevention.Mode, SelfTradeModes)
+	}
+
+	if "remote" == c.Signer.Type {
+		v.check(isURL(c.Signer.Endpoint, "http", "https", "ws", "wss") || strings.HasSuffix(c.Signer.Endpoint, ".ipc"),
+			"signer.endpoint", "must be an ipc path or an http, https, ws or wss url, got %q", c.Signer.Endpoint)
+		return
+	}
+	keys, err := keystoreAddresses(c.Keystore.Keydir)
+	if nil != err {
+		v.check(false, "keystore.keydir", "%s", err.Error())
+		return
+	}
+	for _, address := range addresses {
+		if common.IsHexAddress(address) {
+			v.check(keys[common.HexToAddress(address)], "keystore.keydir", "miner %s doesn't exist in %s", address, c.Keystore.Keydir)
+		}
+	}
+}
+
+// keystoreAddresses reads the address of every key file in dir as the keystore does
+func keystoreAddresses(dir string) (map[common.Address]bool, error) {
+	files, err := ioutil.ReadDir(dir)
+	if nil != err {
+		return nil, err
+	}
+	addresses := make(map[common.Address]bool)
+	for _, fi := range files {
+		if fi.IsDir() || strings.HasPrefix(fi.Name(), ".") || strings.HasSuffix(fi.Name(), "~") {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		if nil != err {
+			continue
+		}
+		var key struct {
+			Address string `json:"address"`
+		}
+		if nil == json.Unmarshal(data, &key) && common.IsHexAddress(key.Address) {
+			addresses[common.HexToAddress(key.Address)] = true
+		}
+	}
+	return addresses, nil
+}
+
+func isURL(raw string, schemes ...string) bool {
+	u, err := url.Parse(raw)
+	if nil != err || "" == u.Host {
+		return false
+	}
+	for _, scheme := range schemes {
+		if u.Scheme == scheme {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package config_test
+
+import (
+	"github.com/Loopring/relay/config"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+const minerAddress = "0x750aD4351bB728ceC7d639A9511F9D6488f1E259"
+
+func validConfig(t *testing.T, keydir string) *config.GlobalConfig {
+	c, err := config.Load("relay.toml", nil, []string{"keystore.keydir=" + keydir})
+	if nil != err {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestUnknownKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeConfig(t, dir, "local.toml", "[miner]\nsubsidi = 0.5\n")
+	file := writeConfig(t, dir, "relay.toml", "title = \"relay\"\ninclude = [\"local.toml\"]\n[mysql]\nhostnam = \"db\"\n[miner.timing_matcher]\nduraton = 1\n[unknown]\nx = 1\n")
+	_, err = config.Load(file, nil, nil)
+	errs, ok := err.(config.ValidationErrors)
+	if !ok || len(errs) != 4 {
+		t.Fatalf("all unknown keys should be reported, got %v", err)
+	}
+	expected := []string{"relay.toml:4: unknown key mysql.hostnam", "relay.toml:6: unknown key miner.timing_matcher.duraton", "relay.toml:7: unknown key unknown", "local.toml:2: unknown key miner.subsidi"}
+	for i, e := range expected {
+		if !strings.HasSuffix(errs[i].Error(), e) {
+			t.Fatalf("expected %s, got %s", e, errs[i].Error())
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	keydir, err := ioutil.TempDir("", "keystore")
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(keydir)
+	writeConfig(t, keydir, "UTC--miner", `{"address":"750ad4351bb728cec7d639a9511f9d6488f1e259"}`)
+
+	c := validConfig(t, keydir)
+	if err := config.Validate(c); nil != err {
+		t.Fatalf("config should be valid, got %s", err.Error())
+	}
+
+	c.Title = ""
+	c.Miner.TimingMatcher.Duration = 0
+	c.Miner.TimingMatcher.StarvedOrdersRatio = 1.5
+	c.Miner.TimingMatcher.RingSelector = "unknown"
+	c.Miner.TimingMatcher.MarketRingSelectors = map[string]string{"LRC-WETH": "fairness", "RDN-WETH": "max"}
+	c.Miner.TimingMatcher.SelfTradePrevention.Mode = "cancel"
+	c.OrderManager.MinerOrderPriority = "fee"
+	c.Miner.WalletSplit = 1.5
+	c.Miner.MaxGasLimit = c.Miner.MinGasLimit - 1
+	c.Miner.NormalMiners[0].Address = "0x123"
+	c.Miner.PercentMiners = []config.PercentMinerAddress{{Address: "0x4bad3053d574cd54513babe21db3f09bea1d387d"}}
+	c.GatewayFilters.BaseFilter.MinSplitPercentage = 2
+	c.Accessor.RawUrls = []string{"127.0.0.1:8545"}
+	c.Common.ProtocolImpl.Address = map[string]string{}
+	c.Rest.Port = c.Jsonrpc.Port
+	c.Extractor.MaxStallTime = 0
+	err = config.Validate(c)
+	errs, ok := err.(config.ValidationErrors)
+	if !ok {
+		t.Fatalf("expected validation errors, got %v", err)
+	}
+	for _, key := range []string{
+		"title:",
+		"miner.timing_matcher.duration:",
+		"miner.timing_matcher.starved_orders_ratio:",
+		"miner.timing_matcher.ring_selector: must be one of greedy, max_total, fairness",
+		"miner.timing_matcher.market_ring_selectors.RDN-WETH:",
+		"miner.timing_matcher.self_trade_prevention.mode:",
+		"order_manager.miner_order_priority:",
+		"miner.wallet_split:",
+		"miner.max_gas_limit:",
+		"miner.normal_miners[0].address:",
+		"gateway_filters.base_filter.min_split_percentage: must be between",
+		"gateway_filters.base_filter.min_split_percentage: must not be greater",
+		"accessor.raw_urls[0]:",
+		"common.protocol_impl.address:",
+		"rest.port: port 8083 is already used by jsonrpc.port",
+		"extractor.max_stall_time:",
+		"keystore.keydir: miner 0x4bad3053d574cd54513babe21db3f09bea1d387d doesn't exist",
+	} {
+		found := false
+		for _, e := range errs {
+			found = found || strings.HasPrefix(e.Error(), key)
+		}
+		if !found {
+			t.Errorf("error of %s isn't reported in:\n%s", key, err.Error())
+		}
+	}
+	if strings.Contains(err.Error(), "LRC-WETH") {
+		t.Errorf("supported ring selector shouldn't be reported in:\n%s", err.Error())
+	}
+
+	relay := validConfig(t, keydir)
+	relay.Mode = "relay"
+	relay.Miner.TimingMatcher.Duration = 0
+	relay.Keystore.Keydir = "/nonexistent"
+	if err := config.Validate(relay); nil != err {
+		t.Fatalf("miner options shouldn't be checked in relay mode, got %s", err.Error())
+	}
+}
@@ -0,0 +1,124 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package console
+
+import (
+	"fmt"
+	"github.com/Loopring/relay/crypto"
+	"github.com/Loopring/relay/gateway"
+	"github.com/Loopring/relay/miner"
+	"github.com/Loopring/relay/node"
+	"github.com/Loopring/relay/usermanager"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const keyCtrlC = 3
+
+// the services registered by relay, the methods are named as the rpc server does
+var services = map[string]reflect.Type{
+	"loopring":  reflect.TypeOf(&gateway.WalletServiceImpl{}),
+	"miner":     reflect.TypeOf(&miner.AdminApi{}),
+	"account":   reflect.TypeOf(&crypto.AccountAdminApi{}),
+	"whitelist": reflect.TypeOf(&usermanager.AdminApi{}),
+	"node":      reflect.TypeOf(&node.AdminApi{}),
+}
+
+// supportedMethods returns the methods of the modules supported by endpoint and the helpers
+func supportedMethods(modules map[string]string) []string {
+	methods := []string{"exit", "help", "methods"}
+	for name := range helpers {
+		methods = append(methods, name)
+	}
+	for namespace := range modules {
+		typ, ok := services[namespace]
+		if !ok {
+			continue
+		}
+		for i := 0; i < typ.NumMethod(); i++ {
+			if method := typ.Method(i); "" == method.PkgPath {
+				methods = append(methods, namespace+"_"+formatName(method.Name))
+			}
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+func formatName(name string) string {
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToLower(r)) + name[size:]
+}
+
+// completeMethod returns the methods start with prefix
+func completeMethod(methods []string, prefix string) []string {
+	candidates := []string{}
+	for _, method := range methods {
+		if strings.HasPrefix(method, prefix) {
+			candidates = append(candidates, method)
+		}
+	}
+	return candidates
+}
+
+func commonPrefix(candidates []string) string {
+	if len(candidates) == 0 {
+		return ""
+	}
+	prefix := candidates[0]
+	for _, candidate := range candidates[1:] {
+		for !strings.HasPrefix(candidate, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+// autoComplete completes the method before cursor by tab, the candidates are printed if it's ambiguous,
+// ctrl-c clears the line
+func (c *Console) autoComplete(line string, pos int, key rune) (string, int, bool) {
+	switch key {
+	case keyCtrlC:
+		return "", 0, true
+	case '\t':
+	default:
+		return "", 0, false
+	}
+	prefix := line[:pos]
+	if strings.ContainsAny(prefix, " \t") {
+		return "", 0, false
+	}
+	candidates := completeMethod(c.methods, prefix)
+	if len(candidates) == 0 {
+		return "", 0, false
+	}
+	suffix := line[pos:]
+	completed := candidates[0]
+	if len(candidates) > 1 {
+		if completed = commonPrefix(candidates); completed == prefix {
+			fmt.Fprintln(c.out, strings.Join(candidates, "  "))
+		}
+	} else if !strings.HasPrefix(suffix, " ") {
+		completed += " "
+	}
+	return completed + suffix, len(completed), true
+}
@@ -18,5 +18,270 @@
 
 package console
 
-//todo：need to imp it，
-//i think it shouldn't be started until the rpc module finished .
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Loopring/relay/crypto"
+	"github.com/Loopring/relay/crypto/passphrase"
+	"github.com/Loopring/relay/types"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/rpc"
+	"golang.org/x/crypto/ssh/terminal"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// the console isn't a javascript one like geth's, every statement is a jsonrpc method
+// or a helper followed by its params in json, such as:
+//   loopring_getBalance {"owner":"0x..."}
+//   order.new {"owner":"0x...","tokenS":"LRC","tokenB":"WETH","amountS":"0x...","amountB":"0x..."}
+
+const prompt = "> "
+
+var ErrExit = errors.New("exit")
+
+type Config struct {
+	Endpoint    string             // jsonrpc endpoint of relay, http, ws or ipc
+	Datadir     string             // keystore to sign orders
+	Sources     passphrase.Sources // passphrases of owners, it's asked on terminal if not found
+	HistoryFile string             // no history is kept if it's empty
+	Out         io.Writer
+}
+
+type Console struct {
+	endpoint    string
+	client      *rpc.Client
+	datadir     string
+	sources     passphrase.Sources
+	historyFile string
+	out         io.Writer
+	term        *terminal.Terminal
+	methods     []string
+	order       *types.Order
+}
+
+func New(config Config) (*Console, error) {
+	client, err := rpc.Dial(config.Endpoint)
+	if nil != err {
+		return nil, err
+	}
+	c, err := newConsole(client, config)
+	if nil != err {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect %s, err:%s", config.Endpoint, err.Error())
+	}
+	return c, nil
+}
+
+func newConsole(client *rpc.Client, config Config) (*Console, error) {
+	modules, err := client.SupportedModules()
+	if nil != err {
+		return nil, err
+	}
+	c := &Console{
+		endpoint:    config.Endpoint,
+		client:      client,
+		datadir:     config.Datadir,
+		sources:     config.Sources,
+		historyFile: config.HistoryFile,
+		out:         config.Out,
+		methods:     supportedMethods(modules),
+	}
+	if nil == c.out {
+		c.out = os.Stdout
+	}
+	if "" != c.datadir {
+		ks := keystore.NewKeyStore(c.datadir, keystore.StandardScryptN, keystore.StandardScryptP)
+		crypto.Initialize(crypto.NewKSCrypto(true, ks))
+	}
+	return c, nil
+}
+
+// Evaluate executes a statement and prints the result
+func (c *Console) Evaluate(statement string) error {
+	statement = strings.TrimSpace(statement)
+	if "" == statement || strings.HasPrefix(statement, "//") {
+		return nil
+	}
+	name, args, err := parseStatement(statement)
+	if nil != err {
+		return err
+	}
+	switch name {
+	case "exit", "quit":
+		return ErrExit
+	case "help":
+		c.printHelp()
+		return nil
+	case "methods":
+		fmt.Fprintln(c.out, strings.Join(c.methods, "\n"))
+		return nil
+	}
+	if h, ok := helpers[name]; ok {
+		return h.call(c, args)
+	}
+
+	params := make([]interface{}, len(args))
+	for idx, arg := range args {
+		params[idx] = arg
+	}
+	var result json.RawMessage
+	if err := c.client.Call(&result, name, params...); nil != err {
+		return err
+	}
+	return c.printJson(result)
+}
+
+// Execute evaluates the statements separated by newline, it stops at the first error
+func (c *Console) Execute(statements string) error {
+	return c.Run(strings.NewReader(statements))
+}
+
+// Run evaluates the statements read from r line by line, it stops at the first error
+func (c *Console) Run(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if err := c.Evaluate(scanner.Text()); ErrExit == err {
+			return nil
+		} else if nil != err {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// Interactive reads statements from terminal until exit or ctrl-d, the statements are
+// read as a script if stdin isn't a terminal
+func (c *Console) Interactive() error {
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		return c.Run(os.Stdin)
+	}
+	state, err := terminal.MakeRaw(fd)
+	if nil != err {
+		return err
+	}
+	defer terminal.Restore(fd, state)
+
+	rw := &termReadWriter{in: os.Stdin, out: c.out}
+	c.term = terminal.NewTerminal(rw, prompt)
+	if width, height, err := terminal.GetSize(fd); nil == err {
+		c.term.SetSize(width, height)
+	}
+	c.loadHistory(rw)
+	c.term.AutoCompleteCallback = c.autoComplete
+
+	out := c.out
+	c.out = c.term
+	defer func() {
+		c.out = out
+		c.term = nil
+	}()
+
+	fmt.Fprintf(c.out, "connected to relay %s\n", c.endpoint)
+	fmt.Fprintln(c.out, "type help for the commands, tab to complete the method, ctrl-d or exit to quit")
+	for {
+		line, err := c.term.ReadLine()
+		if io.EOF == err {
+			return nil
+		} else if nil != err && terminal.ErrPasteIndicator != err {
+			return err
+		}
+		c.appendHistory(line)
+		if err := c.Evaluate(line); ErrExit == err {
+			return nil
+		} else if nil != err {
+			fmt.Fprintf(c.out, "error: %s\n", err.Error())
+		}
+	}
+}
+
+func (c *Console) Stop() {
+	c.client.Close()
+}
+
+func (c *Console) printJson(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if nil != err {
+		return err
+	}
+	_, err = fmt.Fprintln(c.out, string(data))
+	return err
+}
+
+func (c *Console) printHelp() {
+	fmt.Fprintln(c.out, "statements are a jsonrpc method or a helper followed by params in json, strings can be unquoted if there isn't any object in params:")
+	fmt.Fprintln(c.out, "  loopring_getDepth {\"market\":\"LRC-WETH\",\"length\":10}")
+	fmt.Fprintln(c.out, "  whitelist_add 0x...")
+	fmt.Fprintln(c.out, "helpers:")
+	names := []string{}
+	for name := range helpers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(c.out, "  %-14s%s\n", name, helpers[name].usage)
+	}
+	fmt.Fprintf(c.out, "  %-14s%s\n", "methods", "list the methods of relay")
+	fmt.Fprintf(c.out, "  %-14s%s\n", "exit", "quit the console")
+}
+
+// parseStatement splits statement to the method and its params, the params are a stream of json values,
+// otherwise they are separated by whitespace and the ones aren't json are taken as strings
+func parseStatement(statement string) (string, []json.RawMessage, error) {
+	fields := strings.SplitN(statement, " ", 2)
+	name := strings.TrimSpace(fields[0])
+	args := []json.RawMessage{}
+	if len(fields) < 2 || "" == strings.TrimSpace(fields[1]) {
+		return name, args, nil
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(fields[1]))
+	for {
+		var arg json.RawMessage
+		if err := decoder.Decode(&arg); io.EOF == err {
+			return name, args, nil
+		} else if nil != err {
+			break
+		}
+		args = append(args, arg)
+	}
+
+	args = []json.RawMessage{}
+	for _, field := range strings.Fields(fields[1]) {
+		if json.Valid([]byte(field)) {
+			args = append(args, json.RawMessage(field))
+		} else if data, err := json.Marshal(field); nil != err {
+			return name, args, err
+		} else {
+			args = append(args, json.RawMessage(data))
+		}
+	}
+	return name, args, nil
+}
+
+// termReadWriter replays the history into the terminal, the output is discarded meanwhile
+type termReadWriter struct {
+	in      io.Reader
+	out     io.Writer
+	history io.Reader
+}
+
+func (rw *termReadWriter) Read(p []byte) (int, error) {
+	if nil != rw.history {
+		return rw.history.Read(p)
+	}
+	return rw.in.Read(p)
+}
+
+func (rw *termReadWriter) Write(p []byte) (int, error) {
+	if nil != rw.history {
+		return len(p), nil
+	}
+	return rw.out.Write(p)
+}
@@ -0,0 +1,188 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package console
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/Loopring/relay/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type StatusApi struct{}
+
+func (a *StatusApi) Status() (map[string]string, error) {
+	return map[string]string{"mode": "full"}, nil
+}
+
+func (a *StatusApi) Echo(v string, n int) (string, error) {
+	return strings.Repeat(v, n), nil
+}
+
+func newTestConsole(t *testing.T) (*Console, *bytes.Buffer) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("node", &StatusApi{}); nil != err {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	c, err := newConsole(rpc.DialInProc(server), Config{Out: out})
+	if nil != err {
+		t.Fatal(err)
+	}
+	return c, out
+}
+
+func TestParseStatement(t *testing.T) {
+	cases := []struct {
+		statement string
+		name      string
+		args      []string
+	}{
+		{"node_status", "node_status", []string{}},
+		{`loopring_getDepth {"market":"LRC-WETH", "length":10}`, "loopring_getDepth", []string{`{"market":"LRC-WETH", "length":10}`}},
+		{`node_echo "a" 3`, "node_echo", []string{`"a"`, `3`}},
+		{"whitelist_add 0xa1", "whitelist_add", []string{`"0xa1"`}},
+		{"node_echo ab 2", "node_echo", []string{`"ab"`, `2`}},
+	}
+	for _, c := range cases {
+		name, args, err := parseStatement(c.statement)
+		if nil != err {
+			t.Fatalf("statement:%s, err:%s", c.statement, err.Error())
+		}
+		if name != c.name || len(args) != len(c.args) {
+			t.Fatalf("statement:%s, name:%s, args:%d", c.statement, name, len(args))
+		}
+		for idx, arg := range args {
+			if string(arg) != c.args[idx] {
+				t.Errorf("statement:%s, arg:%s, expect:%s", c.statement, string(arg), c.args[idx])
+			}
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	c, out := newTestConsole(t)
+	if err := c.Evaluate("node_echo ab 2"); nil != err {
+		t.Fatal(err)
+	}
+	var res string
+	if err := json.Unmarshal(out.Bytes(), &res); nil != err || "abab" != res {
+		t.Fatalf("result:%s", out.String())
+	}
+
+	out.Reset()
+	if err := c.Execute("// comment\nnode_status\nexit\nnode_echo a 1"); nil != err {
+		t.Fatal(err)
+	}
+	status := map[string]string{}
+	if err := json.Unmarshal(out.Bytes(), &status); nil != err || "full" != status["mode"] {
+		t.Fatalf("result:%s", out.String())
+	}
+
+	if err := c.Execute("node_unknown"); nil == err {
+		t.Fatal("unknown method should fail")
+	}
+	if err := c.Evaluate("order.show"); nil == err {
+		t.Fatal("order.show without order should fail")
+	}
+}
+
+func TestAutoComplete(t *testing.T) {
+	c, _ := newTestConsole(t)
+	if candidates := completeMethod(c.methods, "node_"); len(candidates) != 2 || "node_reload" != candidates[0] || "node_status" != candidates[1] {
+		t.Fatalf("candidates:%v", candidates)
+	}
+	if candidates := completeMethod(c.methods, "loopring_"); len(candidates) != 0 {
+		t.Fatalf("loopring isn't supported by endpoint, candidates:%v", candidates)
+	}
+	if line, pos, ok := c.autoComplete("node_s", 6, '\t'); !ok || "node_status " != line || 12 != pos {
+		t.Fatalf("line:%s, pos:%d", line, pos)
+	}
+	if line, pos, ok := c.autoComplete("order.s", 7, '\t'); !ok || "order.s" != line || 7 != pos {
+		t.Fatalf("line:%s, pos:%d", line, pos)
+	}
+	if "order.s" != commonPrefix([]string{"order.show", "order.sign", "order.submit"}) {
+		t.Fatal("wrong common prefix")
+	}
+	if _, _, ok := c.autoComplete("node_status no", 14, '\t'); ok {
+		t.Fatal("params shouldn't be completed")
+	}
+}
+
+func TestHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "console")
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := &Console{historyFile: filepath.Join(dir, "history")}
+	for i := 0; i < maxHistory+10; i++ {
+		c.appendHistory("node_status")
+	}
+	c.appendHistory("node_echo \x1b[A 1")
+	c.appendHistory("")
+	c.appendHistory(`account_unlock 0x48ff2269e58a373120ffdbbdee3fbcea854ac30a "passphrase"`)
+	c.appendHistory(`  Account_Unlock 0x48ff2269e58a373120ffdbbdee3fbcea854ac30a "passphrase" 60`)
+	lines := readHistory(c.historyFile)
+	if len(lines) != maxHistory {
+		t.Fatalf("lines:%d", len(lines))
+	}
+	for _, line := range lines {
+		if "node_status" != line {
+			t.Fatalf("line:%q", line)
+		}
+	}
+	if info, err := os.Stat(c.historyFile); nil != err || info.Mode().Perm() != 0600 {
+		t.Fatalf("history file should only be accessible by the user")
+	}
+}
+
+type PowApi struct {
+	difficulty string
+}
+
+func (a *PowApi) GetPowDifficulty() (string, error) {
+	return a.difficulty, nil
+}
+
+func TestSignOrderPowDifficulty(t *testing.T) {
+	c, _ := newTestConsole(t)
+	c.order = &types.Order{}
+	if err := c.Evaluate("order.sign"); nil == err || !strings.Contains(err.Error(), "failed to get pow difficulty") {
+		t.Errorf("difficulty should be got from relay, err:%v", err)
+	}
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("loopring", &PowApi{difficulty: "0xzz"}); nil != err {
+		t.Fatal(err)
+	}
+	c.client = rpc.DialInProc(server)
+	if err := c.Evaluate("order.sign"); nil == err || !strings.Contains(err.Error(), "invalid pow difficulty") {
+		t.Errorf("difficulty of relay should be parsed, err:%v", err)
+	}
+	if err := c.Evaluate("order.sign -1"); nil == err || !strings.Contains(err.Error(), "invalid pow difficulty") {
+		t.Errorf("difficulty in params should be parsed, err:%v", err)
+	}
+}
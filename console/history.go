@@ -0,0 +1,113 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package console
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/Loopring/relay/log"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// the same as the size of terminal's history
+const maxHistory = 100
+
+// the methods may carry a passphrase in params, like account_unlock, they are never written to history file
+var passphraseMethodRegexp = regexp.MustCompile(`(?i)unlock|passphrase|password`)
+
+// loadHistory reads the latest lines of history file into terminal, so they can be accessed with up and down keys
+func (c *Console) loadHistory(rw *termReadWriter) {
+	lines := readHistory(c.historyFile)
+	if len(lines) == 0 {
+		return
+	}
+	buf := &bytes.Buffer{}
+	for _, line := range lines {
+		buf.WriteString(line)
+		buf.WriteByte('\r')
+	}
+	rw.history = buf
+	defer func() {
+		rw.history = nil
+	}()
+	for range lines {
+		if _, err := c.term.ReadLine(); nil != err {
+			return
+		}
+	}
+}
+
+func (c *Console) appendHistory(line string) {
+	if "" == c.historyFile || !isHistoryLine(line) || carryPassphrase(line) {
+		return
+	}
+	file, err := os.OpenFile(c.historyFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if nil != err {
+		log.Errorf("failed to open history file:%s, err:%s", c.historyFile, err.Error())
+		return
+	}
+	defer file.Close()
+	if _, err := file.WriteString(line + "\n"); nil != err {
+		log.Errorf("failed to write history file:%s, err:%s", c.historyFile, err.Error())
+	}
+}
+
+func readHistory(historyFile string) []string {
+	lines := []string{}
+	if "" == historyFile {
+		return lines
+	}
+	file, err := os.Open(historyFile)
+	if nil != err {
+		return lines
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := scanner.Text(); isHistoryLine(line) && !carryPassphrase(line) {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > maxHistory {
+		lines = lines[len(lines)-maxHistory:]
+	}
+	return lines
+}
+
+// the lines are replayed as key presses, so only the printable ones are kept
+func isHistoryLine(line string) bool {
+	if "" == line || len(line) > 4096 {
+		return false
+	}
+	for _, r := range line {
+		if !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func carryPassphrase(line string) bool {
+	fields := strings.Fields(line)
+	return len(fields) > 0 && passphraseMethodRegexp.MatchString(fields[0])
+}
@@ -0,0 +1,290 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package console
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Loopring/relay/crypto"
+	"github.com/Loopring/relay/crypto/passphrase"
+	"github.com/Loopring/relay/gateway"
+	"github.com/Loopring/relay/types"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+)
+
+// order.sign gives up searching the pow nonce after it
+const powSearchTimeout = 5 * time.Minute
+
+type helper struct {
+	usage string
+	call  func(c *Console, args []json.RawMessage) error
+}
+
+// the helpers build an order, sign it with an account in keystore and submit it
+var helpers = map[string]helper{
+	"order.new":    {usage: "order.new {spec}, create an order, the spec is OrderSpec", call: (*Console).newOrder},
+	"order.show":   {usage: "show the order", call: (*Console).showOrder},
+	"order.hash":   {usage: "show the hash of the order", call: (*Console).orderHash},
+	"order.sign":   {usage: "order.sign [powDifficulty], sign the order by owner in keystore and compute the pow nonce, the difficulty of relay is used by default", call: (*Console).signOrder},
+	"order.submit": {usage: "submit the signed order to relay", call: (*Console).submitOrder},
+}
+
+// OrderSpec is the param of order.new, tokens are symbols or addresses, amounts are hex or decimal
+// in the smallest unit of token, the omitted fields are the defaults as commented
+type OrderSpec struct {
+	Owner                 common.Address `json:"owner"`
+	TokenS                string         `json:"tokenS"`
+	TokenB                string         `json:"tokenB"`
+	AmountS               string         `json:"amountS"`
+	AmountB               string         `json:"amountB"`
+	LrcFee                string         `json:"lrcFee"`     // 0
+	ValidSince            int64          `json:"validSince"` // now
+	ValidUntil            int64          `json:"validUntil"` // validSince + 1 day
+	BuyNoMoreThanAmountB  bool           `json:"buyNoMoreThanAmountB"`
+	MarginSplitPercentage *uint8         `json:"marginSplitPercentage"` // 50
+	WalletAddress         common.Address `json:"walletAddress"`
+	DelegateAddress       common.Address `json:"delegateAddress"` // the first delegate of relay
+	OrderType             string         `json:"orderType"`       // market_order
+}
+
+func (c *Console) newOrder(args []json.RawMessage) error {
+	if len(args) != 1 {
+		return errors.New("order.new needs the order spec in json")
+	}
+	spec := &OrderSpec{}
+	if err := json.Unmarshal(args[0], spec); nil != err {
+		return err
+	}
+	if types.IsZeroAddress(spec.Owner) {
+		return errors.New("owner can't be empty")
+	}
+	tokenS, err := c.token(spec.TokenS)
+	if nil != err {
+		return err
+	}
+	tokenB, err := c.token(spec.TokenB)
+	if nil != err {
+		return err
+	}
+	amountS, err := parseBig(spec.AmountS)
+	if nil != err {
+		return fmt.Errorf("invalid amountS, err:%s", err.Error())
+	}
+	amountB, err := parseBig(spec.AmountB)
+	if nil != err {
+		return fmt.Errorf("invalid amountB, err:%s", err.Error())
+	}
+	lrcFee := big.NewInt(0)
+	if "" != spec.LrcFee {
+		if lrcFee, err = parseBig(spec.LrcFee); nil != err {
+			return fmt.Errorf("invalid lrcFee, err:%s", err.Error())
+		}
+	}
+	marginSplit := uint8(50)
+	if nil != spec.MarginSplitPercentage {
+		marginSplit = *spec.MarginSplitPercentage
+	}
+	if marginSplit > 100 {
+		return errors.New("marginSplitPercentage should be in [0, 100]")
+	}
+	validSince := spec.ValidSince
+	if validSince <= 0 {
+		validSince = time.Now().Unix()
+	}
+	validUntil := spec.ValidUntil
+	if validUntil <= 0 {
+		validUntil = validSince + 86400
+	}
+	orderType := spec.OrderType
+	if "" == orderType {
+		orderType = types.ORDER_TYPE_MARKET
+	}
+	delegate, protocol, err := c.delegate(spec.DelegateAddress)
+	if nil != err {
+		return err
+	}
+
+	// the auth key is only used to sign the ring including this order by the wallet
+	authKey, err := ethCrypto.GenerateKey()
+	if nil != err {
+		return err
+	}
+	authPrivateKey, err := crypto.NewPrivateKeyCrypto(true, common.ToHex(ethCrypto.FromECDSA(authKey)))
+	if nil != err {
+		return err
+	}
+
+	order := &types.Order{
+		Protocol:              protocol,
+		DelegateAddress:       delegate,
+		AuthAddr:              authPrivateKey.Address(),
+		AuthPrivateKey:        authPrivateKey,
+		WalletAddress:         spec.WalletAddress,
+		TokenS:                tokenS,
+		TokenB:                tokenB,
+		AmountS:               amountS,
+		AmountB:               amountB,
+		ValidSince:            big.NewInt(validSince),
+		ValidUntil:            big.NewInt(validUntil),
+		LrcFee:                lrcFee,
+		BuyNoMoreThanAmountB:  spec.BuyNoMoreThanAmountB,
+		MarginSplitPercentage: marginSplit,
+		Owner:                 spec.Owner,
+		OrderType:             orderType,
+	}
+	order.Hash = order.GenerateHash()
+	c.order = order
+	return c.showOrder(nil)
+}
+
+func (c *Console) showOrder(args []json.RawMessage) error {
+	if nil == c.order {
+		return errors.New("there isn't any order, create it by order.new")
+	}
+	return c.printJson(types.ToOrderJsonRequest(c.order))
+}
+
+func (c *Console) orderHash(args []json.RawMessage) error {
+	if nil == c.order {
+		return errors.New("there isn't any order, create it by order.new")
+	}
+	return c.printJson(c.order.GenerateHash().Hex())
+}
+
+func (c *Console) signOrder(args []json.RawMessage) error {
+	if nil == c.order {
+		return errors.New("there isn't any order, create it by order.new")
+	}
+	var v string
+	if len(args) > 0 {
+		if err := json.Unmarshal(args[0], &v); nil != err {
+			return fmt.Errorf("invalid pow difficulty, err:%s", err.Error())
+		}
+	} else if err := c.client.Call(&v, "loopring_getPowDifficulty"); nil != err {
+		return fmt.Errorf("failed to get pow difficulty of relay, err:%s", err.Error())
+	}
+	difficulty, err := parseBig(v)
+	if nil != err {
+		return fmt.Errorf("invalid pow difficulty, err:%s", err.Error())
+	}
+	if err := c.unlock(c.order.Owner); nil != err {
+		return err
+	}
+	c.order.Hash = c.order.GenerateHash()
+	if err := c.order.GenerateAndSetSignature(c.order.Owner); nil != err {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), powSearchTimeout)
+	defer cancel()
+	if c.order.PowNonce, err = gateway.SearchPowNonce(ctx, c.order.V, c.order.R, c.order.S, difficulty); nil != err {
+		return err
+	}
+	return c.showOrder(nil)
+}
+
+func (c *Console) submitOrder(args []json.RawMessage) error {
+	if nil == c.order {
+		return errors.New("there isn't any order, create it by order.new")
+	}
+	if (types.Bytes32{}) == c.order.R && (types.Bytes32{}) == c.order.S {
+		return errors.New("the order hasn't been signed, sign it by order.sign")
+	}
+	var orderHash string
+	if err := c.client.Call(&orderHash, "loopring_submitOrder", types.ToOrderJsonRequest(c.order)); nil != err {
+		return err
+	}
+	return c.printJson(orderHash)
+}
+
+// unlock unlocks the owner in keystore, the passphrase is asked on terminal if it isn't in the sources
+func (c *Console) unlock(owner common.Address) error {
+	if "" == c.datadir {
+		return errors.New("the keystore is required to sign, attach with --datadir")
+	}
+	if crypto.CanSign(owner) {
+		return nil
+	}
+	pass, err := c.sources.Passphrase(owner)
+	if passphrase.ErrNotFound == err && nil != c.term {
+		pass, err = c.term.ReadPassword(fmt.Sprintf("passphrase of %s: ", owner.Hex()))
+	}
+	if nil != err {
+		return err
+	}
+	if err := crypto.UnlockKSAccount(accounts.Account{Address: owner}, pass); nil != err {
+		return fmt.Errorf("failed to unlock address:%s, err:%s", owner.Hex(), err.Error())
+	}
+	return nil
+}
+
+// token returns the address of token by symbol or address
+func (c *Console) token(v string) (common.Address, error) {
+	var tokens []types.Token
+	if err := c.client.Call(&tokens, "loopring_getSupportedTokens"); nil != err {
+		return common.Address{}, err
+	}
+	for _, token := range tokens {
+		if strings.ToUpper(token.Symbol) == strings.ToUpper(v) ||
+			(common.IsHexAddress(v) && token.Protocol == common.HexToAddress(v)) {
+			return token.Protocol, nil
+		}
+	}
+	return common.Address{}, fmt.Errorf("token:%s isn't supported by relay", v)
+}
+
+// delegate returns the delegate and its protocol, it's the first delegate of relay if it's not given
+func (c *Console) delegate(delegate common.Address) (common.Address, common.Address, error) {
+	var contracts map[string][]string
+	if err := c.client.Call(&contracts, "loopring_getContracts"); nil != err {
+		return common.Address{}, common.Address{}, err
+	}
+	delegates := []string{}
+	for d := range contracts {
+		delegates = append(delegates, d)
+	}
+	sort.Strings(delegates)
+	for _, d := range delegates {
+		if protocols := contracts[d]; len(protocols) > 0 &&
+			(types.IsZeroAddress(delegate) || common.HexToAddress(d) == delegate) {
+			return common.HexToAddress(d), common.HexToAddress(protocols[0]), nil
+		}
+	}
+	return common.Address{}, common.Address{}, fmt.Errorf("delegate:%s isn't supported by relay", delegate.Hex())
+}
+
+func parseBig(v string) (*big.Int, error) {
+	amount, ok := new(big.Int), false
+	if strings.HasPrefix(v, "0x") {
+		amount, ok = amount.SetString(strings.TrimPrefix(v, "0x"), 16)
+	} else {
+		amount, ok = amount.SetString(v, 10)
+	}
+	if !ok || amount.Sign() < 0 {
+		return nil, fmt.Errorf("%s isn't a hex or decimal number", v)
+	}
+	return amount, nil
+}
@@ -0,0 +1,118 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package crypto
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Loopring/relay/crypto/passphrase"
+	"github.com/Loopring/relay/log"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"time"
+)
+
+type AccountStatus struct {
+	Address    common.Address `json:"address"`
+	Unlocked   bool           `json:"unlocked"`
+	RelockTime int64          `json:"relockTime"`
+}
+
+// AccountAdminApi is registered under the "account" namespace of the admin endpoint,
+// it locks and unlocks the keystore accounts, such as rotating a miner without restart
+type AccountAdminApi struct {
+	source        passphrase.Source
+	relockTimeout time.Duration
+}
+
+func NewAccountAdminApi(source passphrase.Source, relockTimeout time.Duration) *AccountAdminApi {
+	return &AccountAdminApi{source: source, relockTimeout: relockTimeout}
+}
+
+// Unlock unlocks the address, the passphrase is read from the passphrase sources if it's not given,
+// timeout is in seconds, the default is keystore.relock_timeout and 0 means never relock
+func (a *AccountAdminApi) Unlock(address common.Address, pass *string, timeout *int64) (*AccountStatus, error) {
+	var (
+		passphraseStr string
+		err           error
+	)
+	if nil != pass {
+		passphraseStr = *pass
+	} else if nil == a.source {
+		return nil, errors.New("passphrase is required")
+	} else if passphraseStr, err = a.source.Passphrase(address); nil != err {
+		return nil, fmt.Errorf("failed to get passphrase of address:%s, err:%s", address.Hex(), err.Error())
+	}
+	relockTimeout := a.relockTimeout
+	if nil != timeout {
+		if *timeout < 0 {
+			return nil, errors.New("timeout must not be negative")
+		}
+		relockTimeout = time.Duration(*timeout) * time.Second
+	}
+	if err := TimedUnlockKSAccount(accounts.Account{Address: address}, passphraseStr, relockTimeout); nil != err {
+		log.Errorf("failed to unlock address:%s, err:%s", address.Hex(), err.Error())
+		return nil, err
+	}
+	log.Infof("unlocked address:%s through admin api, relock timeout:%s", address.Hex(), relockTimeout.String())
+	return a.status(address)
+}
+
+func (a *AccountAdminApi) Lock(address common.Address) (*AccountStatus, error) {
+	if err := LockKSAccount(address); nil != err {
+		return nil, err
+	}
+	log.Infof("locked address:%s through admin api", address.Hex())
+	return a.status(address)
+}
+
+// List returns the accounts in keystore and their lock status
+func (a *AccountAdminApi) List() ([]AccountStatus, error) {
+	ksAccounts, err := KSAccounts()
+	if nil != err {
+		return nil, err
+	}
+	statuses := []AccountStatus{}
+	for _, acc := range ksAccounts {
+		statuses = append(statuses, toAccountStatus(acc))
+	}
+	return statuses, nil
+}
+
+func (a *AccountAdminApi) status(address common.Address) (*AccountStatus, error) {
+	ksAccounts, err := KSAccounts()
+	if nil != err {
+		return nil, err
+	}
+	for _, acc := range ksAccounts {
+		if acc.Address == address {
+			status := toAccountStatus(acc)
+			return &status, nil
+		}
+	}
+	return nil, fmt.Errorf("address:%s isn't in keystore", address.Hex())
+}
+
+func toAccountStatus(acc KSAccountStatus) AccountStatus {
+	status := AccountStatus{Address: acc.Address, Unlocked: acc.Unlocked}
+	if acc.Unlocked && !acc.RelockTime.IsZero() {
+		status.RelockTime = acc.RelockTime.Unix()
+	}
+	return status
+}
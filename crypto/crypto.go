@@ -20,6 +20,7 @@ package crypto
 
 import (
 	"crypto/ecdsa"
+	"errors"
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/common"
@@ -28,6 +29,8 @@ import (
 	ethCrypto "github.com/ethereum/go-ethereum/crypto"
 	"math/big"
 	"strings"
+	"sync"
+	"time"
 )
 
 type EthCrypto struct {
@@ -78,8 +81,10 @@ func (c EthCrypto) SigToVRS(sig []byte) (v byte, r []byte, s []byte) {
 
 type EthKSCrypto struct {
 	EthCrypto
-	ks               *keystore.KeyStore
-	unlockedAccounts map[common.Address]bool
+	ks  *keystore.KeyStore
+	mtx *sync.RWMutex
+	// unlocked accounts and the time they relock, zero means never
+	unlockedAccounts map[common.Address]time.Time
 }
 
 func (c EthKSCrypto) Sign(hashPre []byte, signerAddr common.Address) ([]byte, error) {
@@ -89,13 +94,68 @@ func (c EthKSCrypto) Sign(hashPre []byte, signerAddr common.Address) ([]byte, er
 }
 
 func (c EthKSCrypto) UnlockAccount(acc accounts.Account, passphrase string) error {
-	c.unlockedAccounts[acc.Address] = true
-	return c.ks.Unlock(acc, passphrase)
+	return c.TimedUnlockAccount(acc, passphrase, 0)
+}
+
+// TimedUnlockAccount unlocks the account, it will be locked again after timeout if timeout > 0
+func (c EthKSCrypto) TimedUnlockAccount(acc accounts.Account, passphrase string, timeout time.Duration) error {
+	if nil == c.ks {
+		return errors.New("keystore hasn't been initialized")
+	}
+	if err := c.ks.TimedUnlock(acc, passphrase, timeout); nil != err {
+		return err
+	}
+	// keystore doesn't change the timeout of account unlocked indefinitely, relock it with the verified passphrase
+	if relockTime, unlocked := c.RelockTime(acc.Address); timeout > 0 && unlocked && relockTime.IsZero() {
+		c.ks.Lock(acc.Address)
+		if err := c.ks.TimedUnlock(acc, passphrase, timeout); nil != err {
+			return err
+		}
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if timeout > 0 {
+		c.unlockedAccounts[acc.Address] = time.Now().Add(timeout)
+	} else {
+		c.unlockedAccounts[acc.Address] = time.Time{}
+	}
+	return nil
+}
+
+func (c EthKSCrypto) LockAccount(addr common.Address) error {
+	if nil == c.ks {
+		return errors.New("keystore hasn't been initialized")
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	delete(c.unlockedAccounts, addr)
+	return c.ks.Lock(addr)
 }
 
 func (c EthKSCrypto) IsUnlocked(addr common.Address) bool {
-	_, exists := c.unlockedAccounts[addr]
-	return exists
+	_, unlocked := c.RelockTime(addr)
+	return unlocked
+}
+
+// RelockTime returns the time the account will be locked again, it's zero if the account won't be relocked
+func (c EthKSCrypto) RelockTime(addr common.Address) (time.Time, bool) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	relockTime, exists := c.unlockedAccounts[addr]
+	if !exists || (!relockTime.IsZero() && time.Now().After(relockTime)) {
+		return time.Time{}, false
+	}
+	return relockTime, true
+}
+
+func (c EthKSCrypto) Accounts() []common.Address {
+	addresses := []common.Address{}
+	if nil != c.ks {
+		for _, acc := range c.ks.Accounts() {
+			addresses = append(addresses, acc.Address)
+		}
+	}
+	return addresses
 }
 
 func (c EthKSCrypto) SignTx(addr common.Address, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
@@ -104,7 +164,7 @@ func (c EthKSCrypto) SignTx(addr common.Address, tx *types.Transaction, chainID
 }
 
 func NewKSCrypto(homestead bool, ks *keystore.KeyStore) EthKSCrypto {
-	return EthKSCrypto{EthCrypto: EthCrypto{homestead: homestead}, ks: ks, unlockedAccounts: make(map[common.Address]bool)}
+	return EthKSCrypto{EthCrypto: EthCrypto{homestead: homestead}, ks: ks, mtx: &sync.RWMutex{}, unlockedAccounts: make(map[common.Address]time.Time)}
 }
 
 type EthPrivateKeyCrypto struct {
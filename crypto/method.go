@@ -26,6 +26,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	ethCrypto "github.com/ethereum/go-ethereum/crypto"
 	"math/big"
+	"time"
 )
 
 func ValidateSignatureValues(v byte, r, s []byte) bool {
@@ -74,6 +75,61 @@ func IsKSAccountUnlocked(addr common.Address) bool {
 	}
 }
 
+func TimedUnlockKSAccount(acc accounts.Account, passphrase string, timeout time.Duration) error {
+	if c, ok := crypto.(EthKSCrypto); ok {
+		return c.TimedUnlockAccount(acc, passphrase, timeout)
+	} else {
+		return errors.New("can't unlock ")
+	}
+}
+
+func LockKSAccount(addr common.Address) error {
+	if c, ok := crypto.(EthKSCrypto); ok {
+		return c.LockAccount(addr)
+	} else {
+		return errors.New("can't lock ")
+	}
+}
+
+type KSAccountStatus struct {
+	Address    common.Address
+	Unlocked   bool
+	RelockTime time.Time
+}
+
+// KSAccounts returns all accounts in keystore with their lock status
+func KSAccounts() ([]KSAccountStatus, error) {
+	if c, ok := crypto.(EthKSCrypto); ok {
+		statuses := []KSAccountStatus{}
+		for _, addr := range c.Accounts() {
+			status := KSAccountStatus{Address: addr}
+			status.RelockTime, status.Unlocked = c.RelockTime(addr)
+			statuses = append(statuses, status)
+		}
+		return statuses, nil
+	} else {
+		return nil, errors.New("the signer is not keystore")
+	}
+}
+
+// CanSign reports whether the address can be used to sign now,
+// keystore accounts should be unlocked and not expired
+func CanSign(addr common.Address) bool {
+	if c, ok := crypto.(EthKSCrypto); ok {
+		return c.IsUnlocked(addr)
+	}
+	return true
+}
+
+// RemoteAccounts returns the accounts managed by the remote signer
+func RemoteAccounts() ([]common.Address, error) {
+	if c, ok := crypto.(*EthRemoteCrypto); ok {
+		return c.Accounts()
+	} else {
+		return nil, errors.New("the signer is not remote")
+	}
+}
+
 func SignTx(a common.Address, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
 	return crypto.SignTx(a, tx, chainID)
 }
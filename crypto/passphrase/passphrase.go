@@ -0,0 +1,194 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package passphrase
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	ENV_PASSWORD        = "RELAY_PASSWORD"
+	ENV_PASSWORD_PREFIX = "RELAY_PASSWORD_"
+	// set by systemd when LoadCredential= is used
+	ENV_CREDENTIALS_DIR     = "CREDENTIALS_DIRECTORY"
+	DEFAULT_CREDENTIALS_DIR = "/run/secrets"
+)
+
+var ErrNotFound = errors.New("passphrase not found")
+
+// Source supplies the passphrase used to unlock a keystore account,
+// it returns ErrNotFound if it doesn't know the address
+type Source interface {
+	Name() string
+	Passphrase(addr common.Address) (string, error)
+}
+
+// Sources tries each source in order
+type Sources []Source
+
+func (sources Sources) Name() string {
+	names := []string{}
+	for _, source := range sources {
+		names = append(names, source.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+func (sources Sources) Passphrase(addr common.Address) (string, error) {
+	for _, source := range sources {
+		if passphrase, err := source.Passphrase(addr); nil == err {
+			return passphrase, nil
+		} else if ErrNotFound != err {
+			return "", fmt.Errorf("%s: %s", source.Name(), err.Error())
+		}
+	}
+	return "", ErrNotFound
+}
+
+// FileSource reads one passphrase per line, the lines match the unlocked accounts in order
+type FileSource struct {
+	path        string
+	passphrases map[common.Address]string
+}
+
+func NewFileSource(path string, addresses []common.Address) (*FileSource, error) {
+	if err := CheckSecretFile(path); nil != err {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if nil != err {
+		return nil, err
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\r\n"), "\n")
+	if len(lines) < len(addresses) {
+		return nil, fmt.Errorf("password file:%s contains %d passwords, but %d accounts to unlock", path, len(lines), len(addresses))
+	}
+	source := &FileSource{path: path, passphrases: make(map[common.Address]string)}
+	for idx, addr := range addresses {
+		source.passphrases[addr] = strings.TrimRight(lines[idx], "\r")
+	}
+	return source, nil
+}
+
+func (source *FileSource) Name() string {
+	return "file:" + source.path
+}
+
+func (source *FileSource) Passphrase(addr common.Address) (string, error) {
+	if passphrase, exists := source.passphrases[addr]; exists {
+		return passphrase, nil
+	}
+	return "", ErrNotFound
+}
+
+// StaticSource holds the passphrases given on command line
+type StaticSource map[common.Address]string
+
+func (source StaticSource) Name() string {
+	return "static"
+}
+
+func (source StaticSource) Passphrase(addr common.Address) (string, error) {
+	if passphrase, exists := source[addr]; exists {
+		return passphrase, nil
+	}
+	return "", ErrNotFound
+}
+
+// EnvSource reads RELAY_PASSWORD_<ADDRESS> (hex address without 0x, upper case), then RELAY_PASSWORD
+type EnvSource struct{}
+
+func NewEnvSource() EnvSource {
+	return EnvSource{}
+}
+
+func (source EnvSource) Name() string {
+	return "env"
+}
+
+func (source EnvSource) Passphrase(addr common.Address) (string, error) {
+	if passphrase, exists := os.LookupEnv(EnvName(addr)); exists {
+		return passphrase, nil
+	}
+	if passphrase, exists := os.LookupEnv(ENV_PASSWORD); exists {
+		return passphrase, nil
+	}
+	return "", ErrNotFound
+}
+
+func EnvName(addr common.Address) string {
+	return ENV_PASSWORD_PREFIX + strings.ToUpper(strings.TrimPrefix(strings.ToLower(addr.Hex()), "0x"))
+}
+
+// DirSource reads the file named by the address in a credentials directory,
+// such as the one of systemd LoadCredential= or docker secrets.
+// files are read on each call, so the passphrases can be rotated without restart
+type DirSource struct {
+	dir string
+}
+
+func NewDirSource(dir string) (*DirSource, error) {
+	if info, err := os.Stat(dir); nil != err {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("credentials directory:%s is not a directory", dir)
+	}
+	return &DirSource{dir: dir}, nil
+}
+
+// DefaultCredentialsDir returns $CREDENTIALS_DIRECTORY, or /run/secrets if it exists
+func DefaultCredentialsDir() string {
+	if dir := os.Getenv(ENV_CREDENTIALS_DIR); "" != dir {
+		return dir
+	}
+	if info, err := os.Stat(DEFAULT_CREDENTIALS_DIR); nil == err && info.IsDir() {
+		return DEFAULT_CREDENTIALS_DIR
+	}
+	return ""
+}
+
+func (source *DirSource) Name() string {
+	return "dir:" + source.dir
+}
+
+func (source *DirSource) Passphrase(addr common.Address) (string, error) {
+	lower := strings.ToLower(addr.Hex())
+	for _, name := range []string{lower, strings.TrimPrefix(lower, "0x"), addr.Hex()} {
+		path := filepath.Join(source.dir, name)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		if err := CheckCredentialFile(path); nil != err {
+			return "", err
+		}
+		data, err := ioutil.ReadFile(path)
+		if nil != err {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	return "", ErrNotFound
+}
@@ -0,0 +1,87 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package passphrase_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Loopring/relay/crypto/passphrase"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	addr1 = common.HexToAddress("0x4bad3053d574cd54513babe21db3f09bea1d387d")
+	addr2 = common.HexToAddress("0x1b978a1d302335a6f2ebe4b8823b5e17c3c84135")
+)
+
+func TestFileSource(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "passphrase")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "passwords")
+	ioutil.WriteFile(path, []byte("pass1\npass2\n"), 0644)
+
+	if _, err := passphrase.NewFileSource(path, []common.Address{addr1, addr2}); nil == err {
+		t.Fatalf("password file readable by others should be rejected")
+	}
+	os.Chmod(path, 0600)
+	source, err := passphrase.NewFileSource(path, []common.Address{addr1, addr2})
+	if nil != err {
+		t.Fatalf("err:%s", err.Error())
+	}
+	if p, _ := source.Passphrase(addr2); "pass2" != p {
+		t.Fatalf("expect pass2, but got %s", p)
+	}
+	if _, err := passphrase.NewFileSource(path, []common.Address{addr1, addr2, common.HexToAddress("0x01")}); nil == err {
+		t.Fatalf("password file contains less passwords than accounts should be rejected")
+	}
+}
+
+func TestSources(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "credentials")
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "0x4bad3053d574cd54513babe21db3f09bea1d387d"), []byte("dir1\n"), 0400)
+
+	dirSource, err := passphrase.NewDirSource(dir)
+	if nil != err {
+		t.Fatalf("err:%s", err.Error())
+	}
+	os.Setenv(passphrase.EnvName(addr1), "env1")
+	os.Setenv(passphrase.EnvName(addr2), "env2")
+	defer os.Unsetenv(passphrase.EnvName(addr1))
+	defer os.Unsetenv(passphrase.EnvName(addr2))
+
+	sources := passphrase.Sources{dirSource, passphrase.NewEnvSource()}
+	if p, _ := sources.Passphrase(addr1); "dir1" != p {
+		t.Fatalf("expect dir1, but got %s", p)
+	}
+	if p, _ := sources.Passphrase(addr2); "env2" != p {
+		t.Fatalf("expect env2, but got %s", p)
+	}
+	if _, err := sources.Passphrase(common.HexToAddress("0x01")); passphrase.ErrNotFound != err {
+		t.Fatalf("expect ErrNotFound")
+	}
+
+	os.Chmod(filepath.Join(dir, "0x4bad3053d574cd54513babe21db3f09bea1d387d"), 0666)
+	if _, err := sources.Passphrase(addr1); nil == err {
+		t.Fatalf("credential writable by others should be rejected")
+	}
+}
@@ -0,0 +1,66 @@
+// +build !windows
+
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package passphrase
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// CheckSecretFile requires a regular file owned by the current user and not accessible by group or others
+func CheckSecretFile(path string) error {
+	info, err := checkRegularFile(path)
+	if nil != err {
+		return err
+	}
+	if perm := info.Mode().Perm(); perm&0077 != 0 {
+		return fmt.Errorf("permissions %#o of %s are too open, it should be 0600 or 0400", perm, path)
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != os.Geteuid() {
+		return fmt.Errorf("%s should be owned by the user running relay", path)
+	}
+	return nil
+}
+
+// CheckCredentialFile is less strict than CheckSecretFile, the files in credentials directory
+// are owned by the service manager and can be readable by others, but mustn't be writable
+func CheckCredentialFile(path string) error {
+	info, err := checkRegularFile(path)
+	if nil != err {
+		return err
+	}
+	if perm := info.Mode().Perm(); perm&0022 != 0 {
+		return fmt.Errorf("permissions %#o of %s are too open, it mustn't be writable by group or others", perm, path)
+	}
+	return nil
+}
+
+func checkRegularFile(path string) (os.FileInfo, error) {
+	info, err := os.Stat(path)
+	if nil != err {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a regular file", path)
+	}
+	return info, nil
+}
@@ -0,0 +1,42 @@
+// +build windows
+
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package passphrase
+
+import (
+	"fmt"
+	"os"
+)
+
+// unix permissions aren't available on windows, only the type of file is checked
+func CheckSecretFile(path string) error {
+	return CheckCredentialFile(path)
+}
+
+func CheckCredentialFile(path string) error {
+	info, err := os.Stat(path)
+	if nil != err {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", path)
+	}
+	return nil
+}
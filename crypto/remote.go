@@ -0,0 +1,155 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package crypto
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"math/big"
+)
+
+const (
+	SIGNER_KEYSTORE = "keystore"
+	SIGNER_REMOTE   = "remote"
+
+	//the content type of account_signData, the data is signed with the prefix "\x19Ethereum Signed Message:\n"
+	SIGN_DATA_TEXT_PLAIN = "text/plain"
+)
+
+// SendTxArgs is the transaction sent to account_signTransaction
+type SendTxArgs struct {
+	From     common.Address  `json:"from"`
+	To       *common.Address `json:"to"`
+	Gas      *hexutil.Big    `json:"gas"`
+	GasPrice *hexutil.Big    `json:"gasPrice"`
+	Value    *hexutil.Big    `json:"value"`
+	Nonce    hexutil.Uint64  `json:"nonce"`
+	Data     hexutil.Bytes   `json:"data"`
+	ChainId  *hexutil.Big    `json:"chainId,omitempty"`
+}
+
+func (args *SendTxArgs) ToTransaction() *types.Transaction {
+	if nil == args.To {
+		return types.NewContractCreation(uint64(args.Nonce), args.Value.ToInt(), args.Gas.ToInt(), args.GasPrice.ToInt(), args.Data)
+	}
+	return types.NewTransaction(uint64(args.Nonce), *args.To, args.Value.ToInt(), args.Gas.ToInt(), args.GasPrice.ToInt(), args.Data)
+}
+
+// SignTxResult is the result of account_signTransaction
+type SignTxResult struct {
+	Raw hexutil.Bytes      `json:"raw"`
+	Tx  *types.Transaction `json:"tx"`
+}
+
+// EthRemoteCrypto sends Sign and SignTx to an external signer, the keys are never loaded by relay.
+// The signer serves the clef style apis: account_list, account_signData and account_signTransaction,
+// on an ipc path or a http endpoint.
+type EthRemoteCrypto struct {
+	EthCrypto
+	endpoint string
+	client   *rpc.Client
+}
+
+func NewRemoteCrypto(homestead bool, endpoint string) (*EthRemoteCrypto, error) {
+	if "" == endpoint {
+		return nil, errors.New("the endpoint of remote signer is empty")
+	}
+	client, err := rpc.Dial(endpoint)
+	if nil != err {
+		return nil, fmt.Errorf("dial remote signer:%s err:%s", endpoint, err.Error())
+	}
+	return &EthRemoteCrypto{EthCrypto: EthCrypto{homestead: homestead}, endpoint: endpoint, client: client}, nil
+}
+
+func (c *EthRemoteCrypto) Accounts() ([]common.Address, error) {
+	var accounts []common.Address
+	err := c.client.Call(&accounts, "account_list")
+	return accounts, err
+}
+
+func (c *EthRemoteCrypto) Sign(hashPre []byte, signerAddr common.Address) ([]byte, error) {
+	var sig hexutil.Bytes
+	if err := c.client.Call(&sig, "account_signData", SIGN_DATA_TEXT_PLAIN, signerAddr, hexutil.Bytes(hashPre)); nil != err {
+		return nil, err
+	}
+	if len(sig) != 65 {
+		return nil, fmt.Errorf("invalid signature length:%d from remote signer", len(sig))
+	}
+	//clef returns v of 27 or 28
+	if sig[64] >= 27 {
+		sig[64] -= 27
+	}
+
+	if addr, err := c.SigToAddress(hashPre, sig); nil != err {
+		return nil, err
+	} else if common.BytesToAddress(addr) != signerAddr {
+		return nil, fmt.Errorf("remote signer signed by:%s, not:%s", common.BytesToAddress(addr).Hex(), signerAddr.Hex())
+	}
+	return sig, nil
+}
+
+func (c *EthRemoteCrypto) SignTx(addr common.Address, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	args := &SendTxArgs{
+		From:     addr,
+		To:       tx.To(),
+		Gas:      (*hexutil.Big)(tx.Gas()),
+		GasPrice: (*hexutil.Big)(tx.GasPrice()),
+		Value:    (*hexutil.Big)(tx.Value()),
+		Nonce:    hexutil.Uint64(tx.Nonce()),
+		Data:     tx.Data(),
+	}
+	var signer types.Signer = types.HomesteadSigner{}
+	if nil != chainID {
+		args.ChainId = (*hexutil.Big)(chainID)
+		signer = types.NewEIP155Signer(chainID)
+	}
+
+	var res SignTxResult
+	if err := c.client.Call(&res, "account_signTransaction", args); nil != err {
+		return nil, err
+	}
+	signedTx := new(types.Transaction)
+	if err := rlp.DecodeBytes(res.Raw, signedTx); nil != err {
+		return nil, err
+	}
+
+	//the signed tx must be the one requested
+	if signedTx.Nonce() != tx.Nonce() || signedTx.Gas().Cmp(tx.Gas()) != 0 || signedTx.GasPrice().Cmp(tx.GasPrice()) != 0 ||
+		signedTx.Value().Cmp(tx.Value()) != 0 || !sameAddress(signedTx.To(), tx.To()) || common.Bytes2Hex(signedTx.Data()) != common.Bytes2Hex(tx.Data()) {
+		return nil, errors.New("the tx signed by remote signer is different from the one requested")
+	}
+	if sender, err := types.Sender(signer, signedTx); nil != err {
+		return nil, err
+	} else if sender != addr {
+		return nil, fmt.Errorf("remote signer signed by:%s, not:%s", sender.Hex(), addr.Hex())
+	}
+	return signedTx, nil
+}
+
+func sameAddress(a, b *common.Address) bool {
+	if nil == a || nil == b {
+		return a == b
+	}
+	return *a == *b
+}
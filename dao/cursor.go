@@ -0,0 +1,133 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package dao
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"strings"
+)
+
+// keyset of page query, rows are sorted by the key columns desc
+type cursorKeyset struct {
+	timeColumn     string
+	blockColumn    string
+	logIndexColumn string
+}
+
+// the key columns must not change after the row is saved, otherwise a row updated between two pages is skipped or repeated
+var (
+	// orders are listed by create_time as before, id breaks the tie
+	orderKeyset     = cursorKeyset{timeColumn: "create_time"}
+	fillKeyset      = cursorKeyset{blockColumn: "block_number", logIndexColumn: "log_index"}
+	ringMinedKeyset = cursorKeyset{blockColumn: "block_number"}
+	// views are listed by id, newest saved first, update_time changes when a pending tx is mined or failed
+	txViewKeyset = cursorKeyset{}
+)
+
+// Cursor points at the last row of a page, the next page starts after it
+type Cursor struct {
+	Time        int64
+	BlockNumber int64
+	LogIndex    int64
+	ID          int
+}
+
+func (c *Cursor) Encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%d:%d:%d", c.Time, c.BlockNumber, c.LogIndex, c.ID)))
+}
+
+// DecodeCursor returns nil if str is empty
+func DecodeCursor(str string) (*Cursor, error) {
+	if "" == str {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(str)
+	if nil != err {
+		return nil, errors.New("invalid cursor")
+	}
+	c := &Cursor{}
+	if _, err := fmt.Sscanf(string(data), "%d:%d:%d:%d", &c.Time, &c.BlockNumber, &c.LogIndex, &c.ID); nil != err {
+		return nil, errors.New("invalid cursor")
+	}
+	return c, nil
+}
+
+// keys returns the configured key columns and their values in c, id is always the last one
+func (k cursorKeyset) keys(c *Cursor) ([]string, []interface{}) {
+	columns := make([]string, 0)
+	values := make([]interface{}, 0)
+	if "" != k.timeColumn {
+		columns = append(columns, k.timeColumn)
+		values = append(values, c.Time)
+	}
+	if "" != k.blockColumn {
+		columns = append(columns, k.blockColumn)
+		values = append(values, c.BlockNumber)
+	}
+	if "" != k.logIndexColumn {
+		columns = append(columns, k.logIndexColumn)
+		values = append(values, c.LogIndex)
+	}
+	return append(columns, "id"), append(values, c.ID)
+}
+
+func (k cursorKeyset) order() string {
+	columns, _ := k.keys(&Cursor{})
+	return strings.Join(columns, " desc, ") + " desc"
+}
+
+// where selects the rows after c, (a < ?) OR (a = ? AND b < ?) OR ...
+func (k cursorKeyset) where(db *gorm.DB, c *Cursor) *gorm.DB {
+	if nil == c {
+		return db
+	}
+	columns, values := k.keys(c)
+	conds := make([]string, 0)
+	args := make([]interface{}, 0)
+	for i := range columns {
+		cond := ""
+		for j := 0; j < i; j++ {
+			cond += columns[j] + " = ? AND "
+			args = append(args, values[j])
+		}
+		conds = append(conds, "("+cond+columns[i]+" < ?)")
+		args = append(args, values[i])
+	}
+	return db.Where(strings.Join(conds, " OR "), args...)
+}
+
+// page returns db with keyset ordering, paged by cursor if it isn't nil, otherwise by pageIndex
+func (k cursorKeyset) page(db *gorm.DB, c *Cursor, pageIndex, pageSize int) *gorm.DB {
+	db = k.where(db, c).Order(k.order()).Limit(pageSize)
+	if nil == c {
+		db = db.Offset((pageIndex - 1) * pageSize)
+	}
+	return db
+}
+
+// nextCursor returns empty string if the page isn't full, meaning there is no more rows
+func nextCursor(rows, pageSize int, last *Cursor) string {
+	if rows < pageSize || rows == 0 || nil == last {
+		return ""
+	}
+	return last.Encode()
+}
@@ -0,0 +1,45 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package dao_test
+
+import (
+	"github.com/Loopring/relay/dao"
+	"testing"
+)
+
+func TestCursor_Encode(t *testing.T) {
+	c := &dao.Cursor{Time: 1520000000, BlockNumber: 5203144, LogIndex: 17, ID: 2381}
+	decoded, err := dao.DecodeCursor(c.Encode())
+	if nil != err {
+		t.Fatalf("decode cursor err:%s", err.Error())
+	}
+	if *decoded != *c {
+		t.Fatalf("decoded cursor %+v not equal to %+v", decoded, c)
+	}
+
+	if c, err := dao.DecodeCursor(""); nil != err || nil != c {
+		t.Fatalf("empty cursor should be decoded as nil")
+	}
+
+	for _, invalid := range []string{"abc", "!!", "MToy"} {
+		if _, err := dao.DecodeCursor(invalid); nil == err {
+			t.Fatalf("cursor %s should be invalid", invalid)
+		}
+	}
+}
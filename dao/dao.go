@@ -27,10 +27,11 @@ import (
 )
 
 type PageResult struct {
-	Data      []interface{} `json:"data"`
-	PageIndex int           `json:"pageIndex"`
-	PageSize  int           `json:"pageSize"`
-	Total     int           `json:"total"`
+	Data       []interface{} `json:"data"`
+	PageIndex  int           `json:"pageIndex"`
+	PageSize   int           `json:"pageSize"`
+	Total      int           `json:"total"`
+	NextCursor string        `json:"nextCursor,omitempty"`
 }
 
 type RdsServiceImpl struct {
@@ -57,12 +58,21 @@ func NewRdsService(options config.MysqlOptions) *RdsServiceImpl {
 	db.DB().SetMaxOpenConns(options.MaxOpenConnections)
 
 	db.LogMode(options.Debug)
+	registerMetricsCallbacks(db)
 
 	impl.db = db
 
 	return impl
 }
 
+func (s *RdsServiceImpl) Ping() error {
+	return s.db.DB().Ping()
+}
+
+func (s *RdsServiceImpl) Close() error {
+	return s.db.Close()
+}
+
 func (s *RdsServiceImpl) Prepare() {
 	var tables []interface{}
 
@@ -82,6 +92,10 @@ func (s *RdsServiceImpl) Prepare() {
 	tables = append(tables, &TransactionEntity{})
 	tables = append(tables, &TransactionView{})
 	tables = append(tables, &CheckPoint{})
+	tables = append(tables, &RingSimulation{})
+	tables = append(tables, &RingProfit{})
+	tables = append(tables, &OwnerGroup{})
+	tables = append(tables, &SelfTradeRecord{})
 	//tables = append(tables, &RingMinedMethod{})
 
 	for _, t := range tables {
@@ -129,21 +129,27 @@ func (s *RdsServiceImpl) FindFillsByRingHash(ringHash common.Hash) ([]FillEvent,
 	return fills, err
 }
 
-func (s *RdsServiceImpl) FillsPageQuery(query map[string]interface{}, pageIndex, pageSize int) (res PageResult, err error) {
+func (s *RdsServiceImpl) FillsPageQuery(query map[string]interface{}, cursor *Cursor, pageIndex, pageSize int) (res PageResult, err error) {
 	fills := make([]FillEvent, 0)
 	res = PageResult{PageIndex: pageIndex, PageSize: pageSize, Data: make([]interface{}, 0)}
-	err = s.db.Where(query).Where("fork=?", false).Order("create_time desc").Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&fills).Error
+	err = fillKeyset.page(s.db.Where(query).Where("fork=?", false), cursor, pageIndex, pageSize).Find(&fills).Error
 	if err != nil {
 		return res, err
 	}
-	err = s.db.Model(&FillEvent{}).Where(query).Where("fork=?", false).Count(&res.Total).Error
-	if err != nil {
-		return res, err
+	if nil == cursor {
+		err = s.db.Model(&FillEvent{}).Where(query).Where("fork=?", false).Count(&res.Total).Error
+		if err != nil {
+			return res, err
+		}
 	}
 
 	for _, fill := range fills {
 		res.Data = append(res.Data, fill)
 	}
+	if len(fills) > 0 {
+		last := fills[len(fills)-1]
+		res.NextCursor = nextCursor(len(fills), pageSize, &Cursor{BlockNumber: last.BlockNumber, LogIndex: last.LogIndex, ID: last.ID})
+	}
 	return
 }
 
@@ -178,6 +184,16 @@ func (s *RdsServiceImpl) QueryRecentFills(market, owner string, start int64, end
 	return
 }
 
+// QueryFillsByTime returns all fills of market in [start, end] sorted by create_time asc
+func (s *RdsServiceImpl) QueryFillsByTime(market string, start, end int64) (fills []FillEvent, err error) {
+	err = s.db.Where("market = ?", market).
+		Where("create_time >= ? AND create_time <= ?", start, end).
+		Where("fork=?", false).
+		Order("create_time asc").
+		Find(&fills).Error
+	return
+}
+
 func buildTimeQueryString(start, end int64) string {
 	rst := ""
 	if start != 0 && end == 0 {
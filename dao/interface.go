@@ -28,6 +28,8 @@ import (
 type RdsService interface {
 	// create tables
 	Prepare()
+	Ping() error
+	Close() error
 
 	// base functions
 	Add(item interface{}) error
@@ -46,15 +48,18 @@ type RdsService interface {
 	GetOrdersByHash(orderhashs []string) (map[string]Order, error)
 	MarkMinerOrders(filterOrderhashs []string, blockNumber int64) error
 	GetOrdersForMiner(protocol, tokenS, tokenB string, length int, filterStatus []types.OrderStatus, reservedTime, startBlockNumber, endBlockNumber int64) ([]*Order, error)
+	GetStarvedOrdersForMiner(protocol, tokenS, tokenB string, length int, filterStatus []types.OrderStatus, reservedTime, startBlockNumber, endBlockNumber, evaluatedBefore int64) ([]*Order, error)
+	MarkMinerEvaluatedOrders(orderhashs []string, roundNumber int64) error
 	GetCutoffOrders(owner common.Address, cutoffTime *big.Int) ([]Order, error)
 	GetCutoffPairOrders(owner, token1, token2 common.Address, cutoffTime *big.Int) ([]Order, error)
 	SetCutOffOrders(orderHashList []common.Hash, blockNumber *big.Int) error
 	GetOrderBook(protocol, tokenS, tokenB common.Address, length int) ([]Order, error)
-	OrderPageQuery(query map[string]interface{}, statusList []int, pageIndex, pageSize int) (PageResult, error)
+	OrderPageQuery(query map[string]interface{}, statusList []int, cursor *Cursor, pageIndex, pageSize int) (PageResult, error)
 	UpdateBroadcastTimeByHash(hash string, bt int) error
 	UpdateOrderWhileRollbackCutoff(orderhash common.Hash, status types.OrderStatus, blockNumber *big.Int) error
 	UpdateOrderWhileFill(hash common.Hash, status types.OrderStatus, dealtAmountS, dealtAmountB, splitAmountS, splitAmountB, blockNumber *big.Int) error
 	UpdateOrderWhileCancel(hash common.Hash, status types.OrderStatus, cancelledAmountS, cancelledAmountB, blockNumber *big.Int) error
+	UpdateOrderStatus(orderhash common.Hash, status types.OrderStatus) error
 	GetFrozenAmount(owner common.Address, token common.Address, statusSet []types.OrderStatus, delegateAddress common.Address) ([]Order, error)
 	GetFrozenLrcFee(owner common.Address, statusSet []types.OrderStatus) ([]Order, error)
 
@@ -67,9 +72,10 @@ type RdsService interface {
 	// fill event table
 	FindFillEvent(txhash string, FillIndex int64) (*FillEvent, error)
 	QueryRecentFills(mkt, owner string, start int64, end int64) (fills []FillEvent, err error)
+	QueryFillsByTime(market string, start, end int64) (fills []FillEvent, err error)
 	GetFillForkEvents(from, to int64) ([]FillEvent, error)
 	RollBackFill(from, to int64) error
-	FillsPageQuery(query map[string]interface{}, pageIndex, pageSize int) (res PageResult, err error)
+	FillsPageQuery(query map[string]interface{}, cursor *Cursor, pageIndex, pageSize int) (res PageResult, err error)
 	GetLatestFills(query map[string]interface{}, limit int) (res []FillEvent, err error)
 	FindFillsByRingHash(ringHash common.Hash) ([]FillEvent, error)
 
@@ -92,11 +98,20 @@ type RdsService interface {
 	TrendQueryLatest(query Trend, pageIndex, pageSize int) (trends []Trend, err error)
 	TrendQueryByTime(intervals, market string, start, end int64) (trends []Trend, err error)
 	TrendQueryByInterval(intervals, market string, start, end int64) (trends []Trend, err error)
+	TrendQueryLastBefore(intervals, market string, before int64) (trend Trend, err error)
 	TrendQueryForProof(mkt string, interval string, start int64) (trends []Trend, err error)
 
 	// white list
 	GetWhiteList() ([]WhiteList, error)
 	FindWhiteListUserByAddress(address common.Address) (*WhiteList, error)
+	DelWhiteListUser(address common.Address) error
+
+	// owner group
+	GetOwnerGroups() ([]OwnerGroup, error)
+	FindOwnerGroupByAddress(owner common.Address) (*OwnerGroup, error)
+
+	// self trade
+	GetSelfTradeRecords(orderhash string, limit int) ([]SelfTradeRecord, error)
 
 	//ringSubmitInfo
 	//UpdateRingSubmitInfoProtocolTxHash(ringhash common.Hash, txHash string) error
@@ -106,10 +121,18 @@ type RdsService interface {
 	UpdateRingSubmitInfoResult(submitResult *types.RingSubmitResultEvent) error
 	GetRingForSubmitByHash(ringhash common.Hash) (RingSubmitInfo, error)
 	GetRingHashesByTxHash(txHash common.Hash) ([]*RingSubmitInfo, error)
-	RingMinedPageQuery(query map[string]interface{}, pageIndex, pageSize int) (res PageResult, err error)
+	GetPendingBatchTxHashes(limit int) ([]string, error)
+	RingMinedPageQuery(query map[string]interface{}, cursor *Cursor, pageIndex, pageSize int) (res PageResult, err error)
 	GetRingminedMethods(lastId int, limit int) ([]RingMinedEvent, error)
+	GetLatestRingMined(limit int) ([]RingMinedEvent, error)
+	RingSimulationStats(since int64) ([]RingSimulationStat, error)
 	GetFilledOrderByRinghash(ringhash common.Hash) ([]*FilledOrder, error)
 
+	// ring profit table
+	FindRingProfit(ringhash, txHash string) (*RingProfit, error)
+	GetUnreconciledRingProfits(limit int) ([]RingProfit, error)
+	GetRingProfits(start, end int64, miner string) ([]RingProfit, error)
+
 	// transactions
 	GetTransactionById(id int) (Transaction, error)
 
@@ -128,7 +151,8 @@ type RdsService interface {
 	GetTxViewByOwnerAndHashs(owner string, hashs []string) ([]TransactionView, error)
 	GetPendingTxViewByOwner(owner string) ([]TransactionView, error)
 	GetTxViewCountByOwner(owner string, symbol string, status types.TxStatus, typ txtyp.TxType) (int, error)
-	GetTxViewByOwner(owner string, symbol string, status types.TxStatus, typ txtyp.TxType, limit, offset int) ([]TransactionView, error)
+	GetTxViewByOwner(owner string, symbol string, status types.TxStatus, typ txtyp.TxType, cursor *Cursor, limit, offset int) ([]TransactionView, string, error)
+	GetMinedTxViewsByOwner(owner string, end int64, afterId, limit int) ([]TransactionView, error)
 	RollBackTxView(from, to int64) error
 
 	// checkpoint
@@ -0,0 +1,61 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package dao
+
+import (
+	"github.com/Loopring/relay/metrics"
+	"github.com/jinzhu/gorm"
+	"time"
+)
+
+const metricsStartTimeKey = "relay:metrics_start_time"
+
+// registerMetricsCallbacks records the latency and errors of each statement by operation and table
+func registerMetricsCallbacks(db *gorm.DB) {
+	callbacks := db.Callback()
+
+	callbacks.Create().Before("gorm:begin_transaction").Register("relay:metrics_before_create", metricsStart)
+	callbacks.Create().After("gorm:commit_or_rollback_transaction").Register("relay:metrics_after_create", metricsEnd("create"))
+	callbacks.Update().Before("gorm:begin_transaction").Register("relay:metrics_before_update", metricsStart)
+	callbacks.Update().After("gorm:commit_or_rollback_transaction").Register("relay:metrics_after_update", metricsEnd("update"))
+	callbacks.Delete().Before("gorm:begin_transaction").Register("relay:metrics_before_delete", metricsStart)
+	callbacks.Delete().After("gorm:commit_or_rollback_transaction").Register("relay:metrics_after_delete", metricsEnd("delete"))
+	callbacks.Query().Before("gorm:query").Register("relay:metrics_before_query", metricsStart)
+	callbacks.Query().After("gorm:after_query").Register("relay:metrics_after_query", metricsEnd("query"))
+	callbacks.RowQuery().Before("gorm:row_query").Register("relay:metrics_before_row_query", metricsStart)
+	callbacks.RowQuery().After("gorm:row_query").Register("relay:metrics_after_row_query", metricsEnd("row_query"))
+}
+
+func metricsStart(scope *gorm.Scope) {
+	scope.InstanceSet(metricsStartTimeKey, time.Now())
+}
+
+func metricsEnd(operation string) func(scope *gorm.Scope) {
+	return func(scope *gorm.Scope) {
+		value, exists := scope.InstanceGet(metricsStartTimeKey)
+		if !exists {
+			return
+		}
+		table := scope.TableName()
+		metrics.Timer("relay_mysql_query_duration_seconds", "operation", operation, "table", table).UpdateSince(value.(time.Time))
+		if scope.HasError() && !scope.DB().RecordNotFound() {
+			metrics.Counter("relay_mysql_query_errors_total", "operation", operation, "table", table).Inc(1)
+		}
+	}
+}
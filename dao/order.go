@@ -26,6 +26,7 @@ import (
 	"github.com/Loopring/relay/market/util"
 	"github.com/Loopring/relay/types"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/jinzhu/gorm"
 	"math/big"
 	"strconv"
 	"strings"
@@ -67,6 +68,7 @@ type Order struct {
 	SplitAmountB          string  `gorm:"column:split_amount_b;type:varchar(40)"`
 	Status                uint8   `gorm:"column:status;type:tinyint(4)"`
 	MinerBlockMark        int64   `gorm:"column:miner_block_mark;type:bigint"`
+	MinerEvaluatedRound   int64   `gorm:"column:miner_evaluated_round;type:bigint"`
 	BroadcastTime         int     `gorm:"column:broadcast_time;type:bigint"`
 	Market                string  `gorm:"column:market;type:varchar(40)"`
 	Side                  string  `gorm:"column:side;type:varchar(40)`
@@ -227,6 +229,44 @@ func (s *RdsServiceImpl) GetOrdersForMiner(protocol, tokenS, tokenB string, leng
 	return list, err
 }
 
+// GetStarvedOrdersForMiner returns orders not evaluated by miner since evaluatedBefore, the orders delayed out of
+// [startBlockNumber, endBlockNumber] by miner_block_mark are skipped as GetOrdersForMiner does
+func (s *RdsServiceImpl) GetStarvedOrdersForMiner(protocol, tokenS, tokenB string, length int, filterStatus []types.OrderStatus, reservedTime, startBlockNumber, endBlockNumber, evaluatedBefore int64) ([]*Order, error) {
+	var (
+		list []*Order
+		err  error
+	)
+
+	if len(filterStatus) < 1 {
+		return list, errors.New("should filter cutoff and finished orders")
+	}
+
+	nowtime := time.Now().Unix()
+	err = s.db.Where("delegate_address = ? and token_s = ? and token_b = ?", protocol, tokenS, tokenB).
+		Where("valid_since < ?", nowtime).
+		Where("valid_until >= ? ", nowtime+reservedTime).
+		Where("status not in (?) ", filterStatus).
+		Where("order_type = ? ", types.ORDER_TYPE_MARKET).
+		Where("miner_block_mark between ? and ?", startBlockNumber, endBlockNumber).
+		Where("miner_evaluated_round < ?", evaluatedBefore).
+		Order("miner_evaluated_round asc, price desc").
+		Limit(length).
+		Find(&list).
+		Error
+
+	return list, err
+}
+
+func (s *RdsServiceImpl) MarkMinerEvaluatedOrders(orderhashs []string, roundNumber int64) error {
+	if len(orderhashs) == 0 {
+		return nil
+	}
+
+	return s.db.Model(&Order{}).
+		Where("order_hash in (?)", orderhashs).
+		Update("miner_evaluated_round", roundNumber).Error
+}
+
 func (s *RdsServiceImpl) GetOrdersByHash(orderhashs []string) (map[string]Order, error) {
 	var (
 		list []Order
@@ -308,13 +348,15 @@ func (s *RdsServiceImpl) GetOrderBook(delegate, tokenS, tokenB common.Address, l
 	return list, err
 }
 
-func (s *RdsServiceImpl) OrderPageQuery(query map[string]interface{}, statusList []int, pageIndex, pageSize int) (PageResult, error) {
+func (s *RdsServiceImpl) OrderPageQuery(query map[string]interface{}, statusList []int, cursor *Cursor, pageIndex, pageSize int) (PageResult, error) {
 	var (
 		orders        []Order
 		err           error
 		data          = make([]interface{}, 0)
 		pageResult    PageResult
 		statusStrList = make([]string, 0)
+		findDb        *gorm.DB
+		countDb       *gorm.DB
 	)
 
 	if pageIndex <= 0 {
@@ -325,38 +367,24 @@ func (s *RdsServiceImpl) OrderPageQuery(query map[string]interface{}, statusList
 		pageSize = 20
 	}
 
-	pageResult = PageResult{data, pageIndex, pageSize, 0}
+	pageResult = PageResult{Data: data, PageIndex: pageIndex, PageSize: pageSize}
 
 	openedStatus := []types.OrderStatus{types.ORDER_NEW, types.ORDER_PARTIAL}
 	now := time.Now().Unix()
 
 	if len(statusList) == 1 {
 		if statusList[0] == 6 {
-			if err = s.db.Where(query).
+			findDb = s.db.Where(query).
 				Where("valid_until < ?", now).
-				Where("status in (?)", openedStatus).
-				Offset((pageIndex - 1) * pageSize).Order("create_time DESC").Limit(pageSize).Find(&orders).Error; err != nil {
-				return pageResult, err
-			}
+				Where("status in (?)", openedStatus)
 
-			err = s.db.Model(&Order{}).Where(query).
+			countDb = s.db.Model(&Order{}).Where(query).
 				Where("valid_until < ?", now).
-				Where("status in (?)", openedStatus).Count(&pageResult.Total).Error
-
-			if err != nil {
-				return pageResult, err
-			}
-
+				Where("status in (?)", openedStatus)
 		} else {
 			query["status"] = statusList[0]
-			if err = s.db.Where(query).Offset((pageIndex - 1) * pageSize).Order("create_time DESC").Limit(pageSize).Find(&orders).Error; err != nil {
-				return pageResult, err
-			}
-
-			err = s.db.Model(&Order{}).Where(query).Count(&pageResult.Total).Error
-			if err != nil {
-				return pageResult, err
-			}
+			findDb = s.db.Where(query)
+			countDb = s.db.Model(&Order{}).Where(query)
 		}
 
 	} else if len(statusList) > 1 {
@@ -366,43 +394,34 @@ func (s *RdsServiceImpl) OrderPageQuery(query map[string]interface{}, statusList
 
 		queryOpened := allContain(statusList, openedStatus)
 		if queryOpened {
-			if err = s.db.Where(query).
+			findDb = s.db.Where(query).
 				Where("status in (?)", statusStrList).
 				Where("valid_since < ?", now).
-				Where("valid_until >= ? ", now).
-				Offset((pageIndex - 1) * pageSize).Order("create_time DESC").Limit(pageSize).Find(&orders).Error; err != nil {
-				return pageResult, err
-			}
+				Where("valid_until >= ? ", now)
 
-			err = s.db.Model(&Order{}).Where(query).
+			countDb = s.db.Model(&Order{}).Where(query).
 				Where("valid_since < ?", now).
 				Where("valid_until >= ? ", now).
-				Where("status in (?)", openedStatus).Count(&pageResult.Total).Error
-
-			if err != nil {
-				return pageResult, err
-			}
-
+				Where("status in (?)", openedStatus)
 		} else {
-			if err = s.db.Where(query).Where("status in (?)", statusStrList).Offset((pageIndex - 1) * pageSize).Order("create_time DESC").Limit(pageSize).Find(&orders).Error; err != nil {
-				return pageResult, err
-			}
-
-			err = s.db.Model(&Order{}).Where(query).
-				Where("status in (?)", openedStatus).Count(&pageResult.Total).Error
+			findDb = s.db.Where(query).Where("status in (?)", statusStrList)
 
-			if err != nil {
-				return pageResult, err
-			}
+			countDb = s.db.Model(&Order{}).Where(query).
+				Where("status in (?)", openedStatus)
 		}
 
 	} else {
-		if err = s.db.Where(query).Offset((pageIndex - 1) * pageSize).Order("create_time DESC").Limit(pageSize).Find(&orders).Error; err != nil {
-			return pageResult, err
-		}
+		findDb = s.db.Where(query)
+		countDb = s.db.Model(&Order{}).Where(query)
+	}
 
-		err = s.db.Model(&Order{}).Where(query).Count(&pageResult.Total).Error
-		if err != nil {
+	if err = orderKeyset.page(findDb, cursor, pageIndex, pageSize).Find(&orders).Error; err != nil {
+		return pageResult, err
+	}
+
+	// total isn't counted while paging by cursor
+	if nil == cursor {
+		if err = countDb.Count(&pageResult.Total).Error; err != nil {
 			return pageResult, err
 		}
 	}
@@ -412,6 +431,9 @@ func (s *RdsServiceImpl) OrderPageQuery(query map[string]interface{}, statusList
 	}
 
 	pageResult.Data = data
+	if len(orders) > 0 {
+		pageResult.NextCursor = nextCursor(len(orders), pageSize, &Cursor{Time: orders[len(orders)-1].CreateTime, ID: orders[len(orders)-1].ID})
+	}
 
 	return pageResult, err
 }
@@ -474,6 +496,10 @@ func (s *RdsServiceImpl) UpdateOrderWhileRollbackCutoff(orderhash common.Hash, s
 	return s.db.Model(&Order{}).Where("order_hash = ?", orderhash.Hex()).Update(items).Error
 }
 
+func (s *RdsServiceImpl) UpdateOrderStatus(orderhash common.Hash, status types.OrderStatus) error {
+	return s.db.Model(&Order{}).Where("order_hash = ?", orderhash.Hex()).Update("status", uint8(status)).Error
+}
+
 func (s *RdsServiceImpl) GetFrozenAmount(owner common.Address, token common.Address, statusSet []types.OrderStatus, delegateAddress common.Address) ([]Order, error) {
 	var (
 		list []Order
@@ -0,0 +1,54 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package dao
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// OwnerGroup links owners that are treated as the same party by self-trade prevention
+type OwnerGroup struct {
+	ID         int    `gorm:"column:id;primary_key;"`
+	GroupName  string `gorm:"column:group_name;type:varchar(64);index"`
+	Owner      string `gorm:"column:owner;type:varchar(42);unique_index"`
+	CreateTime int64  `gorm:"column:create_time"`
+	IsDeleted  bool   `gorm:"column:is_deleted"`
+}
+
+func (s *RdsServiceImpl) GetOwnerGroups() ([]OwnerGroup, error) {
+	var (
+		list []OwnerGroup
+		err  error
+	)
+
+	err = s.db.Where("is_deleted = false").Find(&list).Error
+
+	return list, err
+}
+
+func (s *RdsServiceImpl) FindOwnerGroupByAddress(owner common.Address) (*OwnerGroup, error) {
+	var (
+		group OwnerGroup
+		err   error
+	)
+
+	err = s.db.Where("owner = ?", owner.Hex()).First(&group).Error
+
+	return &group, err
+}
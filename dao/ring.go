@@ -129,6 +129,7 @@ type RingSubmitInfo struct {
 	ProtocolGasPrice string `gorm:"column:protocol_gas_price;type:varchar(50)"`
 	ProtocolUsedGas  string `gorm:"column:protocol_used_gas;type:varchar(50)"`
 	ProtocolTxHash   string `gorm:"column:protocol_tx_hash;type:varchar(82)"`
+	BatchSize        int    `gorm:"column:batch_size;type:int"`
 
 	Status      int       `gorm:"column:status;type:int"`
 	RingIndex   string    `gorm:"column:ring_index;type:varchar(50)"`
@@ -234,6 +235,23 @@ func (s *RdsServiceImpl) GetRingHashesByTxHash(txHash common.Hash) ([]*RingSubmi
 	return infos, err
 }
 
+// GetPendingBatchTxHashes returns the txs that submitted more than one ring and haven't been resolved
+func (s *RdsServiceImpl) GetPendingBatchTxHashes(limit int) ([]string, error) {
+	var (
+		err      error
+		txHashes []string
+	)
+
+	err = s.db.Model(&RingSubmitInfo{}).
+		Where("batch_size > 1 and status = ?", uint8(types.TX_STATUS_PENDING)).
+		Group("protocol_tx_hash").
+		Limit(limit).
+		Pluck("protocol_tx_hash", &txHashes).
+		Error
+
+	return txHashes, err
+}
+
 func (s *RdsServiceImpl) UpdateRingSubmitInfoSubmitUsedGas(txHash string, usedGas *big.Int) error {
 	dbForUpdate := s.db.Model(&RingSubmitInfo{}).Where("protocol_tx_hash = ?", txHash)
 	return dbForUpdate.Update("protocol_used_gas", getBigIntString(usedGas)).Error
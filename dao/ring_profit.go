@@ -0,0 +1,97 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package dao
+
+import (
+	"github.com/Loopring/relay/types"
+)
+
+// RingProfit is the estimated and the realised profit of a ring submitted by this miner,
+// the legal values are in the currency of marketcap.
+type RingProfit struct {
+	ID             int     `gorm:"column:id;primary_key;" json:"id"`
+	RingHash       string  `gorm:"column:ringhash;type:varchar(82);index" json:"ringhash"`
+	UniqueId       string  `gorm:"column:unique_id;type:varchar(82)" json:"uniqueId"`
+	TxHash         string  `gorm:"column:tx_hash;type:varchar(82)" json:"txHash"`
+	Protocol       string  `gorm:"column:protocol_address;type:varchar(42)" json:"protocol"`
+	Miner          string  `gorm:"column:miner;type:varchar(42);index" json:"miner"`
+	FeeRecipient   string  `gorm:"column:fee_recipient;type:varchar(42)" json:"feeRecipient"`
+	OrdersCount    int     `gorm:"column:order_count" json:"orderCount"`
+	Status         int     `gorm:"column:status;type:int" json:"status"`
+	Reconciled     bool    `gorm:"column:reconciled" json:"reconciled"`
+	Note           string  `gorm:"column:note;type:varchar(100)" json:"note"`
+	EstLegalFee    float64 `gorm:"column:est_legal_fee" json:"estLegalFee"`
+	EstLegalReward float64 `gorm:"column:est_legal_reward" json:"estLegalReward"`
+	EstLegalCost   float64 `gorm:"column:est_legal_cost" json:"estLegalCost"`
+	EstReceived    float64 `gorm:"column:est_received" json:"estReceived"`
+	EstGas         string  `gorm:"column:est_gas;type:varchar(50)" json:"estGas"`
+	GasPrice       string  `gorm:"column:gas_price;type:varchar(50)" json:"gasPrice"`
+	UsedGas        string  `gorm:"column:used_gas;type:varchar(50)" json:"usedGas"`
+	LrcFee         string  `gorm:"column:lrc_fee;type:varchar(40)" json:"lrcFee"`
+	LrcReward      string  `gorm:"column:lrc_reward;type:varchar(40)" json:"lrcReward"`
+	LegalFee       float64 `gorm:"column:legal_fee" json:"legalFee"`
+	LegalReward    float64 `gorm:"column:legal_reward" json:"legalReward"`
+	LegalGasCost   float64 `gorm:"column:legal_gas_cost" json:"legalGasCost"`
+	LegalProfit    float64 `gorm:"column:legal_profit" json:"legalProfit"`
+	BlockNumber    int64   `gorm:"column:block_number;type:bigint" json:"blockNumber"`
+	CreateTime     int64   `gorm:"column:create_time;type:bigint;index" json:"createTime"`
+	UpdateTime     int64   `gorm:"column:update_time;type:bigint" json:"updateTime"`
+}
+
+func (s *RdsServiceImpl) FindRingProfit(ringhash, txHash string) (*RingProfit, error) {
+	var (
+		profit RingProfit
+		err    error
+	)
+
+	err = s.db.Where("ringhash = ? and tx_hash = ?", ringhash, txHash).First(&profit).Error
+
+	return &profit, err
+}
+
+// GetUnreconciledRingProfits returns the rings that have been mined or failed and the realised profit isn't computed
+func (s *RdsServiceImpl) GetUnreconciledRingProfits(limit int) ([]RingProfit, error) {
+	var (
+		profits []RingProfit
+		err     error
+	)
+
+	err = s.db.Where("reconciled = ? and status in (?)", false, []uint8{uint8(types.TX_STATUS_SUCCESS), uint8(types.TX_STATUS_FAILED)}).
+		Order("id asc").
+		Limit(limit).
+		Find(&profits).
+		Error
+
+	return profits, err
+}
+
+func (s *RdsServiceImpl) GetRingProfits(start, end int64, miner string) ([]RingProfit, error) {
+	var (
+		profits []RingProfit
+		err     error
+	)
+
+	db := s.db.Where("create_time >= ? and create_time < ?", start, end)
+	if "" != miner {
+		db = db.Where("miner = ?", miner)
+	}
+	err = db.Order("create_time asc").Find(&profits).Error
+
+	return profits, err
+}
@@ -0,0 +1,56 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package dao
+
+// RingSimulation is the outcome of the eth_call of a ring against the pending block before it is submitted
+type RingSimulation struct {
+	ID              int    `gorm:"column:id;primary_key;"`
+	RingHash        string `gorm:"column:ringhash;type:varchar(82);index"`
+	UniqueId        string `gorm:"column:unique_id;type:varchar(82)"`
+	ProtocolAddress string `gorm:"column:protocol_address;type:varchar(42)"`
+	Miner           string `gorm:"column:miner;type:varchar(42)"`
+	OrdersCount     int    `gorm:"column:order_count"`
+	Success         bool   `gorm:"column:success"`
+	ReasonCode      string `gorm:"column:reason_code;type:varchar(40);index"`
+	Reason          string `gorm:"column:reason;type:text"`
+	EstimatedGas    string `gorm:"column:estimated_gas;type:varchar(50)"`
+	CreateTime      int64  `gorm:"column:create_time;type:bigint"`
+}
+
+type RingSimulationStat struct {
+	ReasonCode string `gorm:"column:reason_code" json:"reasonCode"`
+	Count      int    `gorm:"column:count" json:"count"`
+}
+
+// RingSimulationStats returns the count of simulations of each reason code since timestamp
+func (s *RdsServiceImpl) RingSimulationStats(since int64) ([]RingSimulationStat, error) {
+	var (
+		stats []RingSimulationStat
+		err   error
+	)
+
+	err = s.db.Model(&RingSimulation{}).
+		Select("reason_code, count(*) as count").
+		Where("create_time >= ?", since).
+		Group("reason_code").
+		Scan(&stats).
+		Error
+
+	return stats, err
+}
@@ -121,23 +121,29 @@ func (s *RdsServiceImpl) RollBackRingMined(from, to int64) error {
 	return s.db.Model(&RingMinedEvent{}).Where("block_number > ? and block_number <= ?", from, to).Update("fork", true).Error
 }
 
-func (s *RdsServiceImpl) RingMinedPageQuery(query map[string]interface{}, pageIndex, pageSize int) (res PageResult, err error) {
+func (s *RdsServiceImpl) RingMinedPageQuery(query map[string]interface{}, cursor *Cursor, pageIndex, pageSize int) (res PageResult, err error) {
 	ringMined := make([]RingMinedEvent, 0)
 	res = PageResult{PageIndex: pageIndex, PageSize: pageSize, Data: make([]interface{}, 0)}
 
-	err = s.db.Where(query).Where("fork = ?", false).Order("time desc").Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&ringMined).Error
+	err = ringMinedKeyset.page(s.db.Where(query).Where("fork = ?", false), cursor, pageIndex, pageSize).Find(&ringMined).Error
 
 	if err != nil {
 		return res, err
 	}
-	err = s.db.Model(&RingMinedEvent{}).Where(query).Where("fork = ?", false).Count(&res.Total).Error
-	if err != nil {
-		return res, err
+	if nil == cursor {
+		err = s.db.Model(&RingMinedEvent{}).Where(query).Where("fork = ?", false).Count(&res.Total).Error
+		if err != nil {
+			return res, err
+		}
 	}
 
 	for _, rm := range ringMined {
 		res.Data = append(res.Data, rm)
 	}
+	if len(ringMined) > 0 {
+		last := ringMined[len(ringMined)-1]
+		res.NextCursor = nextCursor(len(ringMined), pageSize, &Cursor{BlockNumber: last.BlockNumber, ID: last.ID})
+	}
 	return
 }
 
@@ -155,6 +161,23 @@ func (s *RdsServiceImpl) GetRingminedMethods(lastId int, limit int) ([]RingMined
 	return list, err
 }
 
+// GetLatestRingMined returns the latest successfully mined rings, newest first
+func (s *RdsServiceImpl) GetLatestRingMined(limit int) ([]RingMinedEvent, error) {
+	var (
+		list []RingMinedEvent
+		err  error
+	)
+
+	err = s.db.Where("fork = ?", false).
+		Where("status = ?", uint8(types.TX_STATUS_SUCCESS)).
+		Order("id desc").
+		Limit(limit).
+		Find(&list).
+		Error
+
+	return list, err
+}
+
 /*
 type RingMinedMethod struct {
 	ID              int    `gorm:"column:id;primary_key" json:"id"`
@@ -0,0 +1,47 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package dao
+
+// SelfTradeRecord is a match prevented by self-trade prevention
+type SelfTradeRecord struct {
+	ID                 int    `gorm:"column:id;primary_key;"`
+	Market             string `gorm:"column:market;type:varchar(42)"`
+	Rule               string `gorm:"column:rule;type:varchar(32)"`
+	Mode               string `gorm:"column:mode;type:varchar(32)"`
+	OrderHashA         string `gorm:"column:order_hash_a;type:varchar(82);index"`
+	OrderHashB         string `gorm:"column:order_hash_b;type:varchar(82);index"`
+	OwnerA             string `gorm:"column:owner_a;type:varchar(42)"`
+	OwnerB             string `gorm:"column:owner_b;type:varchar(42)"`
+	CancelledOrderHash string `gorm:"column:cancelled_order_hash;type:varchar(82)"`
+	CreateTime         int64  `gorm:"column:create_time"`
+}
+
+func (s *RdsServiceImpl) GetSelfTradeRecords(orderhash string, limit int) ([]SelfTradeRecord, error) {
+	var (
+		list []SelfTradeRecord
+		err  error
+	)
+
+	err = s.db.Where("order_hash_a = ? or order_hash_b = ?", orderhash, orderhash).
+		Order("create_time desc").
+		Limit(limit).
+		Find(&list).Error
+
+	return list, err
+}
@@ -133,12 +133,38 @@ func (s *RdsServiceImpl) GetTxViewCountByOwner(owner string, symbol string, stat
 	return number, err
 }
 
-func (s *RdsServiceImpl) GetTxViewByOwner(owner string, symbol string, status types.TxStatus, typ txtyp.TxType, limit, offset int) ([]TransactionView, error) {
+func (s *RdsServiceImpl) GetTxViewByOwner(owner string, symbol string, status types.TxStatus, typ txtyp.TxType, cursor *Cursor, limit, offset int) ([]TransactionView, string, error) {
 	var txs []TransactionView
 
 	query := assembleTxViewQuery(owner, symbol, status, typ)
 
-	err := s.db.Where(query).Order("update_time DESC").Limit(limit).Offset(offset).Find(&txs).Error
+	db := txViewKeyset.where(s.db.Where(query), cursor).Order(txViewKeyset.order()).Limit(limit)
+	if nil == cursor {
+		db = db.Offset(offset)
+	}
+	if err := db.Find(&txs).Error; err != nil {
+		return txs, "", err
+	}
+
+	next := ""
+	if len(txs) > 0 {
+		next = nextCursor(len(txs), limit, &Cursor{ID: txs[len(txs)-1].ID})
+	}
+	return txs, next, nil
+}
+
+// GetMinedTxViewsByOwner returns mined views created before end, sorted by id asc
+func (s *RdsServiceImpl) GetMinedTxViewsByOwner(owner string, end int64, afterId, limit int) ([]TransactionView, error) {
+	var txs []TransactionView
+
+	err := s.db.Where("owner=?", owner).
+		Where("status=?", types.TX_STATUS_SUCCESS).
+		Where("fork=?", false).
+		Where("create_time<=?", end).
+		Where("id>?", afterId).
+		Order("id ASC").
+		Limit(limit).
+		Find(&txs).Error
 
 	return txs, err
 }
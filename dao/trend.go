@@ -60,6 +60,11 @@ func (s *RdsServiceImpl) TrendQueryByInterval(intervals, market string, start, e
 	return
 }
 
+func (s *RdsServiceImpl) TrendQueryLastBefore(intervals, market string, before int64) (trend Trend, err error) {
+	err = s.db.Model(&Trend{}).Where("intervals = ? and market = ? and start < ?", intervals, market, before).Order("start desc").First(&trend).Error
+	return
+}
+
 func (s *RdsServiceImpl) TrendQueryForProof(mkt, interval string, start int64) (trends []Trend, err error) {
 	trends = make([]Trend, 0)
 	err = s.db.Model(&Trend{}).Where("intervals = ? and market = ? and start >= ?", interval, mkt, start).Find(&trends).Error
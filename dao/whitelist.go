@@ -53,6 +53,10 @@ func (s *RdsServiceImpl) FindWhiteListUserByAddress(address common.Address) (*Wh
 	return &user, err
 }
 
+func (s *RdsServiceImpl) DelWhiteListUser(address common.Address) error {
+	return s.db.Where("owner = ?", address.Hex()).Delete(&WhiteList{}).Error
+}
+
 func (w *WhiteList) ConvertDown(src *types.WhiteListUser) error {
 	w.Owner = src.Owner.Hex()
 	w.CreateTime = src.CreateTime
@@ -33,6 +33,10 @@ import (
 
 var accessor *ethNodeAccessor
 
+func HealthyNodes() int {
+	return accessor.HealthyNodes()
+}
+
 func BlockNumber(result interface{}) error {
 	return accessor.RetryCall("latest", 5, result, "eth_blockNumber")
 }
@@ -60,7 +64,7 @@ func GetBlockByNumber(result interface{}, blockNumber *big.Int, withObject bool)
 func GetBlockByHash(result types.CheckNull, blockHash string, withObject bool) error {
 	for _, c := range accessor.clients {
 		//todo:is it need retrycall
-		if err := c.client.Call(result, "eth_getBlockByHash", blockHash, withObject); nil == err {
+		if err := c.call(result, "eth_getBlockByHash", blockHash, withObject); nil == err {
 			if !result.IsNull() {
 				return nil
 			}
@@ -77,7 +81,7 @@ func GetTransactionReceipt(result interface{}, txHash string, blockParameter str
 
 func GetTransactionByHash(result types.CheckNull, txHash string, blockParameter string) error {
 	for _, c := range accessor.clients {
-		if err := c.client.Call(result, "eth_getTransactionByHash", txHash); nil == err {
+		if err := c.call(result, "eth_getTransactionByHash", txHash); nil == err {
 			if !result.IsNull() {
 				return nil
 			}
@@ -113,10 +117,23 @@ func EstimateGas(callData []byte, to common.Address, blockNumber string) (gas, g
 	return accessor.EstimateGas(blockNumber, callData, to)
 }
 
+// EstimateGasOfCall is the same as EstimateGas except that the sender is set, the nodes estimate against the pending state
+func EstimateGasOfCall(callArg *CallArg, blockNumber string) (*big.Int, error) {
+	var gas types.Big
+	if err := accessor.RetryCall(blockNumber, 2, &gas, "eth_estimateGas", callArg); nil != err {
+		return nil, err
+	}
+	return gas.BigInt(), nil
+}
+
 func SignAndSendTransaction(sender common.Address, to common.Address, gas, gasPrice, value *big.Int, callData []byte, needPreExe bool) (string, error) {
 	return accessor.ContractSendTransactionByData("latest", sender, to, gas, gasPrice, value, callData, needPreExe)
 }
 
+func SendTransactionWithGas(sender common.Address, to common.Address, gas, gasPrice, value *big.Int, callData []byte) (string, error) {
+	return accessor.SendTransactionWithGas(sender, to, gas, gasPrice, value, callData)
+}
+
 func ContractSendTransactionMethod(routeParam string, a *abi.ABI, contractAddress common.Address) func(sender common.Address, methodName string, gas, gasPrice, value *big.Int, args ...interface{}) (string, error) {
 	return accessor.ContractSendTransactionMethod(routeParam, a, contractAddress)
 }
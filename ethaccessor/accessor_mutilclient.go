@@ -22,12 +22,14 @@ import (
 	"errors"
 	"github.com/Loopring/relay/cache"
 	"github.com/Loopring/relay/log"
+	"github.com/Loopring/relay/metrics"
 	"github.com/Loopring/relay/types"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/rpc"
 	"math/big"
 	"math/rand"
+	neturl "net/url"
 	"strings"
 	"sync"
 	"time"
@@ -40,13 +42,18 @@ const (
 	cacheDuration      = 86400 * 3
 )
 
+var ErrNoUsableNode = errors.New("there isn't an usable ethnode")
+
 type MutilClient struct {
 	clients       map[string]*RpcClient
 	downedClients map[string]*RpcClient
+	healthMtx     sync.RWMutex
+	healthyNodes  int
 }
 
 type RpcClient struct {
 	url         string
+	host        string // label of metrics, the url may contain the api key
 	client      *rpc.Client
 	blockNumber *big.Int
 }
@@ -71,6 +78,7 @@ func NewMutilClient(urls []string) *MutilClient {
 func (mc *MutilClient) newRpcClient(url string) {
 	rpcClient := &RpcClient{}
 	rpcClient.url = url
+	rpcClient.host = urlHost(url)
 	if client, err := rpc.DialHTTP(url); nil != err {
 		log.Errorf("rpc.Dail err : %s, url:%s", err.Error(), url)
 		mc.downedClients[url] = rpcClient
@@ -80,6 +88,35 @@ func (mc *MutilClient) newRpcClient(url string) {
 	}
 }
 
+func (c *RpcClient) call(result interface{}, method string, args ...interface{}) error {
+	start := time.Now()
+	err := c.client.Call(result, method, args...)
+	c.observe(method, start, err)
+	return err
+}
+
+func (c *RpcClient) batchCall(b []rpc.BatchElem) error {
+	start := time.Now()
+	err := c.client.BatchCall(b)
+	c.observe("batch", start, err)
+	return err
+}
+
+func (c *RpcClient) observe(method string, start time.Time, err error) {
+	metrics.Timer("relay_ethnode_rpc_duration_seconds", "node", c.host, "method", method).UpdateSince(start)
+	if nil != err {
+		metrics.Counter("relay_ethnode_rpc_errors_total", "node", c.host, "method", method).Inc(1)
+	}
+}
+
+// urlHost returns the host and port of the url without the user info, path and query
+func urlHost(rawUrl string) string {
+	if u, err := neturl.Parse(rawUrl); nil == err && "" != u.Host {
+		return u.Host
+	}
+	return "unknown"
+}
+
 func (mc *MutilClient) bestClient(routeParam string) *RpcClient {
 	//latest,pending
 
@@ -143,19 +180,33 @@ func (mc *MutilClient) bestClient(routeParam string) *RpcClient {
 }
 
 func (mc *MutilClient) syncBlockNumber() {
+	healthy := 0
 	for _, client := range mc.clients {
 		var blockNumber types.Big
-		if err := client.client.Call(&blockNumber, "eth_blockNumber"); nil != err {
+		if err := client.call(&blockNumber, "eth_blockNumber"); nil != err {
 			mc.downedClients[client.url] = client
 		} else {
+			healthy++
 			delete(mc.downedClients, client.url)
 			client.blockNumber = blockNumber.BigInt()
+			metrics.Gauge("relay_ethnode_block_number", "node", client.host).Update(blockNumber.Int64())
 			blockNumberStr := blockNumber.BigInt().String()
 			cache.SAdd(USAGE_CLIENT_BLOCK+blockNumberStr, cacheDuration, []byte(client.url))
 			cache.ZAdd(BLOCKS, int64(0), []byte(blockNumberStr), []byte(blockNumberStr))
 			cache.ZRemRangeByScore(BLOCKS, int64(0), blockNumber.Int64()-blocks_count)
 		}
 	}
+	metrics.Gauge("relay_ethnode_healthy_nodes").Update(int64(healthy))
+	mc.healthMtx.Lock()
+	mc.healthyNodes = healthy
+	mc.healthMtx.Unlock()
+}
+
+// HealthyNodes returns the number of nodes answered eth_blockNumber in the last sync
+func (mc *MutilClient) HealthyNodes() int {
+	mc.healthMtx.RLock()
+	defer mc.healthMtx.RUnlock()
+	return mc.healthyNodes
 }
 
 func (mc *MutilClient) startSyncBlockNumber() {
@@ -222,7 +273,7 @@ func (mc *MutilClient) Call(routeParam string, result interface{}, method string
 			err error
 		)
 		for _,client := range mc.clients {
-			if err1 := client.client.Call(result, method, args...); nil == err1 {
+			if err1 := client.call(result, method, args...); nil == err1 {
 				sendSuccess = true
 			} else {
 				err = err1
@@ -236,10 +287,10 @@ func (mc *MutilClient) Call(routeParam string, result interface{}, method string
 	} else {
 		rpcClient := mc.bestClient(routeParam)
 		if nil == rpcClient {
-			return "", errors.New("there isn't an usable ethnode")
+			return "", ErrNoUsableNode
 		}
 		log.Debugf("rpcClient:%s, %s", rpcClient.url, routeParam)
-		err = rpcClient.client.Call(result, method, args...)
+		err = rpcClient.call(result, method, args...)
 		return rpcClient.url, err
 	}
 }
@@ -247,9 +298,9 @@ func (mc *MutilClient) Call(routeParam string, result interface{}, method string
 func (mc *MutilClient) BatchCall(routeParam string, b []rpc.BatchElem) (node string, err error) {
 	rpcClient := mc.bestClient(routeParam)
 	if nil == rpcClient {
-		return "", errors.New("there isn't an usable ethnode")
+		return "", ErrNoUsableNode
 	}
-	err = rpcClient.client.BatchCall(b)
+	err = rpcClient.batchCall(b)
 	return rpcClient.url, err
 }
 
@@ -356,15 +356,31 @@ func (accessor *ethNodeAccessor) ContractSendTransactionByData(routeParam string
 			gas = estimagetGas
 		}
 	}
+	//todo:modify it
+	//if gas.Cmp(big.NewInt(int64(350000)))  {
+	gas.SetString("500000", 0)
+	//}
+	return accessor.sendTransaction(sender, to, gas, gasPrice, value, callData)
+}
+
+// SendTransactionWithGas sends the transaction with the gas as it is
+func (accessor *ethNodeAccessor) SendTransactionWithGas(sender common.Address, to common.Address, gas, gasPrice, value *big.Int, callData []byte) (string, error) {
+	if nil == gasPrice || gasPrice.Cmp(big.NewInt(0)) <= 0 {
+		return "", errors.New("gasPrice must be setted.")
+	}
+	if nil == gas || gas.Cmp(big.NewInt(0)) <= 0 {
+		return "", errors.New("gas must be setted.")
+	}
+	return accessor.sendTransaction(sender, to, gas, gasPrice, value, callData)
+}
+
+func (accessor *ethNodeAccessor) sendTransaction(sender common.Address, to common.Address, gas, gasPrice, value *big.Int, callData []byte) (string, error) {
+	var txHash string
 	nonce := accessor.addressCurrentNonce(sender)
 	log.Infof("nonce:%s, gas:%s", nonce.String(), gas.String())
 	if value == nil {
 		value = big.NewInt(0)
 	}
-	//todo:modify it
-	//if gas.Cmp(big.NewInt(int64(350000)))  {
-	gas.SetString("500000", 0)
-	//}
 	transaction := ethTypes.NewTransaction(nonce.Uint64(),
 		common.HexToAddress(to.Hex()),
 		value,
@@ -434,6 +450,7 @@ func (iterator *BlockIterator) Next() (interface{}, error) {
 				}
 			}
 		}
+		iterator.headNumber = blockNumber.Uint64()
 	}
 
 	block, err := iterator.ethClient.GetFullBlock(iterator.currentNumber, iterator.withTxData)
@@ -547,6 +564,11 @@ func (accessor *ethNodeAccessor) GetFullBlock(blockNumber *big.Int, withTxObject
 	}
 }
 
+// HeadNumber returns the latest block number of the chain seen by the last call of Next
+func (iterator *BlockIterator) HeadNumber() uint64 {
+	return iterator.headNumber
+}
+
 func (iterator *BlockIterator) Prev() (interface{}, error) {
 	var block interface{}
 	if iterator.withTxData {
@@ -189,6 +189,7 @@ type BlockIterator struct {
 	ethClient     *ethNodeAccessor
 	withTxData    bool
 	confirms      uint64
+	headNumber    uint64
 }
 
 type CallArg struct {
@@ -64,6 +64,7 @@ const (
 	Miner_SubmitRing_Method          = "Miner_SubmitRing_Method"
 	Miner_SubmitRingHash_Method      = "Miner_SubmitRingHash_Method"
 	Miner_BatchSubmitRingHash_Method = "Miner_BatchSubmitRingHash_Method"
+	MinerBalanceLow                  = "MinerBalanceLow"
 
 	// Block
 	Block_New = "Block_New"
@@ -81,6 +82,7 @@ const (
 	// socketio notify event types
 	LoopringTickerUpdated = "LoopringTickerUpdated"
 	TrendUpdated          = "TrendUpdated"
+	CandleUpdated         = "CandleUpdated"
 	PortfolioUpdated      = "PortfolioUpdated"
 	BalanceUpdated        = "BalanceUpdated"
 	DepthUpdated          = "DepthUpdated"
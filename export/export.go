@@ -0,0 +1,248 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Loopring/relay/dao"
+	util "github.com/Loopring/relay/market/util"
+	"github.com/Loopring/relay/marketcap"
+	txtyp "github.com/Loopring/relay/txmanager/types"
+	"github.com/ethereum/go-ethereum/common"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+const (
+	FORMAT_CSV    = "csv"
+	FORMAT_NDJSON = "ndjson"
+
+	batchSize = 500
+)
+
+type ExportQuery struct {
+	Owner    string `json:"owner"`
+	Start    int64  `json:"start"`
+	End      int64  `json:"end"`
+	Format   string `json:"format"`
+	Currency string `json:"currency"`
+}
+
+// Record is one row of the statement, amounts are in token units and values are in currency
+type Record struct {
+	Time             int64  `json:"time"`
+	TxHash           string `json:"txHash"`
+	BlockNumber      int64  `json:"blockNumber"`
+	LogIndex         int64  `json:"logIndex"`
+	Type             string `json:"type"`
+	Symbol           string `json:"symbol"`
+	Amount           string `json:"amount"`
+	Currency         string `json:"currency"`
+	Price            string `json:"price"`
+	Value            string `json:"value"`
+	CostBasis        string `json:"costBasis"`
+	RealizedGain     string `json:"realizedGain"`
+	Balance          string `json:"balance"`
+	BalanceCostBasis string `json:"balanceCostBasis"`
+	PriceEstimated   bool   `json:"priceEstimated"`
+}
+
+var csvHeader = []string{"time", "tx_hash", "block_number", "log_index", "type", "symbol", "amount", "currency",
+	"price", "value", "cost_basis", "realized_gain", "balance", "balance_cost_basis", "price_estimated"}
+
+func (r *Record) csvRow() []string {
+	return []string{strconv.FormatInt(r.Time, 10), r.TxHash, strconv.FormatInt(r.BlockNumber, 10), strconv.FormatInt(r.LogIndex, 10),
+		r.Type, r.Symbol, r.Amount, r.Currency, r.Price, r.Value, r.CostBasis, r.RealizedGain, r.Balance, r.BalanceCostBasis,
+		strconv.FormatBool(r.PriceEstimated)}
+}
+
+type Exporter struct {
+	rds       dao.RdsService
+	marketCap marketcap.MarketCapProvider
+}
+
+func NewExporter(rds dao.RdsService, marketCap marketcap.MarketCapProvider) *Exporter {
+	e := &Exporter{}
+	e.rds = rds
+	e.marketCap = marketCap
+	return e
+}
+
+func (query *ExportQuery) validate() error {
+	if !common.IsHexAddress(query.Owner) {
+		return errors.New("owner must be an address")
+	}
+	if query.End <= 0 {
+		return errors.New("end must be a unix timestamp")
+	}
+	if query.Start > query.End {
+		return errors.New("start can't be later than end")
+	}
+	if "" == query.Format {
+		query.Format = FORMAT_CSV
+	}
+	if FORMAT_CSV != query.Format && FORMAT_NDJSON != query.Format {
+		return errors.New("unsupported format:" + query.Format)
+	}
+	if "" == query.Currency {
+		query.Currency = "USD"
+	}
+	query.Currency = strings.ToUpper(query.Currency)
+	if "USD" != query.Currency && "CNY" != query.Currency && "BTC" != query.Currency {
+		return errors.New("unsupported currency:" + query.Currency)
+	}
+	return nil
+}
+
+// Export streams mined transactions of owner in [start, end] to w.
+// The cost basis depends on all the history, so views are replayed from the first one
+// and only those after start are written. The export fails if a view can't be replayed,
+// the cost basis of the rows after it would be wrong without its amount.
+func (e *Exporter) Export(query ExportQuery, w io.Writer) error {
+	if err := query.validate(); nil != err {
+		return err
+	}
+	owner := common.HexToAddress(query.Owner).Hex()
+
+	var csvWriter *csv.Writer
+	if FORMAT_CSV == query.Format {
+		csvWriter = csv.NewWriter(w)
+		if err := csvWriter.Write(csvHeader); nil != err {
+			return err
+		}
+	}
+	encoder := json.NewEncoder(w)
+
+	ledger := NewLedger()
+	afterId := 0
+	for {
+		views, err := e.rds.GetMinedTxViewsByOwner(owner, query.End, afterId, batchSize)
+		if nil != err {
+			return err
+		}
+		for _, view := range views {
+			record, err := e.replay(ledger, &view, query.Currency)
+			if nil != err {
+				return fmt.Errorf("export view:%d of tx:%s failed, err:%s", view.ID, view.TxHash, err.Error())
+			}
+			if nil == record || view.CreateTime < query.Start {
+				continue
+			}
+			if nil != csvWriter {
+				err = csvWriter.Write(record.csvRow())
+			} else {
+				err = encoder.Encode(record)
+			}
+			if nil != err {
+				return err
+			}
+		}
+		if nil != csvWriter {
+			csvWriter.Flush()
+			if err := csvWriter.Error(); nil != err {
+				return err
+			}
+		}
+		if len(views) < batchSize {
+			return nil
+		}
+		afterId = views[len(views)-1].ID
+	}
+}
+
+// replay applies view to ledger and returns its record, approve and unsupported views are skipped
+func (e *Exporter) replay(ledger *Ledger, view *dao.TransactionView, currency string) (*Record, error) {
+	typ := txtyp.TxType(view.Type)
+	record := &Record{
+		Time:        view.CreateTime,
+		TxHash:      view.TxHash,
+		BlockNumber: view.BlockNumber,
+		LogIndex:    view.LogIndex,
+		Type:        txtyp.TypeStr(typ),
+		Symbol:      view.Symbol,
+		Currency:    currency,
+	}
+
+	switch typ {
+	case txtyp.TX_TYPE_CANCEL_ORDER, txtyp.TX_TYPE_CUTOFF, txtyp.TX_TYPE_CUTOFF_PAIR:
+		return record, nil
+	case txtyp.TX_TYPE_BUY, txtyp.TX_TYPE_RECEIVE, txtyp.TX_TYPE_LRC_REWARD, txtyp.TX_TYPE_CONVERT_INCOME,
+		txtyp.TX_TYPE_SELL, txtyp.TX_TYPE_SEND, txtyp.TX_TYPE_LRC_FEE, txtyp.TX_TYPE_CONVERT_OUTCOME:
+	default:
+		return nil, nil
+	}
+
+	// eth and weth share lots, wrap and unwrap don't change the holding
+	ledgerSymbol := view.Symbol
+	if txtyp.SYMBOL_ETH == ledgerSymbol {
+		ledgerSymbol = txtyp.SYMBOL_WETH
+	}
+	token, ok := util.AllTokens()[ledgerSymbol]
+	if !ok {
+		return nil, fmt.Errorf("unsupported token:%s", view.Symbol)
+	}
+	rawAmount, ok := new(big.Int).SetString(view.Amount, 0)
+	if !ok {
+		return nil, fmt.Errorf("invalid amount:%s", view.Amount)
+	}
+	amount := new(big.Rat).SetFrac(rawAmount, token.Decimals)
+	price, err := e.marketCap.GetHistoryMarketCapByCurrency(token.Protocol, currency, view.CreateTime)
+	if marketcap.ErrNoMarketCapHistory == err {
+		// the current price keeps the cost basis going, the row is flagged as it isn't the price of that time
+		record.PriceEstimated = true
+		price, err = e.marketCap.GetMarketCapByCurrency(token.Protocol, currency)
+	}
+	if nil != err {
+		return nil, err
+	}
+	value := new(big.Rat).Mul(amount, price)
+
+	record.Amount = formatRat(amount, 18)
+	record.Price = formatRat(price, 8)
+	record.Value = formatRat(value, 8)
+
+	switch typ {
+	case txtyp.TX_TYPE_BUY, txtyp.TX_TYPE_RECEIVE, txtyp.TX_TYPE_LRC_REWARD:
+		ledger.In(ledgerSymbol, amount, price)
+		record.CostBasis = record.Value
+	case txtyp.TX_TYPE_SELL, txtyp.TX_TYPE_SEND, txtyp.TX_TYPE_LRC_FEE:
+		cost := ledger.Out(ledgerSymbol, amount)
+		record.CostBasis = formatRat(cost, 8)
+		record.RealizedGain = formatRat(new(big.Rat).Sub(value, cost), 8)
+	}
+
+	balance, balanceCost := ledger.Balance(ledgerSymbol)
+	record.Balance = formatRat(balance, 18)
+	record.BalanceCostBasis = formatRat(balanceCost, 8)
+	return record, nil
+}
+
+// formatRat trims the trailing zeros of decimal string
+func formatRat(v *big.Rat, prec int) string {
+	s := v.FloatString(prec)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
@@ -0,0 +1,54 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package export_test
+
+import (
+	"bytes"
+	"github.com/Loopring/relay/dao"
+	"github.com/Loopring/relay/export"
+	txtyp "github.com/Loopring/relay/txmanager/types"
+	"strings"
+	"testing"
+)
+
+// viewsRds returns the views once, the other methods of RdsService aren't called
+type viewsRds struct {
+	dao.RdsService
+	views []dao.TransactionView
+}
+
+func (r *viewsRds) GetMinedTxViewsByOwner(owner string, end int64, afterId, limit int) ([]dao.TransactionView, error) {
+	if afterId > 0 {
+		return []dao.TransactionView{}, nil
+	}
+	return r.views, nil
+}
+
+func TestExport_ReplayFailed(t *testing.T) {
+	rds := &viewsRds{views: []dao.TransactionView{
+		{ID: 1, Type: uint8(txtyp.TX_TYPE_CUTOFF), TxHash: "0x1", CreateTime: 100},
+		{ID: 2, Type: uint8(txtyp.TX_TYPE_RECEIVE), TxHash: "0x2", Symbol: "UNLISTED", Amount: "1000", CreateTime: 200},
+	}}
+	e := export.NewExporter(rds, nil)
+	w := &bytes.Buffer{}
+	err := e.Export(export.ExportQuery{Owner: "0x48ff2269e58a373120ffdbbdee3fbcea854ac30a", End: 1000}, w)
+	if nil == err || !strings.Contains(err.Error(), "0x2") {
+		t.Fatalf("export should fail with the view can't be replayed, got %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package export
+
+import (
+	"math/big"
+)
+
+type lot struct {
+	amount *big.Rat
+	price  *big.Rat
+}
+
+// Ledger keeps the lots of every token, outgoing amount consumes the earliest lots first
+type Ledger struct {
+	lots map[string][]*lot
+}
+
+func NewLedger() *Ledger {
+	l := &Ledger{}
+	l.lots = make(map[string][]*lot)
+	return l
+}
+
+// In adds a lot of amount acquired at price per unit
+func (l *Ledger) In(symbol string, amount, price *big.Rat) {
+	if amount.Sign() <= 0 {
+		return
+	}
+	l.lots[symbol] = append(l.lots[symbol], &lot{amount: new(big.Rat).Set(amount), price: new(big.Rat).Set(price)})
+}
+
+// Out consumes amount from lots and returns its cost basis,
+// amount exceeding the lots is treated as zero cost
+func (l *Ledger) Out(symbol string, amount *big.Rat) *big.Rat {
+	cost := new(big.Rat)
+	remain := new(big.Rat).Set(amount)
+	lots := l.lots[symbol]
+	for len(lots) > 0 && remain.Sign() > 0 {
+		first := lots[0]
+		used := first.amount
+		if first.amount.Cmp(remain) > 0 {
+			used = remain
+		}
+		cost.Add(cost, new(big.Rat).Mul(used, first.price))
+		if first.amount.Cmp(remain) > 0 {
+			first.amount = new(big.Rat).Sub(first.amount, remain)
+			remain = new(big.Rat)
+		} else {
+			remain = new(big.Rat).Sub(remain, first.amount)
+			lots = lots[1:]
+		}
+	}
+	l.lots[symbol] = lots
+	return cost
+}
+
+// Balance returns the amount and cost basis of lots held
+func (l *Ledger) Balance(symbol string) (amount, cost *big.Rat) {
+	amount = new(big.Rat)
+	cost = new(big.Rat)
+	for _, v := range l.lots[symbol] {
+		amount.Add(amount, v.amount)
+		cost.Add(cost, new(big.Rat).Mul(v.amount, v.price))
+	}
+	return amount, cost
+}
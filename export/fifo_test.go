@@ -0,0 +1,46 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package export_test
+
+import (
+	"github.com/Loopring/relay/export"
+	"math/big"
+	"testing"
+)
+
+func TestLedger_Out(t *testing.T) {
+	l := export.NewLedger()
+	l.In("LRC", big.NewRat(10, 1), big.NewRat(1, 1))
+	l.In("LRC", big.NewRat(10, 1), big.NewRat(2, 1))
+
+	if cost := l.Out("LRC", big.NewRat(15, 1)); cost.Cmp(big.NewRat(20, 1)) != 0 {
+		t.Fatalf("cost basis should be 20, got %s", cost.FloatString(2))
+	}
+	amount, cost := l.Balance("LRC")
+	if amount.Cmp(big.NewRat(5, 1)) != 0 || cost.Cmp(big.NewRat(10, 1)) != 0 {
+		t.Fatalf("balance should be 5 with cost 10, got %s and %s", amount.FloatString(2), cost.FloatString(2))
+	}
+
+	if cost := l.Out("LRC", big.NewRat(8, 1)); cost.Cmp(big.NewRat(10, 1)) != 0 {
+		t.Fatalf("exceeding amount should be zero cost, got %s", cost.FloatString(2))
+	}
+	if amount, _ := l.Balance("LRC"); amount.Sign() != 0 {
+		t.Fatalf("balance should be empty, got %s", amount.FloatString(2))
+	}
+}
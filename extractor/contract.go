@@ -237,7 +237,7 @@ func (processor *AbiProcessor) HasSpender(spender common.Address) bool {
 }
 
 func (processor *AbiProcessor) loadProtocolAddress() {
-	for _, v := range util.AllTokens {
+	for _, v := range util.AllTokens() {
 		processor.protocols[v.Protocol] = v.Symbol
 		log.Infof("extractor,contract protocol %s->%s", v.Symbol, v.Protocol.Hex())
 	}
@@ -19,12 +19,14 @@
 package extractor
 
 import (
+	"errors"
 	"fmt"
 	"github.com/Loopring/relay/config"
 	"github.com/Loopring/relay/dao"
 	"github.com/Loopring/relay/ethaccessor"
 	"github.com/Loopring/relay/eventemiter"
 	"github.com/Loopring/relay/log"
+	"github.com/Loopring/relay/metrics"
 	"github.com/Loopring/relay/types"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -43,10 +45,14 @@ const (
 	defaultForkWaitingTime = 10
 )
 
+var errDrained = errors.New("extractor,drained")
+
 type ExtractorService interface {
 	Start()
 	Stop()
 	ForkProcess(block *types.Block) error
+	Progress() (processed uint64, processedTime time.Time)
+	Drain()
 }
 
 // TODO(fukun):不同的channel，应当交给orderbook统一进行后续处理，可以将channel作为函数返回值、全局变量、参数等方式
@@ -63,6 +69,11 @@ type ExtractorServiceImpl struct {
 	pendingTxWatcher *eventemitter.Watcher
 	syncComplete     bool
 	forkComplete     bool
+	processedNumber  uint64
+	processedTime    time.Time
+	// held while a block is processed, Drain waits it
+	processMtx sync.Mutex
+	drained    bool
 }
 
 func NewExtractorService(options config.ExtractorOptions, db dao.RdsService) *ExtractorServiceImpl {
@@ -100,7 +111,9 @@ func (l *ExtractorServiceImpl) Start() {
 			case <-l.stop:
 				return
 			default:
-				if err := l.ProcessBlock(); nil != err {
+				if err := l.ProcessBlock(); errDrained == err {
+					return
+				} else if nil != err {
 					log.Error(err.Error())
 					time.Sleep(1 * time.Second)
 				}
@@ -117,6 +130,13 @@ func (l *ExtractorServiceImpl) Stop() {
 	l.stop <- true
 }
 
+// Drain waits the block being processed, no block is processed after it returns
+func (l *ExtractorServiceImpl) Drain() {
+	l.processMtx.Lock()
+	defer l.processMtx.Unlock()
+	l.drained = true
+}
+
 // 重启(分叉)时先关停subscribeEvents，然后关
 func (l *ExtractorServiceImpl) ForkProcess(currentBlock *types.Block) error {
 	forkEvent, err := l.detector.Detect(currentBlock)
@@ -147,6 +167,13 @@ func (l *ExtractorServiceImpl) ForkProcess(currentBlock *types.Block) error {
 	return fmt.Errorf("extractor,detected chain fork")
 }
 
+// Progress returns the number of the block being processed and when the extractor got it
+func (l *ExtractorServiceImpl) Progress() (processed uint64, processedTime time.Time) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	return l.processedNumber, l.processedTime
+}
+
 func (l *ExtractorServiceImpl) Sync(blockNumber *big.Int) {
 	var syncBlock types.Big
 	if err := ethaccessor.BlockNumber(&syncBlock); err != nil {
@@ -181,10 +208,25 @@ func (l *ExtractorServiceImpl) ProcessBlock() error {
 		return fmt.Errorf("extractor,iterator next error:%s", err.Error())
 	}
 
+	l.processMtx.Lock()
+	defer l.processMtx.Unlock()
+	if l.drained {
+		return errDrained
+	}
+
 	// get current block
 	block := inter.(*ethaccessor.BlockWithTxAndReceipt)
 	log.Infof("extractor,get block:%s->%s, transaction number:%d", block.Number.BigInt().String(), block.Hash.Hex(), len(block.Transactions))
 
+	start := time.Now()
+	l.lock.Lock()
+	l.processedNumber = block.Number.Uint64()
+	l.processedTime = start
+	l.lock.Unlock()
+	metrics.Gauge("relay_extractor_block_number").Update(block.Number.Int64())
+	metrics.Gauge("relay_extractor_block_lag").Update(int64(l.iterator.HeadNumber()) - block.Number.Int64())
+	defer metrics.Timer("relay_extractor_block_process_seconds").UpdateSince(start)
+
 	currentBlock := &types.Block{}
 	currentBlock.BlockNumber = block.Number.BigInt()
 	currentBlock.ParentHash = block.ParentHash
@@ -0,0 +1,109 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package gateway_test
+
+import (
+	"errors"
+	"github.com/Loopring/relay/crypto"
+	"github.com/Loopring/relay/gateway"
+	"github.com/Loopring/relay/market"
+	"github.com/Loopring/relay/ordermanager"
+	"github.com/Loopring/relay/types"
+	"github.com/ethereum/go-ethereum/common"
+	"testing"
+	"time"
+)
+
+// cancelOrderManager only serves CancelOrder, the other methods of OrderManager aren't called
+type cancelOrderManager struct {
+	ordermanager.OrderManager
+	orders    map[common.Hash]*types.OrderState
+	cancelled []common.Hash
+}
+
+func (m *cancelOrderManager) GetOrderByHash(hash common.Hash) (*types.OrderState, error) {
+	if state, ok := m.orders[hash]; ok {
+		return state, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+func (m *cancelOrderManager) CancelOrderOffChain(orderhash common.Hash) error {
+	m.cancelled = append(m.cancelled, orderhash)
+	return nil
+}
+
+func signCancelOrder(t *testing.T, c crypto.EthPrivateKeyCrypto, orderHash common.Hash, timestamp int64) gateway.CancelOrderQuery {
+	sig, err := crypto.Sign(types.CancelOrderHash(orderHash, timestamp), c.Address())
+	if nil != err {
+		t.Fatal(err.Error())
+	}
+	v, r, s := crypto.SigToVRS(sig)
+	return gateway.CancelOrderQuery{OrderHash: orderHash.Hex(), Timestamp: timestamp, V: v, R: types.BytesToBytes32(r), S: types.BytesToBytes32(s)}
+}
+
+func TestWalletServiceImpl_CancelOrder(t *testing.T) {
+	owner, err := crypto.NewPrivateKeyCrypto(true, "acfe437a8e0f65124c44647737c0471b8adc9a0763f139df76766f46d6af8e15")
+	if nil != err {
+		t.Fatal(err.Error())
+	}
+	other, _ := crypto.NewPrivateKeyCrypto(true, "7d0a1121fb170361b6483d922d72258e6d4da9aa65234ac7ba0c9c833e6adc71")
+	crypto.Initialize(owner)
+
+	orderHash := common.HexToHash("0x1")
+	state := &types.OrderState{}
+	state.RawOrder.Hash = orderHash
+	state.RawOrder.Owner = owner.Address()
+	om := &cancelOrderManager{orders: map[common.Hash]*types.OrderState{orderHash: state}}
+	w := gateway.NewWalletService(market.TrendManager{}, om, market.AccountManager{}, nil, market.CollectorImpl{}, nil, "")
+
+	now := time.Now().Unix()
+	if _, err := w.CancelOrder(gateway.CancelOrderQuery{}); nil == err {
+		t.Errorf("empty order hash should be rejected")
+	}
+	if _, err := w.CancelOrder(signCancelOrder(t, owner, orderHash, now-3600)); nil == err {
+		t.Errorf("expired timestamp should be rejected")
+	}
+	if _, err := w.CancelOrder(signCancelOrder(t, owner, common.HexToHash("0x2"), now)); nil == err {
+		t.Errorf("unknown order should be rejected")
+	}
+
+	// the signature of another account, or of another timestamp, isn't the owner's
+	crypto.Initialize(other)
+	if _, err := w.CancelOrder(signCancelOrder(t, other, orderHash, now)); nil == err {
+		t.Errorf("cancel signed by other account should be rejected")
+	}
+	crypto.Initialize(owner)
+	query := signCancelOrder(t, owner, orderHash, now)
+	query.Timestamp = now + 1
+	if _, err := w.CancelOrder(query); nil == err {
+		t.Errorf("signature of another timestamp should be rejected")
+	}
+	if len(om.cancelled) != 0 {
+		t.Fatalf("order shouldn't be cancelled, got %v", om.cancelled)
+	}
+
+	res, err := w.CancelOrder(signCancelOrder(t, owner, orderHash, now))
+	if nil != err || orderHash.Hex() != res {
+		t.Fatalf("order should be cancelled, res:%s err:%v", res, err)
+	}
+	if len(om.cancelled) != 1 || om.cancelled[0] != orderHash {
+		t.Errorf("order should be cancelled off chain, got %v", om.cancelled)
+	}
+}
@@ -19,6 +19,7 @@
 package gateway
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/binary"
 	"fmt"
@@ -28,11 +29,13 @@ import (
 	"github.com/Loopring/relay/market"
 	"github.com/Loopring/relay/market/util"
 	"github.com/Loopring/relay/marketcap"
+	"github.com/Loopring/relay/metrics"
 	"github.com/Loopring/relay/ordermanager"
 	"github.com/Loopring/relay/types"
 	"github.com/ethereum/go-ethereum/common"
 	"math/big"
 	"qiniupkg.com/x/errors.v7"
+	"sync"
 	"time"
 )
 
@@ -46,7 +49,12 @@ type Gateway struct {
 	marketCap        marketcap.MarketCapProvider
 }
 
-var gateway Gateway
+var (
+	gateway    Gateway
+	filtersMtx sync.RWMutex
+	stopped    bool
+	stoppedMtx sync.RWMutex
+)
 
 type Filter interface {
 	filter(o *types.Order) (bool, error)
@@ -57,11 +65,43 @@ func Initialize(filterOptions *config.GatewayFiltersOptions, options *config.Gat
 	gatewayWatcher := &eventemitter.Watcher{Concurrent: false, Handle: HandleOrder}
 	eventemitter.On(eventemitter.GatewayNewOrder, gatewayWatcher)
 
-	gateway = Gateway{filters: make([]Filter, 0), om: om, isBroadcast: options.IsBroadcast, maxBroadcastTime: options.MaxBroadcastTime, am: am}
+	gateway = Gateway{om: om, isBroadcast: options.IsBroadcast, maxBroadcastTime: options.MaxBroadcastTime, am: am}
 	//gateway.ipfsPubService = NewIPFSPubService(ipfsOptions)
 
 	gateway.marketCap = marketCap
 
+	gateway.filters = newFilters(filterOptions, om)
+}
+
+// Reload replaces the filters with the ones built from filterOptions, orders being filtered keep the old ones
+func Reload(filterOptions *config.GatewayFiltersOptions) {
+	filters := newFilters(filterOptions, gateway.om)
+
+	filtersMtx.Lock()
+	defer filtersMtx.Unlock()
+	gateway.filters = filters
+}
+
+// Stop rejects the orders submitted later, it's the first step of shutdown
+func Stop() {
+	stoppedMtx.Lock()
+	defer stoppedMtx.Unlock()
+	stopped = true
+}
+
+func isStopped() bool {
+	stoppedMtx.RLock()
+	defer stoppedMtx.RUnlock()
+	return stopped
+}
+
+func currentFilters() []Filter {
+	filtersMtx.RLock()
+	defer filtersMtx.RUnlock()
+	return gateway.filters
+}
+
+func newFilters(filterOptions *config.GatewayFiltersOptions, om ordermanager.OrderManager) []Filter {
 	// new pow filter
 	powFilter := &PowFilter{Difficulty: types.HexToBigint(filterOptions.PowFilter.Difficulty)}
 
@@ -93,11 +133,7 @@ func Initialize(filterOptions *config.GatewayFiltersOptions, options *config.Gat
 	// new cutoff filter
 	cutoffFilter := &CutoffFilter{om: om}
 
-	gateway.filters = append(gateway.filters, powFilter)
-	gateway.filters = append(gateway.filters, baseFilter)
-	gateway.filters = append(gateway.filters, signFilter)
-	gateway.filters = append(gateway.filters, tokenFilter)
-	gateway.filters = append(gateway.filters, cutoffFilter)
+	return []Filter{powFilter, baseFilter, signFilter, tokenFilter, cutoffFilter}
 }
 
 func HandleInputOrder(input eventemitter.EventData) (orderHash string, err error) {
@@ -109,21 +145,29 @@ func HandleInputOrder(input eventemitter.EventData) (orderHash string, err error
 	order.Hash = order.GenerateHash()
 	orderHash = order.Hash.Hex()
 
+	if isStopped() {
+		metrics.Counter("relay_gateway_orders_total", "result", "rejected", "reason", "stopped").Inc(1)
+		return orderHash, errors.New("relay is shutting down, please submit later")
+	}
+
 	//var broadcastTime int
 
 	//TODO(xiaolu) 这里需要测试一下，超时error和查询数据为空的error，处理方式不应该一样
 	if state, err = gateway.om.GetOrderByHash(order.Hash); err != nil && err.Error() == "record not found" {
 		if err = generatePrice(order); err != nil {
+			metrics.Counter("relay_gateway_orders_total", "result", "rejected", "reason", "price").Inc(1)
 			return orderHash, err
 		}
 
-		for _, v := range gateway.filters {
+		for _, v := range currentFilters() {
 			valid, err := v.filter(order)
 			if !valid {
 				log.Errorf(err.Error())
+				metrics.Counter("relay_gateway_orders_total", "result", "rejected", "reason", filterName(v)).Inc(1)
 				return orderHash, err
 			}
 		}
+		metrics.Counter("relay_gateway_orders_total", "result", "accepted", "reason", "").Inc(1)
 		state = &types.OrderState{}
 		state.RawOrder = *order
 		//broadcastTime = 0
@@ -131,6 +175,7 @@ func HandleInputOrder(input eventemitter.EventData) (orderHash string, err error
 	} else {
 		//broadcastTime = state.BroadcastTime
 		log.Infof("gateway,order %s exist,will not insert again", order.Hash.Hex())
+		metrics.Counter("relay_gateway_orders_total", "result", "rejected", "reason", "existed").Inc(1)
 		return orderHash, errors.New("order existed, please not submit again")
 	}
 
@@ -187,6 +232,23 @@ func generatePrice(order *types.Order) error {
 	return nil
 }
 
+func filterName(f Filter) string {
+	switch f.(type) {
+	case *BaseFilter:
+		return "base"
+	case *SignFilter:
+		return "sign"
+	case *TokenFilter:
+		return "token"
+	case *CutoffFilter:
+		return "cutoff"
+	case *PowFilter:
+		return "pow"
+	default:
+		return "unknown"
+	}
+}
+
 type BaseFilter struct {
 	MinLrcFee             *big.Int
 	MinLrcHold            int64
@@ -213,7 +275,7 @@ func (f *BaseFilter) filter(o *types.Order) (bool, error) {
 
 		if b, ok := balances["LRC"]; ok {
 			lrcHold := big.NewInt(f.MinLrcHold)
-			lrcHold = lrcHold.Mul(lrcHold, util.AllTokens["LRC"].Decimals)
+			lrcHold = lrcHold.Mul(lrcHold, util.AllTokens()["LRC"].Decimals)
 			if b.Cmp(lrcHold) < 1 {
 				return false, fmt.Errorf("gateway,base filter,owner holds lrc less than %d ", f.MinLrcHold)
 			}
@@ -315,7 +377,7 @@ type TokenFilter struct {
 func (f *TokenFilter) filter(o *types.Order) (bool, error) {
 	supportTokenS := false
 	supportTokenB := false
-	for _, v := range util.AllTokens {
+	for _, v := range util.AllTokens() {
 		if v.Protocol == o.TokenS && !v.Deny {
 			supportTokenS = true
 		}
@@ -375,8 +437,6 @@ func GetPow(v uint8, r types.Bytes32, s types.Bytes32, powNonce uint64) *big.Int
 	input = append(input, nonce...)
 
 	hash := sha256.New()
-	fmt.Println(input)
-	fmt.Println(common.Bytes2Hex(input))
 	hash.Write(input)
 
 	rst := hash.Sum(nil)
@@ -385,6 +445,37 @@ func GetPow(v uint8, r types.Bytes32, s types.Bytes32, powNonce uint64) *big.Int
 	return bigRst
 }
 
+// PowDifficulty returns the difficulty of the pow filter in use
+func PowDifficulty() *big.Int {
+	for _, f := range currentFilters() {
+		if powFilter, ok := f.(*PowFilter); ok {
+			return powFilter.Difficulty
+		}
+	}
+	return big.NewInt(0)
+}
+
+// ctx is checked once every powSearchCheckInterval nonces
+const powSearchCheckInterval = 1024
+
+// SearchPowNonce returns the first nonce that makes the pow of signature not less than difficulty,
+// it gives up when ctx is done
+func SearchPowNonce(ctx context.Context, v uint8, r types.Bytes32, s types.Bytes32, difficulty *big.Int) (uint64, error) {
+	for nonce := uint64(1); nonce != 0; nonce++ {
+		if GetPow(v, r, s, nonce).Cmp(difficulty) >= 0 {
+			return nonce, nil
+		}
+		if nonce%powSearchCheckInterval == 0 {
+			select {
+			case <-ctx.Done():
+				return 0, fmt.Errorf("pow nonce isn't found in %d tries, err:%s", nonce, ctx.Err().Error())
+			default:
+			}
+		}
+	}
+	return 0, errors.New("pow nonce isn't found")
+}
+
 func Uint64ToByteArray(src uint64) []byte {
 	rst := make([]byte, 8)
 	binary.LittleEndian.PutUint64(rst, src)
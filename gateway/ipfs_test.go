@@ -46,8 +46,8 @@ func TestSingleOrder(t *testing.T) {
 	entity := test.Entity()
 
 	// get keystore and unlock account
-	tokenAddressA := util.AllTokens[TOKEN_SYMBOL].Protocol
-	tokenAddressB := util.AllTokens[WETH].Protocol
+	tokenAddressA := util.AllTokens()[TOKEN_SYMBOL].Protocol
+	tokenAddressB := util.AllTokens()[WETH].Protocol
 	testAcc := entity.Accounts[0]
 
 	ks := keystore.NewKeyStore(c.Keystore.Keydir, keystore.StandardScryptN, keystore.StandardScryptP)
@@ -66,9 +66,9 @@ func TestRing(t *testing.T) {
 	entity := test.Entity()
 
 	// get ipfs shell and sub order
-	lrc := util.SupportTokens[TOKEN_SYMBOL].Protocol
+	lrc := util.SupportTokens()[TOKEN_SYMBOL].Protocol
 
-	eth := util.SupportMarkets[WETH].Protocol
+	eth := util.SupportMarkets()[WETH].Protocol
 
 	account1 := entity.Accounts[0]
 	account2 := entity.Accounts[1]
@@ -89,8 +89,8 @@ func TestRing(t *testing.T) {
 func TestBatchRing(t *testing.T) {
 	entity := test.Entity()
 
-	lrc := util.SupportTokens[TOKEN_SYMBOL].Protocol
-	eth := util.SupportMarkets[WETH].Protocol
+	lrc := util.SupportTokens()[TOKEN_SYMBOL].Protocol
+	eth := util.SupportMarkets()[WETH].Protocol
 
 	account1 := entity.Accounts[0]
 	account2 := entity.Accounts[1]
@@ -165,8 +165,8 @@ func TestMatcher_Case1(t *testing.T) {
 
 	_, entity := MatchTestPrepare()
 
-	tokenAddressA := util.SupportTokens["LRC"].Protocol
-	tokenAddressB := util.SupportMarkets["WETH"].Protocol
+	tokenAddressA := util.SupportTokens()["LRC"].Protocol
+	tokenAddressB := util.SupportMarkets()["WETH"].Protocol
 
 	tokenCallMethodA := ethaccessor.ContractCallMethod(ethaccessor.Erc20Abi(), tokenAddressA)
 	tokenCallMethodB := ethaccessor.ContractCallMethod(ethaccessor.Erc20Abi(), tokenAddressB)
@@ -201,8 +201,8 @@ func TestMatcher_Case2(t *testing.T) {
 	)
 	_, entity := MatchTestPrepare()
 
-	tokenAddressA := util.SupportTokens["EOS"].Protocol
-	tokenAddressB := util.SupportMarkets["WETH"].Protocol
+	tokenAddressA := util.SupportTokens()["EOS"].Protocol
+	tokenAddressB := util.SupportMarkets()["WETH"].Protocol
 
 	tokenCallMethodA := ethaccessor.ContractCallMethod(ethaccessor.Erc20Abi(), tokenAddressA)
 	tokenCallMethodB := ethaccessor.ContractCallMethod(ethaccessor.Erc20Abi(), tokenAddressB)
@@ -233,8 +233,8 @@ func TestBalanceSub(t *testing.T) {
 	account2 := test.Entity().Accounts[1].Address
 	miner := test.Entity().Creator.Address
 
-	lrcTokenAddress := util.AllTokens["LRC"].Protocol
-	wethTokenAddress := util.AllTokens["WETH"].Protocol
+	lrcTokenAddress := util.AllTokens()["LRC"].Protocol
+	wethTokenAddress := util.AllTokens()["WETH"].Protocol
 
 	accounts := []common.Address{account1, account2, miner}
 	tokens := []common.Address{lrcTokenAddress, wethTokenAddress}
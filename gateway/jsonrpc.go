@@ -19,6 +19,7 @@
 package gateway
 
 import (
+	"context"
 	"fmt"
 	"github.com/Loopring/relay/log"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -40,12 +41,14 @@ type JsonrpcService interface {
 type JsonrpcServiceImpl struct {
 	port          string
 	walletService *WalletServiceImpl
+	server        *http.Server
 }
 
 func NewJsonrpcService(port string, walletService *WalletServiceImpl) *JsonrpcServiceImpl {
 	l := &JsonrpcServiceImpl{}
 	l.port = port
 	l.walletService = walletService
+	l.server = &http.Server{}
 	return l
 }
 
@@ -53,7 +56,7 @@ func (j *JsonrpcServiceImpl) Start() {
 
 	handler := rpc.NewServer()
 	if err := handler.RegisterName("loopring", j.walletService); err != nil {
-		fmt.Println(err)
+		log.Errorf("failed to register jsonrpc service, err:%s", err.Error())
 		return
 	}
 
@@ -66,14 +69,18 @@ func (j *JsonrpcServiceImpl) Start() {
 		return
 	}
 	//httpServer := rpc.NewHTTPServer([]string{"*"}, handler)
-	httpServer := &http.Server{Handler: newCorsHandler(handler, []string{"*"})}
-	//httpServer.Handler = newCorsHandler(handler, []string{"*"})
-	go httpServer.Serve(listener)
+	j.server.Handler = newCorsHandler(handler, []string{"*"})
+	go j.server.Serve(listener)
 	log.Info(fmt.Sprintf("HTTP endpoint opened on " + j.port))
 
 	return
 }
 
+// Shutdown stops accepting connections and waits the running requests until ctx is done
+func (j *JsonrpcServiceImpl) Shutdown(ctx context.Context) error {
+	return j.server.Shutdown(ctx)
+}
+
 func newCorsHandler(srv *rpc.Server, allowedOrigins []string) http.Handler {
 	// disable CORS support if user has not specified a custom CORS configuration
 	if len(allowedOrigins) == 0 {
@@ -0,0 +1,56 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package gateway_test
+
+import (
+	"context"
+	"github.com/Loopring/relay/gateway"
+	"github.com/Loopring/relay/types"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestSearchPowNonce(t *testing.T) {
+	r := types.BytesToBytes32(common.FromHex("0xbbc27e0aa7a3df3942ab7886b78d205d7bf8161abbece04e8d841f0de508522e"))
+	s := types.BytesToBytes32(common.FromHex("0x2b19076f2fe24b58eedd00f0151d058bd7b1bf5fa38759c15902f03552492042"))
+	// about 1 of 16 nonces reaches it
+	difficulty := new(big.Int).Lsh(big.NewInt(15), 252)
+
+	nonce, err := gateway.SearchPowNonce(context.Background(), 27, r, s, difficulty)
+	if nil != err {
+		t.Fatalf("nonce should be found, err:%s", err.Error())
+	}
+	if gateway.GetPow(27, r, s, nonce).Cmp(difficulty) < 0 {
+		t.Errorf("pow of nonce %d is less than the difficulty", nonce)
+	}
+	for n := uint64(1); n < nonce; n++ {
+		if gateway.GetPow(27, r, s, n).Cmp(difficulty) >= 0 {
+			t.Errorf("nonce %d isn't the first one, %d reaches the difficulty", nonce, n)
+		}
+	}
+
+	// the search of a difficulty that can't be reached gives up when ctx is done
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := gateway.SearchPowNonce(ctx, 27, r, s, new(big.Int).Lsh(big.NewInt(1), 256)); nil == err {
+		t.Errorf("search should give up when ctx is done")
+	}
+}
@@ -0,0 +1,606 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package gateway
+
+import (
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/Loopring/relay/ethaccessor"
+	"github.com/Loopring/relay/export"
+	"github.com/Loopring/relay/log"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/garyburd/redigo/redis"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jinzhu/gorm"
+	"github.com/rs/cors"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const RestPathPrefix = "/api/v1"
+
+const (
+	REST_40000 = "40000" // bad request
+	REST_40400 = "40400" // route not found
+	REST_40500 = "40500" // method not allowed
+)
+
+const defaultRestCacheMaxAge = 5
+
+// statement is streamed rather than wrapped in RestDataBody, so it isn't one of RestRoutes
+const restExportPath = "/owners/{owner}/export"
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// RestRoute maps a http path onto a WalletServiceImpl method, the query struct of the method
+// is filled with path params first, then url query params (GET) or json body (POST).
+type RestRoute struct {
+	HttpMethod    string
+	Path          string
+	ServiceMethod string
+	Summary       string
+	Public        bool // public market data, response is cacheable
+}
+
+var RestRoutes = []RestRoute{
+	{"GET", "/markets", "GetSupportedMarket", "supported markets", true},
+	{"GET", "/tokens", "GetSupportedTokens", "supported tokens", true},
+	{"GET", "/contracts", "GetContracts", "loopring protocol and delegate addresses", true},
+	{"GET", "/tickers", "GetTicker", "24hr tickers of all markets in loopring", true},
+	{"GET", "/markets/{market}/tickers", "GetTickers", "tickers of market in other exchanges", true},
+	{"GET", "/markets/{market}/depth", "GetDepth", "depth of market", true},
+	{"GET", "/markets/{market}/fills", "GetFills", "fills of market", true},
+	{"GET", "/markets/{market}/trades", "GetLatestFills", "latest trades of market", true},
+	{"GET", "/markets/{market}/trend", "GetTrend", "trend of market", true},
+	{"GET", "/rings", "GetRingMined", "mined rings", true},
+	{"GET", "/rings/{ringIndex}", "GetRingMinedDetail", "mined ring detail with fills", true},
+	{"GET", "/price-quote", "GetPriceQuote", "token prices in legal currency", true},
+	{"GET", "/gas-price", "GetEstimateGasPrice", "estimated gas price", true},
+	{"GET", "/orders/{orderHash}", "GetOrderByHash", "order by hash", false},
+	{"POST", "/orders", "SubmitOrder", "submit order", false},
+	{"GET", "/owners/{owner}/orders", "GetOrders", "orders of owner", false},
+	{"GET", "/owners/{owner}/fills", "GetFills", "fills of owner", false},
+	{"GET", "/owners/{owner}/balance", "GetBalance", "balances and allowances of owner", false},
+	{"GET", "/owners/{owner}/portfolio", "GetPortfolio", "portfolio of owner", false},
+	{"GET", "/owners/{owner}/transactions", "GetTransactions", "transactions of owner", false},
+	{"GET", "/owners/{owner}/pending-transactions", "GetPendingTransactions", "pending transactions of owner", false},
+	{"GET", "/owners/{owner}/frozen-lrc-fee", "GetFrozenLRCFee", "lrc fee frozen by open orders of owner", false},
+	{"GET", "/owners/{owner}/allocated-allowance", "GetEstimatedAllocatedAllowance", "allowance allocated by open orders of owner", false},
+	{"GET", "/owners/{address}/cutoff", "GetCutoff", "cutoff timestamp of owner", false},
+}
+
+type RestErrorBody struct {
+	Error RestError `json:"error"`
+}
+
+type RestError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type RestDataBody struct {
+	Data interface{} `json:"data"`
+}
+
+type RestServiceImpl struct {
+	port          string
+	cacheMaxAge   int
+	walletService *WalletServiceImpl
+	exporter      *export.Exporter
+	server        *http.Server
+	openapi       []byte
+}
+
+func NewRestService(port string, cacheMaxAge int, walletService *WalletServiceImpl, exporter *export.Exporter) *RestServiceImpl {
+	s := &RestServiceImpl{}
+	s.port = port
+	s.cacheMaxAge = cacheMaxAge
+	if s.cacheMaxAge <= 0 {
+		s.cacheMaxAge = defaultRestCacheMaxAge
+	}
+	s.walletService = walletService
+	s.exporter = exporter
+	s.server = &http.Server{}
+	doc := GenerateOpenApi(RestRoutes)
+	doc["paths"].(map[string]interface{})[restExportPath] = map[string]interface{}{"get": exportOperation()}
+	s.openapi, _ = json.Marshal(doc)
+	return s
+}
+
+func (s *RestServiceImpl) Start() {
+	if "" == s.port {
+		log.Info("rest port not configured, rest api disabled")
+		return
+	}
+
+	var (
+		listener net.Listener
+		err      error
+	)
+	if listener, err = net.Listen("tcp", ":"+s.port); err != nil {
+		log.Errorf("rest listen err:%s", err.Error())
+		return
+	}
+
+	c := cors.New(cors.Options{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{"POST", "GET"},
+		AllowedHeaders: []string{"*"},
+		ExposedHeaders: []string{"ETag"},
+		MaxAge:         600,
+	})
+	s.server.Handler = c.Handler(s)
+	go s.server.Serve(listener)
+	log.Info("REST endpoint opened on " + s.port)
+}
+
+func (s *RestServiceImpl) Stop() {
+	if nil != s.server {
+		s.server.Close()
+	}
+}
+
+// Shutdown stops accepting connections and waits the running requests until ctx is done
+func (s *RestServiceImpl) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
+
+func (s *RestServiceImpl) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, RestPathPrefix) {
+		writeRestError(w, http.StatusNotFound, REST_40400, "no route for "+r.URL.Path)
+		return
+	}
+	path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, RestPathPrefix), "/")
+
+	if "/openapi.json" == path {
+		writeRestCacheable(w, r, s.openapi, s.cacheMaxAge)
+		return
+	}
+
+	if params, ok := matchRestPath(restExportPath, path); ok {
+		if "GET" != r.Method {
+			writeRestError(w, http.StatusMethodNotAllowed, REST_40500, "method "+r.Method+" not allowed for "+path)
+			return
+		}
+		s.export(w, r, params)
+		return
+	}
+
+	pathMatched := false
+	for _, route := range RestRoutes {
+		params, ok := matchRestPath(route.Path, path)
+		if !ok {
+			continue
+		}
+		pathMatched = true
+		if route.HttpMethod != r.Method {
+			continue
+		}
+		s.invoke(w, r, route, params)
+		return
+	}
+
+	if pathMatched {
+		writeRestError(w, http.StatusMethodNotAllowed, REST_40500, "method "+r.Method+" not allowed for "+path)
+	} else {
+		writeRestError(w, http.StatusNotFound, REST_40400, "no route for "+r.URL.Path)
+	}
+}
+
+func (s *RestServiceImpl) invoke(w http.ResponseWriter, r *http.Request, route RestRoute, params map[string]string) {
+	method := reflect.ValueOf(s.walletService).MethodByName(route.ServiceMethod)
+	if !method.IsValid() {
+		writeRestError(w, http.StatusInternalServerError, SYS_10001, "service method "+route.ServiceMethod+" not found")
+		return
+	}
+
+	args := make([]reflect.Value, 0)
+	if method.Type().NumIn() == 1 {
+		arg, err := buildRestArg(method.Type().In(0), r, params)
+		if nil != err {
+			writeRestError(w, http.StatusBadRequest, REST_40000, err.Error())
+			return
+		}
+		args = append(args, arg)
+	}
+
+	defer func() {
+		if e := recover(); nil != e {
+			log.Errorf("rest invoke %s err:%v", route.ServiceMethod, e)
+			writeRestError(w, http.StatusInternalServerError, SYS_10001, "internal error")
+		}
+	}()
+	results := method.Call(args)
+	if errValue := results[len(results)-1]; !errValue.IsNil() {
+		err := errValue.Interface().(error)
+		status := restErrorStatus(err)
+		if status >= http.StatusInternalServerError {
+			log.Errorf("rest invoke %s err:%s", route.ServiceMethod, err.Error())
+		}
+		writeRestError(w, status, SYS_10001, err.Error())
+		return
+	}
+
+	body, err := json.Marshal(RestDataBody{Data: results[0].Interface()})
+	if nil != err {
+		writeRestError(w, http.StatusInternalServerError, SYS_10001, err.Error())
+		return
+	}
+
+	if route.Public {
+		writeRestCacheable(w, r, body, s.cacheMaxAge)
+	} else {
+		w.Header().Set("Cache-Control", "no-store")
+		writeRestBody(w, http.StatusOK, body)
+	}
+}
+
+func (s *RestServiceImpl) export(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if nil == s.exporter {
+		writeRestError(w, http.StatusNotFound, REST_40400, "export not enabled")
+		return
+	}
+
+	query := export.ExportQuery{}
+	arg, err := buildRestArg(reflect.TypeOf(query), r, params)
+	if nil != err {
+		writeRestError(w, http.StatusBadRequest, REST_40000, err.Error())
+		return
+	}
+	query = arg.Interface().(export.ExportQuery)
+	if 0 == query.End {
+		query.End = time.Now().Unix()
+	}
+
+	// headers are deferred until the first write, errors before it are reported as json
+	ew := &exportResponseWriter{w: w, format: query.Format}
+	if err := s.exporter.Export(query, ew); nil != err {
+		if !ew.started {
+			writeRestError(w, restErrorStatus(err), SYS_10001, err.Error())
+		} else {
+			// the rows are partly sent, the response is aborted so the statement isn't taken as complete
+			log.Errorf("export statement of owner:%s, err:%s", query.Owner, err.Error())
+			panic(http.ErrAbortHandler)
+		}
+	}
+}
+
+// exportResponseWriter writes headers while the first row is written
+type exportResponseWriter struct {
+	w       http.ResponseWriter
+	format  string
+	started bool
+}
+
+func (ew *exportResponseWriter) Write(p []byte) (int, error) {
+	if !ew.started {
+		ew.started = true
+		if export.FORMAT_NDJSON == ew.format {
+			ew.w.Header().Set("Content-Type", "application/x-ndjson")
+		} else {
+			ew.w.Header().Set("Content-Type", "text/csv;charset=utf-8")
+			ew.w.Header().Set("Content-Disposition", "attachment; filename=statement.csv")
+		}
+		ew.w.Header().Set("Cache-Control", "no-store")
+		ew.w.WriteHeader(http.StatusOK)
+	}
+	n, err := ew.w.Write(p)
+	if f, ok := ew.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return n, err
+}
+
+func buildRestArg(typ reflect.Type, r *http.Request, params map[string]string) (reflect.Value, error) {
+	isPtr := typ.Kind() == reflect.Ptr
+	if isPtr {
+		typ = typ.Elem()
+	}
+	arg := reflect.New(typ)
+
+	if "POST" == r.Method && nil != r.Body {
+		if err := json.NewDecoder(r.Body).Decode(arg.Interface()); nil != err {
+			return arg, fmt.Errorf("invalid json body, %s", err.Error())
+		}
+	}
+
+	if typ.Kind() == reflect.Struct {
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			name := restFieldName(field)
+			var values []string
+			if v, ok := params[name]; ok {
+				values = []string{v}
+			} else if vs, ok := r.URL.Query()[name]; ok {
+				values = vs
+			} else {
+				continue
+			}
+			if err := setRestField(arg.Elem().Field(i), values); nil != err {
+				return arg, fmt.Errorf("invalid param %s, %s", name, err.Error())
+			}
+		}
+	}
+
+	if isPtr {
+		return arg, nil
+	}
+	return arg.Elem(), nil
+}
+
+func setRestField(field reflect.Value, values []string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(values[0])
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		i, err := strconv.ParseInt(values[0], 10, 64)
+		if nil != err {
+			return err
+		}
+		field.SetInt(i)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(values[0])
+		if nil != err {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", field.Type().String())
+		}
+		items := make([]string, 0)
+		for _, v := range values {
+			items = append(items, strings.Split(v, ",")...)
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type().String())
+	}
+	return nil
+}
+
+// restFieldName returns the json name of field, some query structs have malformed json tags
+// and are matched by the lower camel case field name the same as encoding/json does
+func restFieldName(field reflect.StructField) string {
+	if tag := strings.Split(field.Tag.Get("json"), ",")[0]; "" != tag && "-" != tag {
+		return tag
+	}
+	name := []rune(field.Name)
+	name[0] = unicode.ToLower(name[0])
+	return string(name)
+}
+
+func matchRestPath(pattern, path string) (map[string]string, bool) {
+	patternParts := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(patternParts) != len(pathParts) {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for i, part := range patternParts {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			if "" == pathParts[i] {
+				return nil, false
+			}
+			params[strings.Trim(part, "{}")] = pathParts[i]
+		} else if part != pathParts[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func writeRestCacheable(w http.ResponseWriter, r *http.Request, body []byte, maxAge int) {
+	hash := sha1.Sum(body)
+	etag := `"` + hex.EncodeToString(hash[:]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(maxAge))
+	if match := r.Header.Get("If-None-Match"); "" != match && (match == etag || match == "*") {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeRestBody(w, http.StatusOK, body)
+}
+
+// restErrorStatus maps the error returned by service to http status, the errors of mysql, redis and eth node
+// are 5xx, the others are caused by the request and mapped to 400
+func restErrorStatus(err error) int {
+	switch err {
+	case ethaccessor.ErrNoUsableNode, mysql.ErrInvalidConn, driver.ErrBadConn, sql.ErrConnDone, context.DeadlineExceeded:
+		return http.StatusServiceUnavailable
+	}
+	switch err.(type) {
+	case internalError, *mysql.MySQLError, gorm.Errors, redis.Error:
+		return http.StatusInternalServerError
+	case rpc.Error:
+		return http.StatusBadGateway
+	case net.Error:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusBadRequest
+}
+
+func writeRestError(w http.ResponseWriter, status int, code, message string) {
+	body, _ := json.Marshal(RestErrorBody{Error: RestError{Code: code, Message: message}})
+	w.Header().Set("Cache-Control", "no-store")
+	writeRestBody(w, status, body)
+}
+
+func writeRestBody(w http.ResponseWriter, status int, body []byte) {
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// GenerateOpenApi builds an openapi 3.0 document from routes and the query structs of WalletServiceImpl
+func GenerateOpenApi(routes []RestRoute) map[string]interface{} {
+	serviceType := reflect.TypeOf(&WalletServiceImpl{})
+	paths := make(map[string]interface{})
+
+	for _, route := range routes {
+		operation := map[string]interface{}{
+			"summary":     route.Summary,
+			"operationId": restOperationId(route),
+			"responses": map[string]interface{}{
+				"200":     map[string]interface{}{"description": "{\"data\": result of loopring_" + lowerFirst(route.ServiceMethod) + "}"},
+				"default": map[string]interface{}{"description": "error", "content": jsonContent(map[string]interface{}{"$ref": "#/components/schemas/Error"})},
+			},
+		}
+
+		pathParams := make(map[string]bool)
+		parameters := make([]interface{}, 0)
+		for _, part := range strings.Split(route.Path, "/") {
+			if strings.HasPrefix(part, "{") {
+				name := strings.Trim(part, "{}")
+				pathParams[name] = true
+				parameters = append(parameters, map[string]interface{}{"name": name, "in": "path", "required": true, "schema": map[string]interface{}{"type": "string"}})
+			}
+		}
+
+		if method, ok := serviceType.MethodByName(route.ServiceMethod); ok && method.Type.NumIn() == 2 {
+			argType := method.Type.In(1)
+			if argType.Kind() == reflect.Ptr {
+				argType = argType.Elem()
+			}
+			if "POST" == route.HttpMethod {
+				operation["requestBody"] = map[string]interface{}{"required": true, "content": jsonContent(openApiSchema(argType))}
+			} else if argType.Kind() == reflect.Struct {
+				for i := 0; i < argType.NumField(); i++ {
+					name := restFieldName(argType.Field(i))
+					if pathParams[name] {
+						continue
+					}
+					parameters = append(parameters, map[string]interface{}{"name": name, "in": "query", "schema": openApiSchema(argType.Field(i).Type)})
+				}
+			}
+		}
+		operation["parameters"] = parameters
+
+		item, ok := paths[route.Path].(map[string]interface{})
+		if !ok {
+			item = make(map[string]interface{})
+			paths[route.Path] = item
+		}
+		item[strings.ToLower(route.HttpMethod)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.0",
+		"info":    map[string]interface{}{"title": "Loopring Relay REST API", "version": "v1"},
+		"servers": []interface{}{map[string]interface{}{"url": RestPathPrefix}},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": map[string]interface{}{
+				"Error": openApiSchema(reflect.TypeOf(RestErrorBody{})),
+			},
+		},
+	}
+}
+
+func exportOperation() map[string]interface{} {
+	parameters := []interface{}{map[string]interface{}{"name": "owner", "in": "path", "required": true, "schema": map[string]interface{}{"type": "string"}}}
+	for _, name := range []string{"start", "end"} {
+		parameters = append(parameters, map[string]interface{}{"name": name, "in": "query", "description": "unix timestamp", "schema": map[string]interface{}{"type": "integer"}})
+	}
+	parameters = append(parameters,
+		map[string]interface{}{"name": "format", "in": "query", "schema": map[string]interface{}{"type": "string", "enum": []string{export.FORMAT_CSV, export.FORMAT_NDJSON}}},
+		map[string]interface{}{"name": "currency", "in": "query", "schema": map[string]interface{}{"type": "string", "enum": []string{"USD", "CNY", "BTC"}}})
+
+	return map[string]interface{}{
+		"summary":     "statement of owner with fiat value and fifo cost basis",
+		"operationId": "getOwnersOwnerExport",
+		"parameters":  parameters,
+		"responses": map[string]interface{}{
+			"200": map[string]interface{}{
+				"description": "one row per transaction",
+				"content": map[string]interface{}{
+					"text/csv":             map[string]interface{}{"schema": map[string]interface{}{"type": "string"}},
+					"application/x-ndjson": map[string]interface{}{"schema": openApiSchema(reflect.TypeOf(export.Record{}))},
+				},
+			},
+			"default": map[string]interface{}{"description": "error", "content": jsonContent(map[string]interface{}{"$ref": "#/components/schemas/Error"})},
+		},
+	}
+}
+
+func openApiSchema(typ reflect.Type) map[string]interface{} {
+	// addresses, hashes and big numbers are encoded as string
+	if typ.Implements(textMarshalerType) || reflect.PtrTo(typ).Implements(textMarshalerType) {
+		return map[string]interface{}{"type": "string"}
+	}
+	switch typ.Kind() {
+	case reflect.Ptr:
+		return openApiSchema(typ.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": openApiSchema(typ.Elem())}
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		for i := 0; i < typ.NumField(); i++ {
+			if "" != typ.Field(i).PkgPath || "-" == typ.Field(i).Tag.Get("json") {
+				continue
+			}
+			properties[restFieldName(typ.Field(i))] = openApiSchema(typ.Field(i).Type)
+		}
+		return map[string]interface{}{"type": "object", "properties": properties}
+	default:
+		return map[string]interface{}{"type": "object"}
+	}
+}
+
+func restOperationId(route RestRoute) string {
+	id := strings.ToLower(route.HttpMethod)
+	for _, part := range strings.Split(route.Path, "/") {
+		for _, word := range strings.Split(strings.Trim(part, "{}"), "-") {
+			id += strings.Title(word)
+		}
+	}
+	return id
+}
+
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+}
+
+func lowerFirst(s string) string {
+	if "" == s {
+		return s
+	}
+	r := []rune(s)
+	r[0] = unicode.ToLower(r[0])
+	return string(r)
+}
@@ -0,0 +1,53 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package gateway
+
+import (
+	"errors"
+	"github.com/Loopring/relay/ethaccessor"
+	"github.com/go-sql-driver/mysql"
+	"net"
+	"net/http"
+	"testing"
+)
+
+type rpcError struct{}
+
+func (e *rpcError) Error() string  { return "execution reverted" }
+func (e *rpcError) ErrorCode() int { return -32000 }
+
+func TestRestErrorStatus(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+	}{
+		{errors.New("owner can't be null"), http.StatusBadRequest},
+		{internalError{errors.New("query ring error occurs")}, http.StatusInternalServerError},
+		{&mysql.MySQLError{Number: 1146, Message: "table doesn't exist"}, http.StatusInternalServerError},
+		{mysql.ErrInvalidConn, http.StatusServiceUnavailable},
+		{ethaccessor.ErrNoUsableNode, http.StatusServiceUnavailable},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, http.StatusServiceUnavailable},
+		{&rpcError{}, http.StatusBadGateway},
+	}
+	for _, c := range cases {
+		if status := restErrorStatus(c.err); c.status != status {
+			t.Errorf("status of %q should be %d, got %d", c.err.Error(), c.status, status)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package gateway_test
+
+import (
+	"encoding/json"
+	"github.com/Loopring/relay/config"
+	"github.com/Loopring/relay/gateway"
+	"github.com/Loopring/relay/log"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func init() {
+	log.Initialize(config.LogOptions{ZapOpts: zap.NewDevelopmentConfig()})
+}
+
+func newRestService() *gateway.RestServiceImpl {
+	return gateway.NewRestService("", 0, &gateway.WalletServiceImpl{}, nil)
+}
+
+func serveRest(s *gateway.RestServiceImpl, method, target, body string) *httptest.ResponseRecorder {
+	var req *http.Request
+	if "" == body {
+		req = httptest.NewRequest(method, target, nil)
+	} else {
+		req = httptest.NewRequest(method, target, strings.NewReader(body))
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	return w
+}
+
+func restErrorOf(t *testing.T, w *httptest.ResponseRecorder) gateway.RestError {
+	body := gateway.RestErrorBody{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); nil != err {
+		t.Fatalf("invalid error body:%s, err:%s", w.Body.String(), err.Error())
+	}
+	return body.Error
+}
+
+func TestRestServiceImpl_Routing(t *testing.T) {
+	s := newRestService()
+
+	cases := []struct {
+		method string
+		target string
+		status int
+		code   string
+	}{
+		{"GET", "/api/v1/markets", http.StatusOK, ""},
+		{"GET", "/api/v1/unknown", http.StatusNotFound, gateway.REST_40400},
+		{"GET", "/api/v1/markets/LRC-WETH/unknown", http.StatusNotFound, gateway.REST_40400},
+		{"POST", "/api/v1/markets", http.StatusMethodNotAllowed, gateway.REST_40500},
+		{"GET", "/api/v1/orders", http.StatusMethodNotAllowed, gateway.REST_40500},
+		{"POST", "/api/v1/owners/0x1/export", http.StatusMethodNotAllowed, gateway.REST_40500},
+		{"GET", "/api/v1/owners/0x1/export", http.StatusNotFound, gateway.REST_40400},
+	}
+	for _, c := range cases {
+		w := serveRest(s, c.method, c.target, "")
+		if c.status != w.Code {
+			t.Errorf("%s %s status:%d, expect:%d", c.method, c.target, w.Code, c.status)
+			continue
+		}
+		if "" != c.code {
+			if code := restErrorOf(t, w).Code; c.code != code {
+				t.Errorf("%s %s code:%s, expect:%s", c.method, c.target, code, c.code)
+			}
+		}
+	}
+}
+
+func TestRestServiceImpl_PublicCache(t *testing.T) {
+	s := newRestService()
+
+	w := serveRest(s, "GET", "/api/v1/markets/", "")
+	if http.StatusOK != w.Code {
+		t.Fatalf("status:%d, body:%s", w.Code, w.Body.String())
+	}
+	body := gateway.RestDataBody{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); nil != err {
+		t.Fatalf("invalid data body:%s", w.Body.String())
+	}
+	etag := w.Header().Get("ETag")
+	if "" == etag || !strings.HasPrefix(w.Header().Get("Cache-Control"), "public") {
+		t.Fatalf("public route should be cacheable, headers:%v", w.Header())
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/markets", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	if http.StatusNotModified != w.Code {
+		t.Errorf("status:%d, expect:%d", w.Code, http.StatusNotModified)
+	}
+
+	w = serveRest(s, "GET", "/api/v1/openapi.json", "")
+	doc := make(map[string]interface{})
+	if http.StatusOK != w.Code || nil != json.Unmarshal(w.Body.Bytes(), &doc) {
+		t.Fatalf("openapi status:%d, body:%s", w.Code, w.Body.String())
+	}
+	paths := doc["paths"].(map[string]interface{})
+	for _, route := range gateway.RestRoutes {
+		if _, ok := paths[route.Path]; !ok {
+			t.Errorf("openapi path %s not found", route.Path)
+		}
+	}
+}
+
+func TestRestServiceImpl_ParamsAndErrors(t *testing.T) {
+	s := newRestService()
+
+	// int param can't be parsed, rejected before the service is called
+	w := serveRest(s, "GET", "/api/v1/markets/LRC-WETH/fills?pageIndex=abc", "")
+	if http.StatusBadRequest != w.Code {
+		t.Fatalf("status:%d, expect:%d", w.Code, http.StatusBadRequest)
+	}
+	if e := restErrorOf(t, w); gateway.REST_40000 != e.Code || !strings.Contains(e.Message, "pageIndex") {
+		t.Errorf("unexpected error:%+v", e)
+	}
+
+	// body of post isn't json
+	w = serveRest(s, "POST", "/api/v1/orders", "{")
+	if e := restErrorOf(t, w); http.StatusBadRequest != w.Code || gateway.REST_40000 != e.Code {
+		t.Errorf("status:%d, error:%+v", w.Code, e)
+	}
+
+	// path param is set, the error returned by service is mapped to 400
+	w = serveRest(s, "GET", "/api/v1/markets/LRC-WETH/depth", "")
+	e := restErrorOf(t, w)
+	if http.StatusBadRequest != w.Code || gateway.SYS_10001 != e.Code {
+		t.Errorf("status:%d, error:%+v", w.Code, e)
+	}
+	if !strings.Contains(e.Message, "contract address") {
+		t.Errorf("service error not returned, message:%s", e.Message)
+	}
+	if "no-store" != w.Header().Get("Cache-Control") {
+		t.Errorf("error shouldn't be cached, headers:%v", w.Header())
+	}
+
+	// panic in service is recovered and mapped to 500
+	w = serveRest(s, "GET", "/api/v1/orders/0x1", "")
+	if e := restErrorOf(t, w); http.StatusInternalServerError != w.Code || gateway.SYS_10001 != e.Code {
+		t.Errorf("status:%d, error:%+v", w.Code, e)
+	}
+}
@@ -19,10 +19,8 @@
 package gateway
 
 import (
-	"encoding/json"
-	"fmt"
+	"github.com/Loopring/relay/log"
 	"github.com/gorilla/websocket"
-	"log"
 	"qiniupkg.com/x/errors.v7"
 	"strconv"
 	"time"
@@ -48,26 +46,19 @@ func (c *SocketClient) read() {
 		c.node.unregister <- c
 		c.conn.Close()
 	}()
-	fmt.Print("read ------->1")
 	c.conn.SetReadLimit(maxMessageSize)
 	c.conn.SetReadDeadline(time.Now().Add(pongWait))
 	c.conn.SetPongHandler(func(string) error { c.conn.SetReadDeadline(time.Now().Add(pongWait)); return nil })
-	fmt.Print("read ------->2")
 	for {
-		fmt.Print("read ------->33")
 		req := &WebsocketRequest{}
 		err := c.conn.ReadJSON(&req)
-		fmt.Print(req)
-		fmt.Print(err)
-		fmt.Print("read ------->3333")
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("error: %v", err)
+				log.Errorf("socket client read err:%s", err.Error())
 			}
 			break
 		}
 
-		fmt.Print("read ------->4")
 		resp, err := c.handler(*req)
 		if err == nil {
 			c.send <- resp
@@ -77,9 +68,6 @@ func (c *SocketClient) read() {
 
 func (c *SocketClient) handler(req WebsocketRequest) (interface{}, error) {
 	walletService := WalletServiceImpl{}
-	fmt.Print("read ------->5")
-	fmt.Println(req)
-	fmt.Print("read ------->6")
 
 	if method, ok := req["method"]; ok {
 		switch method {
@@ -122,13 +110,8 @@ func (c *SocketClient) write() {
 		c.conn.Close()
 	}()
 	for {
-		fmt.Print("read ------->7")
 		select {
 		case message, ok := <-c.send:
-			fmt.Println("read ------->8")
-			fmt.Println(message)
-			fmt.Println(ok)
-			fmt.Println(json.Marshal(message))
 			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if !ok {
 				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
@@ -136,8 +119,7 @@ func (c *SocketClient) write() {
 			}
 			err := c.conn.WriteJSON(&message)
 			if err != nil {
-				fmt.Println("write error occurs")
-				fmt.Println(err)
+				log.Errorf("socket client write err:%s", err.Error())
 				return
 			}
 
@@ -1,13 +1,14 @@
 package gateway
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"github.com/Loopring/relay/ethaccessor"
 	"github.com/Loopring/relay/eventemiter"
 	"github.com/Loopring/relay/log"
 	"github.com/Loopring/relay/market/util"
+	"github.com/Loopring/relay/metrics"
 	txtyp "github.com/Loopring/relay/txmanager/types"
 	"github.com/Loopring/relay/types"
 	"github.com/ethereum/go-ethereum/common"
@@ -80,6 +81,7 @@ const (
 	eventKeyTickers         = "tickers"
 	eventKeyLoopringTickers = "loopringTickers"
 	eventKeyTrends          = "trends"
+	eventKeyCandles         = "candles"
 	eventKeyPortfolio       = "portfolio"
 	eventKeyMarketCap       = "marketcap"
 	eventKeyBalance         = "balance"
@@ -105,6 +107,7 @@ var EventTypeRoute = map[string]InvokeInfo{
 	eventKeyTickers:         {"GetTickers", SingleMarket{}, true, emitTypeByCron, DefaultCronSpec5Second},
 	eventKeyLoopringTickers: {"GetTicker", nil, true, emitTypeByEvent, DefaultCronSpec5Second},
 	eventKeyTrends:          {"GetTrend", TrendQuery{}, true, emitTypeByEvent, DefaultCronSpec10Second},
+	eventKeyCandles:         {"GetTrend", TrendQuery{}, true, emitTypeByEvent, DefaultCronSpec10Second},
 	// portfolio has been remove from loopr2
 	// eventKeyPortfolio:       {"GetPortfolio", SingleOwner{}, false, emitTypeByEvent, DefaultCronSpec3Second},
 	eventKeyPortfolio:   {"GetPortfolio", SingleOwner{}, false, emitTypeByCron, DefaultCronSpec3Second},
@@ -127,6 +130,7 @@ type SocketIOServiceImpl struct {
 	connIdMap          *sync.Map
 	connBusinessKeyMap map[string]socketio.Conn
 	cron               *cron.Cron
+	httpServer         *http.Server
 }
 
 func NewSocketIOService(port string, walletService WalletServiceImpl) *SocketIOServiceImpl {
@@ -136,6 +140,7 @@ func NewSocketIOService(port string, walletService WalletServiceImpl) *SocketIOS
 	so.connBusinessKeyMap = make(map[string]socketio.Conn)
 	so.connIdMap = &sync.Map{}
 	so.cron = cron.New()
+	so.httpServer = &http.Server{Addr: ":" + port}
 
 	// init event watcher
 	//loopringTickerWatcher := &eventemitter.Watcher{Concurrent: false, Handle: so.broadcastLoopringTicker}
@@ -152,6 +157,8 @@ func NewSocketIOService(port string, walletService WalletServiceImpl) *SocketIOS
 	//eventemitter.On(eventemitter.TransactionEvent, transactionWatcher)
 	//pendingTxWatcher := &eventemitter.Watcher{Concurrent: false, Handle: so.handlePendingTransaction}
 	//eventemitter.On(eventemitter.TransactionEvent, pendingTxWatcher)
+	candleWatcher := &eventemitter.Watcher{Concurrent: false, Handle: so.broadcastCandles}
+	eventemitter.On(eventemitter.CandleUpdated, candleWatcher)
 	return so
 }
 
@@ -165,20 +172,20 @@ func (so *SocketIOServiceImpl) Start() {
 	}
 	server.OnConnect("/", func(s socketio.Conn) error {
 		so.connIdMap.Store(s.ID(), s)
+		metrics.Counter("relay_socketio_connects_total").Inc(1)
+		so.updateConnectionsMetric()
 		return nil
 	})
 	server.OnEvent("/", "test", func(s socketio.Conn, msg string) {
-		fmt.Println("test:", msg)
+		log.Debugf("[SOCKETIO] test message:%s from:%s", msg, s.RemoteAddr())
 		s.Emit("reply", "pong relay msg : "+msg)
-		fmt.Println("emit message finished...")
-		fmt.Println(s.RemoteAddr())
 	})
 
 	for v := range EventTypeRoute {
 		aliasOfV := v
 
 		server.OnEvent("/", aliasOfV+EventPostfixReq, func(s socketio.Conn, msg string) {
-			fmt.Println("input emit msg is ....." + msg)
+			log.Debugf("[SOCKETIO] request of %s, msg:%s", aliasOfV, msg)
 			context := make(map[string]string)
 			if s != nil && s.Context() != nil {
 				context = s.Context().(map[string]string)
@@ -187,7 +194,6 @@ func (so *SocketIOServiceImpl) Start() {
 			s.SetContext(context)
 			so.connIdMap.Store(s.ID(), s)
 			//log.Infof("[SOCKETIO-EMIT]response emit by key : %s, connId : %s", aliasOfV, s.ID())
-			fmt.Println("out emit msg is ....." + aliasOfV)
 			so.EmitNowByEventType(aliasOfV, s, msg)
 		})
 
@@ -228,6 +234,8 @@ func (so *SocketIOServiceImpl) Start() {
 				//log.Info("start trades broadcast")
 				so.broadcastTrades(nil)
 			})
+		case eventKeyCandles:
+			// pushed by CandleUpdated event
 		default:
 			log.Infof("add cron emit %d ", events.emitType)
 			so.cron.AddFunc(spec, func() {
@@ -266,10 +274,11 @@ func (so *SocketIOServiceImpl) Start() {
 	so.cron.Start()
 
 	server.OnError("/", func(e error) {
-		fmt.Println("meet error:", e)
+		log.Debugf("[SOCKETIO] meet error:%s", e.Error())
 		infos := strings.Split(e.Error(), "SOCKETFORLOOPRING")
 		if len(infos) == 2 {
 			so.connIdMap.Delete(infos[0])
+			so.updateConnectionsMetric()
 		}
 
 	})
@@ -277,18 +286,41 @@ func (so *SocketIOServiceImpl) Start() {
 	server.OnDisconnect("/", func(s socketio.Conn, msg string) {
 		s.Close()
 		so.connIdMap.Delete(s.ID())
-		fmt.Println("closed", msg)
+		so.updateConnectionsMetric()
+		log.Debugf("[SOCKETIO] connection %s closed, msg:%s", s.ID(), msg)
 	})
 	go server.Serve()
 	defer server.Close()
 
 	http.Handle("/socket.io/", NewServer(*server))
 	log.Info("Serving at localhost: " + so.port)
-	log.Fatal(http.ListenAndServe(":"+so.port, nil).Error())
+	if err := so.httpServer.ListenAndServe(); http.ErrServerClosed != err {
+		log.Fatal(err.Error())
+	}
 	log.Info("finished listen socket io....")
 
 }
 
+// Shutdown stops the cron pushes, closes the connections and waits the running requests until ctx is done
+func (so *SocketIOServiceImpl) Shutdown(ctx context.Context) error {
+	so.cron.Stop()
+	so.connIdMap.Range(func(key, value interface{}) bool {
+		value.(socketio.Conn).Close()
+		return true
+	})
+	return so.httpServer.Shutdown(ctx)
+}
+
+// connections may be removed by both OnError and OnDisconnect, so the gauge is counted from connIdMap
+func (so *SocketIOServiceImpl) updateConnectionsMetric() {
+	count := int64(0)
+	so.connIdMap.Range(func(key, value interface{}) bool {
+		count++
+		return true
+	})
+	metrics.Gauge("relay_socketio_connections").Update(count)
+}
+
 func (so *SocketIOServiceImpl) EmitNowByEventType(bk string, v socketio.Conn, bv string) {
 	if invokeInfo, ok := EventTypeRoute[bk]; ok {
 		so.handleAfterEmit(bk, invokeInfo.Query, invokeInfo.MethodName, v, bv)
@@ -574,6 +606,43 @@ func (so *SocketIOServiceImpl) broadcastTrends(input eventemitter.EventData) (er
 	return nil
 }
 
+// broadcastCandles emits the latest candle of market to connections subscribed it, the first
+// response of candles_req is the whole range of query, and then every update is the latest candle only
+func (so *SocketIOServiceImpl) broadcastCandles(input eventemitter.EventData) (err error) {
+	mkt := strings.ToUpper(input.(string))
+
+	respMap := make(map[string]string)
+	so.connIdMap.Range(func(key, value interface{}) bool {
+		v := value.(socketio.Conn)
+		if v.Context() != nil {
+			businesses := v.Context().(map[string]string)
+			ctx, ok := businesses[eventKeyCandles]
+			if !ok {
+				return true
+			}
+			candleQuery := &TrendQuery{}
+			if err := json.Unmarshal([]byte(ctx), candleQuery); err != nil || strings.ToUpper(candleQuery.Market) != mkt {
+				return true
+			}
+			interval := strings.ToLower(candleQuery.Interval)
+			if _, ok := respMap[interval]; !ok {
+				resp := SocketIOJsonResp{}
+				candles, err := so.walletService.GetTrend(TrendQuery{Market: mkt, Interval: candleQuery.Interval, Limit: 1})
+				if err != nil {
+					resp = SocketIOJsonResp{Error: err.Error()}
+				} else {
+					resp.Data = candles
+				}
+				respJson, _ := json.Marshal(resp)
+				respMap[interval] = string(respJson[:])
+			}
+			v.Emit(eventKeyCandles+EventPostfixRes, respMap[interval])
+		}
+		return true
+	})
+	return nil
+}
+
 // portfolio has removed from loopr2
 func (so *SocketIOServiceImpl) handlePortfolioUpdate(input eventemitter.EventData) (err error) {
 	return nil
@@ -672,7 +741,6 @@ func (so *SocketIOServiceImpl) handleTransactionUpdate(input eventemitter.EventD
 	req := input.(*txtyp.TransactionView)
 	owner := req.Owner.Hex()
 	log.Infof("received owner is %s ", owner)
-	fmt.Println(so.connIdMap)
 	so.connIdMap.Range(func(key, value interface{}) bool {
 		v := value.(socketio.Conn)
 		if v.Context() != nil {
@@ -715,11 +783,8 @@ func (so *SocketIOServiceImpl) handlePendingTransaction(input eventemitter.Event
 	req := input.(*txtyp.TransactionView)
 	owner := req.Owner.Hex()
 	log.Infof("received owner is %s ", owner)
-	fmt.Println(so.connIdMap)
 	so.connIdMap.Range(func(key, value interface{}) bool {
 		v := value.(socketio.Conn)
-		fmt.Println(key)
-		fmt.Println(value)
 		if v.Context() != nil {
 			businesses := v.Context().(map[string]string)
 			ctx, ok := businesses[eventKeyPendingTx]
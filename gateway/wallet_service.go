@@ -22,6 +22,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/Loopring/relay/cache"
+	"github.com/Loopring/relay/crypto"
 	"github.com/Loopring/relay/dao"
 	"github.com/Loopring/relay/ethaccessor"
 	"github.com/Loopring/relay/eventemiter"
@@ -34,6 +35,7 @@ import (
 	txtyp "github.com/Loopring/relay/txmanager/types"
 	"github.com/Loopring/relay/types"
 	"github.com/ethereum/go-ethereum/common"
+	gocache "github.com/patrickmn/go-cache"
 	"math/big"
 	"qiniupkg.com/x/errors.v7"
 	"sort"
@@ -46,6 +48,7 @@ const DefaultCapCurrency = "CNY"
 const PendingTxPreKey = "PENDING_TX_"
 
 const SYS_10001 = "10001"
+
 const P2P_50001 = "50001"
 const P2P_50002 = "50002"
 const P2P_50003 = "50003"
@@ -54,6 +57,12 @@ const P2P_50005 = "50005"
 const P2P_50006 = "50006"
 const P2P_50008 = "50008"
 
+// internalError marks the error caused by the relay itself rather than the request, like a failed query of mysql,
+// the message is returned as is while the REST API responds 500 for it
+type internalError struct {
+	error
+}
+
 type Portfolio struct {
 	Token      string `json:"token"`
 	Amount     string `json:"amount"`
@@ -61,10 +70,11 @@ type Portfolio struct {
 }
 
 type PageResult struct {
-	Data      []interface{} `json:"data"`
-	PageIndex int           `json:"pageIndex"`
-	PageSize  int           `json:"pageSize"`
-	Total     int           `json:"total"`
+	Data       []interface{} `json:"data"`
+	PageIndex  int           `json:"pageIndex"`
+	PageSize   int           `json:"pageSize"`
+	Total      int           `json:"total"`
+	NextCursor string        `json:"nextCursor,omitempty"`
 }
 
 type Depth struct {
@@ -100,6 +110,9 @@ type SingleMarket struct {
 type TrendQuery struct {
 	Market   string `json:"market"`
 	Interval string `json:"interval"`
+	Start    int64  `json:"start"`
+	End      int64  `json:"end"`
+	Limit    int    `json:"limit"`
 }
 
 type SingleOwner struct {
@@ -145,6 +158,7 @@ type TransactionQuery struct {
 	TrxHashes []string `json:"trxHashes"`
 	PageIndex int      `json:"pageIndex"`
 	PageSize  int      `json:"pageSize"`
+	Cursor    string   `json:"cursor"`
 }
 
 type OrderQuery struct {
@@ -157,6 +171,26 @@ type OrderQuery struct {
 	OrderHash       string `json:"orderHash"`
 	Side            string `json:"side"`
 	OrderType       string `json:"orderType"`
+	Cursor          string `json:"cursor"`
+}
+
+type CancelOrderQuery struct {
+	OrderHash string        `json:"orderHash"`
+	Timestamp int64         `json:"timestamp"`
+	V         uint8         `json:"v"`
+	R         types.Bytes32 `json:"r"`
+	S         types.Bytes32 `json:"s"`
+}
+
+type OrderMatchingStatusResult struct {
+	OrderHash        string                            `json:"orderHash"`
+	Market           string                            `json:"market"`
+	Side             string                            `json:"side"`
+	Price            float64                           `json:"price"`
+	SpendableS       string                            `json:"spendableS"`
+	SpendableLrcFee  string                            `json:"spendableLrcFee"`
+	BestCounterPrice float64                           `json:"bestCounterPrice"`
+	Status           *ordermanager.OrderMatchingStatus `json:"status"`
 }
 
 type DepthQuery struct {
@@ -174,6 +208,7 @@ type FillQuery struct {
 	PageSize        int    `json:"pageSize"`
 	Side            string `json:"side"`
 	OrderType       string `json:"orderType"`
+	Cursor          string `json:"cursor"`
 }
 
 type RingMinedQuery struct {
@@ -182,6 +217,7 @@ type RingMinedQuery struct {
 	RingIndex       string `json:"ringIndex"`
 	PageIndex       int    `json:"pageIndex"`
 	PageSize        int    `json:"pageSize"`
+	Cursor          string `json:"cursor"`
 }
 
 type RawOrderJsonResult struct {
@@ -290,6 +326,7 @@ type WalletServiceImpl struct {
 	tickerCollector market.CollectorImpl
 	rds             dao.RdsService
 	oldWethAddress  string
+	bookCache       *gocache.Cache
 }
 
 func NewWalletService(trendManager market.TrendManager, orderManager ordermanager.OrderManager, accountManager market.AccountManager,
@@ -302,6 +339,7 @@ func NewWalletService(trendManager market.TrendManager, orderManager ordermanage
 	w.tickerCollector = collector
 	w.rds = rds
 	w.oldWethAddress = oldWethAddress
+	w.bookCache = gocache.New(5*time.Second, 5*time.Minute)
 	return w
 }
 func (w *WalletServiceImpl) TestPing(input int) (resp []byte, err error) {
@@ -375,7 +413,7 @@ func (w *WalletServiceImpl) GetPortfolio(query SingleOwner) (res []Portfolio, er
 func (w *WalletServiceImpl) GetPriceQuote(query PriceQuoteQuery) (result PriceQuote, err error) {
 
 	rst := PriceQuote{query.Currency, make([]TokenPrice, 0)}
-	for k, v := range util.AllTokens {
+	for k, v := range util.AllTokens() {
 		price, err := w.marketCap.GetMarketCapByCurrency(v.Protocol, query.Currency)
 		if err != nil {
 			log.Debug(">>>>>>>> get market cap error " + err.Error())
@@ -395,7 +433,7 @@ func (w *WalletServiceImpl) GetTickers(mkt SingleMarket) (result map[string]mark
 	result = make(map[string]market.Ticker)
 	loopringTicker, err := w.trendManager.GetTickerByMarket(mkt.Market)
 	if err == nil {
-		result["loopr"] = loopringTicker
+		result["loopr"] = w.fillBestQuote(loopringTicker)
 	} else {
 		log.Info("get ticker from loopring error" + err.Error())
 		return result, err
@@ -489,8 +527,13 @@ func (w *WalletServiceImpl) SubmitOrder(order *types.OrderJsonRequest) (res stri
 }
 
 func (w *WalletServiceImpl) GetOrders(query *OrderQuery) (res PageResult, err error) {
+	var queryRst dao.PageResult
 	orderQuery, statusList, pi, ps := convertFromQuery(query)
-	queryRst, err := w.orderManager.GetOrders(orderQuery, statusList, pi, ps)
+	if query.Cursor != "" {
+		queryRst, err = w.orderManager.GetOrdersByCursor(orderQuery, statusList, query.Cursor, ps)
+	} else {
+		queryRst, err = w.orderManager.GetOrders(orderQuery, statusList, pi, ps)
+	}
 	if err != nil {
 		log.Info("query order error : " + err.Error())
 	}
@@ -510,6 +553,94 @@ func (w *WalletServiceImpl) GetOrderByHash(query OrderQuery) (order OrderJsonRes
 	}
 }
 
+// the signed timestamp of CancelOrder must be within it
+const cancelOrderSignWindow = 600
+
+// CancelOrder cancels the order in this relay only, the other relays may still fill it until it's cancelled on chain.
+// the owner signs keccak256(orderHash, timestamp) as the order
+func (w *WalletServiceImpl) CancelOrder(query CancelOrderQuery) (res string, err error) {
+	if len(query.OrderHash) == 0 {
+		return res, errors.New("order hash can't be null")
+	}
+	if now := time.Now().Unix(); query.Timestamp < now-cancelOrderSignWindow || query.Timestamp > now+cancelOrderSignWindow {
+		return res, errors.New("timestamp of signature is expired")
+	}
+	orderHash := common.HexToHash(query.OrderHash)
+	state, err := w.orderManager.GetOrderByHash(orderHash)
+	if err != nil {
+		return res, err
+	}
+	sig, _ := crypto.VRSToSig(query.V, query.R.Bytes(), query.S.Bytes())
+	signer, err := crypto.SigToAddress(types.CancelOrderHash(orderHash, query.Timestamp), sig)
+	if err != nil {
+		return res, err
+	}
+	if common.BytesToAddress(signer) != state.RawOrder.Owner {
+		return res, errors.New("cancel order should be signed by the owner")
+	}
+	if err = w.orderManager.CancelOrderOffChain(orderHash); err != nil {
+		return res, err
+	}
+	log.Infof("order:%s cancelled by owner:%s", orderHash.Hex(), state.RawOrder.Owner.Hex())
+	return orderHash.Hex(), nil
+}
+
+func (w *WalletServiceImpl) GetOrderMatchingStatus(query OrderQuery) (res OrderMatchingStatusResult, err error) {
+	if len(query.OrderHash) == 0 {
+		return res, errors.New("order hash can't be null")
+	}
+
+	state, err := w.orderManager.GetOrderByHash(common.HexToHash(query.OrderHash))
+	if err != nil {
+		return res, err
+	}
+	order := state.RawOrder
+
+	res.OrderHash = order.Hash.Hex()
+	res.Market, _ = util.WrapMarketByAddress(order.TokenS.Hex(), order.TokenB.Hex())
+	res.Side = util.GetSide(order.TokenS.Hex(), order.TokenB.Hex())
+	res.Price = util.CalculatePrice(order.AmountS.String(), order.AmountB.String(), order.TokenS.Hex(), order.TokenB.Hex())
+
+	if res.Status, err = ordermanager.GetOrderMatchingStatus(order.Hash); err != nil {
+		return res, err
+	}
+
+	if balance, allowance, err := w.accountManager.GetBalanceAndAllowance(order.Owner, order.TokenS, order.DelegateAddress); err == nil {
+		res.SpendableS = minBigInt(balance, allowance).String()
+	} else {
+		log.Errorf("get balance and allowance of order:%s err:%s", res.OrderHash, err.Error())
+	}
+	if balance, allowance, err := w.accountManager.GetBalanceAndAllowance(order.Owner, util.AliasToAddress("LRC"), order.DelegateAddress); err == nil {
+		res.SpendableLrcFee = minBigInt(balance, allowance).String()
+	}
+
+	counterOrders, err := w.orderManager.GetOrderBook(order.DelegateAddress, order.TokenB, order.TokenS, 50)
+	if err != nil {
+		return res, err
+	}
+	for _, counter := range counterOrders {
+		price := util.CalculatePrice(counter.RawOrder.AmountS.String(), counter.RawOrder.AmountB.String(), counter.RawOrder.TokenS.Hex(), counter.RawOrder.TokenB.Hex())
+		if price <= 0 {
+			continue
+		}
+		//the best bid is the highest one for a sell order, and the best ask is the lowest one for a buy order
+		if res.BestCounterPrice == 0 ||
+			(res.Side == util.SideSell && price > res.BestCounterPrice) ||
+			(res.Side == util.SideBuy && price < res.BestCounterPrice) {
+			res.BestCounterPrice = price
+		}
+	}
+
+	return res, nil
+}
+
+func minBigInt(x, y *big.Int) *big.Int {
+	if x.Cmp(y) > 0 {
+		return y
+	}
+	return x
+}
+
 func (w *WalletServiceImpl) SubmitRingForP2P(p2pRing P2PRingRequest) (res string, err error) {
 
 	maker, err := w.orderManager.GetOrderByHash(common.HexToHash(p2pRing.MakerOrderHash))
@@ -555,7 +686,7 @@ func (w *WalletServiceImpl) SubmitRingForP2P(p2pRing P2PRingRequest) (res string
 
 	err = ordermanager.SaveP2POrderRelation(taker.RawOrder.Owner.Hex(), taker.RawOrder.Hash.Hex(), maker.RawOrder.Owner.Hex(), maker.RawOrder.Hash.Hex(), txHashRst)
 	if err != nil {
-		return res, errors.New(SYS_10001)
+		return res, internalError{errors.New(SYS_10001)}
 	}
 
 	return txHashRst, nil
@@ -592,39 +723,48 @@ func (w *WalletServiceImpl) GetDepth(query DepthQuery) (res Depth, err error) {
 	//(TODO) 考虑到需要聚合的情况，所以每次取2倍的数据，先聚合完了再cut, 不是完美方案，后续再优化
 	asks, askErr := w.orderManager.GetOrderBook(
 		common.HexToAddress(delegateAddress),
-		util.AllTokens[a].Protocol,
-		util.AllTokens[b].Protocol, defaultDepthLength*2)
+		util.AllTokens()[a].Protocol,
+		util.AllTokens()[b].Protocol, defaultDepthLength*2)
 
 	if askErr != nil {
-		err = errors.New("get depth error , please refresh again")
+		err = internalError{errors.New("get depth error , please refresh again")}
 		return
 	}
 
-	depth.Depth.Sell = w.calculateDepth(asks, defaultDepthLength, true, util.AllTokens[a].Decimals, util.AllTokens[b].Decimals)
+	depth.Depth.Sell = w.calculateDepth(asks, defaultDepthLength, true, util.AllTokens()[a].Decimals, util.AllTokens()[b].Decimals)
 
 	bids, bidErr := w.orderManager.GetOrderBook(
 		common.HexToAddress(delegateAddress),
-		util.AllTokens[b].Protocol,
-		util.AllTokens[a].Protocol, defaultDepthLength*2)
+		util.AllTokens()[b].Protocol,
+		util.AllTokens()[a].Protocol, defaultDepthLength*2)
 
 	if bidErr != nil {
-		err = errors.New("get depth error , please refresh again")
+		err = internalError{errors.New("get depth error , please refresh again")}
 		return
 	}
 
-	depth.Depth.Buy = w.calculateDepth(bids, defaultDepthLength, false, util.AllTokens[b].Decimals, util.AllTokens[a].Decimals)
+	depth.Depth.Buy = w.calculateDepth(bids, defaultDepthLength, false, util.AllTokens()[b].Decimals, util.AllTokens()[a].Decimals)
 
 	return depth, err
 }
 
 func (w *WalletServiceImpl) GetFills(query FillQuery) (dao.PageResult, error) {
-	res, err := w.orderManager.FillsPageQuery(fillQueryToMap(query))
+	var (
+		res dao.PageResult
+		err error
+	)
+	fillQuery, pi, ps := fillQueryToMap(query)
+	if query.Cursor != "" {
+		res, err = w.orderManager.FillsCursorQuery(fillQuery, query.Cursor, ps)
+	} else {
+		res, err = w.orderManager.FillsPageQuery(fillQuery, pi, ps)
+	}
 
 	if err != nil {
-		return dao.PageResult{}, nil
+		return dao.PageResult{}, err
 	}
 
-	result := dao.PageResult{PageIndex: res.PageIndex, PageSize: res.PageSize, Total: res.Total, Data: make([]interface{}, 0)}
+	result := dao.PageResult{PageIndex: res.PageIndex, PageSize: res.PageSize, Total: res.Total, NextCursor: res.NextCursor, Data: make([]interface{}, 0)}
 
 	for _, f := range res.Data {
 		fill := f.(dao.FillEvent)
@@ -661,11 +801,58 @@ func (w *WalletServiceImpl) GetLatestFills(query FillQuery) ([]LatestFill, error
 }
 
 func (w *WalletServiceImpl) GetTicker() (res []market.Ticker, err error) {
-	return w.trendManager.GetTicker()
+	res, err = w.trendManager.GetTicker()
+	for i := range res {
+		res[i] = w.fillBestQuote(res[i])
+	}
+	return
+}
+
+type bestQuote struct {
+	bid float64
+	ask float64
+}
+
+// fillBestQuote sets buy/sell of ticker to the best bid/ask in depth of all delegates
+func (w *WalletServiceImpl) fillBestQuote(ticker market.Ticker) market.Ticker {
+	var quote bestQuote
+	if v, ok := w.bookCache.Get(ticker.Market); ok {
+		quote = v.(bestQuote)
+	} else {
+		for delegate := range ethaccessor.DelegateAddresses() {
+			depth, err := w.GetDepth(DepthQuery{DelegateAddress: delegate.Hex(), Market: ticker.Market})
+			if err != nil {
+				continue
+			}
+			if len(depth.Depth.Buy) > 0 {
+				if bid, _ := strconv.ParseFloat(depth.Depth.Buy[0][0], 64); bid > quote.bid {
+					quote.bid = bid
+				}
+			}
+			// sell side is sorted desc too
+			if len(depth.Depth.Sell) > 0 {
+				if ask, _ := strconv.ParseFloat(depth.Depth.Sell[len(depth.Depth.Sell)-1][0], 64); ask > 0 && (quote.ask == 0 || ask < quote.ask) {
+					quote.ask = ask
+				}
+			}
+		}
+		w.bookCache.Set(ticker.Market, quote, gocache.DefaultExpiration)
+	}
+
+	ticker.Buy, _ = strconv.ParseFloat(fmt.Sprintf("%0.8f", quote.bid), 64)
+	ticker.Sell, _ = strconv.ParseFloat(fmt.Sprintf("%0.8f", quote.ask), 64)
+	if quote.bid > 0 && quote.ask > 0 {
+		ticker.Spread, _ = strconv.ParseFloat(fmt.Sprintf("%0.8f", quote.ask-quote.bid), 64)
+	}
+	return ticker
 }
 
 func (w *WalletServiceImpl) GetTrend(query TrendQuery) (res []market.Trend, err error) {
-	res, err = w.trendManager.GetTrends(query.Market, query.Interval)
+	if query.Start > 0 || query.End > 0 || query.Limit > 0 || !market.IsLegacyInterval(query.Interval) {
+		res, err = w.trendManager.GetCandles(query.Market, query.Interval, query.Start, query.End, query.Limit)
+	} else {
+		res, err = w.trendManager.GetTrends(query.Market, query.Interval)
+	}
 	sort.Slice(res, func(i, j int) bool {
 		return res[i].Start > res[j].Start
 	})
@@ -673,7 +860,11 @@ func (w *WalletServiceImpl) GetTrend(query TrendQuery) (res []market.Trend, err
 }
 
 func (w *WalletServiceImpl) GetRingMined(query RingMinedQuery) (res dao.PageResult, err error) {
-	return w.orderManager.RingMinedPageQuery(ringMinedQueryToMap(query))
+	ringQuery, pi, ps := ringMinedQueryToMap(query)
+	if query.Cursor != "" {
+		return w.orderManager.RingMinedCursorQuery(ringQuery, query.Cursor, ps)
+	}
+	return w.orderManager.RingMinedPageQuery(ringQuery, pi, ps)
 }
 
 func (w *WalletServiceImpl) GetRingMinedDetail(query RingMinedQuery) (res RingMinedDetail, err error) {
@@ -687,7 +878,7 @@ func (w *WalletServiceImpl) GetRingMinedDetail(query RingMinedQuery) (res RingMi
 	// todo:如果ringhash重复暂时先取第一条
 	if err != nil || rings.Total > 1 {
 		log.Errorf("query ring error, %s, %d", err.Error(), rings.Total)
-		return res, errors.New("query ring error occurs")
+		return res, internalError{errors.New("query ring error occurs")}
 	}
 
 	if rings.Total == 0 {
@@ -805,13 +996,18 @@ func (w *WalletServiceImpl) GetContracts() (contracts map[string][]string, err e
 	return rst, nil
 }
 
+// GetPowDifficulty returns the difficulty that the pow of a submitted order must reach
+func (w *WalletServiceImpl) GetPowDifficulty() (difficulty string, err error) {
+	return types.BigintToHex(PowDifficulty()), nil
+}
+
 func (w *WalletServiceImpl) GetSupportedMarket() (markets []string, err error) {
-	return util.AllMarkets, err
+	return util.AllMarkets(), err
 }
 
 func (w *WalletServiceImpl) GetSupportedTokens() (markets []types.Token, err error) {
 	markets = make([]types.Token, 0)
-	for _, v := range util.AllTokens {
+	for _, v := range util.AllTokens() {
 		markets = append(markets, v)
 	}
 	return markets, err
@@ -828,11 +1024,14 @@ func (w *WalletServiceImpl) GetTransactions(query TransactionQuery) (PageResult,
 
 	rst.Data = make([]interface{}, 0)
 	rst.PageIndex, rst.PageSize, limit, offset = pagination(query.PageIndex, query.PageSize)
-	rst.Total, err = txmanager.GetAllTransactionCount(query.Owner, query.Symbol, query.Status, query.TxType)
-	if err != nil {
-		return rst, err
+	// total isn't counted while paging by cursor
+	if query.Cursor == "" {
+		rst.Total, err = txmanager.GetAllTransactionCount(query.Owner, query.Symbol, query.Status, query.TxType)
+		if err != nil {
+			return rst, err
+		}
 	}
-	txs, err = txmanager.GetAllTransactions(query.Owner, query.Symbol, query.Status, query.TxType, limit, offset)
+	txs, rst.NextCursor, err = txmanager.GetAllTransactionsByCursor(query.Owner, query.Symbol, query.Status, query.TxType, query.Cursor, limit, offset)
 	for _, v := range txs {
 		rst.Data = append(rst.Data, v)
 	}
@@ -1200,7 +1399,7 @@ func ringMinedQueryToMap(q RingMinedQuery) (map[string]interface{}, int, int) {
 
 func buildOrderResult(src dao.PageResult) PageResult {
 
-	rst := PageResult{Total: src.Total, PageIndex: src.PageIndex, PageSize: src.PageSize, Data: make([]interface{}, 0)}
+	rst := PageResult{Total: src.Total, PageIndex: src.PageIndex, PageSize: src.PageSize, NextCursor: src.NextCursor, Data: make([]interface{}, 0)}
 
 	for _, d := range src.Data {
 		o := d.(types.OrderState)
@@ -1384,7 +1583,7 @@ func toLatestFill(f dao.FillEvent) (latestFill LatestFill, err error) {
 	var amount float64
 	if util.GetSide(f.TokenS, f.TokenB) == util.SideBuy {
 		amountB, _ := new(big.Int).SetString(f.AmountB, 0)
-		tokenB, ok := util.AllTokens[util.AddressToAlias(f.TokenB)]
+		tokenB, ok := util.AllTokens()[util.AddressToAlias(f.TokenB)]
 		if !ok {
 			return latestFill, err
 		}
@@ -1393,7 +1592,7 @@ func toLatestFill(f dao.FillEvent) (latestFill LatestFill, err error) {
 		rst.Amount, _ = strconv.ParseFloat(fmt.Sprintf("%0.8f", amount), 64)
 	} else {
 		amountS, _ := new(big.Int).SetString(f.AmountS, 0)
-		tokenS, ok := util.AllTokens[util.AddressToAlias(f.TokenS)]
+		tokenS, ok := util.AllTokens()[util.AddressToAlias(f.TokenS)]
 		if !ok {
 			return latestFill, err
 		}
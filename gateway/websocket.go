@@ -19,7 +19,6 @@
 package gateway
 
 import (
-	"fmt"
 	"github.com/Loopring/relay/log"
 	"github.com/Loopring/relay/market"
 	"github.com/Loopring/relay/marketcap"
@@ -68,7 +67,6 @@ func (ws *WebsocketServiceImpl) Start() {
 
 func (ws *WebsocketServiceImpl) serve(node *SocketNode, w http.ResponseWriter, r *http.Request) {
 	conn, err := ws.upgrader.Upgrade(w, r, nil)
-	fmt.Print(conn.LocalAddr())
 	if err != nil {
 		log.Error("get ws connection error , " + err.Error())
 		return
@@ -79,7 +79,7 @@ func parseCacheField(field []byte) common.Address {
 //todo:tokens
 func (b AccountBalances) batchReqs(tokens ...common.Address) ethaccessor.BatchBalanceReqs {
 	reqs := ethaccessor.BatchBalanceReqs{}
-	for _, token := range util.AllTokens {
+	for _, token := range util.AllTokens() {
 		req := &ethaccessor.BatchBalanceReq{}
 		req.BlockParameter = "latest"
 		req.Token = token.Protocol
@@ -226,7 +226,7 @@ func parseAllowanceCacheField(data []byte) (token common.Address, spender common
 //todo:tokens
 func (accountAllowances *AccountAllowances) batchReqs(tokens, spenders []common.Address) ethaccessor.BatchErc20AllowanceReqs {
 	reqs := ethaccessor.BatchErc20AllowanceReqs{}
-	for _, v := range util.AllTokens {
+	for _, v := range util.AllTokens() {
 		for _, impl := range ethaccessor.ProtocolAddresses() {
 			req := &ethaccessor.BatchErc20AllowanceReq{}
 			req.BlockParameter = "latest"
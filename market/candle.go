@@ -0,0 +1,293 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package market
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Loopring/relay/dao"
+	"github.com/Loopring/relay/eventemiter"
+	"github.com/Loopring/relay/log"
+	"github.com/Loopring/relay/market/util"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	OneMinute   = "1Min"
+	tsOneMinute = 60
+
+	defaultCandleLimit = 100
+	maxCandleLimit     = 1000
+)
+
+var intervalRegexp = regexp.MustCompile(`^(\d+)\s*([a-zA-Z]+)$`)
+
+var intervalUnits = map[string]int64{
+	"m":      tsOneMinute,
+	"min":    tsOneMinute,
+	"minute": tsOneMinute,
+	"h":      tsOneHour,
+	"hr":     tsOneHour,
+	"hour":   tsOneHour,
+	"d":      tsOneDay,
+	"day":    tsOneDay,
+	"w":      tsOneWeek,
+	"week":   tsOneWeek,
+}
+
+// ParseInterval returns seconds of interval like 1Min, 15m, 1Hr, 4h, 1Day or 1Week,
+// candles are rolled up from 1 minute bars, so month and year aren't supported
+func ParseInterval(interval string) (int64, error) {
+	matches := intervalRegexp.FindStringSubmatch(strings.TrimSpace(interval))
+	if len(matches) != 3 {
+		return 0, fmt.Errorf("invalid interval:%s", interval)
+	}
+	n, err := strconv.ParseInt(matches[1], 10, 64)
+	if nil != err || n <= 0 {
+		return 0, fmt.Errorf("invalid interval:%s", interval)
+	}
+	unit, ok := intervalUnits[strings.TrimSuffix(strings.ToLower(matches[2]), "s")]
+	if !ok {
+		return 0, fmt.Errorf("unsupported interval unit:%s", matches[2])
+	}
+	return n * unit, nil
+}
+
+// IsLegacyInterval returns true if interval is one of the intervals stored by the trend cron job
+func IsLegacyInterval(interval string) bool {
+	for _, v := range allInterval {
+		if strings.ToLower(v) == strings.ToLower(interval) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetCandles rolls up 1 minute bars into candles of interval in [start, end], sorted by start.
+// end defaults to now, if start is 0 or there are more than limit candles, the latest limit candles are returned.
+// Intervals without any fill are filled with flat candles at the last close price.
+func (t *TrendManager) GetCandles(market, interval string, start, end int64, limit int) ([]Trend, error) {
+	market = strings.ToUpper(market)
+	if !util.IsSupportedMarket(market) {
+		return nil, errors.New("unsupported market:" + market)
+	}
+	tsInterval, err := ParseInterval(interval)
+	if nil != err {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultCandleLimit
+	}
+	if limit > maxCandleLimit {
+		limit = maxCandleLimit
+	}
+	if end <= 0 {
+		end = time.Now().Unix()
+	}
+	if start > end {
+		return nil, errors.New("start can't be later than end")
+	}
+
+	lastStart := end - end%tsInterval
+	firstStart := start - start%tsInterval
+	if start <= 0 || (lastStart-firstStart)/tsInterval >= int64(limit) {
+		firstStart = lastStart - int64(limit-1)*tsInterval
+	}
+
+	bars, err := t.rds.TrendQueryByInterval(OneMinute, market, firstStart, lastStart+tsInterval-1)
+	if nil != err {
+		return nil, err
+	}
+	var lastClose float64
+	last, lastErr := t.rds.TrendQueryLastBefore(OneMinute, market, firstStart)
+	if nil == lastErr {
+		lastClose = last.Close
+	}
+	// if there isn't any bar before the range, the 1 minute bars were built since the first one in range
+	historyEnd := lastStart + tsInterval
+	for _, bar := range bars {
+		if bar.Start < historyEnd {
+			historyEnd = bar.Start - bar.Start%tsInterval
+		}
+	}
+
+	candles := RollupCandles(market, interval, tsInterval, bars, lastClose, firstStart, lastStart)
+
+	// candles before the 1 minute bars are read from the trends of cron job, only the legacy intervals are stored
+	if nil != lastErr && historyEnd > firstStart && IsLegacyInterval(interval) {
+		legacy, err := t.rds.TrendQueryByInterval(legacyIntervalName(interval), market, firstStart, historyEnd-1)
+		if nil != err {
+			return nil, err
+		}
+		candles = MergeLegacyCandles(candles, legacy, historyEnd)
+	}
+	return candles, nil
+}
+
+func legacyIntervalName(interval string) string {
+	for _, v := range allInterval {
+		if strings.ToLower(v) == strings.ToLower(interval) {
+			return v
+		}
+	}
+	return interval
+}
+
+// MergeLegacyCandles replaces the candles started before historyEnd with legacy trends,
+// they are flat candles at zero as there isn't any 1 minute bar at that time
+func MergeLegacyCandles(candles []Trend, legacy []dao.Trend, historyEnd int64) []Trend {
+	if len(legacy) == 0 {
+		return candles
+	}
+	sort.Slice(legacy, func(i, j int) bool {
+		return legacy[i].Start < legacy[j].Start
+	})
+
+	merged := make([]Trend, 0, len(candles))
+	for _, trend := range legacy {
+		merged = append(merged, ConvertUp(trend))
+	}
+	for _, candle := range candles {
+		if candle.Start >= historyEnd {
+			merged = append(merged, candle)
+		}
+	}
+	return merged
+}
+
+// RollupCandles aggregates bars into candles from firstStart to lastStart, both are aligned to tsInterval
+func RollupCandles(market, interval string, tsInterval int64, bars []dao.Trend, lastClose float64, firstStart, lastStart int64) []Trend {
+	sort.Slice(bars, func(i, j int) bool {
+		return bars[i].Start < bars[j].Start
+	})
+
+	candles := make([]Trend, 0)
+	idx := 0
+	for start := firstStart; start <= lastStart; start += tsInterval {
+		candle := Trend{
+			Intervals:  interval,
+			Market:     market,
+			CreateTime: time.Now().Unix(),
+			Start:      start,
+			End:        start + tsInterval - 1,
+			Open:       lastClose,
+			Close:      lastClose,
+			High:       lastClose,
+			Low:        lastClose,
+		}
+
+		filled := false
+		for ; idx < len(bars) && bars[idx].Start <= candle.End; idx++ {
+			bar := bars[idx]
+			if bar.Start < start || bar.Close == 0 {
+				continue
+			}
+			if !filled {
+				candle.Open = bar.Open
+				candle.High = bar.High
+				candle.Low = bar.Low
+				filled = true
+			}
+			if bar.High > candle.High {
+				candle.High = bar.High
+			}
+			if bar.Low < candle.Low {
+				candle.Low = bar.Low
+			}
+			candle.Close = bar.Close
+			candle.Vol += bar.Vol
+			candle.Amount += bar.Amount
+		}
+		lastClose = candle.Close
+		candles = append(candles, candle)
+	}
+	return candles
+}
+
+// updateMinuteCandle rebuilds the 1 minute bar of fill from all fills in that minute,
+// so it's safe to handle the same fill more than once
+func (t *TrendManager) updateMinuteCandle(market string, fill *dao.FillEvent) error {
+	start := fill.CreateTime - fill.CreateTime%tsOneMinute
+	end := start + tsOneMinute - 1
+
+	fills, err := t.rds.QueryRecentFills(market, "", start, end)
+	if nil != err {
+		return err
+	}
+	exists := false
+	for _, f := range fills {
+		if f.TxHash == fill.TxHash && f.FillIndex == fill.FillIndex {
+			exists = true
+		}
+	}
+	if !exists {
+		fills = append(fills, *fill)
+	}
+
+	sort.Slice(fills, func(i, j int) bool {
+		return fills[i].CreateTime < fills[j].CreateTime
+	})
+
+	now := time.Now().Unix()
+	bar := &dao.Trend{Intervals: OneMinute, Market: market, Start: start, End: end, CreateTime: now, UpdateTime: now}
+	for _, data := range fills {
+		if data.Side == "" {
+			data.Side = util.GetSide(data.TokenS, data.TokenB)
+		}
+		// the same as ticker, only sell fills are counted
+		if data.Side == util.SideBuy {
+			continue
+		}
+
+		price := util.CalculatePrice(data.AmountS, data.AmountB, data.TokenS, data.TokenB)
+		if price <= 0 {
+			continue
+		}
+		bar.Vol += util.StringToFloat(data.TokenB, data.AmountB)
+		bar.Amount += util.StringToFloat(data.TokenS, data.AmountS)
+		if bar.Open == 0 {
+			bar.Open = price
+			bar.High = price
+			bar.Low = price
+		}
+		if bar.High < price {
+			bar.High = price
+		}
+		if bar.Low > price {
+			bar.Low = price
+		}
+		bar.Close = price
+	}
+
+	if exists, _ := t.rds.TrendQueryByTime(OneMinute, market, start, end); len(exists) > 0 {
+		bar.ID = exists[0].ID
+		bar.CreateTime = exists[0].CreateTime
+	}
+	if err := t.rds.Save(bar); nil != err {
+		return err
+	}
+
+	log.Debugf("minute candle of %s at %d updated", market, start)
+	eventemitter.Emit(eventemitter.CandleUpdated, market)
+	return nil
+}
@@ -0,0 +1,84 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package market_test
+
+import (
+	"github.com/Loopring/relay/dao"
+	"github.com/Loopring/relay/market"
+	"testing"
+)
+
+func TestParseInterval(t *testing.T) {
+	for interval, ts := range map[string]int64{"1Min": 60, "15m": 900, "1Hr": 3600, "4h": 14400, "1Day": 86400, "1Week": 604800, "2days": 172800} {
+		if v, err := market.ParseInterval(interval); nil != err || v != ts {
+			t.Errorf("interval %s should be %d, got %d", interval, ts, v)
+		}
+	}
+	for _, interval := range []string{"", "1Month", "0m", "m"} {
+		if _, err := market.ParseInterval(interval); nil == err {
+			t.Errorf("interval %s should be invalid", interval)
+		}
+	}
+}
+
+func TestRollupCandles(t *testing.T) {
+	bars := []dao.Trend{
+		{Start: 360, End: 419, Open: 2, High: 3, Low: 2, Close: 3, Vol: 1, Amount: 10},
+		{Start: 300, End: 359, Open: 1, High: 2, Low: 1, Close: 2, Vol: 1, Amount: 10},
+	}
+	candles := market.RollupCandles("LRC-WETH", "5m", 300, bars, 1.5, 0, 900)
+	if len(candles) != 4 {
+		t.Fatalf("should be 4 candles, got %d", len(candles))
+	}
+
+	if c := candles[0]; c.Open != 1.5 || c.Close != 1.5 || c.Vol != 0 {
+		t.Errorf("first candle should be flat at last close, got %+v", c)
+	}
+	if c := candles[1]; c.Open != 1 || c.High != 3 || c.Low != 1 || c.Close != 3 || c.Vol != 2 || c.Amount != 20 {
+		t.Errorf("second candle should aggregate the bars, got %+v", c)
+	}
+	for _, c := range candles[2:] {
+		if c.Open != 3 || c.High != 3 || c.Low != 3 || c.Close != 3 || c.Vol != 0 {
+			t.Errorf("gap candle should be flat at 3, got %+v", c)
+		}
+	}
+}
+
+func TestMergeLegacyCandles(t *testing.T) {
+	candles := market.RollupCandles("LRC-WETH", "1Hr", 3600, []dao.Trend{{Start: 7200, End: 7259, Open: 2, High: 2, Low: 2, Close: 2, Vol: 1}}, 0, 0, 10800)
+	legacy := []dao.Trend{
+		{Intervals: "1Hr", Start: 3600, End: 7199, Open: 1.5, High: 1.8, Low: 1.4, Close: 1.6, Vol: 5},
+		{Intervals: "1Hr", Start: 0, End: 3599, Open: 1, High: 1.5, Low: 1, Close: 1.5, Vol: 3},
+	}
+
+	merged := market.MergeLegacyCandles(candles, legacy, 7200)
+	if len(merged) != 4 {
+		t.Fatalf("should be 4 candles, got %d", len(merged))
+	}
+	if merged[0].Start != 0 || merged[0].Close != 1.5 || merged[1].Start != 3600 || merged[1].Vol != 5 {
+		t.Errorf("candles before history should be legacy trends, got %+v %+v", merged[0], merged[1])
+	}
+	if merged[2].Start != 7200 || merged[2].Close != 2 || merged[3].Start != 10800 || merged[3].Close != 2 {
+		t.Errorf("candles of history should be kept, got %+v %+v", merged[2], merged[3])
+	}
+
+	if merged := market.MergeLegacyCandles(candles, nil, 7200); len(merged) != len(candles) {
+		t.Errorf("candles should be kept without legacy trends")
+	}
+}
@@ -0,0 +1,142 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package market
+
+import (
+	"fmt"
+	"sort"
+)
+
+const tickerWindow = 24 * 60 * 60
+
+type tickerTrade struct {
+	time   int64
+	price  float64
+	vol    float64
+	amount float64
+}
+
+// rollingTicker keeps the trades of market in the last 24 hours,
+// high and low are kept by monotonic queues, so every trade is added and evicted only once
+type rollingTicker struct {
+	market    string
+	trades    []tickerTrade
+	highs     []tickerTrade
+	lows      []tickerTrade
+	vol       float64
+	amount    float64
+	lastPrice float64
+}
+
+func newRollingTicker(market string, lastPrice float64) *rollingTicker {
+	return &rollingTicker{market: market, lastPrice: lastPrice}
+}
+
+func (r *rollingTicker) add(trade tickerTrade, now int64) {
+	if trade.price <= 0 || trade.time <= now-tickerWindow {
+		return
+	}
+
+	// fills of the same block may arrive out of order
+	if len(r.trades) > 0 && trade.time < r.trades[len(r.trades)-1].time {
+		r.trades = append(r.trades, trade)
+		sort.SliceStable(r.trades, func(i, j int) bool {
+			return r.trades[i].time < r.trades[j].time
+		})
+		r.rebuild()
+	} else {
+		r.trades = append(r.trades, trade)
+		r.vol += trade.vol
+		r.amount += trade.amount
+		r.pushQueues(trade)
+		r.lastPrice = trade.price
+	}
+	r.evict(now)
+}
+
+func (r *rollingTicker) pushQueues(trade tickerTrade) {
+	for len(r.highs) > 0 && r.highs[len(r.highs)-1].price <= trade.price {
+		r.highs = r.highs[:len(r.highs)-1]
+	}
+	r.highs = append(r.highs, trade)
+	for len(r.lows) > 0 && r.lows[len(r.lows)-1].price >= trade.price {
+		r.lows = r.lows[:len(r.lows)-1]
+	}
+	r.lows = append(r.lows, trade)
+}
+
+func (r *rollingTicker) rebuild() {
+	r.highs, r.lows = nil, nil
+	r.vol, r.amount = 0, 0
+	for _, trade := range r.trades {
+		r.vol += trade.vol
+		r.amount += trade.amount
+		r.pushQueues(trade)
+	}
+	if len(r.trades) > 0 {
+		r.lastPrice = r.trades[len(r.trades)-1].price
+	}
+}
+
+// evict removes trades out of the window ended at now
+func (r *rollingTicker) evict(now int64) {
+	from := now - tickerWindow
+	for len(r.trades) > 0 && r.trades[0].time <= from {
+		r.vol -= r.trades[0].vol
+		r.amount -= r.trades[0].amount
+		r.trades = r.trades[1:]
+	}
+	for len(r.highs) > 0 && r.highs[0].time <= from {
+		r.highs = r.highs[1:]
+	}
+	for len(r.lows) > 0 && r.lows[0].time <= from {
+		r.lows = r.lows[1:]
+	}
+	if len(r.trades) == 0 {
+		r.vol, r.amount = 0, 0
+	}
+}
+
+// ticker of the window ended at now, prices are kept at the last price if there isn't any trade
+func (r *rollingTicker) ticker(now int64) Ticker {
+	r.evict(now)
+
+	result := Ticker{Market: r.market}
+	result.Last = r.lastPrice
+	result.Close = r.lastPrice
+	result.TradeCount = len(r.trades)
+	if len(r.trades) == 0 {
+		result.Open = r.lastPrice
+		result.High = r.lastPrice
+		result.Low = r.lastPrice
+	} else {
+		result.Open = r.trades[0].price
+		result.High = r.highs[0].price
+		result.Low = r.lows[0].price
+		result.Vol = r.vol
+		result.Amount = r.amount
+		if r.amount > 0 {
+			result.Vwap = r.vol / r.amount
+		}
+	}
+	if result.Open > 0 && result.Last > 0 {
+		result.Change = fmt.Sprintf("%.2f%%", 100*(result.Last-result.Open)/result.Open)
+	}
+	return result
+}
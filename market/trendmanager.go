@@ -28,7 +28,6 @@ import (
 	"github.com/Loopring/relay/log"
 	"github.com/Loopring/relay/market/util"
 	"github.com/Loopring/relay/types"
-	gocache "github.com/patrickmn/go-cache"
 	"github.com/robfig/cron"
 	"sort"
 	"strconv"
@@ -49,30 +48,32 @@ const (
 	//TwoHour = "2Hr"
 	//OneDay = "1Day"
 
-	tsOneHour        = 60 * 60
-	tsTwoHour        = 2 * tsOneHour
-	tsFourHour       = 4 * tsOneHour
-	tsOneDay         = 24 * tsOneHour
-	tsOneWeek        = 7 * tsOneDay
-	localCacheTicker = "LocalCacheTicker"
+	tsOneHour  = 60 * 60
+	tsTwoHour  = 2 * tsOneHour
+	tsFourHour = 4 * tsOneHour
+	tsOneDay   = 24 * tsOneHour
+	tsOneWeek  = 7 * tsOneDay
 )
 
 var allInterval = []string{OneHour, TwoHour, FourHour, OneDay, OneWeek}
 
 type Ticker struct {
-	Market    string  `json:"market"`
-	Exchange  string  `json:"exchange"`
-	Intervals string  `json:"interval"`
-	Amount    float64 `json:"amount"`
-	Vol       float64 `json:"vol"`
-	Open      float64 `json:"open"`
-	Close     float64 `json:"close"`
-	High      float64 `json:"high"`
-	Low       float64 `json:"low"`
-	Last      float64 `json:"last"`
-	Buy       float64 `json:"buy"`
-	Sell      float64 `json:"sell"`
-	Change    string  `json:"change"`
+	Market     string  `json:"market"`
+	Exchange   string  `json:"exchange"`
+	Intervals  string  `json:"interval"`
+	Amount     float64 `json:"amount"`
+	Vol        float64 `json:"vol"`
+	Open       float64 `json:"open"`
+	Close      float64 `json:"close"`
+	High       float64 `json:"high"`
+	Low        float64 `json:"low"`
+	Last       float64 `json:"last"`
+	Buy        float64 `json:"buy"`
+	Sell       float64 `json:"sell"`
+	Change     string  `json:"change"`
+	Vwap       float64 `json:"vwap"`
+	TradeCount int     `json:"tradeCount"`
+	Spread     float64 `json:"spread"`
 }
 
 type Cache struct {
@@ -100,7 +101,8 @@ type TrendManager struct {
 	rds         dao.RdsService
 	cron        *cron.Cron
 	cronJobLock bool
-	localCache  *gocache.Cache
+	tickers     map[string]*rollingTicker
+	tickerMtx   *sync.Mutex
 }
 
 var once sync.Once
@@ -113,8 +115,10 @@ func NewTrendManager(dao dao.RdsService, cronJobLock bool) TrendManager {
 
 	once.Do(func() {
 		trendManager = TrendManager{rds: dao, cron: cron.New(), cronJobLock: cronJobLock}
-		trendManager.localCache = gocache.New(5*time.Second, 5*time.Minute)
+		trendManager.tickers = make(map[string]*rollingTicker)
+		trendManager.tickerMtx = &sync.Mutex{}
 		trendManager.LoadCache()
+		trendManager.loadTickers()
 		if cronJobLock {
 			trendManager.startScheduleUpdate()
 		}
@@ -134,7 +138,7 @@ func (t *TrendManager) ProofRead() {
 		return
 	}
 
-	for _, mkt := range util.AllMarkets {
+	for _, mkt := range util.AllMarkets() {
 		copyOfMkt := mkt
 		go func(market string) {
 			for _, interval := range allInterval {
@@ -211,7 +215,7 @@ func (t *TrendManager) refreshCacheByInterval(interval string) {
 	log.Info("start refresh cache by interval " + interval)
 
 	//trendMap := make(map[string]Cache)
-	for _, mkt := range util.AllMarkets {
+	for _, mkt := range util.AllMarkets() {
 		mktCache := Cache{}
 		mktCache.Trends = make([]Trend, 0)
 
@@ -246,8 +250,7 @@ func (t *TrendManager) refreshMinIntervalCache() {
 	log.Info("start refresh 1hr cache......")
 
 	//trendMap := make(map[string]Cache)
-	tickerMap := make(map[string]Ticker)
-	for _, mkt := range util.AllMarkets {
+	for _, mkt := range util.AllMarkets() {
 		mktCache := Cache{}
 		mktCache.Trends = make([]Trend, 0)
 		mktCache.Fills = make([]dao.FillEvent, 0)
@@ -278,117 +281,13 @@ func (t *TrendManager) refreshMinIntervalCache() {
 
 		//trendMap[mkt] = mktCache
 
-		ticker := calculateTicker(mkt, fills, mktCache.Trends, firstSecondThisHour)
-
-		tickerMap[mkt] = ticker
-
 		setTrendCache(OneHour, mkt, mktCache, 0)
-		setLprTickerCache(tickerMap, 0)
 	}
 
 	//t.c.Set(trendKeyPre+strings.ToLower(OneHour), trendMap, cache.NoExpiration)
-	//t.c.Set(tickerKey, tickerMap, cache.NoExpiration)
 
 }
 
-func calculateTicker(market string, fills []dao.FillEvent, trends []Trend, now time.Time) Ticker {
-
-	var result = Ticker{Market: market}
-
-	if len(fills) == 0 && len(trends) == 0 {
-		return result
-	}
-
-	before24Hour := now.Unix() - 24*60*60
-
-	var (
-		high   float64
-		low    float64
-		vol    float64
-		amount float64
-	)
-
-	copyOfTrends := make([]Trend, 0)
-
-	for _, t := range trends {
-		copyOfTrends = append(copyOfTrends, t)
-	}
-
-	sort.Slice(copyOfTrends, func(i, j int) bool {
-		return copyOfTrends[i].Start < copyOfTrends[j].Start
-	})
-
-	for _, data := range copyOfTrends {
-
-		if data.Start < before24Hour {
-			continue
-		}
-
-		vol += data.Vol
-		amount += data.Amount
-
-		if result.Open == 0 && data.Open != 0 {
-			result.Open = data.Open
-		}
-
-		if high == 0 || high < data.High {
-			high = data.High
-		}
-		if low == 0 || (low > data.Low && data.Low != 0) {
-			low = data.Low
-		}
-
-		if data.Close != 0 {
-			result.Last = data.Close
-			result.Close = data.Close
-		}
-	}
-
-	for _, data := range fills {
-
-		if data.Side == "" {
-			data.Side = util.GetSide(data.TokenS, data.TokenB)
-		}
-
-		if data.Side == util.SideBuy {
-			vol += util.StringToFloat(data.TokenS, data.AmountS)
-			amount += util.StringToFloat(data.TokenB, data.AmountB)
-		} else {
-			vol += util.StringToFloat(data.TokenB, data.AmountB)
-			amount += util.StringToFloat(data.TokenS, data.AmountS)
-		}
-
-		price := util.CalculatePrice(data.AmountS, data.AmountB, data.TokenS, data.TokenB)
-
-		if result.Open == 0 && price != 0 {
-			result.Open = price
-		}
-
-		if price != 0 {
-			result.Last = price
-			result.Close = price
-		}
-
-		if high == 0 || high < price {
-			high = price
-		}
-		if low == 0 || (low > price && price != 0) {
-			low = price
-		}
-	}
-
-	result.High = high
-	result.Low = low
-
-	if result.Open > 0 && result.Last > 0 {
-		result.Change = fmt.Sprintf("%.2f%%", 100*(result.Last-result.Open)/result.Open)
-	}
-
-	result.Vol = vol
-	result.Amount = amount
-	return result
-}
-
 func (t *TrendManager) startScheduleUpdate() {
 	t.cron.AddFunc("10 1 * * * *", t.ScheduleUpdate)
 	t.cron.AddFunc("0 30 1 * * *", t.ProofRead)
@@ -416,7 +315,7 @@ func (t *TrendManager) insertByTrend(interval string) error {
 	start := end.Unix() - getTsInterval(interval) + 1
 	//multiple := tsInterval / tsOneHour
 
-	for _, mkt := range util.AllMarkets {
+	for _, mkt := range util.AllMarkets() {
 
 		trends, err := t.rds.TrendQueryByInterval(OneHour, mkt, start, end.Unix())
 
@@ -667,7 +566,7 @@ func (t *TrendManager) ScheduleUpdate() {
 
 	var wg sync.WaitGroup
 
-	for _, mkt := range util.AllMarkets {
+	for _, mkt := range util.AllMarkets() {
 		now := time.Now()
 		firstSecondThisHour := time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), 0, 1, 0, now.Location())
 
@@ -914,108 +813,138 @@ func (t *TrendManager) GetTrends(market, interval string) (trends []Trend, err e
 }
 
 func (t *TrendManager) GetTicker() (tickers []Ticker, err error) {
+	if !t.cacheReady {
+		return nil, errors.New("cache is not ready , please access later")
+	}
 
-	//log.Info("GetTicker Method Invoked")
+	tickerMap := t.currentTickers()
+	markets := make([]string, 0)
+	for mkt := range tickerMap {
+		markets = append(markets, mkt)
+	}
+	sort.Strings(markets)
 
-	if t.cacheReady {
+	tickers = make([]Ticker, 0)
+	for _, mkt := range markets {
+		tickers = append(tickers, roundTicker(tickerMap[mkt]))
+	}
+	return tickers, nil
+}
 
-		//log.Info("[TICKER]ticker key used in GetTicker")
-		if tickerCache, err := redisCache.Get(tickerKey); err == nil {
-			var tickerMap map[string]Ticker
-			json.Unmarshal(tickerCache, &tickerMap)
-			tickers = make([]Ticker, 0)
-			for _, v := range tickerMap {
-				v.Amount, _ = strconv.ParseFloat(fmt.Sprintf("%0.8f", v.Amount), 64)
-				v.Vol, _ = strconv.ParseFloat(fmt.Sprintf("%0.8f", v.Vol), 64)
-				v.Buy, _ = strconv.ParseFloat(fmt.Sprintf("%0.8f", v.Last), 64)
-				v.Sell, _ = strconv.ParseFloat(fmt.Sprintf("%0.8f", v.Last), 64)
-				v.Open, _ = strconv.ParseFloat(fmt.Sprintf("%0.8f", v.Open), 64)
-				v.Close, _ = strconv.ParseFloat(fmt.Sprintf("%0.8f", v.Close), 64)
-				v.Last, _ = strconv.ParseFloat(fmt.Sprintf("%0.8f", v.Last), 64)
-				v.High, _ = strconv.ParseFloat(fmt.Sprintf("%0.8f", v.High), 64)
-				v.Low, _ = strconv.ParseFloat(fmt.Sprintf("%0.8f", v.Low), 64)
-
-				if v.Change == "+0.00%" || v.Change == "-0.00%" {
-					v.Change = "0.00%"
-				}
+func (t *TrendManager) GetTickerByMarket(mkt string) (ticker Ticker, err error) {
+	if !t.cacheReady {
+		return ticker, errors.New("cache is not ready , please access later")
+	}
 
-				tickers = append(tickers, v)
-			}
+	t.tickerMtx.Lock()
+	defer t.tickerMtx.Unlock()
+	if r, ok := t.tickers[mkt]; ok {
+		return roundTicker(r.ticker(time.Now().Unix())), nil
+	}
+	return ticker, errors.New("get ticker error, no value found of market " + mkt)
+}
 
-		} else {
-			err = errors.New("get ticker from cache error, no value found")
+func roundTicker(v Ticker) Ticker {
+	v.Amount, _ = strconv.ParseFloat(fmt.Sprintf("%0.8f", v.Amount), 64)
+	v.Vol, _ = strconv.ParseFloat(fmt.Sprintf("%0.8f", v.Vol), 64)
+	v.Open, _ = strconv.ParseFloat(fmt.Sprintf("%0.8f", v.Open), 64)
+	v.Close, _ = strconv.ParseFloat(fmt.Sprintf("%0.8f", v.Close), 64)
+	v.Last, _ = strconv.ParseFloat(fmt.Sprintf("%0.8f", v.Last), 64)
+	v.High, _ = strconv.ParseFloat(fmt.Sprintf("%0.8f", v.High), 64)
+	v.Low, _ = strconv.ParseFloat(fmt.Sprintf("%0.8f", v.Low), 64)
+	v.Vwap, _ = strconv.ParseFloat(fmt.Sprintf("%0.8f", v.Vwap), 64)
+
+	if v.Change == "+0.00%" || v.Change == "-0.00%" {
+		v.Change = "0.00%"
+	}
+	return v
+}
+
+// loadTickers fills the 24h windows with fills in db, the last price before window
+// is taken from the latest 1 minute bar or 1 hour trend
+func (t *TrendManager) loadTickers() {
+	now := time.Now().Unix()
+	t.tickerMtx.Lock()
+	defer t.tickerMtx.Unlock()
+
+	for _, mkt := range util.AllMarkets() {
+		var lastPrice float64
+		if bar, err := t.rds.TrendQueryLastBefore(OneMinute, mkt, now-tickerWindow); err == nil {
+			lastPrice = bar.Close
+		} else if trend, err := t.rds.TrendQueryLastBefore(OneHour, mkt, now-tickerWindow); err == nil {
+			lastPrice = trend.Close
 		}
 
-		//if tickerInCache, ok := t.c.Get(tickerKey); ok {
-		//	tickerMap := tickerInCache.(map[string]Ticker)
-		//	tickers = make([]Ticker, 0)
-		//	for _, v := range tickerMap {
-		//		v.Buy = strconv.FormatFloat(v.Last, 'f', -1, 64)
-		//		v.Sell = strconv.FormatFloat(v.Last, 'f', -1, 64)
-		//		tickers = append(tickers, v)
-		//	}
-		//} else {
-		//	err = errors.New("get ticker from cache error, no value found")
-		//}
-	} else {
-		err = errors.New("cache is not ready , please access later")
+		r := newRollingTicker(mkt, lastPrice)
+		fills, err := t.rds.QueryFillsByTime(mkt, now-tickerWindow+1, now)
+		if err != nil {
+			log.Errorf("load ticker fills of %s err:%s", mkt, err.Error())
+		}
+		for _, f := range fills {
+			if trade, ok := toTickerTrade(&f); ok {
+				r.add(trade, now)
+			}
+		}
+		t.tickers[mkt] = r
 	}
+	t.saveTickers(now)
+}
 
-	if len(tickers) == 0 {
-		return tickers, nil
+// addTickerTrade updates the 24h window of market incrementally
+func (t *TrendManager) addTickerTrade(market string, fill *dao.FillEvent) {
+	trade, ok := toTickerTrade(fill)
+	if !ok {
+		return
 	}
 
-	tickerMap := make(map[string]Ticker)
-	markets := make([]string, 0)
-	for _, t := range tickers {
-		tickerMap[t.Market] = t
-		markets = append(markets, t.Market)
+	now := time.Now().Unix()
+	t.tickerMtx.Lock()
+	defer t.tickerMtx.Unlock()
+	r, exists := t.tickers[market]
+	if !exists {
+		r = newRollingTicker(market, 0)
+		t.tickers[market] = r
 	}
+	r.add(trade, now)
+	t.saveTickers(now)
+}
 
-	sort.Strings(markets)
-	result := make([]Ticker, 0)
+func (t *TrendManager) currentTickers() map[string]Ticker {
+	now := time.Now().Unix()
+	t.tickerMtx.Lock()
+	defer t.tickerMtx.Unlock()
 
-	for _, m := range markets {
-		result = append(result, tickerMap[m])
+	tickerMap := make(map[string]Ticker)
+	for mkt, r := range t.tickers {
+		tickerMap[mkt] = r.ticker(now)
 	}
-	return result, nil
+	return tickerMap
 }
 
-func (t *TrendManager) GetTickerByMarket(mkt string) (ticker Ticker, err error) {
+// saveTickers must be called with tickerMtx locked
+func (t *TrendManager) saveTickers(now int64) {
+	tickerMap := make(map[string]Ticker)
+	for mkt, r := range t.tickers {
+		tickerMap[mkt] = r.ticker(now)
+	}
+	setLprTickerCache(tickerMap, 0)
+}
 
-	if t.cacheReady {
-		//log.Info("[TICKER]ticker key used in GetTickerByMarket")
-
-		localCacheValue, ok := t.localCache.Get(localCacheTicker)
-		if ok {
-			//log.Info("[TICKER] get cache from local " + mkt)
-			tickerMap := localCacheValue.(map[string]Ticker)
-			for k, v := range tickerMap {
-				if k == mkt {
-					v.Buy, _ = strconv.ParseFloat(fmt.Sprintf("%0.8f", v.Last), 64)
-					v.Sell, _ = strconv.ParseFloat(fmt.Sprintf("%0.8f", v.Last), 64)
-					return v, err
-				}
-			}
-		} else if tickerCache, err := redisCache.Get(tickerKey); err == nil {
-			//log.Info("[TICKER] get cache from redis " + mkt)
-			var tickerMap map[string]Ticker
-			json.Unmarshal(tickerCache, &tickerMap)
-			t.localCache.Set(localCacheTicker, tickerMap, 5*time.Second)
-			for k, v := range tickerMap {
-				if k == mkt {
-					v.Buy, _ = strconv.ParseFloat(fmt.Sprintf("%0.8f", v.Last), 64)
-					v.Sell, _ = strconv.ParseFloat(fmt.Sprintf("%0.8f", v.Last), 64)
-					return v, err
-				}
-			}
-		} else {
-			err = errors.New("get ticker from cache error, no value found")
-		}
-	} else {
-		err = errors.New("cache is not ready , please access later")
+// only sell fills are counted, vol is in quote token and amount is in base token
+func toTickerTrade(fill *dao.FillEvent) (tickerTrade, bool) {
+	side := fill.Side
+	if side == "" {
+		side = util.GetSide(fill.TokenS, fill.TokenB)
 	}
-	return
+	if side == util.SideBuy {
+		return tickerTrade{}, false
+	}
+	return tickerTrade{
+		time:   fill.CreateTime,
+		price:  util.CalculatePrice(fill.AmountS, fill.AmountB, fill.TokenS, fill.TokenB),
+		vol:    util.StringToFloat(fill.TokenB, fill.AmountB),
+		amount: util.StringToFloat(fill.TokenS, fill.AmountS),
+	}, true
 }
 
 func ConvertUp(src dao.Trend) Trend {
@@ -1067,21 +996,24 @@ func (t *TrendManager) HandleOrderFilled(input eventemitter.EventData) (err erro
 			return
 		}
 
+		if candleErr := t.updateMinuteCandle(market, newFillModel); candleErr != nil {
+			log.Errorf("update minute candle of %s err:%s", market, candleErr.Error())
+		}
+
 		if trendInCache, err := redisCache.Get(buildTrendKey(OneHour, market)); err == nil {
 			var tc Cache
 			json.Unmarshal(trendInCache, &tc)
 			tc.Fills = append(tc.Fills, *newFillModel)
 			setTrendCache(OneHour, market, tc, 0)
 			//t.c.Set(trendKeyPre+strings.ToLower(OneHour), trendMap, cache.NoExpiration)
-			t.reCalTicker(market)
 		} else {
 			fills := make([]dao.FillEvent, 0)
 			fills = append(fills, *newFillModel)
 			newCache := Cache{make([]Trend, 0), fills}
 			setTrendCache(OneHour, market, newCache, 0)
 			//t.c.Set(trendKeyPre+strings.ToLower(OneHour), newCache, cache.NoExpiration)
-			t.reCalTicker(market)
 		}
+		t.addTickerTrade(market, newFillModel)
 	} else {
 		err = errors.New("cache is not ready , please access later")
 	}
@@ -1089,24 +1021,6 @@ func (t *TrendManager) HandleOrderFilled(input eventemitter.EventData) (err erro
 	return
 }
 
-func (t *TrendManager) reCalTicker(market string) {
-	//trendInCache, _ := t.c.Get(trendKeyPre + strings.ToLower(OneHour))
-	trendInCache, _ := redisCache.Get(buildTrendKey(OneHour, market))
-	var mktCache Cache
-	json.Unmarshal(trendInCache, &mktCache)
-	now := time.Now()
-	firstSecondThisHour := time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), 0, 1, 0, now.Location())
-	ticker := calculateTicker(market, mktCache.Fills, mktCache.Trends, firstSecondThisHour)
-	//tickerInCache, _ := t.c.Get(tickerKey)
-	//log.Info("[TICKER]ticker key used in reCalTicker")
-	tickerInCache, _ := redisCache.Get(tickerKey)
-	var tickerMap map[string]Ticker
-	json.Unmarshal(tickerInCache, &tickerMap)
-	tickerMap[market] = ticker
-	setLprTickerCache(tickerMap, 0)
-	//t.c.Set(tickerKey, tickerMap, cache.NoExpiration)
-}
-
 func setTrendCache(interval, market string, mktCache Cache, ttl int64) {
 	cacheKey := buildTrendKey(interval, market)
 	tickerByte, err := json.Marshal(mktCache)
@@ -86,8 +86,8 @@ func TestTrendManager_GetTicker(t *testing.T) {
 	order := types.Order{}
 	order.AmountS = big.NewInt(1000000)
 	order.LrcFee = big.NewInt(500000000000000000)
-	order.TokenS = util.AllTokens["RDN"].Protocol
-	order.TokenB = util.AllTokens["WETH"].Protocol
+	order.TokenS = util.AllTokens()["RDN"].Protocol
+	order.TokenB = util.AllTokens()["WETH"].Protocol
 	amountS := big.NewInt(0)
 	amountS.SetString("3800000000000000000", 10)
 	order.AmountS = amountS
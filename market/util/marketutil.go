@@ -32,6 +32,8 @@ import (
 	"math/big"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 const SideSell = "sell"
@@ -55,20 +57,64 @@ func StringToFloat(token string, amount string) float64 {
 	return result
 }
 
+// tables are replaced as a whole and never modified after stored, so they can be read without lock
+type tables struct {
+	supportTokens  map[string]types.Token // token symbol to entity
+	allTokens      map[string]types.Token
+	supportMarkets map[string]types.Token // token symbol to contract hex address
+	allMarkets     []string
+	allTokenPairs  []TokenPair
+	symbolTokenMap map[common.Address]string
+}
+
 var (
-	SupportTokens  map[string]types.Token // token symbol to entity
-	AllTokens      map[string]types.Token
-	SupportMarkets map[string]types.Token // token symbol to contract hex address
-	AllMarkets     []string
-	AllTokenPairs  []TokenPair
-	SymbolTokenMap map[common.Address]string
+	currentTables atomic.Value
+
+	// serializes the writers of the tables
+	tablesMtx sync.Mutex
 )
 
+func init() {
+	currentTables.Store(newTables([]types.Token{}))
+}
+
+func loadTables() *tables {
+	return currentTables.Load().(*tables)
+}
+
+// the maps and slices returned below are shared by all readers and shouldn't be modified
+
+func SupportTokens() map[string]types.Token {
+	return loadTables().supportTokens
+}
+
+func AllTokens() map[string]types.Token {
+	return loadTables().allTokens
+}
+
+func SupportMarkets() map[string]types.Token {
+	return loadTables().supportMarkets
+}
+
+func AllMarkets() []string {
+	return loadTables().allMarkets
+}
+
+func AllTokenPairs() []TokenPair {
+	return loadTables().allTokenPairs
+}
+
+func SymbolTokenMap() map[common.Address]string {
+	return loadTables().symbolTokenMap
+}
+
 func StartRefreshCron(option config.MarketOptions) {
 	mktCron := cron.New()
 	mktCron.AddFunc("1 0/10 * * * *", func() {
 		log.Info("start market util refresh.....")
-		SupportTokens, SupportMarkets, AllTokens, AllMarkets, AllTokenPairs, SymbolTokenMap = getTokenAndMarketFromDB(option.TokenFile)
+		if err := Reload(option); nil != err {
+			log.Errorf("market util refresh failed, err:%s", err.Error())
+		}
 	})
 	mktCron.Start()
 }
@@ -101,67 +147,70 @@ func (t *token) convert() types.Token {
 	return dst
 }
 
-func getTokenAndMarketFromDB(tokenfile string) (
-	supportTokens map[string]types.Token,
-	supportMarkets map[string]types.Token,
-	allTokens map[string]types.Token,
-	allMarkets []string,
-	allTokenPairs []TokenPair,
-	symbolTokenMap map[common.Address]string) {
-
-	supportTokens = make(map[string]types.Token)
-	allTokens = make(map[string]types.Token)
-	supportMarkets = make(map[string]types.Token)
-	allMarkets = make([]string, 0)
-	allTokenPairs = make([]TokenPair, 0)
-	symbolTokenMap = make(map[common.Address]string)
-
+func getTokenAndMarketFromDB(tokenfile string) (*tables, error) {
 	var list []token
 	fn, err := os.Open(tokenfile)
 	if err != nil {
-		log.Fatalf("market util load tokens failed:%s", err.Error())
+		return nil, fmt.Errorf("market util load tokens failed:%s", err.Error())
 	}
+	defer fn.Close()
 	bs, err := ioutil.ReadAll(fn)
 	if err != nil {
-		log.Fatalf("market util read tokens json file failed:%s", err.Error())
+		return nil, fmt.Errorf("market util read tokens json file failed:%s", err.Error())
 	}
 	if err := json.Unmarshal(bs, &list); err != nil {
-		log.Fatalf("market util unmarshal tokens failed:%s", err.Error())
+		return nil, fmt.Errorf("market util unmarshal tokens failed:%s", err.Error())
 	}
 
+	tokens := []types.Token{}
 	for _, v := range list {
+		tokens = append(tokens, v.convert())
+	}
+	return newTables(tokens), nil
+}
+
+func newTables(tokens []types.Token) *tables {
+	t := &tables{
+		supportTokens:  make(map[string]types.Token),
+		allTokens:      make(map[string]types.Token),
+		supportMarkets: make(map[string]types.Token),
+		allMarkets:     make([]string, 0),
+		allTokenPairs:  make([]TokenPair, 0),
+		symbolTokenMap: make(map[common.Address]string),
+	}
+
+	for _, v := range tokens {
 		if v.Deny == false {
-			t := v.convert()
-			if t.IsMarket == true {
-				supportMarkets[t.Symbol] = t
+			if v.IsMarket == true {
+				t.supportMarkets[v.Symbol] = v
 			} else {
-				supportTokens[t.Symbol] = t
-				log.Infof("market util,supported token:%s", t.Symbol)
+				t.supportTokens[v.Symbol] = v
+				log.Infof("market util,supported token:%s", v.Symbol)
 			}
 		}
 	}
 
 	// set all tokens
-	for k, v := range supportTokens {
-		allTokens[k] = v
-		symbolTokenMap[v.Protocol] = v.Symbol
+	for k, v := range t.supportTokens {
+		t.allTokens[k] = v
+		t.symbolTokenMap[v.Protocol] = v.Symbol
 	}
-	for k, v := range supportMarkets {
-		allTokens[k] = v
-		symbolTokenMap[v.Protocol] = v.Symbol
+	for k, v := range t.supportMarkets {
+		t.allTokens[k] = v
+		t.symbolTokenMap[v.Protocol] = v.Symbol
 	}
 
 	// set all markets
-	for k := range allTokens { // lrc,omg
-		for kk := range supportMarkets { //eth
+	for k := range t.allTokens { // lrc,omg
+		for kk := range t.supportMarkets { //eth
 			o, ok := MarketBaseOrder[k]
 			if ok {
 				baseOrder := MarketBaseOrder[kk]
 				if o < baseOrder {
-					allMarkets = append(allMarkets, k+"-"+kk)
+					t.allMarkets = append(t.allMarkets, k+"-"+kk)
 				}
 			} else {
-				allMarkets = append(allMarkets, k+"-"+kk)
+				t.allMarkets = append(t.allMarkets, k+"-"+kk)
 			}
 			log.Infof("market util,supported market:%s", k+"-"+kk)
 		}
@@ -169,8 +218,8 @@ func getTokenAndMarketFromDB(tokenfile string) (
 
 	// set all token pairs
 	pairsMap := make(map[string]TokenPair, 0)
-	for _, v := range supportMarkets {
-		for _, vv := range allTokens {
+	for _, v := range t.supportMarkets {
+		for _, vv := range t.allTokens {
 			if v.Symbol != vv.Symbol {
 				pairsMap[v.Symbol+"-"+vv.Symbol] = TokenPair{v.Protocol, vv.Protocol}
 				pairsMap[vv.Symbol+"-"+v.Symbol] = TokenPair{vv.Protocol, v.Protocol}
@@ -179,20 +228,41 @@ func getTokenAndMarketFromDB(tokenfile string) (
 	}
 
 	for _, v := range pairsMap {
-		allTokenPairs = append(allTokenPairs, v)
+		t.allTokenPairs = append(t.allTokenPairs, v)
 	}
 
-	return
+	return t
 }
 
-func Initialize(options config.MarketOptions) {
-
-	SupportTokens = make(map[string]types.Token)
-	SupportMarkets = make(map[string]types.Token)
-	AllTokens = make(map[string]types.Token)
-	SymbolTokenMap = make(map[common.Address]string)
+// copy returns a table can be modified without affecting the readers of t
+func (t *tables) copy() *tables {
+	c := &tables{
+		supportTokens:  make(map[string]types.Token),
+		allTokens:      make(map[string]types.Token),
+		supportMarkets: make(map[string]types.Token),
+		allMarkets:     append([]string{}, t.allMarkets...),
+		allTokenPairs:  append([]TokenPair{}, t.allTokenPairs...),
+		symbolTokenMap: make(map[common.Address]string),
+	}
+	for k, v := range t.supportTokens {
+		c.supportTokens[k] = v
+	}
+	for k, v := range t.allTokens {
+		c.allTokens[k] = v
+	}
+	for k, v := range t.supportMarkets {
+		c.supportMarkets[k] = v
+	}
+	for k, v := range t.symbolTokenMap {
+		c.symbolTokenMap[k] = v
+	}
+	return c
+}
 
-	SupportTokens, SupportMarkets, AllTokens, AllMarkets, AllTokenPairs, SymbolTokenMap = getTokenAndMarketFromDB(options.TokenFile)
+func Initialize(options config.MarketOptions) {
+	if err := Reload(options); nil != err {
+		log.Fatalf(err.Error())
+	}
 
 	// StartRefreshCron(rds)
 
@@ -202,8 +272,33 @@ func Initialize(options config.MarketOptions) {
 	eventemitter.On(eventemitter.TokenUnRegistered, tokenUnRegisterWatcher)
 }
 
+// Reload reads the token file again and replaces all the token and market tables together,
+// the tables are kept if the file can't be loaded
+func Reload(options config.MarketOptions) error {
+	t, err := getTokenAndMarketFromDB(options.TokenFile)
+	if nil != err {
+		return err
+	}
+
+	tablesMtx.Lock()
+	defer tablesMtx.Unlock()
+	currentTables.Store(t)
+	return nil
+}
+
+// SetTokens replaces all the token and market tables by the tokens, it's used by tests to run without token file
+func SetTokens(tokens []types.Token) {
+	t := newTables(tokens)
+	tablesMtx.Lock()
+	defer tablesMtx.Unlock()
+	currentTables.Store(t)
+}
+
 func TokenRegister(input eventemitter.EventData) error {
 	evt := input.(*types.TokenRegisterEvent)
+	tablesMtx.Lock()
+	defer tablesMtx.Unlock()
+	t := loadTables().copy()
 
 	var token types.Token
 	token.Protocol = evt.Token
@@ -213,40 +308,45 @@ func TokenRegister(input eventemitter.EventData) error {
 	token.Time = evt.BlockTime
 
 	// todo: how to get source token.Source = ""
-	SupportTokens[token.Symbol] = token
-	AllTokens[token.Symbol] = token
+	t.supportTokens[token.Symbol] = token
+	t.allTokens[token.Symbol] = token
 
 	pairsMap := make(map[string]TokenPair, 0)
-	for _, v := range SupportMarkets {
+	for _, v := range t.supportMarkets {
 		pairsMap[v.Symbol+"-"+token.Symbol] = TokenPair{v.Protocol, token.Protocol}
 		pairsMap[token.Symbol+"-"+v.Symbol] = TokenPair{token.Protocol, v.Protocol}
 	}
 	for _, v := range pairsMap {
-		AllTokenPairs = append(AllTokenPairs, v)
+		t.allTokenPairs = append(t.allTokenPairs, v)
 	}
+	currentTables.Store(t)
 	return nil
 }
 
 func TokenUnRegister(input eventemitter.EventData) error {
 	evt := input.(*types.TokenUnRegisterEvent)
+	tablesMtx.Lock()
+	defer tablesMtx.Unlock()
+	t := loadTables().copy()
 
-	delete(SupportTokens, strings.ToUpper(evt.Symbol))
-	delete(AllTokens, strings.ToUpper(evt.Symbol))
+	delete(t.supportTokens, strings.ToUpper(evt.Symbol))
+	delete(t.allTokens, strings.ToUpper(evt.Symbol))
 
 	var list []TokenPair
-	for _, v := range AllTokenPairs {
+	for _, v := range t.allTokenPairs {
 		if v.TokenS == evt.Token || v.TokenB == evt.Token {
 			continue
 		}
 		list = append(list, v)
 	}
-	AllTokenPairs = list
+	t.allTokenPairs = list
+	currentTables.Store(t)
 
 	return nil
 }
 
 func WethTokenAddress() common.Address {
-	return AllTokens["WETH"].Protocol
+	return AllTokens()["WETH"].Protocol
 }
 
 func WrapMarket(s, b string) (market string, err error) {
@@ -289,21 +389,21 @@ func UnWrapToAddress(market string) (s, b common.Address) {
 }
 
 func IsSupportedMarket(market string) bool {
-	_, ok := SupportMarkets[strings.ToUpper(market)]
+	_, ok := SupportMarkets()[strings.ToUpper(market)]
 	return ok
 }
 
 func isSupportedToken(token string) bool {
-	_, ok := SupportTokens[strings.ToUpper(token)]
+	_, ok := SupportTokens()[strings.ToUpper(token)]
 	return ok
 }
 
 func AliasToAddress(t string) common.Address {
-	return AllTokens[t].Protocol
+	return AllTokens()[t].Protocol
 }
 
 func AddressToAlias(t string) string {
-	for k, v := range AllTokens {
+	for k, v := range AllTokens() {
 		if strings.ToUpper(t) == strings.ToUpper(v.Protocol.Hex()) {
 			return k
 		}
@@ -312,7 +412,7 @@ func AddressToAlias(t string) string {
 }
 
 func AddressToToken(t common.Address) (*types.Token, error) {
-	for _, v := range AllTokens {
+	for _, v := range AllTokens() {
 		if v.Protocol == t {
 			return &v, nil
 		}
@@ -328,11 +428,11 @@ func CalculatePrice(amountS, amountB string, s, b string) float64 {
 
 	result := new(big.Rat).SetInt64(0)
 
-	tokenS, ok := AllTokens[AddressToAlias(s)]
+	tokenS, ok := AllTokens()[AddressToAlias(s)]
 	if !ok {
 		return 0
 	}
-	tokenB, ok := AllTokens[AddressToAlias(b)]
+	tokenB, ok := AllTokens()[AddressToAlias(b)]
 	if !ok {
 		return 0
 	}
@@ -393,7 +493,7 @@ func IsAddress(token string) bool {
 }
 
 func GetSymbolWithAddress(address common.Address) (string, error) {
-	if symbol, ok := SymbolTokenMap[address]; ok {
+	if symbol, ok := SymbolTokenMap()[address]; ok {
 		return symbol, nil
 	}
 	return "", fmt.Errorf("market util, unsupported address:%s", address.Hex())
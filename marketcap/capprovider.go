@@ -68,6 +68,7 @@ type MarketCapProvider interface {
 	GetMarketCap(tokenAddress common.Address) (*big.Rat, error)
 	GetEthCap() (*big.Rat, error)
 	GetMarketCapByCurrency(tokenAddress common.Address, currencyStr string) (*big.Rat, error)
+	GetHistoryMarketCapByCurrency(tokenAddress common.Address, currencyStr string, timestamp int64) (*big.Rat, error)
 }
 
 type CapProvider_LocalCap struct {
@@ -87,7 +88,7 @@ func NewLocalCap() *CapProvider_LocalCap {
 }
 
 func (cap *CapProvider_LocalCap) Start() {
-	for _, marketStr := range util.AllMarkets {
+	for _, marketStr := range util.AllMarkets() {
 		tokenAddress, _ := util.UnWrapToAddress(marketStr)
 		token, _ := util.AddressToToken(tokenAddress)
 		c := &types.CurrencyMarketCap{}
@@ -109,7 +110,11 @@ func (cap *CapProvider_LocalCap) Stop() {
 	for _, stopFunc := range cap.stopFuncs {
 		stopFunc()
 	}
-	cap.stopChan <- true
+	// nothing receives it while the syncing in Start is disabled
+	select {
+	case cap.stopChan <- true:
+	default:
+	}
 }
 
 //func (cap *CapProvider_LocalCap) LegalCurrencyValue(tokenAddress common.Address, amount *big.Rat) (*big.Rat, error) {
@@ -184,6 +189,10 @@ func (cap *MixMarketCap) GetMarketCapByCurrency(tokenAddress common.Address, cur
 	return cap.selectCap(tokenAddress).GetMarketCapByCurrency(tokenAddress, currencyStr)
 }
 
+func (cap *MixMarketCap) GetHistoryMarketCapByCurrency(tokenAddress common.Address, currencyStr string, timestamp int64) (*big.Rat, error) {
+	return cap.selectCap(tokenAddress).GetHistoryMarketCapByCurrency(tokenAddress, currencyStr, timestamp)
+}
+
 type CapProvider_CoinMarketCap struct {
 	baseUrl         string
 	tokenMarketCaps map[common.Address]*types.CurrencyMarketCap
@@ -198,7 +207,7 @@ func (p *CapProvider_CoinMarketCap) LegalCurrencyValue(tokenAddress common.Addre
 }
 
 func (p *CapProvider_CoinMarketCap) LegalCurrencyValueOfEth(amount *big.Rat) (*big.Rat, error) {
-	tokenAddress := util.AllTokens["WETH"].Protocol
+	tokenAddress := util.AllTokens()["WETH"].Protocol
 	return p.LegalCurrencyValueByCurrency(tokenAddress, amount, p.currency)
 }
 
@@ -220,7 +229,7 @@ func (p *CapProvider_CoinMarketCap) GetMarketCap(tokenAddress common.Address) (*
 }
 
 func (p *CapProvider_CoinMarketCap) GetEthCap() (*big.Rat, error) {
-	return p.GetMarketCapByCurrency(util.AllTokens["WETH"].Protocol, p.currency)
+	return p.GetMarketCapByCurrency(util.AllTokens()["WETH"].Protocol, p.currency)
 }
 
 func (p *CapProvider_CoinMarketCap) GetMarketCapByCurrency(tokenAddress common.Address, currencyStr string) (*big.Rat, error) {
@@ -236,8 +245,8 @@ func (p *CapProvider_CoinMarketCap) GetMarketCapByCurrency(tokenAddress common.A
 			v = c.PriceBtc
 		}
 		if "VITE" == c.Symbol || "ARP" == c.Symbol {
-			wethCap, _ := p.GetMarketCapByCurrency(util.AllTokens["WETH"].Protocol, currencyStr)
-			v = wethCap.Mul(wethCap, util.AllTokens[c.Symbol].IcoPrice)
+			wethCap, _ := p.GetMarketCapByCurrency(util.AllTokens()["WETH"].Protocol, currencyStr)
+			v = wethCap.Mul(wethCap, util.AllTokens()[c.Symbol].IcoPrice)
 		}
 		if v == nil {
 			return nil, errors.New("tokenCap is nil")
@@ -254,6 +263,23 @@ func (p *CapProvider_CoinMarketCap) GetMarketCapByCurrency(tokenAddress common.A
 	}
 }
 
+// GetHistoryMarketCapByCurrency returns the price synced before timestamp,
+// ErrNoMarketCapHistory is returned if there isn't any history, the current price isn't a substitute of it
+func (p *CapProvider_CoinMarketCap) GetHistoryMarketCapByCurrency(tokenAddress common.Address, currencyStr string, timestamp int64) (*big.Rat, error) {
+	c, exists := p.tokenMarketCaps[tokenAddress]
+	if !exists {
+		return nil, errors.New("not found tokenCap:" + tokenAddress.Hex())
+	}
+	if "VITE" == c.Symbol || "ARP" == c.Symbol {
+		wethCap, err := p.GetHistoryMarketCapByCurrency(util.AllTokens()["WETH"].Protocol, currencyStr, timestamp)
+		if nil != err {
+			return nil, err
+		}
+		return wethCap.Mul(wethCap, util.AllTokens()[c.Symbol].IcoPrice), nil
+	}
+	return getMarketCapHistory(tokenAddress, StringToLegalCurrency(currencyStr), timestamp)
+}
+
 func (p *CapProvider_CoinMarketCap) Stop() {
 	p.stopChan <- true
 }
@@ -309,6 +335,10 @@ func (p *CapProvider_CoinMarketCap) syncMarketCap() error {
 					syncedTokens[p.tokenMarketCaps[tokenAddress].Address] = true
 				}
 			}
+			now := time.Now().Unix()
+			for tokenAddress := range syncedTokens {
+				saveMarketCapHistory(p.tokenMarketCaps[tokenAddress], now)
+			}
 			for _, tokenCap := range p.tokenMarketCaps {
 				if _, exists := syncedTokens[tokenCap.Address]; !exists && "VITE" != tokenCap.Symbol && "ARP" != tokenCap.Symbol {
 					//todo:
@@ -331,7 +361,7 @@ func NewMarketCapProvider(options config.MarketCapOptions) *CapProvider_CoinMark
 		//default 5 min
 		provider.duration = 5
 	}
-	for _, v := range util.AllTokens {
+	for _, v := range util.AllTokens() {
 		if "ARP" == v.Symbol || "VITE" == v.Symbol {
 			c := &types.CurrencyMarketCap{}
 			c.Address = v.Protocol
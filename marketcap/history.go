@@ -0,0 +1,88 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package marketcap
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Loopring/relay/cache"
+	"github.com/Loopring/relay/log"
+	"github.com/Loopring/relay/types"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"strings"
+)
+
+// ErrNoMarketCapHistory is returned if there isn't any price synced before the timestamp
+var ErrNoMarketCapHistory = errors.New("no marketcap history")
+
+const (
+	MarketCapHistoryPrefix = "marketcap_history_"
+	marketCapHistoryTtl    = 86400 * 400
+)
+
+// every synced price is saved into a sorted set of token scored by timestamp,
+// the member is "timestamp|cny|usd|btc"
+func saveMarketCapHistory(c *types.CurrencyMarketCap, timestamp int64) {
+	member := fmt.Sprintf("%d|%s|%s|%s", timestamp, ratString(c.PriceCny), ratString(c.PriceUsd), ratString(c.PriceBtc))
+	key := MarketCapHistoryPrefix + strings.ToLower(c.Address.Hex())
+	if err := cache.ZAdd(key, marketCapHistoryTtl, []byte(fmt.Sprintf("%d", timestamp)), []byte(member)); nil != err {
+		log.Errorf("save marketcap history of token:%s, err:%s", c.Symbol, err.Error())
+		return
+	}
+	cache.ZRemRangeByScore(key, 0, timestamp-marketCapHistoryTtl)
+}
+
+// getMarketCapHistory returns the latest price synced before timestamp
+func getMarketCapHistory(tokenAddress common.Address, currency LegalCurrency, timestamp int64) (*big.Rat, error) {
+	key := MarketCapHistoryPrefix + strings.ToLower(tokenAddress.Hex())
+	data, err := cache.ZRevRangeByScore(key, timestamp, 0, 0, 1)
+	if nil != err {
+		return nil, err
+	}
+	if len(data) == 0 || len(data[0]) == 0 {
+		return nil, ErrNoMarketCapHistory
+	}
+
+	fields := strings.Split(string(data[0]), "|")
+	if len(fields) != 4 {
+		return nil, errors.New("invalid marketcap history:" + string(data[0]))
+	}
+
+	var price string
+	switch currency {
+	case CNY:
+		price = fields[1]
+	case USD:
+		price = fields[2]
+	case BTC:
+		price = fields[3]
+	}
+	if v, ok := new(big.Rat).SetString(price); ok {
+		return v, nil
+	}
+	return nil, ErrNoMarketCapHistory
+}
+
+func ratString(v *big.Rat) string {
+	if nil == v {
+		return ""
+	}
+	return v.FloatString(10)
+}
@@ -0,0 +1,222 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+// Package metrics keeps labelled counters, gauges and timers backed by go-metrics
+// and exposes them in the prometheus text format.
+package metrics
+
+import (
+	"fmt"
+	gometrics "github.com/rcrowley/go-metrics"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	kindCounter = "counter"
+	kindGauge   = "gauge"
+	kindSummary = "summary"
+)
+
+var quantiles = []float64{0.5, 0.9, 0.99}
+
+// Summary records durations, quantiles are computed over a decaying sample
+// while sum and count cover every observation.
+type Summary struct {
+	hist  gometrics.Histogram
+	sum   gometrics.Counter
+	count gometrics.Counter
+}
+
+func newSummary() *Summary {
+	return &Summary{
+		hist:  gometrics.NewHistogram(gometrics.NewExpDecaySample(1028, 0.015)),
+		sum:   gometrics.NewCounter(),
+		count: gometrics.NewCounter(),
+	}
+}
+
+func (t *Summary) Update(d time.Duration) {
+	t.hist.Update(int64(d))
+	t.sum.Inc(int64(d))
+	t.count.Inc(1)
+}
+
+func (t *Summary) UpdateSince(start time.Time) {
+	t.Update(time.Since(start))
+}
+
+type family struct {
+	kind   string
+	series map[string]interface{}
+}
+
+type Registry struct {
+	mtx      sync.RWMutex
+	families map[string]*family
+}
+
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+var defaultRegistry = NewRegistry()
+
+// Counter returns the counter of name with labels given as key, value pairs.
+func Counter(name string, labels ...string) gometrics.Counter {
+	return defaultRegistry.Counter(name, labels...)
+}
+
+func Gauge(name string, labels ...string) gometrics.Gauge {
+	return defaultRegistry.Gauge(name, labels...)
+}
+
+func Timer(name string, labels ...string) *Summary {
+	return defaultRegistry.Timer(name, labels...)
+}
+
+func WritePrometheus(w io.Writer) {
+	defaultRegistry.WritePrometheus(w)
+}
+
+func Handler() http.Handler {
+	return defaultRegistry
+}
+
+func (r *Registry) Counter(name string, labels ...string) gometrics.Counter {
+	return r.getOrRegister(name, kindCounter, labels, func() interface{} { return gometrics.NewCounter() }).(gometrics.Counter)
+}
+
+func (r *Registry) Gauge(name string, labels ...string) gometrics.Gauge {
+	return r.getOrRegister(name, kindGauge, labels, func() interface{} { return gometrics.NewGauge() }).(gometrics.Gauge)
+}
+
+func (r *Registry) Timer(name string, labels ...string) *Summary {
+	return r.getOrRegister(name, kindSummary, labels, func() interface{} { return newSummary() }).(*Summary)
+}
+
+func (r *Registry) getOrRegister(name, kind string, labels []string, create func() interface{}) interface{} {
+	key := labelString(labels)
+
+	r.mtx.RLock()
+	if f, exists := r.families[name]; exists {
+		if m, exists := f.series[key]; exists {
+			r.mtx.RUnlock()
+			return m
+		}
+	}
+	r.mtx.RUnlock()
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	f, exists := r.families[name]
+	if !exists {
+		f = &family{kind: kind, series: make(map[string]interface{})}
+		r.families[name] = f
+	} else if f.kind != kind {
+		panic(fmt.Sprintf("metric %s registered as %s, not %s", name, f.kind, kind))
+	}
+	m, exists := f.series[key]
+	if !exists {
+		m = create()
+		f.series[key] = m
+	}
+	return m
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	r.WritePrometheus(w)
+}
+
+// WritePrometheus writes all metrics sorted by name, timers are written as summaries in seconds.
+func (r *Registry) WritePrometheus(w io.Writer) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := r.families[name]
+		keys := make([]string, 0, len(f.series))
+		for key := range f.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		fmt.Fprintf(w, "# TYPE %s %s\n", name, f.kind)
+		for _, key := range keys {
+			switch m := f.series[key].(type) {
+			case gometrics.Counter:
+				fmt.Fprintf(w, "%s%s %d\n", name, braces(key), m.Count())
+			case gometrics.Gauge:
+				fmt.Fprintf(w, "%s%s %d\n", name, braces(key), m.Value())
+			case *Summary:
+				values := m.hist.Percentiles(quantiles)
+				for i, q := range quantiles {
+					fmt.Fprintf(w, "%s%s %s\n", name, braces(joinLabels(key, fmt.Sprintf(`quantile="%g"`, q))), seconds(values[i]))
+				}
+				fmt.Fprintf(w, "%s_sum%s %s\n", name, braces(key), seconds(float64(m.sum.Count())))
+				fmt.Fprintf(w, "%s_count%s %d\n", name, braces(key), m.count.Count())
+			}
+		}
+	}
+}
+
+func labelString(labels []string) string {
+	if len(labels)%2 != 0 {
+		panic("metrics labels must be key, value pairs")
+	}
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, labels[i], escape(labels[i+1])))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func joinLabels(a, b string) string {
+	if "" == a {
+		return b
+	}
+	return a + "," + b
+}
+
+func braces(labels string) string {
+	if "" == labels {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+func seconds(nanos float64) string {
+	return fmt.Sprintf("%g", nanos/float64(time.Second))
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escape(value string) string {
+	return labelEscaper.Replace(value)
+}
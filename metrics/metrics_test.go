@@ -0,0 +1,58 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package metrics_test
+
+import (
+	"bytes"
+	"github.com/Loopring/relay/metrics"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	r := metrics.NewRegistry()
+	r.Counter("relay_orders_total", "result", "rejected", "filter", "pow").Inc(2)
+	r.Counter("relay_orders_total", "result", "accepted", "filter", "").Inc(1)
+	r.Gauge("relay_block_lag").Update(3)
+	r.Timer("relay_rpc_duration_seconds", "node", `http://"a"`).Update(2 * time.Second)
+
+	buf := &bytes.Buffer{}
+	r.WritePrometheus(buf)
+	out := buf.String()
+
+	for _, line := range []string{
+		"# TYPE relay_block_lag gauge",
+		"relay_block_lag 3",
+		"# TYPE relay_orders_total counter",
+		`relay_orders_total{result="accepted",filter=""} 1`,
+		`relay_orders_total{result="rejected",filter="pow"} 2`,
+		"# TYPE relay_rpc_duration_seconds summary",
+		`relay_rpc_duration_seconds{node="http://\"a\"",quantile="0.5"} 2`,
+		`relay_rpc_duration_seconds_sum{node="http://\"a\""} 2`,
+		`relay_rpc_duration_seconds_count{node="http://\"a\""} 1`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("missing line %s in:\n%s", line, out)
+		}
+	}
+	if strings.Index(out, "relay_block_lag") > strings.Index(out, "relay_orders_total") {
+		t.Errorf("metrics should be sorted by name:\n%s", out)
+	}
+}
@@ -0,0 +1,73 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package miner
+
+import (
+	"errors"
+	"github.com/Loopring/relay/dao"
+	"time"
+)
+
+// AdminApi is registered under the "miner" namespace of the admin endpoint
+type AdminApi struct {
+	gasModel     *GasModel
+	batcher      *RingBatcher
+	ledger       *ProfitLedger
+	balanceGuard *BalanceGuard
+	rds          dao.RdsService
+}
+
+func NewAdminApi(gasModel *GasModel, batcher *RingBatcher, ledger *ProfitLedger, balanceGuard *BalanceGuard, rds dao.RdsService) *AdminApi {
+	return &AdminApi{gasModel: gasModel, batcher: batcher, ledger: ledger, balanceGuard: balanceGuard, rds: rds}
+}
+
+// GasEstimates returns the estimates of the gas model and their error rates
+func (a *AdminApi) GasEstimates() (*GasModelReport, error) {
+	return a.gasModel.Report(), nil
+}
+
+// SimulationStats returns the count of ring simulations of each reason code in the last hours
+func (a *AdminApi) SimulationStats(hours int) ([]dao.RingSimulationStat, error) {
+	if hours <= 0 {
+		hours = 24
+	}
+	return a.rds.RingSimulationStats(time.Now().Unix() - int64(hours)*3600)
+}
+
+// BatchReport returns the rings submitted in batches and the gas saved compared with submitting them separately
+func (a *AdminApi) BatchReport() (*BatchSubmitReport, error) {
+	if nil == a.batcher {
+		return nil, errors.New("batch submission isn't enabled")
+	}
+	return a.batcher.Report(), nil
+}
+
+// DailyProfits returns the estimated and the realised profit of each day and miner in the last days,
+// miner is optional
+func (a *AdminApi) DailyProfits(days int, miner *string) ([]DailyProfit, error) {
+	if nil == miner {
+		return a.ledger.DailyProfits(days, "")
+	}
+	return a.ledger.DailyProfits(days, *miner)
+}
+
+// Balances returns the last checked balances of miner addresses and the fee recipient
+func (a *AdminApi) Balances() ([]BalanceState, error) {
+	return a.balanceGuard.States(), nil
+}
@@ -0,0 +1,229 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package miner
+
+import (
+	"github.com/Loopring/relay/config"
+	"github.com/Loopring/relay/ethaccessor"
+	"github.com/Loopring/relay/eventemiter"
+	"github.com/Loopring/relay/log"
+	"github.com/Loopring/relay/types"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"sync"
+	"time"
+)
+
+const (
+	BALANCE_ROLE_NORMAL_MINER  = "normal_miner"
+	BALANCE_ROLE_PERCENT_MINER = "percent_miner"
+	BALANCE_ROLE_FEE_RECIPIENT = "fee_recipient"
+
+	defaultBalanceGuardInterval = 60
+)
+
+var ethAddress = common.Address{}
+
+type balanceKey struct {
+	address common.Address
+	role    string
+	token   common.Address
+}
+
+type BalanceState struct {
+	Address   common.Address `json:"address"`
+	Role      string         `json:"role"`
+	Token     common.Address `json:"token"`
+	Balance   string         `json:"balance"`
+	Threshold string         `json:"threshold"`
+	Low       bool           `json:"low"`
+	CheckTime int64          `json:"checkTime"`
+}
+
+type balanceCheck struct {
+	address   common.Address
+	role      string
+	token     common.Address
+	threshold *big.Int
+}
+
+// BalanceGuard checks the ETH and LRC balances of miner addresses and the fee recipient,
+// a miner address below the threshold isn't used to send rings and MinerBalanceLow is emitted.
+type BalanceGuard struct {
+	interval  time.Duration
+	checks    []balanceCheck
+	balanceOf func(address, token common.Address) (*big.Int, error)
+
+	mtx    sync.RWMutex
+	states map[balanceKey]*BalanceState
+
+	stopChan chan bool
+}
+
+// ETH and LRC are both of 18 decimals
+func toWei(amount float64) *big.Int {
+	v, _ := new(big.Float).Mul(big.NewFloat(amount), big.NewFloat(1e18)).Int(nil)
+	return v
+}
+
+func NewBalanceGuard(options config.BalanceGuardOptions, minerOptions config.MinerOptions) *BalanceGuard {
+	g := &BalanceGuard{}
+	g.interval = time.Duration(options.Interval) * time.Second
+	if options.Interval <= 0 {
+		g.interval = defaultBalanceGuardInterval * time.Second
+	}
+	g.states = make(map[balanceKey]*BalanceState)
+	g.balanceOf = chainBalanceOf
+
+	lrcAddresses := []common.Address{}
+	for _, impl := range ethaccessor.ProtocolAddresses() {
+		lrcAddresses = append(lrcAddresses, impl.LrcTokenAddress)
+	}
+	addMinerChecks := func(address common.Address, role string) {
+		if options.MinerMinEth > 0 {
+			g.checks = append(g.checks, balanceCheck{address: address, role: role, token: ethAddress, threshold: toWei(options.MinerMinEth)})
+		}
+		if options.MinerMinLrc > 0 {
+			for _, lrcAddress := range lrcAddresses {
+				g.checks = append(g.checks, balanceCheck{address: address, role: role, token: lrcAddress, threshold: toWei(options.MinerMinLrc)})
+			}
+		}
+	}
+	for _, addr := range minerOptions.NormalMiners {
+		addMinerChecks(common.HexToAddress(addr.Address), BALANCE_ROLE_NORMAL_MINER)
+	}
+	for _, addr := range minerOptions.PercentMiners {
+		addMinerChecks(common.HexToAddress(addr.Address), BALANCE_ROLE_PERCENT_MINER)
+	}
+	if options.FeeRecipientMinLrc > 0 && common.IsHexAddress(minerOptions.FeeReceipt) {
+		for _, lrcAddress := range lrcAddresses {
+			g.checks = append(g.checks, balanceCheck{address: common.HexToAddress(minerOptions.FeeReceipt), role: BALANCE_ROLE_FEE_RECIPIENT, token: lrcAddress, threshold: toWei(options.FeeRecipientMinLrc)})
+		}
+	}
+	return g
+}
+
+func (g *BalanceGuard) start() {
+	g.stopChan = make(chan bool)
+	g.Check()
+	go func() {
+		for {
+			select {
+			case <-time.After(g.interval):
+				g.Check()
+			case <-g.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+func (g *BalanceGuard) stop() {
+	if nil != g.stopChan {
+		close(g.stopChan)
+	}
+}
+
+// Check gets the balances, MinerBalanceLow is emitted when a balance falls below the threshold
+func (g *BalanceGuard) Check() {
+	for _, c := range g.checks {
+		balance, err := g.balanceOf(c.address, c.token)
+		if nil != err {
+			log.Errorf("balance guard, address:%s token:%s err:%s", c.address.Hex(), c.token.Hex(), err.Error())
+			continue
+		}
+		low := balance.Cmp(c.threshold) < 0
+		key := balanceKey{address: c.address, role: c.role, token: c.token}
+
+		g.mtx.Lock()
+		state, exists := g.states[key]
+		wasLow := exists && state.Low
+		g.states[key] = &BalanceState{
+			Address:   c.address,
+			Role:      c.role,
+			Token:     c.token,
+			Balance:   balance.String(),
+			Threshold: c.threshold.String(),
+			Low:       low,
+			CheckTime: time.Now().Unix(),
+		}
+		g.mtx.Unlock()
+
+		if low && !wasLow {
+			log.Errorf("balance guard, balance of %s:%s token:%s is %s, lower than %s", c.role, c.address.Hex(), c.token.Hex(), balance.String(), c.threshold.String())
+			eventemitter.Emit(eventemitter.MinerBalanceLow, &types.MinerBalanceLowEvent{
+				Address:   c.address,
+				Role:      c.role,
+				Token:     c.token,
+				Balance:   balance,
+				Threshold: c.threshold,
+				Time:      time.Now().Unix(),
+			})
+		} else if !low && wasLow {
+			log.Infof("balance guard, balance of %s:%s token:%s recovered to %s", c.role, c.address.Hex(), c.token.Hex(), balance.String())
+		}
+	}
+}
+
+func chainBalanceOf(address, token common.Address) (*big.Int, error) {
+	if token == ethAddress {
+		var balance types.Big
+		if err := ethaccessor.GetBalance(&balance, address, "latest"); nil != err {
+			return nil, err
+		}
+		return balance.BigInt(), nil
+	}
+	return ethaccessor.Erc20Balance(token, address, "latest")
+}
+
+// SenderLow returns whether any balance of the miner address is lower than its threshold
+func (g *BalanceGuard) SenderLow(address common.Address) bool {
+	g.mtx.RLock()
+	defer g.mtx.RUnlock()
+	for key, state := range g.states {
+		if key.address == address && BALANCE_ROLE_FEE_RECIPIENT != key.role && state.Low {
+			return true
+		}
+	}
+	return false
+}
+
+// FeeRecipientLrcLow returns whether the fee recipient hasn't enough LRC to pay the lrcReward
+func (g *BalanceGuard) FeeRecipientLrcLow(lrcAddress common.Address) bool {
+	g.mtx.RLock()
+	defer g.mtx.RUnlock()
+	for key, state := range g.states {
+		if BALANCE_ROLE_FEE_RECIPIENT == key.role && key.token == lrcAddress && state.Low {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *BalanceGuard) States() []BalanceState {
+	g.mtx.RLock()
+	defer g.mtx.RUnlock()
+	states := []BalanceState{}
+	for _, c := range g.checks {
+		if state, ok := g.states[balanceKey{address: c.address, role: c.role, token: c.token}]; ok {
+			states = append(states, *state)
+		}
+	}
+	return states
+}
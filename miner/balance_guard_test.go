@@ -0,0 +1,162 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package miner
+
+import (
+	"errors"
+	"github.com/Loopring/relay/eventemiter"
+	"github.com/Loopring/relay/types"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"sync"
+	"testing"
+)
+
+var (
+	guardMiner        = common.HexToAddress("0x11")
+	guardFeeRecipient = common.HexToAddress("0x12")
+	guardLrc          = common.HexToAddress("0x13")
+)
+
+type guardBalances struct {
+	mtx      sync.Mutex
+	balances map[common.Address]map[common.Address]*big.Int
+}
+
+func (b *guardBalances) set(address, token common.Address, amount float64) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if _, ok := b.balances[address]; !ok {
+		b.balances[address] = make(map[common.Address]*big.Int)
+	}
+	b.balances[address][token] = toWei(amount)
+}
+
+func (b *guardBalances) balanceOf(address, token common.Address) (*big.Int, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if balance, ok := b.balances[address][token]; ok {
+		return balance, nil
+	}
+	return nil, errors.New("balance not found")
+}
+
+func newTestBalanceGuard() (*BalanceGuard, *guardBalances) {
+	balances := &guardBalances{balances: make(map[common.Address]map[common.Address]*big.Int)}
+	g := &BalanceGuard{states: make(map[balanceKey]*BalanceState), balanceOf: balances.balanceOf}
+	g.checks = []balanceCheck{
+		{address: guardMiner, role: BALANCE_ROLE_NORMAL_MINER, token: ethAddress, threshold: toWei(0.5)},
+		{address: guardMiner, role: BALANCE_ROLE_NORMAL_MINER, token: guardLrc, threshold: toWei(0)},
+		{address: guardFeeRecipient, role: BALANCE_ROLE_FEE_RECIPIENT, token: guardLrc, threshold: toWei(1000)},
+	}
+	return g, balances
+}
+
+func watchBalanceLow() (events *[]*types.MinerBalanceLowEvent, stop func()) {
+	var mtx sync.Mutex
+	events = &[]*types.MinerBalanceLowEvent{}
+	watcher := &eventemitter.Watcher{Concurrent: false, Handle: func(eventData eventemitter.EventData) error {
+		mtx.Lock()
+		defer mtx.Unlock()
+		*events = append(*events, eventData.(*types.MinerBalanceLowEvent))
+		return nil
+	}}
+	eventemitter.On(eventemitter.MinerBalanceLow, watcher)
+	return events, func() { eventemitter.Un(eventemitter.MinerBalanceLow, watcher) }
+}
+
+func TestToWei(t *testing.T) {
+	if v := toWei(0.5); v.String() != "500000000000000000" {
+		t.Errorf("0.5 should be 5e17 wei, got %s", v.String())
+	}
+	if v := toWei(1000); v.String() != "1000000000000000000000" {
+		t.Errorf("1000 should be 1e21 wei, got %s", v.String())
+	}
+}
+
+func TestBalanceGuard_Check(t *testing.T) {
+	events, stop := watchBalanceLow()
+	defer stop()
+
+	g, balances := newTestBalanceGuard()
+	balances.set(guardMiner, ethAddress, 1)
+	balances.set(guardMiner, guardLrc, 0)
+	balances.set(guardFeeRecipient, guardLrc, 2000)
+	g.Check()
+	if g.SenderLow(guardMiner) || g.FeeRecipientLrcLow(guardLrc) || len(*events) != 0 {
+		t.Fatalf("balances are enough, got states %+v", g.States())
+	}
+
+	// MinerBalanceLow is only emitted when the balance falls below the threshold
+	balances.set(guardMiner, ethAddress, 0.1)
+	g.Check()
+	g.Check()
+	if !g.SenderLow(guardMiner) {
+		t.Errorf("miner should be low")
+	}
+	if g.SenderLow(guardFeeRecipient) || g.FeeRecipientLrcLow(guardLrc) {
+		t.Errorf("only the miner is low")
+	}
+	if len(*events) != 1 {
+		t.Fatalf("MinerBalanceLow should be emitted once, got %d", len(*events))
+	}
+	if e := (*events)[0]; e.Address != guardMiner || e.Role != BALANCE_ROLE_NORMAL_MINER || e.Token != ethAddress || e.Threshold.Cmp(toWei(0.5)) != 0 {
+		t.Errorf("unexpected event %+v", e)
+	}
+
+	// recovered and falls again
+	balances.set(guardMiner, ethAddress, 0.5)
+	g.Check()
+	if g.SenderLow(guardMiner) {
+		t.Errorf("miner should be recovered")
+	}
+	balances.set(guardMiner, ethAddress, 0.2)
+	g.Check()
+	if len(*events) != 2 {
+		t.Errorf("MinerBalanceLow should be emitted again after recovered, got %d", len(*events))
+	}
+
+	balances.set(guardFeeRecipient, guardLrc, 999)
+	g.Check()
+	if !g.FeeRecipientLrcLow(guardLrc) || g.FeeRecipientLrcLow(common.HexToAddress("0x14")) {
+		t.Errorf("fee recipient should be low for the lrc only")
+	}
+}
+
+func TestBalanceGuard_States(t *testing.T) {
+	g, balances := newTestBalanceGuard()
+	if states := g.States(); len(states) != 0 {
+		t.Fatalf("states should be empty before checked, got %+v", states)
+	}
+
+	// the state of a failed query isn't changed
+	balances.set(guardMiner, ethAddress, 0.1)
+	balances.set(guardFeeRecipient, guardLrc, 2000)
+	g.Check()
+	states := g.States()
+	if len(states) != 2 {
+		t.Fatalf("should be the states of checked balances, got %+v", states)
+	}
+	if s := states[0]; s.Address != guardMiner || s.Token != ethAddress || !s.Low || s.Balance != toWei(0.1).String() || s.Threshold != toWei(0.5).String() {
+		t.Errorf("unexpected state %+v", s)
+	}
+	if s := states[1]; s.Role != BALANCE_ROLE_FEE_RECIPIENT || s.Low {
+		t.Errorf("unexpected state %+v", s)
+	}
+}
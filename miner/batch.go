@@ -0,0 +1,336 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package miner
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Loopring/relay/config"
+	"github.com/Loopring/relay/dao"
+	"github.com/Loopring/relay/ethaccessor"
+	"github.com/Loopring/relay/types"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBatchMethod   = "submitRings"
+	defaultBatchMaxRings = 5
+	batchRecentSize      = 50
+)
+
+// RingBatch is the rings of a matching round that are submitted by one tx
+type RingBatch struct {
+	Miner    common.Address
+	Protocol common.Address
+	Infos    []*types.RingSubmitInfo
+}
+
+func (batch *RingBatch) gas() *big.Int {
+	gas := big.NewInt(0)
+	for _, info := range batch.Infos {
+		if nil != info.ProtocolGas {
+			gas.Add(gas, info.ProtocolGas)
+		}
+	}
+	return gas
+}
+
+func (batch *RingBatch) gasPrice() *big.Int {
+	gasPrice := big.NewInt(0)
+	for _, info := range batch.Infos {
+		if nil != info.ProtocolGasPrice && info.ProtocolGasPrice.Cmp(gasPrice) > 0 {
+			gasPrice.Set(info.ProtocolGasPrice)
+		}
+	}
+	return gasPrice
+}
+
+// rings in a batch must not share an order, or the balance of an owner on tokenS,
+// the latter one would fail after the former one has been mined
+func ringBatchKeys(info *types.RingSubmitInfo) []string {
+	keys := []string{}
+	for _, o := range info.RawRing.Orders {
+		order := o.OrderState.RawOrder
+		keys = append(keys, "order_"+strings.ToLower(order.Hash.Hex()))
+		keys = append(keys, "balance_"+strings.ToLower(order.Owner.Hex())+"_"+strings.ToLower(order.TokenS.Hex()))
+	}
+	return keys
+}
+
+type BatchRingResult struct {
+	RingHash  common.Hash
+	UniqueId  common.Hash
+	Status    types.TxStatus
+	RingIndex *big.Int
+	UsedGas   *big.Int
+	Err       error
+}
+
+type BatchResult struct {
+	TxHash      string `json:"txHash"`
+	BlockNumber int64  `json:"blockNumber"`
+	Rings       int    `json:"rings"`
+	Mined       int    `json:"mined"`
+	Failed      int    `json:"failed"`
+	UsedGas     int64  `json:"usedGas"`
+	Estimated   bool   `json:"estimated"`
+	SeparateGas int64  `json:"separateGas"`
+	SavedGas    int64  `json:"savedGas"`
+	Time        int64  `json:"time"`
+}
+
+// BatchSubmitReport compares the gas used by batches with the gas that separately mined rings used.
+// Only the batches whose rings all have enough samples in gas model are estimated,
+// SeparateGas and SavedGas are the sum of them.
+type BatchSubmitReport struct {
+	Batches     int           `json:"batches"`
+	Rings       int           `json:"rings"`
+	Mined       int           `json:"mined"`
+	Failed      int           `json:"failed"`
+	UsedGas     int64         `json:"usedGas"`
+	Estimated   int           `json:"estimated"`
+	SeparateGas int64         `json:"separateGas"`
+	SavedGas    int64         `json:"savedGas"`
+	Recent      []BatchResult `json:"recent"`
+}
+
+// RingBatcher packs the rings that don't overlap into one tx to the helper contract,
+// which calls submitRing of the protocol with each part of data and must not revert when one of them fails.
+type RingBatcher struct {
+	helperAddress common.Address
+	helperAbi     *abi.ABI
+	method        string
+	maxRings      int
+	maxGas        *big.Int
+	ringMinedId   common.Hash
+	gasModel      *GasModel
+
+	mtx    sync.RWMutex
+	report BatchSubmitReport
+}
+
+func NewRingBatcher(options config.BatchSubmitOptions) (*RingBatcher, error) {
+	if !common.IsHexAddress(options.HelperAddress) {
+		return nil, errors.New("miner.batch.helperAddress must be a address")
+	}
+	helperAbi, err := ethaccessor.NewAbi(options.HelperAbi)
+	if nil != err {
+		return nil, err
+	}
+	b := &RingBatcher{}
+	b.helperAddress = common.HexToAddress(options.HelperAddress)
+	b.helperAbi = helperAbi
+	b.method = options.Method
+	if "" == b.method {
+		b.method = defaultBatchMethod
+	}
+	if _, ok := helperAbi.Methods[b.method]; !ok {
+		return nil, fmt.Errorf("method:%s not found in miner.batch.helperAbi", b.method)
+	}
+	b.maxRings = options.MaxRings
+	if b.maxRings <= 0 {
+		b.maxRings = defaultBatchMaxRings
+	}
+	if options.MaxGas > 0 {
+		b.maxGas = big.NewInt(options.MaxGas)
+	}
+	b.ringMinedId = ethaccessor.ProtocolImplAbi().Events[ethaccessor.EVENT_RING_MINED].Id()
+	b.report.Recent = []BatchResult{}
+	return b, nil
+}
+
+// Pack keeps the order of rings, a ring is put into the first batch of the same miner and protocol that it doesn't overlap
+func (b *RingBatcher) Pack(infos []*types.RingSubmitInfo) []*RingBatch {
+	batches := []*RingBatch{}
+	batchKeys := []map[string]bool{}
+	for _, info := range infos {
+		keys := ringBatchKeys(info)
+		packed := false
+		for idx, batch := range batches {
+			if batch.Miner != info.Miner || batch.Protocol != info.ProtocolAddress || len(batch.Infos) >= b.maxRings {
+				continue
+			}
+			if nil != b.maxGas && nil != info.ProtocolGas && new(big.Int).Add(batch.gas(), info.ProtocolGas).Cmp(b.maxGas) > 0 {
+				continue
+			}
+			overlapped := false
+			for _, key := range keys {
+				if batchKeys[idx][key] {
+					overlapped = true
+					break
+				}
+			}
+			if !overlapped {
+				batch.Infos = append(batch.Infos, info)
+				for _, key := range keys {
+					batchKeys[idx][key] = true
+				}
+				packed = true
+				break
+			}
+		}
+		if !packed {
+			batches = append(batches, &RingBatch{Miner: info.Miner, Protocol: info.ProtocolAddress, Infos: []*types.RingSubmitInfo{info}})
+			km := make(map[string]bool)
+			for _, key := range keys {
+				km[key] = true
+			}
+			batchKeys = append(batchKeys, km)
+		}
+	}
+	return batches
+}
+
+func (b *RingBatcher) HelperAddress() common.Address {
+	return b.helperAddress
+}
+
+// CallData is the method of helper with the protocol, the concatenated submitRing data and the length of each part
+func (b *RingBatcher) CallData(batch *RingBatch) ([]byte, error) {
+	data := []byte{}
+	lengths := []*big.Int{}
+	for _, info := range batch.Infos {
+		data = append(data, info.ProtocolData...)
+		lengths = append(lengths, big.NewInt(int64(len(info.ProtocolData))))
+	}
+	return b.helperAbi.Pack(b.method, batch.Protocol, data, lengths)
+}
+
+// Gas returns the gas limit and price of the batch tx
+func (b *RingBatcher) Gas(batch *RingBatch) (*big.Int, *big.Int) {
+	gas := batch.gas()
+	if nil != b.maxGas && gas.Cmp(b.maxGas) > 0 {
+		gas.Set(b.maxGas)
+	}
+	return gas, batch.gasPrice()
+}
+
+func (b *RingBatcher) SetGasModel(gasModel *GasModel) {
+	b.gasModel = gasModel
+}
+
+// Resolve tells the result of each ring in the batch by the RingMined logs in receipt,
+// the used gas of the tx is shared by all rings in it.
+func (b *RingBatcher) Resolve(receipt *ethaccessor.TransactionReceipt, infos []*dao.RingSubmitInfo) []*BatchRingResult {
+	results := []*BatchRingResult{}
+	if len(infos) == 0 {
+		return results
+	}
+
+	txFailed := receipt.StatusInvalid() || (receipt.AfterByzantiumFork() && receipt.Status.BigInt().Cmp(big.NewInt(1)) != 0)
+	minedIndexes := make(map[common.Hash]*big.Int)
+	if !txFailed {
+		protocol := common.HexToAddress(infos[0].ProtocolAddress)
+		for _, evtLog := range receipt.Logs {
+			if common.HexToAddress(evtLog.Address) != protocol || evtLog.EventId() != b.ringMinedId || len(evtLog.Topics) < 2 {
+				continue
+			}
+			ringIndex := big.NewInt(0)
+			if data := common.FromHex(evtLog.Data); len(data) >= 32 {
+				ringIndex.SetBytes(data[0:32])
+			}
+			minedIndexes[common.HexToHash(evtLog.Topics[1])] = ringIndex
+		}
+	}
+
+	usedGas := new(big.Int).Div(receipt.GasUsed.BigInt(), big.NewInt(int64(len(infos))))
+	result := BatchResult{
+		TxHash:      receipt.TransactionHash,
+		BlockNumber: receipt.BlockNumber.Int64(),
+		Rings:       len(infos),
+		UsedGas:     receipt.GasUsed.Int64(),
+		Time:        time.Now().Unix(),
+	}
+	for _, info := range infos {
+		r := &BatchRingResult{
+			RingHash:  common.HexToHash(info.RingHash),
+			UniqueId:  common.HexToHash(info.UniqueId),
+			RingIndex: big.NewInt(0),
+			UsedGas:   usedGas,
+		}
+		if ringIndex, ok := minedIndexes[r.RingHash]; ok {
+			r.Status = types.TX_STATUS_SUCCESS
+			r.RingIndex = ringIndex
+			r.Err = errors.New("")
+			result.Mined++
+		} else {
+			r.Status = types.TX_STATUS_FAILED
+			if txFailed {
+				r.Err = errors.New("batch tx failed")
+			} else {
+				r.Err = errors.New("ring failed in batch tx")
+			}
+			result.Failed++
+		}
+		results = append(results, r)
+	}
+	// the gas limit of rings isn't what they would use, the saving is based on the gas used by separately mined rings
+	result.SeparateGas, result.Estimated = b.separateGas(infos)
+	if result.Estimated {
+		result.SavedGas = result.SeparateGas - result.UsedGas
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.report.Batches++
+	b.report.Rings += result.Rings
+	b.report.Mined += result.Mined
+	b.report.Failed += result.Failed
+	b.report.UsedGas += result.UsedGas
+	if result.Estimated {
+		b.report.Estimated++
+		b.report.SeparateGas += result.SeparateGas
+		b.report.SavedGas += result.SavedGas
+	}
+	b.report.Recent = append(b.report.Recent, result)
+	if len(b.report.Recent) > batchRecentSize {
+		b.report.Recent = b.report.Recent[len(b.report.Recent)-batchRecentSize:]
+	}
+	return results
+}
+
+// separateGas returns the gas that the rings would use if they were submitted one by one
+func (b *RingBatcher) separateGas(infos []*dao.RingSubmitInfo) (int64, bool) {
+	if nil == b.gasModel {
+		return 0, false
+	}
+	separateGas := int64(0)
+	for _, info := range infos {
+		gas, ok := b.gasModel.UsedGas(common.HexToHash(info.RingHash), int(info.OrdersCount))
+		if !ok {
+			return 0, false
+		}
+		separateGas += gas
+	}
+	return separateGas, true
+}
+
+func (b *RingBatcher) Report() *BatchSubmitReport {
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+	report := b.report
+	report.Recent = make([]BatchResult, len(b.report.Recent))
+	copy(report.Recent, b.report.Recent)
+	return &report
+}
@@ -0,0 +1,178 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package miner
+
+import (
+	"github.com/Loopring/relay/config"
+	"github.com/Loopring/relay/dao"
+	"github.com/Loopring/relay/ethaccessor"
+	"github.com/Loopring/relay/types"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"testing"
+)
+
+var (
+	batchMiner    = common.HexToAddress("0x1")
+	batchProtocol = common.HexToAddress("0x2")
+)
+
+type batchOrder struct {
+	hash   int64
+	owner  int64
+	tokenS int64
+}
+
+func batchRing(gas int64, orders ...batchOrder) *types.RingSubmitInfo {
+	ring := &types.Ring{}
+	for _, o := range orders {
+		filled := &types.FilledOrder{}
+		filled.OrderState.RawOrder.Hash = common.BigToHash(big.NewInt(o.hash))
+		filled.OrderState.RawOrder.Owner = common.BigToAddress(big.NewInt(o.owner))
+		filled.OrderState.RawOrder.TokenS = common.BigToAddress(big.NewInt(o.tokenS))
+		ring.Orders = append(ring.Orders, filled)
+	}
+	return &types.RingSubmitInfo{RawRing: ring, Miner: batchMiner, ProtocolAddress: batchProtocol, ProtocolGas: big.NewInt(gas)}
+}
+
+func batchSizes(batches []*RingBatch) []int {
+	sizes := []int{}
+	for _, batch := range batches {
+		sizes = append(sizes, len(batch.Infos))
+	}
+	return sizes
+}
+
+func sameSizes(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRingBatcher_Pack(t *testing.T) {
+	b := &RingBatcher{maxRings: 5}
+
+	// the second ring shares order 1, the third shares the tokenS balance of owner 20,
+	// the last one has the same owner but another tokenS
+	r1 := batchRing(300000, batchOrder{1, 10, 100}, batchOrder{2, 20, 200})
+	r2 := batchRing(300000, batchOrder{1, 10, 100}, batchOrder{3, 30, 300})
+	r3 := batchRing(300000, batchOrder{4, 20, 200}, batchOrder{5, 40, 400})
+	r4 := batchRing(300000, batchOrder{6, 10, 200}, batchOrder{7, 50, 500})
+	batches := b.Pack([]*types.RingSubmitInfo{r1, r2, r3, r4})
+	if sizes := batchSizes(batches); !sameSizes(sizes, []int{2, 2}) {
+		t.Fatalf("overlapped rings should be in different batches, got %v", sizes)
+	}
+	if batches[0].Infos[0] != r1 || batches[0].Infos[1] != r4 || batches[1].Infos[0] != r2 || batches[1].Infos[1] != r3 {
+		t.Errorf("ring should be put into the first batch it doesn't overlap")
+	}
+
+	// rings of other miners or protocols are never packed together
+	r5 := batchRing(300000, batchOrder{8, 60, 600})
+	r5.Miner = common.HexToAddress("0x3")
+	r6 := batchRing(300000, batchOrder{9, 70, 700})
+	r6.ProtocolAddress = common.HexToAddress("0x4")
+	if sizes := batchSizes(b.Pack([]*types.RingSubmitInfo{r1, r5, r6})); !sameSizes(sizes, []int{1, 1, 1}) {
+		t.Errorf("rings of different miner or protocol should be in different batches, got %v", sizes)
+	}
+}
+
+func TestRingBatcher_PackLimits(t *testing.T) {
+	infos := []*types.RingSubmitInfo{}
+	for i := int64(0); i < 5; i++ {
+		infos = append(infos, batchRing(300000, batchOrder{i * 2, i * 2, 100}, batchOrder{i*2 + 1, i*2 + 1, 200}))
+	}
+
+	b := &RingBatcher{maxRings: 2}
+	if sizes := batchSizes(b.Pack(infos)); !sameSizes(sizes, []int{2, 2, 1}) {
+		t.Errorf("batch should have at most maxRings rings, got %v", sizes)
+	}
+
+	b = &RingBatcher{maxRings: 5, maxGas: big.NewInt(700000)}
+	batches := b.Pack(infos)
+	if sizes := batchSizes(batches); !sameSizes(sizes, []int{2, 2, 1}) {
+		t.Errorf("gas of batch should be at most maxGas, got %v", sizes)
+	}
+	if gas, _ := b.Gas(batches[0]); gas.Int64() != 600000 {
+		t.Errorf("gas limit of batch should be the sum of rings, got %s", gas.String())
+	}
+
+	// a ring more than maxGas is submitted alone, the gas limit is capped
+	large := batchRing(900000, batchOrder{20, 20, 100})
+	batches = b.Pack([]*types.RingSubmitInfo{large, infos[0]})
+	if sizes := batchSizes(batches); !sameSizes(sizes, []int{1, 1}) {
+		t.Errorf("ring more than maxGas should be alone, got %v", sizes)
+	}
+	if gas, _ := b.Gas(batches[0]); gas.Int64() != 700000 {
+		t.Errorf("gas limit should be capped by maxGas, got %s", gas.String())
+	}
+}
+
+func TestRingBatcher_Resolve(t *testing.T) {
+	b := &RingBatcher{ringMinedId: common.HexToHash("0xaa")}
+	b.report.Recent = []BatchResult{}
+
+	mined := common.HexToHash("0x11")
+	failed := common.HexToHash("0x12")
+	infos := []*dao.RingSubmitInfo{
+		{RingHash: mined.Hex(), ProtocolAddress: batchProtocol.Hex(), OrdersCount: 2, ProtocolGas: "0x7a120"},
+		{RingHash: failed.Hex(), ProtocolAddress: batchProtocol.Hex(), OrdersCount: 2, ProtocolGas: "0x7a120"},
+	}
+	receipt := &ethaccessor.TransactionReceipt{
+		BlockNumber: *types.NewBigWithInt(5000000),
+		GasUsed:     *types.NewBigWithInt(350000),
+		Status:      types.NewBigWithInt(1),
+		Logs: []ethaccessor.Log{{
+			Address: batchProtocol.Hex(),
+			Topics:  []string{b.ringMinedId.Hex(), mined.Hex()},
+			Data:    common.ToHex(common.LeftPadBytes(big.NewInt(7).Bytes(), 32)),
+		}},
+	}
+
+	// without samples of separately mined rings, the saving isn't estimated
+	results := b.Resolve(receipt, infos)
+	if results[0].Status != types.TX_STATUS_SUCCESS || results[0].RingIndex.Int64() != 7 || results[1].Status != types.TX_STATUS_FAILED {
+		t.Fatalf("status of rings should be resolved by logs, got %+v %+v", results[0], results[1])
+	}
+	if results[0].UsedGas.Int64() != 175000 {
+		t.Errorf("used gas should be shared by rings, got %s", results[0].UsedGas.String())
+	}
+	if r := b.Report(); r.Estimated != 0 || r.SeparateGas != 0 || r.SavedGas != 0 {
+		t.Errorf("batch shouldn't be estimated by the gas limit, got %+v", r)
+	}
+
+	// the saving is based on the mean gas used by separately mined rings of the same length
+	gasModel := NewGasModel(config.GasModelOptions{MinSamples: 2}, nil)
+	gasModel.add(gasKey{length: 2, splitCount: 0}, 200000)
+	gasModel.add(gasKey{length: 2, splitCount: 0}, 240000)
+	b.SetGasModel(gasModel)
+	b.Resolve(receipt, infos)
+	r := b.Report()
+	if r.Batches != 2 || r.Estimated != 1 || r.SeparateGas != 440000 || r.SavedGas != 90000 || r.UsedGas != 700000 {
+		t.Errorf("saving should be estimated by used gas, got %+v", r)
+	}
+	if last := r.Recent[len(r.Recent)-1]; !last.Estimated || last.SavedGas != 90000 || last.Mined != 1 || last.Failed != 1 {
+		t.Errorf("unexpected batch result %+v", last)
+	}
+}
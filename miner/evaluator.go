@@ -30,18 +30,24 @@ import (
 	"github.com/Loopring/relay/marketcap"
 	"github.com/Loopring/relay/types"
 	"github.com/ethereum/go-ethereum/common"
+	"sync"
 )
 
 type Evaluator struct {
 	marketCapProvider         marketcap.MarketCapProvider
 	rateRatioCVSThreshold     int64
 	gasUsedWithLength         map[int]*big.Int
+	gasModel                  *GasModel
+	balanceGuard              *BalanceGuard
 	realCostRate, walletSplit *big.Rat
 
 	minGasPrice, maxGasPrice *big.Int
 	feeReceipt               common.Address
 
 	matcher Matcher
+
+	// guards the options swapped by Reload
+	mtx sync.RWMutex
 }
 
 func ReducedRate(ringState *types.Ring) *big.Rat {
@@ -174,7 +180,10 @@ func (e *Evaluator) ComputeRing(ringState *types.Ring) error {
 	if nil != err {
 		return err
 	} else {
-		if cvs.Int64() <= e.rateRatioCVSThreshold {
+		e.mtx.RLock()
+		rateRatioCVSThreshold := e.rateRatioCVSThreshold
+		e.mtx.RUnlock()
+		if cvs.Int64() <= rateRatioCVSThreshold {
 			return nil
 		} else {
 			for _, o := range ringState.Orders {
@@ -198,6 +207,10 @@ func (e *Evaluator) computeFeeOfRingAndOrder(ringState *types.Ring) error {
 		if feeReceiptLrcAvailableAmount, err = e.matcher.GetAccountAvailableAmount(e.feeReceipt, lrcAddress, impl.DelegateAddress); nil != err {
 			return err
 		}
+		// the margin split isn't selected while the fee recipient can't pay the lrcReward
+		if nil != e.balanceGuard && e.balanceGuard.FeeRecipientLrcLow(lrcAddress) {
+			feeReceiptLrcAvailableAmount = new(big.Rat)
+		}
 	} else {
 		return errors.New("not support this protocol: " + ringState.Orders[0].OrderState.RawOrder.Protocol.Hex())
 	}
@@ -367,22 +380,31 @@ func (e *Evaluator) getLegalCurrency(tokenAddress common.Address, amount *big.Ra
 }
 
 func (e *Evaluator) evaluateReceived(ringState *types.Ring) {
+	e.mtx.RLock()
+	realCostRate, walletSplit := e.realCostRate, e.walletSplit
+	minGasPrice, maxGasPrice := e.minGasPrice, e.maxGasPrice
+	e.mtx.RUnlock()
+
 	ringState.Received = big.NewRat(int64(0), int64(1))
-	ringState.GasPrice = ethaccessor.EstimateGasPrice(e.minGasPrice, e.maxGasPrice)
+	ringState.GasPrice = ethaccessor.EstimateGasPrice(minGasPrice, maxGasPrice)
 	//log.Debugf("len(ringState.Orders):%d", len(ringState.Orders))
-	ringState.Gas = new(big.Int)
-	ringState.Gas.Set(e.gasUsedWithLength[len(ringState.Orders)])
+	if nil != e.gasModel {
+		ringState.Gas = e.gasModel.Estimate(ringState)
+	} else {
+		ringState.Gas = new(big.Int)
+		ringState.Gas.Set(e.gasUsedWithLength[len(ringState.Orders)])
+	}
 	protocolCost := new(big.Int)
 	protocolCost.Mul(ringState.Gas, ringState.GasPrice)
 
 	costEth := new(big.Rat).SetInt(protocolCost)
 	ringState.LegalCost, _ = e.marketCapProvider.LegalCurrencyValueOfEth(costEth)
 
-	log.Debugf("legalFee:%s, cost:%s, realCostRate:%s, protocolCost:%s, gas:%s, gasPrice:%s", ringState.LegalFee.FloatString(2), ringState.LegalCost.FloatString(2), e.realCostRate.FloatString(2), protocolCost.String(), ringState.Gas.String(), ringState.GasPrice.String())
-	ringState.LegalCost.Mul(ringState.LegalCost, e.realCostRate)
-	log.Debugf("legalFee:%s, cost:%s, realCostRate:%s", ringState.LegalFee.FloatString(2), ringState.LegalCost.FloatString(2), e.realCostRate.FloatString(2))
+	log.Debugf("legalFee:%s, cost:%s, realCostRate:%s, protocolCost:%s, gas:%s, gasPrice:%s", ringState.LegalFee.FloatString(2), ringState.LegalCost.FloatString(2), realCostRate.FloatString(2), protocolCost.String(), ringState.Gas.String(), ringState.GasPrice.String())
+	ringState.LegalCost.Mul(ringState.LegalCost, realCostRate)
+	log.Debugf("legalFee:%s, cost:%s, realCostRate:%s", ringState.LegalFee.FloatString(2), ringState.LegalCost.FloatString(2), realCostRate.FloatString(2))
 	ringState.Received.Sub(ringState.LegalFee, ringState.LegalCost)
-	ringState.Received.Mul(ringState.Received, e.walletSplit)
+	ringState.Received.Mul(ringState.Received, walletSplit)
 	return
 }
 
@@ -392,21 +414,41 @@ func NewEvaluator(marketCapProvider marketcap.MarketCapProvider, minerOptions co
 	//todo:confirm this value
 	gasUsedMap[3] = big.NewInt(500000)
 	gasUsedMap[4] = big.NewInt(500000)
-	e := &Evaluator{marketCapProvider: marketCapProvider, rateRatioCVSThreshold: minerOptions.RateRatioCVSThreshold, gasUsedWithLength: gasUsedMap}
-	e.realCostRate = new(big.Rat)
-	if int64(minerOptions.Subsidy) >= 1 {
-		e.realCostRate.SetInt64(int64(0))
-	} else {
-		e.realCostRate.SetFloat64(float64(1.0) - minerOptions.Subsidy)
-	}
+	e := &Evaluator{marketCapProvider: marketCapProvider, gasUsedWithLength: gasUsedMap}
 	e.feeReceipt = common.HexToAddress(minerOptions.FeeReceipt)
-	e.walletSplit = new(big.Rat)
-	e.walletSplit.SetFloat64(minerOptions.WalletSplit)
 	e.minGasPrice = big.NewInt(minerOptions.MinGasLimit)
 	e.maxGasPrice = big.NewInt(minerOptions.MaxGasLimit)
+	e.Reload(minerOptions)
 	return e
 }
 
+// Reload applies the thresholds, subsidy and wallet split of minerOptions, the gas limits are applied by
+// RingSubmitter.Reload, rings being evaluated keep the values they started with
+func (e *Evaluator) Reload(minerOptions config.MinerOptions) {
+	realCostRate := new(big.Rat)
+	if int64(minerOptions.Subsidy) >= 1 {
+		realCostRate.SetInt64(int64(0))
+	} else {
+		realCostRate.SetFloat64(float64(1.0) - minerOptions.Subsidy)
+	}
+	walletSplit := new(big.Rat)
+	walletSplit.SetFloat64(minerOptions.WalletSplit)
+
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	e.rateRatioCVSThreshold = minerOptions.RateRatioCVSThreshold
+	e.realCostRate = realCostRate
+	e.walletSplit = walletSplit
+}
+
 func (e *Evaluator) SetMatcher(matcher Matcher) {
 	e.matcher = matcher
 }
+
+func (e *Evaluator) SetGasModel(gasModel *GasModel) {
+	e.gasModel = gasModel
+}
+
+func (e *Evaluator) SetBalanceGuard(balanceGuard *BalanceGuard) {
+	e.balanceGuard = balanceGuard
+}
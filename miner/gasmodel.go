@@ -0,0 +1,436 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package miner
+
+import (
+	"github.com/Loopring/relay/config"
+	"github.com/Loopring/relay/dao"
+	"github.com/Loopring/relay/log"
+	"github.com/Loopring/relay/types"
+	"github.com/ethereum/go-ethereum/common"
+	"math"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultGasModelHistorySize = 200
+	defaultGasModelMinSamples  = 10
+	defaultGasModelGas         = 500000
+	// predictions of rings that never got mined are dropped after this
+	gasPredictionTtl = 86400
+	// the expired predictions are swept at most once in this interval rather than on every Predict
+	gasPredictionSweepInterval = 600
+	// splitCount of samples whose fee selection is unknown
+	unknownSplitCount = -1
+)
+
+// the gas used by a ring depends on its length and how many orders select the margin split,
+// splitCount is the number of orders with FeeSelection == 1
+type gasKey struct {
+	length     int
+	splitCount int
+}
+
+// gasSamples is a ring buffer of the latest used gas
+type gasSamples struct {
+	values []int64
+	next   int
+}
+
+func (s *gasSamples) add(gas int64, size int) {
+	if len(s.values) < size {
+		s.values = append(s.values, gas)
+	} else {
+		s.values[s.next] = gas
+		s.next = (s.next + 1) % size
+	}
+}
+
+func (s *gasSamples) meanAndStdDev() (float64, float64) {
+	if len(s.values) == 0 {
+		return 0, 0
+	}
+	sum := float64(0)
+	for _, v := range s.values {
+		sum += float64(v)
+	}
+	mean := sum / float64(len(s.values))
+	variance := float64(0)
+	for _, v := range s.values {
+		variance += (float64(v) - mean) * (float64(v) - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(s.values)))
+}
+
+type gasPrediction struct {
+	key        gasKey
+	gas        int64
+	createTime int64
+}
+
+type gasErrors struct {
+	count          int
+	underEstimated int
+	absErrorRate   float64
+}
+
+func (e *gasErrors) add(predicted, used int64) {
+	e.count++
+	if predicted < used {
+		e.underEstimated++
+	}
+	e.absErrorRate += math.Abs(float64(predicted-used)) / float64(used)
+}
+
+func (e *gasErrors) meanAbsErrorRate() float64 {
+	if e.count == 0 {
+		return 0
+	}
+	return e.absErrorRate / float64(e.count)
+}
+
+type GasEstimate struct {
+	Length           int     `json:"length"`
+	SplitCount       int     `json:"splitCount"`
+	Samples          int     `json:"samples"`
+	Mean             int64   `json:"mean"`
+	StdDev           int64   `json:"stdDev"`
+	Estimate         int64   `json:"estimate"`
+	Predictions      int     `json:"predictions"`
+	UnderEstimated   int     `json:"underEstimated"`
+	MeanAbsErrorRate float64 `json:"meanAbsErrorRate"`
+}
+
+type PendingGasCheck struct {
+	Count            int     `json:"count"`
+	Failed           int     `json:"failed"`
+	UnderEstimated   int     `json:"underEstimated"`
+	MeanAbsErrorRate float64 `json:"meanAbsErrorRate"`
+}
+
+type GasModelReport struct {
+	DefaultGas       int64           `json:"defaultGas"`
+	MinSamples       int             `json:"minSamples"`
+	StdDevFactor     float64         `json:"stdDevFactor"`
+	Estimates        []GasEstimate   `json:"estimates"`
+	Predictions      int             `json:"predictions"`
+	UnderEstimated   int             `json:"underEstimated"`
+	MeanAbsErrorRate float64         `json:"meanAbsErrorRate"`
+	PendingCheck     PendingGasCheck `json:"pendingCheck"`
+}
+
+// GasModel estimates the gas of a ring from the used gas of mined rings with the same length and fee selection.
+// The estimate is mean + StdDevFactor*stddev, it falls back to the rings with the same length,
+// and then to DefaultGas, while there are not enough samples.
+type GasModel struct {
+	options config.GasModelOptions
+	rds     dao.RdsService
+
+	mtx         sync.RWMutex
+	samples     map[gasKey]*gasSamples
+	lengths     map[int]*gasSamples
+	predictions map[common.Hash]gasPrediction
+	errors      map[gasKey]*gasErrors
+	pending     gasErrors
+	pendingFail int
+	lastId      int
+	lastSweep   int64
+}
+
+func NewGasModel(options config.GasModelOptions, rds dao.RdsService) *GasModel {
+	if options.HistorySize <= 0 {
+		options.HistorySize = defaultGasModelHistorySize
+	}
+	if options.MinSamples <= 0 {
+		options.MinSamples = defaultGasModelMinSamples
+	}
+	if options.DefaultGas <= 0 {
+		options.DefaultGas = defaultGasModelGas
+	}
+	if options.StdDevFactor < 0 {
+		options.StdDevFactor = 0
+	}
+	m := &GasModel{}
+	m.options = options
+	m.rds = rds
+	m.samples = make(map[gasKey]*gasSamples)
+	m.lengths = make(map[int]*gasSamples)
+	m.predictions = make(map[common.Hash]gasPrediction)
+	m.errors = make(map[gasKey]*gasErrors)
+	if nil != rds {
+		m.loadHistory()
+	}
+	return m
+}
+
+func ringGasKey(ringState *types.Ring) gasKey {
+	key := gasKey{length: len(ringState.Orders)}
+	for _, o := range ringState.Orders {
+		if 1 == o.FeeSelection {
+			key.splitCount++
+		}
+	}
+	return key
+}
+
+// Estimate should be called after the fee selection of orders has been computed
+func (m *GasModel) Estimate(ringState *types.Ring) *big.Int {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	return big.NewInt(m.estimate(ringGasKey(ringState)))
+}
+
+func (m *GasModel) estimate(key gasKey) int64 {
+	if s, ok := m.samples[key]; ok && len(s.values) >= m.options.MinSamples {
+		return m.estimateOf(s)
+	}
+	if s, ok := m.lengths[key.length]; ok && len(s.values) >= m.options.MinSamples {
+		return m.estimateOf(s)
+	}
+	return m.options.DefaultGas
+}
+
+func (m *GasModel) estimateOf(s *gasSamples) int64 {
+	mean, stdDev := s.meanAndStdDev()
+	return int64(math.Ceil(mean + m.options.StdDevFactor*stdDev))
+}
+
+// Predict records the gas predicted for a ring to be submitted, it is compared with the used gas after mined
+func (m *GasModel) Predict(ringState *types.Ring) {
+	if nil == ringState.Gas {
+		return
+	}
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	now := time.Now().Unix()
+	if m.lastSweep+gasPredictionSweepInterval <= now {
+		m.sweepPredictions(now)
+	}
+	m.predictions[ringState.Hash] = gasPrediction{key: ringGasKey(ringState), gas: ringState.Gas.Int64(), createTime: now}
+}
+
+func (m *GasModel) sweepPredictions(now int64) {
+	for hash, p := range m.predictions {
+		if p.createTime+gasPredictionTtl < now {
+			delete(m.predictions, hash)
+		}
+	}
+	m.lastSweep = now
+}
+
+// PendingChecked records the result of eth_estimateGas against the pending state
+func (m *GasModel) PendingChecked(ringState *types.Ring, gas *big.Int, err error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if nil != err || nil == gas || gas.Sign() <= 0 {
+		m.pendingFail++
+		return
+	}
+	m.pending.add(m.estimate(ringGasKey(ringState)), gas.Int64())
+}
+
+// Learn adds the used gas of a mined ring, rings submitted by this miner are also used to compute the error rate
+func (m *GasModel) Learn(evt *dao.RingMinedEvent) {
+	m.mtx.RLock()
+	learned := evt.ID <= m.lastId
+	m.mtx.RUnlock()
+	if learned || uint8(types.TX_STATUS_SUCCESS) != evt.Status || evt.Fork {
+		return
+	}
+	gasUsed, ok := new(big.Int).SetString(evt.GasUsed, 0)
+	if !ok || gasUsed.Sign() <= 0 {
+		return
+	}
+
+	// the used gas of a batch tx is shared by all rings in it
+	if infos, err := m.rds.GetRingHashesByTxHash(common.HexToHash(evt.TxHash)); nil == err && (len(infos) > 1 || (len(infos) == 1 && infos[0].BatchSize > 1)) {
+		return
+	}
+
+	ringhash := common.HexToHash(evt.RingHash)
+	key := gasKey{length: evt.TradeAmount, splitCount: unknownSplitCount}
+	if orders, err := m.rds.GetFilledOrderByRinghash(ringhash); nil == err && len(orders) > 0 {
+		key.length = len(orders)
+		key.splitCount = 0
+		for _, o := range orders {
+			if 1 == o.FeeSelection {
+				key.splitCount++
+			}
+		}
+	} else if fills, err := m.rds.FindFillsByRingHash(ringhash); nil == err && len(fills) > 0 {
+		// the fee selection of rings from other miners isn't known, a margin split is only filled when it is selected
+		key.length = len(fills)
+		key.splitCount = 0
+		for _, f := range fills {
+			if !isZeroAmount(f.SplitS) || !isZeroAmount(f.SplitB) {
+				key.splitCount++
+			}
+		}
+	}
+	if key.length <= 1 {
+		return
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.add(key, gasUsed.Int64())
+	if evt.ID > m.lastId {
+		m.lastId = evt.ID
+	}
+	if p, ok := m.predictions[ringhash]; ok {
+		delete(m.predictions, ringhash)
+		if _, ok := m.errors[p.key]; !ok {
+			m.errors[p.key] = &gasErrors{}
+		}
+		m.errors[p.key].add(p.gas, gasUsed.Int64())
+	}
+}
+
+// UsedGas returns the mean gas used by separately mined rings with the same length and fee selection as ringhash,
+// unlike Estimate there isn't a margin or default gas, false is returned if there are not enough samples
+func (m *GasModel) UsedGas(ringhash common.Hash, length int) (int64, bool) {
+	key := gasKey{length: length, splitCount: unknownSplitCount}
+	if nil != m.rds {
+		if orders, err := m.rds.GetFilledOrderByRinghash(ringhash); nil == err && len(orders) > 0 {
+			key.length = len(orders)
+			key.splitCount = 0
+			for _, o := range orders {
+				if 1 == o.FeeSelection {
+					key.splitCount++
+				}
+			}
+		}
+	}
+
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	s, ok := m.samples[key]
+	if !ok || len(s.values) < m.options.MinSamples {
+		s, ok = m.lengths[key.length]
+	}
+	if !ok || len(s.values) < m.options.MinSamples {
+		return 0, false
+	}
+	mean, _ := s.meanAndStdDev()
+	return int64(mean), true
+}
+
+func (m *GasModel) add(key gasKey, gas int64) {
+	if key.splitCount != unknownSplitCount {
+		if _, ok := m.samples[key]; !ok {
+			m.samples[key] = &gasSamples{}
+		}
+		m.samples[key].add(gas, m.options.HistorySize)
+	}
+	if _, ok := m.lengths[key.length]; !ok {
+		m.lengths[key.length] = &gasSamples{}
+	}
+	m.lengths[key.length].add(gas, m.options.HistorySize)
+}
+
+func (m *GasModel) loadHistory() {
+	events, err := m.rds.GetLatestRingMined(m.options.HistorySize * 4)
+	if nil != err {
+		log.Errorf("gas model load history err:%s", err.Error())
+		return
+	}
+	// oldest first, the ring buffer keeps the latest
+	for i := len(events) - 1; i >= 0; i-- {
+		m.Learn(&events[i])
+	}
+	log.Infof("gas model loaded %d mined rings", len(events))
+}
+
+func (m *GasModel) Report() *GasModelReport {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	report := &GasModelReport{
+		DefaultGas:   m.options.DefaultGas,
+		MinSamples:   m.options.MinSamples,
+		StdDevFactor: m.options.StdDevFactor,
+		Estimates:    []GasEstimate{},
+	}
+	keys := make(map[gasKey]bool)
+	for key := range m.samples {
+		keys[key] = true
+	}
+	for key := range m.errors {
+		keys[key] = true
+	}
+	for length := range m.lengths {
+		keys[gasKey{length: length, splitCount: unknownSplitCount}] = true
+	}
+
+	total := gasErrors{}
+	for key := range keys {
+		e := GasEstimate{Length: key.length, SplitCount: key.splitCount}
+		var s *gasSamples
+		if key.splitCount == unknownSplitCount {
+			s = m.lengths[key.length]
+			e.Estimate = m.options.DefaultGas
+			if nil != s && len(s.values) >= m.options.MinSamples {
+				e.Estimate = m.estimateOf(s)
+			}
+		} else {
+			s = m.samples[key]
+			e.Estimate = m.estimate(key)
+		}
+		if nil != s {
+			mean, stdDev := s.meanAndStdDev()
+			e.Samples = len(s.values)
+			e.Mean = int64(mean)
+			e.StdDev = int64(stdDev)
+		}
+		if errs, ok := m.errors[key]; ok {
+			e.Predictions = errs.count
+			e.UnderEstimated = errs.underEstimated
+			e.MeanAbsErrorRate = errs.meanAbsErrorRate()
+			total.count += errs.count
+			total.underEstimated += errs.underEstimated
+			total.absErrorRate += errs.absErrorRate
+		}
+		report.Estimates = append(report.Estimates, e)
+	}
+	sort.Slice(report.Estimates, func(i, j int) bool {
+		if report.Estimates[i].Length != report.Estimates[j].Length {
+			return report.Estimates[i].Length < report.Estimates[j].Length
+		}
+		return report.Estimates[i].SplitCount < report.Estimates[j].SplitCount
+	})
+
+	report.Predictions = total.count
+	report.UnderEstimated = total.underEstimated
+	report.MeanAbsErrorRate = total.meanAbsErrorRate()
+	report.PendingCheck = PendingGasCheck{
+		Count:            m.pending.count + m.pendingFail,
+		Failed:           m.pendingFail,
+		UnderEstimated:   m.pending.underEstimated,
+		MeanAbsErrorRate: m.pending.meanAbsErrorRate(),
+	}
+	return report
+}
+
+func isZeroAmount(amount string) bool {
+	v, ok := new(big.Int).SetString(amount, 0)
+	return !ok || v.Sign() == 0
+}
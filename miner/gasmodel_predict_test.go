@@ -0,0 +1,50 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package miner
+
+import (
+	"github.com/Loopring/relay/config"
+	"github.com/Loopring/relay/types"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestGasModel_SweepPredictions(t *testing.T) {
+	m := NewGasModel(config.GasModelOptions{}, nil)
+	predict := func(n int64) {
+		m.Predict(&types.Ring{Hash: common.BigToHash(big.NewInt(n)), Gas: big.NewInt(200000)})
+	}
+	now := time.Now().Unix()
+	expired := gasPrediction{createTime: now - gasPredictionTtl - 1}
+
+	predict(1)
+	m.predictions[common.BigToHash(big.NewInt(100))] = expired
+	predict(2)
+	if 3 != len(m.predictions) {
+		t.Fatalf("predictions shouldn't be swept again within the interval, got %d", len(m.predictions))
+	}
+
+	m.lastSweep = now - gasPredictionSweepInterval
+	predict(3)
+	if _, ok := m.predictions[common.BigToHash(big.NewInt(100))]; ok || 3 != len(m.predictions) {
+		t.Errorf("the expired prediction should be swept after the interval, got %d", len(m.predictions))
+	}
+}
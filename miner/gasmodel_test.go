@@ -0,0 +1,232 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package miner_test
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Loopring/relay/config"
+	"github.com/Loopring/relay/dao"
+	"github.com/Loopring/relay/log"
+	"github.com/Loopring/relay/miner"
+	"github.com/Loopring/relay/types"
+	"github.com/ethereum/go-ethereum/common"
+	"go.uber.org/zap"
+	"math/big"
+	"testing"
+)
+
+func init() {
+	log.Initialize(config.LogOptions{ZapOpts: zap.NewDevelopmentConfig()})
+}
+
+// gasModelRds only serves the queries of gas model, the other methods of RdsService aren't called
+type gasModelRds struct {
+	dao.RdsService
+	filledOrders map[common.Hash][]*dao.FilledOrder
+	fills        map[common.Hash][]dao.FillEvent
+	submitInfos  map[common.Hash][]*dao.RingSubmitInfo
+	history      []dao.RingMinedEvent
+}
+
+func newGasModelRds() *gasModelRds {
+	return &gasModelRds{
+		filledOrders: make(map[common.Hash][]*dao.FilledOrder),
+		fills:        make(map[common.Hash][]dao.FillEvent),
+		submitInfos:  make(map[common.Hash][]*dao.RingSubmitInfo),
+	}
+}
+
+func (r *gasModelRds) GetLatestRingMined(limit int) ([]dao.RingMinedEvent, error) {
+	return r.history, nil
+}
+
+func (r *gasModelRds) GetRingHashesByTxHash(txHash common.Hash) ([]*dao.RingSubmitInfo, error) {
+	return r.submitInfos[txHash], nil
+}
+
+func (r *gasModelRds) GetFilledOrderByRinghash(ringhash common.Hash) ([]*dao.FilledOrder, error) {
+	if orders, ok := r.filledOrders[ringhash]; ok {
+		return orders, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *gasModelRds) FindFillsByRingHash(ringHash common.Hash) ([]dao.FillEvent, error) {
+	return r.fills[ringHash], nil
+}
+
+// addRing adds filled orders of a ring submitted by this miner, splitCount of them select the margin split
+func (r *gasModelRds) addRing(ringhash common.Hash, length, splitCount int) {
+	orders := make([]*dao.FilledOrder, 0)
+	for i := 0; i < length; i++ {
+		o := &dao.FilledOrder{}
+		if i < splitCount {
+			o.FeeSelection = 1
+		}
+		orders = append(orders, o)
+	}
+	r.filledOrders[ringhash] = orders
+}
+
+func newRingState(length, splitCount int) *types.Ring {
+	ring := &types.Ring{}
+	for i := 0; i < length; i++ {
+		o := &types.FilledOrder{}
+		if i < splitCount {
+			o.FeeSelection = 1
+		}
+		ring.Orders = append(ring.Orders, o)
+	}
+	return ring
+}
+
+var gasModelEventId = 0
+
+func minedEvent(ringhash common.Hash, gasUsed int64) *dao.RingMinedEvent {
+	gasModelEventId++
+	return &dao.RingMinedEvent{
+		ID:          gasModelEventId,
+		RingHash:    ringhash.Hex(),
+		TxHash:      common.BigToHash(big.NewInt(int64(gasModelEventId))).Hex(),
+		Status:      uint8(types.TX_STATUS_SUCCESS),
+		GasUsed:     fmt.Sprintf("%d", gasUsed),
+		TradeAmount: 2,
+	}
+}
+
+func TestGasModel_Estimate(t *testing.T) {
+	rds := newGasModelRds()
+	model := miner.NewGasModel(config.GasModelOptions{HistorySize: 4, MinSamples: 2, DefaultGas: 400000, StdDevFactor: 1}, rds)
+
+	// falls back to the default gas without samples
+	if gas := model.Estimate(newRingState(2, 0)); gas.Int64() != 400000 {
+		t.Fatalf("estimate should be the default gas, got %s", gas.String())
+	}
+
+	for i, gas := range []int64{200000, 220000} {
+		hash := common.BigToHash(big.NewInt(int64(100 + i)))
+		rds.addRing(hash, 2, 0)
+		model.Learn(minedEvent(hash, gas))
+	}
+	// mean 210000 + stddev 10000
+	if gas := model.Estimate(newRingState(2, 0)); gas.Int64() != 220000 {
+		t.Errorf("estimate should be mean + stddev, got %s", gas.String())
+	}
+
+	// falls back to the rings with the same length while the fee selection hasn't enough samples
+	if gas := model.Estimate(newRingState(2, 1)); gas.Int64() != 220000 {
+		t.Errorf("estimate should fall back to the same length, got %s", gas.String())
+	}
+	if gas := model.Estimate(newRingState(3, 0)); gas.Int64() != 400000 {
+		t.Errorf("estimate of unknown length should be the default gas, got %s", gas.String())
+	}
+}
+
+func TestGasModel_Learn(t *testing.T) {
+	rds := newGasModelRds()
+	model := miner.NewGasModel(config.GasModelOptions{HistorySize: 2, MinSamples: 1, DefaultGas: 400000}, rds)
+
+	hash := common.BigToHash(big.NewInt(1))
+	rds.addRing(hash, 2, 1)
+	model.Learn(minedEvent(hash, 100000))
+
+	// only the latest HistorySize samples are kept
+	for _, gas := range []int64{300000, 500000} {
+		h := common.BigToHash(big.NewInt(gas))
+		rds.addRing(h, 2, 1)
+		model.Learn(minedEvent(h, gas))
+	}
+	if gas := model.Estimate(newRingState(2, 1)); gas.Int64() != 400000 {
+		t.Errorf("estimate should be the mean of the latest 2 samples, got %s", gas.String())
+	}
+
+	// failed, forked, learned and batch rings are skipped
+	failed := minedEvent(common.BigToHash(big.NewInt(11)), 900000)
+	failed.Status = uint8(types.TX_STATUS_FAILED)
+	forked := minedEvent(common.BigToHash(big.NewInt(12)), 900000)
+	forked.Fork = true
+	learned := minedEvent(common.BigToHash(big.NewInt(13)), 900000)
+	learned.ID = 1
+	batch := minedEvent(common.BigToHash(big.NewInt(14)), 900000)
+	rds.submitInfos[common.HexToHash(batch.TxHash)] = []*dao.RingSubmitInfo{{BatchSize: 2}}
+	for _, evt := range []*dao.RingMinedEvent{failed, forked, learned, batch} {
+		rds.addRing(common.HexToHash(evt.RingHash), 2, 1)
+		model.Learn(evt)
+	}
+	if gas := model.Estimate(newRingState(2, 1)); gas.Int64() != 400000 {
+		t.Errorf("skipped rings shouldn't be learned, got %s", gas.String())
+	}
+
+	// rings of other miners are keyed by the margin split of fills
+	other := common.BigToHash(big.NewInt(21))
+	rds.fills[other] = []dao.FillEvent{{SplitS: "0x0", SplitB: "0x0"}, {SplitS: "0x10", SplitB: "0x0"}, {SplitS: "0x0", SplitB: "0x0"}}
+	model.Learn(minedEvent(other, 600000))
+	if gas := model.Estimate(newRingState(3, 1)); gas.Int64() != 600000 {
+		t.Errorf("ring of other miner should be learned by fills, got %s", gas.String())
+	}
+}
+
+func TestGasModel_LoadHistory(t *testing.T) {
+	rds := newGasModelRds()
+	for _, gas := range []int64{100000, 200000, 300000} {
+		hash := common.BigToHash(big.NewInt(gas))
+		rds.addRing(hash, 2, 0)
+		// latest first, the same as GetLatestRingMined
+		rds.history = append([]dao.RingMinedEvent{*minedEvent(hash, gas)}, rds.history...)
+	}
+	model := miner.NewGasModel(config.GasModelOptions{HistorySize: 2, MinSamples: 2}, rds)
+	if gas := model.Estimate(newRingState(2, 0)); gas.Int64() != 250000 {
+		t.Errorf("the latest 2 rings of history should be learned, got %s", gas.String())
+	}
+}
+
+func TestGasModel_Report(t *testing.T) {
+	rds := newGasModelRds()
+	model := miner.NewGasModel(config.GasModelOptions{MinSamples: 1, DefaultGas: 400000}, rds)
+
+	hash := common.BigToHash(big.NewInt(1))
+	ring := newRingState(2, 0)
+	ring.Hash = hash
+	ring.Gas = big.NewInt(150000)
+	model.Predict(ring)
+	rds.addRing(hash, 2, 0)
+	model.Learn(minedEvent(hash, 200000))
+
+	model.PendingChecked(ring, big.NewInt(100000), nil)
+	model.PendingChecked(ring, nil, errors.New("reverted"))
+
+	report := model.Report()
+	if report.Predictions != 1 || report.UnderEstimated != 1 || report.MeanAbsErrorRate != 0.25 {
+		t.Errorf("prediction error should be recorded, got %+v", report)
+	}
+	// the estimate at the time of check is 200000 against 100000 used
+	if c := report.PendingCheck; c.Count != 2 || c.Failed != 1 || c.UnderEstimated != 0 || c.MeanAbsErrorRate != 1 {
+		t.Errorf("pending check should be recorded, got %+v", c)
+	}
+	if len(report.Estimates) != 2 {
+		t.Fatalf("should be estimates of the fee selection and the length, got %+v", report.Estimates)
+	}
+	if e := report.Estimates[0]; e.SplitCount != -1 || e.Samples != 1 || e.Estimate != 200000 {
+		t.Errorf("estimate of length is wrong, got %+v", e)
+	}
+	if e := report.Estimates[1]; e.SplitCount != 0 || e.Samples != 1 || e.Predictions != 1 {
+		t.Errorf("estimate of fee selection is wrong, got %+v", e)
+	}
+}
@@ -0,0 +1,340 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package miner
+
+import (
+	"github.com/Loopring/relay/dao"
+	"github.com/Loopring/relay/ethaccessor"
+	"github.com/Loopring/relay/eventemiter"
+	"github.com/Loopring/relay/log"
+	"github.com/Loopring/relay/marketcap"
+	"github.com/Loopring/relay/types"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"sort"
+	"time"
+)
+
+const profitLedgerDateFormat = "2006-01-02"
+
+// DailyProfit is the sum of the profits of rings submitted by a miner in a day(UTC)
+type DailyProfit struct {
+	Date         string  `json:"date"`
+	Miner        string  `json:"miner"`
+	Rings        int     `json:"rings"`
+	Mined        int     `json:"mined"`
+	Failed       int     `json:"failed"`
+	Pending      int     `json:"pending"`
+	EstLegalFee  float64 `json:"estLegalFee"`
+	EstLegalCost float64 `json:"estLegalCost"`
+	EstReceived  float64 `json:"estReceived"`
+	LegalFee     float64 `json:"legalFee"`
+	LegalReward  float64 `json:"legalReward"`
+	LegalGasCost float64 `json:"legalGasCost"`
+	LegalProfit  float64 `json:"legalProfit"`
+}
+
+// the note of a mined ring reconciled without fills, they may never be saved if the block is forked or skipped
+const RING_PROFIT_NOTE_FILLS_MISSING = "fills missing"
+
+// seconds a mined ring waits its fills before reconciled without them
+const fillsMissingTimeout = 3600
+
+// ProfitLedger saves the fee, lrc reward and cost estimated by the evaluator for each submitted ring,
+// and reconciles them with the fills and the gas used after the ring is mined or failed.
+type ProfitLedger struct {
+	rds               dao.RdsService
+	marketCapProvider marketcap.MarketCapProvider
+
+	submitResultWatcher *eventemitter.Watcher
+	ringMinedWatcher    *eventemitter.Watcher
+	stopChan            chan bool
+}
+
+func NewProfitLedger(rds dao.RdsService, marketCapProvider marketcap.MarketCapProvider) *ProfitLedger {
+	l := &ProfitLedger{}
+	l.rds = rds
+	l.marketCapProvider = marketCapProvider
+	return l
+}
+
+func ratToFloat(r *big.Rat) float64 {
+	if nil == r {
+		return 0
+	}
+	f, _ := r.Float64()
+	return f
+}
+
+// Record should be called after the ring is sent
+func (l *ProfitLedger) Record(ringState *types.RingSubmitInfo, txHash common.Hash) {
+	ring := ringState.RawRing
+	p := &dao.RingProfit{}
+	p.RingHash = ringState.Ringhash.Hex()
+	p.UniqueId = ring.GenerateUniqueId().Hex()
+	p.TxHash = txHash.Hex()
+	p.Protocol = ringState.ProtocolAddress.Hex()
+	p.Miner = ringState.Miner.Hex()
+	p.OrdersCount = len(ring.Orders)
+	p.Status = int(types.TX_STATUS_PENDING)
+	p.EstLegalFee = ratToFloat(ring.LegalFee)
+	p.EstLegalCost = ratToFloat(ring.LegalCost)
+	p.EstReceived = ratToFloat(ring.Received)
+	for _, o := range ring.Orders {
+		// the lrcReward of an order is the legal value of lrcFee that paid to the owner when the margin split is selected
+		if 1 == o.FeeSelection {
+			p.EstLegalReward += ratToFloat(o.LrcReward)
+		}
+	}
+	p.EstGas = getBigIntString(ringState.ProtocolGas)
+	p.GasPrice = getBigIntString(ringState.ProtocolGasPrice)
+	p.CreateTime = time.Now().Unix()
+	p.UpdateTime = p.CreateTime
+	if err := l.rds.Add(p); nil != err {
+		log.Errorf("profit ledger, add ring:%s err:%s", p.RingHash, err.Error())
+	}
+}
+
+func getBigIntString(v *big.Int) string {
+	if nil == v {
+		return "0"
+	}
+	return v.String()
+}
+
+func (l *ProfitLedger) start() {
+	l.submitResultWatcher = &eventemitter.Watcher{Concurrent: false, Handle: l.handleSubmitResult}
+	l.ringMinedWatcher = &eventemitter.Watcher{Concurrent: false, Handle: l.handleRingMined}
+	eventemitter.On(eventemitter.Miner_RingSubmitResult, l.submitResultWatcher)
+	eventemitter.On(eventemitter.RingMined, l.ringMinedWatcher)
+
+	// the fills may be saved after the result of ring, they are retried until found
+	l.stopChan = make(chan bool)
+	go func() {
+		for {
+			select {
+			case <-time.After(10 * time.Second):
+				l.reconcileUnreconciled()
+			case <-l.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+func (l *ProfitLedger) stop() {
+	eventemitter.Un(eventemitter.Miner_RingSubmitResult, l.submitResultWatcher)
+	eventemitter.Un(eventemitter.RingMined, l.ringMinedWatcher)
+	close(l.stopChan)
+}
+
+func (l *ProfitLedger) handleSubmitResult(eventData eventemitter.EventData) error {
+	evt := eventData.(*types.RingSubmitResultEvent)
+	p, err := l.rds.FindRingProfit(evt.RingHash.Hex(), evt.TxHash.Hex())
+	if nil != err || p.Reconciled {
+		return nil
+	}
+	p.Status = int(evt.Status)
+	if nil != evt.UsedGas && evt.UsedGas.Sign() > 0 {
+		p.UsedGas = evt.UsedGas.String()
+	}
+	if nil != evt.BlockNumber && evt.BlockNumber.Sign() > 0 {
+		p.BlockNumber = evt.BlockNumber.Int64()
+	}
+	l.reconcile(p)
+	return nil
+}
+
+func (l *ProfitLedger) handleRingMined(eventData eventemitter.EventData) error {
+	evt := eventData.(*types.RingMinedEvent)
+	p, err := l.rds.FindRingProfit(evt.Ringhash.Hex(), evt.TxHash.Hex())
+	if nil != err || p.Reconciled {
+		return nil
+	}
+	p.Status = int(types.TX_STATUS_SUCCESS)
+	p.FeeRecipient = evt.FeeRecipient.Hex()
+	// the gas used of a batch tx is shared by the rings in it, it is set by the submit result
+	if ("" == p.UsedGas || "0" == p.UsedGas) && nil != evt.GasUsed {
+		p.UsedGas = evt.GasUsed.String()
+	}
+	if nil != evt.BlockNumber {
+		p.BlockNumber = evt.BlockNumber.Int64()
+	}
+	l.reconcile(p)
+	return nil
+}
+
+func (l *ProfitLedger) reconcileUnreconciled() {
+	profits, err := l.rds.GetUnreconciledRingProfits(100)
+	if nil != err {
+		log.Errorf("profit ledger err:%s", err.Error())
+		return
+	}
+	for idx := range profits {
+		l.reconcile(&profits[idx])
+	}
+}
+
+// reconcile computes the realised profit of a mined or failed ring, a failed ring only costs the gas
+func (l *ProfitLedger) reconcile(p *dao.RingProfit) {
+	p.UpdateTime = time.Now().Unix()
+	if p.Status == int(types.TX_STATUS_SUCCESS) || p.Status == int(types.TX_STATUS_FAILED) {
+		usedGas, _ := new(big.Int).SetString(p.UsedGas, 0)
+		gasPrice, _ := new(big.Int).SetString(p.GasPrice, 0)
+		if nil != usedGas && nil != gasPrice {
+			cost := new(big.Rat).SetInt(new(big.Int).Mul(usedGas, gasPrice))
+			if legalCost, err := l.marketCapProvider.LegalCurrencyValueOfEth(cost); nil != err {
+				log.Errorf("profit ledger, ring:%s legal gas cost err:%s", p.RingHash, err.Error())
+			} else {
+				p.LegalGasCost = ratToFloat(legalCost)
+			}
+		}
+	}
+
+	switch p.Status {
+	case int(types.TX_STATUS_FAILED):
+		p.LegalFee = 0
+		p.LegalReward = 0
+		p.LegalProfit = -p.LegalGasCost
+		p.Reconciled = true
+	case int(types.TX_STATUS_SUCCESS):
+		if !l.reconcileFills(p) && p.UpdateTime-p.CreateTime > fillsMissingTimeout {
+			// only the gas cost is known, the ring stops blocking the unreconciled ones after it
+			log.Warnf("profit ledger, fills of ring:%s tx:%s are missing, it's reconciled without fee", p.RingHash, p.TxHash)
+			p.LegalFee = 0
+			p.LegalReward = 0
+			p.LegalProfit = -p.LegalGasCost
+			p.Note = RING_PROFIT_NOTE_FILLS_MISSING
+			p.Reconciled = true
+		}
+	}
+
+	if err := l.rds.Save(p); nil != err {
+		log.Errorf("profit ledger, save ring:%s err:%s", p.RingHash, err.Error())
+	}
+}
+
+// reconcileFills returns false if the fills of the ring haven't been saved
+func (l *ProfitLedger) reconcileFills(p *dao.RingProfit) bool {
+	fills, err := l.rds.FindFillsByRingHash(common.HexToHash(p.RingHash))
+	if nil != err || len(fills) == 0 {
+		return false
+	}
+	var lrcAddress common.Address
+	if impl, ok := ethaccessor.ProtocolAddresses()[common.HexToAddress(p.Protocol)]; ok {
+		lrcAddress = impl.LrcTokenAddress
+	}
+
+	lrcFee := big.NewInt(0)
+	lrcReward := big.NewInt(0)
+	legalSplit := float64(0)
+	for _, fill := range fills {
+		if v, ok := new(big.Int).SetString(fill.LrcFee, 0); ok {
+			lrcFee.Add(lrcFee, v)
+		}
+		if v, ok := new(big.Int).SetString(fill.LrcReward, 0); ok {
+			lrcReward.Add(lrcReward, v)
+		}
+		legalSplit += l.legalValue(common.HexToAddress(fill.TokenS), fill.SplitS)
+		legalSplit += l.legalValue(common.HexToAddress(fill.TokenB), fill.SplitB)
+		if fill.BlockNumber > 0 {
+			p.BlockNumber = fill.BlockNumber
+		}
+	}
+	p.LrcFee = lrcFee.String()
+	p.LrcReward = lrcReward.String()
+	p.LegalFee = l.legalValue(lrcAddress, p.LrcFee) + legalSplit
+	p.LegalReward = l.legalValue(lrcAddress, p.LrcReward)
+	p.LegalProfit = p.LegalFee - p.LegalReward - p.LegalGasCost
+	p.Reconciled = true
+	return true
+}
+
+func (l *ProfitLedger) legalValue(token common.Address, amount string) float64 {
+	v, ok := new(big.Int).SetString(amount, 0)
+	if !ok || v.Sign() == 0 {
+		return 0
+	}
+	legal, err := l.marketCapProvider.LegalCurrencyValue(token, new(big.Rat).SetInt(v))
+	if nil != err {
+		log.Errorf("profit ledger, legal value of token:%s err:%s", token.Hex(), err.Error())
+		return 0
+	}
+	return ratToFloat(legal)
+}
+
+// DailyProfits returns the profit of each day and miner in the last days, the miner is optional
+func (l *ProfitLedger) DailyProfits(days int, miner string) ([]DailyProfit, error) {
+	if days <= 0 {
+		days = 7
+	}
+	now := time.Now().UTC()
+	end := now.Unix()
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1-days).Unix()
+	if "" != miner {
+		miner = common.HexToAddress(miner).Hex()
+	}
+	profits, err := l.rds.GetRingProfits(start, end+1, miner)
+	if nil != err {
+		return nil, err
+	}
+	return SumDailyProfits(profits), nil
+}
+
+// SumDailyProfits groups the profits of rings by the day(UTC) of submission and miner
+func SumDailyProfits(profits []dao.RingProfit) []DailyProfit {
+	dailyMap := make(map[string]*DailyProfit)
+	for _, p := range profits {
+		date := time.Unix(p.CreateTime, 0).UTC().Format(profitLedgerDateFormat)
+		key := date + p.Miner
+		daily, ok := dailyMap[key]
+		if !ok {
+			daily = &DailyProfit{Date: date, Miner: p.Miner}
+			dailyMap[key] = daily
+		}
+		daily.Rings++
+		switch p.Status {
+		case int(types.TX_STATUS_SUCCESS):
+			daily.Mined++
+		case int(types.TX_STATUS_FAILED):
+			daily.Failed++
+		default:
+			daily.Pending++
+		}
+		daily.EstLegalFee += p.EstLegalFee
+		daily.EstLegalCost += p.EstLegalCost
+		daily.EstReceived += p.EstReceived
+		daily.LegalFee += p.LegalFee
+		daily.LegalReward += p.LegalReward
+		daily.LegalGasCost += p.LegalGasCost
+		daily.LegalProfit += p.LegalProfit
+	}
+
+	dailies := []DailyProfit{}
+	for _, daily := range dailyMap {
+		dailies = append(dailies, *daily)
+	}
+	sort.Slice(dailies, func(i, j int) bool {
+		if dailies[i].Date != dailies[j].Date {
+			return dailies[i].Date < dailies[j].Date
+		}
+		return dailies[i].Miner < dailies[j].Miner
+	})
+	return dailies
+}
@@ -0,0 +1,73 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package miner
+
+import (
+	"github.com/Loopring/relay/dao"
+	"github.com/Loopring/relay/marketcap"
+	"github.com/Loopring/relay/types"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// noFillsRds has no fills saved, the other methods of RdsService aren't called
+type noFillsRds struct {
+	dao.RdsService
+	saved []dao.RingProfit
+}
+
+func (r *noFillsRds) FindFillsByRingHash(ringHash common.Hash) ([]dao.FillEvent, error) {
+	return []dao.FillEvent{}, nil
+}
+
+func (r *noFillsRds) Save(item interface{}) error {
+	r.saved = append(r.saved, *item.(*dao.RingProfit))
+	return nil
+}
+
+type ethValueProvider struct {
+	marketcap.MarketCapProvider
+}
+
+func (p *ethValueProvider) LegalCurrencyValueOfEth(amount *big.Rat) (*big.Rat, error) {
+	return new(big.Rat).Quo(amount, big.NewRat(1e18, 1)), nil
+}
+
+func TestProfitLedger_ReconcileFillsMissing(t *testing.T) {
+	rds := &noFillsRds{}
+	l := NewProfitLedger(rds, &ethValueProvider{})
+
+	now := time.Now().Unix()
+	recent := &dao.RingProfit{RingHash: "0x1", Status: int(types.TX_STATUS_SUCCESS), UsedGas: "100000", GasPrice: "1000000000000", CreateTime: now - 60}
+	l.reconcile(recent)
+	if recent.Reconciled {
+		t.Errorf("a recent ring should wait its fills")
+	}
+
+	old := &dao.RingProfit{RingHash: "0x2", Status: int(types.TX_STATUS_SUCCESS), UsedGas: "100000", GasPrice: "1000000000000", CreateTime: now - 2*fillsMissingTimeout}
+	l.reconcile(old)
+	if !old.Reconciled || RING_PROFIT_NOTE_FILLS_MISSING != old.Note || 0.1 != old.LegalGasCost || -0.1 != old.LegalProfit {
+		t.Errorf("an old ring without fills should be reconciled with gas cost, got %+v", old)
+	}
+	if len(rds.saved) != 2 || !rds.saved[1].Reconciled {
+		t.Errorf("both rings should be saved, got %+v", rds.saved)
+	}
+}
@@ -0,0 +1,52 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package miner_test
+
+import (
+	"github.com/Loopring/relay/dao"
+	"github.com/Loopring/relay/miner"
+	"github.com/Loopring/relay/types"
+	"testing"
+)
+
+func TestSumDailyProfits(t *testing.T) {
+	day1 := int64(1517443200) // 2018-02-01 00:00:00 UTC
+	day2 := day1 + 86400
+	profits := []dao.RingProfit{
+		{Miner: "0xa", CreateTime: day1 + 10, Status: int(types.TX_STATUS_SUCCESS), EstLegalFee: 3, LegalFee: 2.5, LegalGasCost: 0.5, LegalProfit: 2},
+		{Miner: "0xa", CreateTime: day1 + 20, Status: int(types.TX_STATUS_FAILED), EstLegalFee: 1, LegalGasCost: 0.3, LegalProfit: -0.3},
+		{Miner: "0xb", CreateTime: day1 + 30, Status: int(types.TX_STATUS_PENDING), EstLegalFee: 2},
+		{Miner: "0xa", CreateTime: day2 + 5, Status: int(types.TX_STATUS_SUCCESS), LegalFee: 1, LegalReward: 0.2, LegalProfit: 0.8},
+	}
+
+	dailies := miner.SumDailyProfits(profits)
+	if len(dailies) != 3 {
+		t.Fatalf("expect 3 dailies, got:%d", len(dailies))
+	}
+	a := dailies[0]
+	if a.Date != "2018-02-01" || a.Miner != "0xa" || a.Rings != 2 || a.Mined != 1 || a.Failed != 1 || a.EstLegalFee != 4 || a.LegalProfit != 1.7 {
+		t.Errorf("unexpected daily:%+v", a)
+	}
+	if b := dailies[1]; b.Miner != "0xb" || b.Pending != 1 || b.LegalProfit != 0 {
+		t.Errorf("unexpected daily:%+v", b)
+	}
+	if c := dailies[2]; c.Date != "2018-02-02" || c.LegalReward != 0.2 {
+		t.Errorf("unexpected daily:%+v", c)
+	}
+}
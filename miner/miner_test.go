@@ -99,7 +99,7 @@ func TestMatch(t *testing.T) {
 	submitter, _ := miner.NewSubmitter(cfg.Miner, rdsService, marketCapProvider)
 	evaluator := miner.NewEvaluator(marketCapProvider, cfg.Miner)
 	rds := test.GenerateDaoService()
-	matcher := timing_matcher.NewTimingMatcher(cfg.Miner.TimingMatcher, submitter, evaluator, om, &accountManager, rds)
+	matcher, _ := timing_matcher.NewTimingMatcher(cfg.Miner.TimingMatcher, submitter, evaluator, om, &accountManager, rds)
 	evaluator.SetMatcher(matcher)
 
 	m := miner.NewMiner(submitter, matcher, evaluator, marketCapProvider)
@@ -143,9 +143,9 @@ func TestMiner_PrepareOrders(t *testing.T) {
 	//c := test.Cfg()
 	entity := test.Entity()
 
-	lrc := util.SupportTokens["LRC"].Protocol
+	lrc := util.SupportTokens()["LRC"].Protocol
 
-	eth := util.SupportMarkets["WETH"].Protocol
+	eth := util.SupportMarkets()["WETH"].Protocol
 
 	account1 := entity.Accounts[0]
 	account2 := entity.Accounts[1]
@@ -0,0 +1,58 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package miner
+
+import (
+	"math/big"
+)
+
+var weiPerEther = new(big.Rat).SetInt(big.NewInt(1e18))
+
+// PercentGasPrice is the gas price in wei that spends FeePercent of the legal fee of a ring on gas,
+// (FeePercent/100)*(legalFee/ethPrice)/gasLimit, ethPrice is the legal price of 1 ether.
+func PercentGasPrice(feePercent float64, legalFee, ethPrice *big.Rat, gasLimit *big.Int) *big.Int {
+	if feePercent <= 0 || nil == legalFee || legalFee.Sign() <= 0 || nil == ethPrice || ethPrice.Sign() <= 0 || nil == gasLimit || gasLimit.Sign() <= 0 {
+		return big.NewInt(0)
+	}
+	price := new(big.Rat).SetFloat64(feePercent)
+	price.Quo(price, new(big.Rat).SetInt64(100))
+	price.Mul(price, legalFee)
+	price.Quo(price, ethPrice)
+	price.Mul(price, weiPerEther)
+	price.Quo(price, new(big.Rat).SetInt(gasLimit))
+	return new(big.Int).Quo(price.Num(), price.Denom())
+}
+
+// SelectPercentMiner returns the miner with the highest StartFee that the legal fee reaches,
+// the one with the lowest StartFee is returned by force when there isn't a normal miner to use.
+func SelectPercentMiner(miners []*SplitMinerAddress, legalFee float64, force bool) *SplitMinerAddress {
+	var selected, lowest *SplitMinerAddress
+	for _, m := range miners {
+		if m.StartFee <= legalFee && (nil == selected || m.StartFee > selected.StartFee) {
+			selected = m
+		}
+		if nil == lowest || m.StartFee < lowest.StartFee {
+			lowest = m
+		}
+	}
+	if nil == selected && force {
+		return lowest
+	}
+	return selected
+}
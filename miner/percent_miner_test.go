@@ -0,0 +1,72 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package miner_test
+
+import (
+	"github.com/Loopring/relay/miner"
+	"math/big"
+	"testing"
+)
+
+func TestPercentGasPrice(t *testing.T) {
+	// 10% of 100 legal fee is 10, which is 0.01 ether at the price of 1000, 0.01 ether / 500000 gas = 20 gwei
+	gasPrice := miner.PercentGasPrice(10, big.NewRat(100, 1), big.NewRat(1000, 1), big.NewInt(500000))
+	if gasPrice.Cmp(big.NewInt(20000000000)) != 0 {
+		t.Errorf("expect 20000000000, got:%s", gasPrice.String())
+	}
+
+	// 2.5% of 12.5 legal fee at the price of 800 within 300000 gas
+	gasPrice = miner.PercentGasPrice(2.5, big.NewRat(25, 2), big.NewRat(800, 1), big.NewInt(300000))
+	if gasPrice.Cmp(big.NewInt(1302083333)) != 0 {
+		t.Errorf("expect 1302083333, got:%s", gasPrice.String())
+	}
+
+	for _, gasPrice := range []*big.Int{
+		miner.PercentGasPrice(0, big.NewRat(100, 1), big.NewRat(1000, 1), big.NewInt(500000)),
+		miner.PercentGasPrice(10, nil, big.NewRat(1000, 1), big.NewInt(500000)),
+		miner.PercentGasPrice(10, big.NewRat(100, 1), big.NewRat(0, 1), big.NewInt(500000)),
+		miner.PercentGasPrice(10, big.NewRat(100, 1), big.NewRat(1000, 1), big.NewInt(0)),
+	} {
+		if gasPrice.Sign() != 0 {
+			t.Errorf("expect 0, got:%s", gasPrice.String())
+		}
+	}
+}
+
+func TestSelectPercentMiner(t *testing.T) {
+	low := &miner.SplitMinerAddress{FeePercent: 10, StartFee: 50}
+	high := &miner.SplitMinerAddress{FeePercent: 5, StartFee: 500}
+	miners := []*miner.SplitMinerAddress{high, low}
+
+	if m := miner.SelectPercentMiner(miners, 10, false); nil != m {
+		t.Errorf("expect nil, got StartFee:%f", m.StartFee)
+	}
+	if m := miner.SelectPercentMiner(miners, 10, true); m != low {
+		t.Errorf("expect the lowest StartFee by force")
+	}
+	if m := miner.SelectPercentMiner(miners, 100, false); m != low {
+		t.Errorf("expect StartFee 50")
+	}
+	if m := miner.SelectPercentMiner(miners, 1000, false); m != high {
+		t.Errorf("expect StartFee 500")
+	}
+	if m := miner.SelectPercentMiner(nil, 1000, true); nil != m {
+		t.Errorf("expect nil without percent miners")
+	}
+}
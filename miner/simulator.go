@@ -0,0 +1,228 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package miner
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/Loopring/relay/cache"
+	"github.com/Loopring/relay/config"
+	"github.com/Loopring/relay/dao"
+	"github.com/Loopring/relay/ethaccessor"
+	"github.com/Loopring/relay/log"
+	"github.com/Loopring/relay/types"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	RingQuarantinePrefix        = "miner_ring_quarantine_"
+	defaultRingQuarantineTtl    = 600
+	revertErrorMethod           = "Error"
+	revertErrorAbi              = `[{"type":"function","name":"Error","inputs":[{"name":"reason","type":"string"}]}]`
+	SIMULATION_OK               = "ok"
+	SIMULATION_CUTOFF           = "cutoff"
+	SIMULATION_FILLED_CANCELLED = "filled_or_cancelled"
+	SIMULATION_ALLOWANCE        = "insufficient_balance_or_allowance"
+	SIMULATION_SIGNATURE        = "invalid_signature"
+	SIMULATION_OUT_OF_GAS       = "out_of_gas"
+	SIMULATION_REVERTED         = "reverted"
+	SIMULATION_NODE_ERROR       = "node_error"
+)
+
+var revertDataPattern = regexp.MustCompile(`0x[0-9a-fA-F]{8,}`)
+
+type RingSimulationResult struct {
+	Success    bool
+	ReasonCode string
+	Reason     string
+	Gas        *big.Int
+}
+
+func (r *RingSimulationResult) Error() error {
+	if r.Success {
+		return nil
+	}
+	return fmt.Errorf("ring simulation failed, reasonCode:%s, reason:%s", r.ReasonCode, r.Reason)
+}
+
+// RingSimulator calls submitRing with the calldata of a ring against the pending block before it is submitted,
+// a ring that would revert is quarantined so that it isn't matched again for a while and doesn't burn gas.
+type RingSimulator struct {
+	rds           dao.RdsService
+	quarantineTtl int64
+	revertAbi     *abi.ABI
+}
+
+func NewRingSimulator(options config.RingSimulationOptions, rds dao.RdsService) *RingSimulator {
+	s := &RingSimulator{}
+	s.rds = rds
+	s.quarantineTtl = options.QuarantineTtl
+	if s.quarantineTtl <= 0 {
+		s.quarantineTtl = defaultRingQuarantineTtl
+	}
+	s.revertAbi = newRevertAbi(ethaccessor.ProtocolImplAbi())
+	return s
+}
+
+// newRevertAbi adds the Error(string) of solidity to the methods of protocolAbi,
+// the revert data is decoded as the input of the method with the same selector
+func newRevertAbi(protocolAbi *abi.ABI) *abi.ABI {
+	a := &abi.ABI{Methods: make(map[string]abi.Method)}
+	if nil != protocolAbi {
+		for name, method := range protocolAbi.Methods {
+			a.Methods[name] = method
+		}
+	}
+	errorAbi, err := ethaccessor.NewAbi(revertErrorAbi)
+	if nil != err {
+		log.Errorf("failed to parse revert abi, err:%s", err.Error())
+		return a
+	}
+	a.Methods[revertErrorMethod] = errorAbi.Methods[revertErrorMethod]
+	return a
+}
+
+func (s *RingSimulator) Simulate(ringSubmitInfo *types.RingSubmitInfo) *RingSimulationResult {
+	callArg := &ethaccessor.CallArg{}
+	callArg.From = ringSubmitInfo.Miner
+	callArg.To = ringSubmitInfo.ProtocolAddress
+	callArg.Data = common.ToHex(ringSubmitInfo.ProtocolData)
+
+	result := &RingSimulationResult{}
+	var ret string
+	if err := ethaccessor.Call(&ret, callArg, "pending"); nil != err {
+		s.failed(result, err)
+	} else if reason, ok := s.DecodeRevertReason(common.FromHex(ret)); ok {
+		result.ReasonCode = ClassifyRevertReason(reason)
+		result.Reason = reason
+	} else if gas, err := ethaccessor.EstimateGasOfCall(callArg, "pending"); nil != err {
+		// submitRing returns nothing, a revert without reason can only be told by estimateGas
+		s.failed(result, err)
+	} else {
+		result.Success = true
+		result.ReasonCode = SIMULATION_OK
+		result.Gas = gas
+	}
+
+	s.save(ringSubmitInfo, result)
+	if !result.Success && SIMULATION_NODE_ERROR != result.ReasonCode {
+		s.quarantine(ringSubmitInfo.RawRing.GenerateUniqueId(), result.ReasonCode)
+	}
+	return result
+}
+
+func (s *RingSimulator) failed(result *RingSimulationResult, err error) {
+	result.Reason = err.Error()
+	// an error without code isn't returned by the evm but by the connection
+	if _, ok := err.(interface {
+		ErrorCode() int
+	}); !ok {
+		result.ReasonCode = SIMULATION_NODE_ERROR
+		return
+	}
+	if data := revertDataPattern.FindString(err.Error()); "" != data {
+		if reason, ok := s.DecodeRevertReason(common.FromHex(data)); ok {
+			result.Reason = reason
+		}
+	}
+	result.ReasonCode = ClassifyRevertReason(result.Reason)
+}
+
+// DecodeRevertReason decodes the revert data by the method with the same selector
+func (s *RingSimulator) DecodeRevertReason(data []byte) (string, bool) {
+	if len(data) < 4 {
+		return "", false
+	}
+	for name, method := range s.revertAbi.Methods {
+		if !bytes.Equal(method.Id(), data[:4]) {
+			continue
+		}
+		if revertErrorMethod == name {
+			var reason string
+			if err := s.revertAbi.UnpackMethodInput(&reason, name, data[4:]); nil != err {
+				return hexutil.Encode(data), true
+			}
+			return reason, true
+		}
+		return name, true
+	}
+	return "", false
+}
+
+// ClassifyRevertReason returns the reason code of the revert reason or the error message of node
+func ClassifyRevertReason(reason string) string {
+	r := strings.ToLower(reason)
+	switch {
+	case strings.Contains(r, "cutoff"):
+		return SIMULATION_CUTOFF
+	case strings.Contains(r, "cancel"), strings.Contains(r, "filled"):
+		return SIMULATION_FILLED_CANCELLED
+	case strings.Contains(r, "allowance"), strings.Contains(r, "balance"), strings.Contains(r, "spendable"):
+		return SIMULATION_ALLOWANCE
+	case strings.Contains(r, "signature"):
+		return SIMULATION_SIGNATURE
+	case strings.Contains(r, "gas"):
+		return SIMULATION_OUT_OF_GAS
+	}
+	return SIMULATION_REVERTED
+}
+
+func (s *RingSimulator) save(ringSubmitInfo *types.RingSubmitInfo, result *RingSimulationResult) {
+	if nil == s.rds {
+		return
+	}
+	item := &dao.RingSimulation{}
+	item.RingHash = ringSubmitInfo.Ringhash.Hex()
+	item.UniqueId = ringSubmitInfo.RawRing.GenerateUniqueId().Hex()
+	item.ProtocolAddress = ringSubmitInfo.ProtocolAddress.Hex()
+	item.Miner = ringSubmitInfo.Miner.Hex()
+	item.OrdersCount = len(ringSubmitInfo.RawRing.Orders)
+	item.Success = result.Success
+	item.ReasonCode = result.ReasonCode
+	item.Reason = result.Reason
+	if nil != result.Gas {
+		item.EstimatedGas = result.Gas.String()
+	}
+	item.CreateTime = time.Now().Unix()
+	if err := s.rds.Add(item); nil != err {
+		log.Errorf("failed to save ring simulation:%s, err:%s", item.RingHash, err.Error())
+	}
+}
+
+func (s *RingSimulator) quarantine(uniqueId common.Hash, reasonCode string) {
+	if err := cache.Set(RingQuarantinePrefix+strings.ToLower(uniqueId.Hex()), []byte(reasonCode), s.quarantineTtl); nil != err {
+		log.Errorf("failed to quarantine ring:%s, err:%s", uniqueId.Hex(), err.Error())
+	}
+}
+
+// RingQuarantined returns the reason code if the ring failed in simulation recently
+func RingQuarantined(uniqueId common.Hash) (string, error) {
+	key := RingQuarantinePrefix + strings.ToLower(uniqueId.Hex())
+	if exists, err := cache.Exists(key); nil != err || !exists {
+		return "", err
+	}
+	data, err := cache.Get(key)
+	return string(data), err
+}
@@ -0,0 +1,41 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package miner_test
+
+import (
+	"github.com/Loopring/relay/miner"
+	"testing"
+)
+
+func TestClassifyRevertReason(t *testing.T) {
+	cases := map[string]string{
+		"order cutoff":                       miner.SIMULATION_CUTOFF,
+		"order had been cancelled or filled": miner.SIMULATION_FILLED_CANCELLED,
+		"insufficient spendable amount":      miner.SIMULATION_ALLOWANCE,
+		"invalid signature":                  miner.SIMULATION_SIGNATURE,
+		"out of gas":                         miner.SIMULATION_OUT_OF_GAS,
+		"evm: execution reverted":            miner.SIMULATION_REVERTED,
+		"":                                   miner.SIMULATION_REVERTED,
+	}
+	for reason, code := range cases {
+		if c := miner.ClassifyRevertReason(reason); c != code {
+			t.Errorf("reason:%s, expect:%s, got:%s", reason, code, c)
+		}
+	}
+}
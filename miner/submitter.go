@@ -25,21 +25,24 @@ import (
 	"encoding/json"
 	"github.com/Loopring/relay/cache"
 	"github.com/Loopring/relay/config"
+	"github.com/Loopring/relay/crypto"
 	"github.com/Loopring/relay/dao"
 	"github.com/Loopring/relay/ethaccessor"
 	"github.com/Loopring/relay/eventemiter"
 	"github.com/Loopring/relay/log"
 	"github.com/Loopring/relay/marketcap"
+	"github.com/Loopring/relay/metrics"
 	"github.com/Loopring/relay/types"
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
 	"strconv"
+	"sync"
 	"time"
 )
 
 const SubmitRingMethod_LastId = "submitringmethod_lastid"
 
-//保存ring，并将ring发送到区块链，同样需要分为待完成和已完成
+// 保存ring，并将ring发送到区块链，同样需要分为待完成和已完成
 type RingSubmitter struct {
 	minerAccountForSign accounts.Account
 	//minerNameInfos      map[common.Address][]*types.NameRegistryInfo
@@ -48,6 +51,15 @@ type RingSubmitter struct {
 
 	maxGasLimit *big.Int
 	minGasLimit *big.Int
+	gasLimitMtx sync.RWMutex
+
+	gasModel        *GasModel
+	estimatePending bool
+	estimateMargin  *big.Rat
+	simulator       *RingSimulator
+	batcher         *RingBatcher
+	ledger          *ProfitLedger
+	balanceGuard    *BalanceGuard
 
 	normalMinerAddresses  []*NormalSenderAddress
 	percentMinerAddresses []*SplitMinerAddress
@@ -59,6 +71,14 @@ type RingSubmitter struct {
 	stopFuncs []func()
 }
 
+// Reload applies the gas limits of options, the rings being generated keep the limits they started with
+func (submitter *RingSubmitter) Reload(options config.MinerOptions) {
+	submitter.gasLimitMtx.Lock()
+	defer submitter.gasLimitMtx.Unlock()
+	submitter.maxGasLimit = big.NewInt(options.MaxGasLimit)
+	submitter.minGasLimit = big.NewInt(options.MinGasLimit)
+}
+
 type RingSubmitFailed struct {
 	RingState *types.Ring
 	err       error
@@ -66,8 +86,12 @@ type RingSubmitFailed struct {
 
 func NewSubmitter(options config.MinerOptions, dbService dao.RdsService, marketCapProvider marketcap.MarketCapProvider) (*RingSubmitter, error) {
 	submitter := &RingSubmitter{}
-	submitter.maxGasLimit = big.NewInt(options.MaxGasLimit)
-	submitter.minGasLimit = big.NewInt(options.MinGasLimit)
+	submitter.Reload(options)
+	submitter.estimatePending = options.GasModel.EstimatePending
+	submitter.estimateMargin = new(big.Rat).SetInt64(int64(1))
+	if options.GasModel.EstimateMargin > 1 {
+		submitter.estimateMargin.SetFloat64(options.GasModel.EstimateMargin)
+	}
 	if common.IsHexAddress(options.FeeReceipt) {
 		submitter.feeReceipt = common.HexToAddress(options.FeeReceipt)
 	} else {
@@ -105,6 +129,16 @@ func NewSubmitter(options config.MinerOptions, dbService dao.RdsService, marketC
 
 	submitter.dbService = dbService
 	submitter.marketCapProvider = marketCapProvider
+	if options.Simulation.Enabled {
+		submitter.simulator = NewRingSimulator(options.Simulation, dbService)
+	}
+	if options.Batch.Enabled {
+		batcher, err := NewRingBatcher(options.Batch)
+		if nil != err {
+			return submitter, err
+		}
+		submitter.batcher = batcher
+	}
 
 	submitter.stopFuncs = []func(){}
 	return submitter, nil
@@ -115,7 +149,10 @@ func (submitter *RingSubmitter) listenBlockNew() {
 	go func() {
 		for {
 			select {
-			case blockEvent := <-blockEventChan:
+			case blockEvent, ok := <-blockEventChan:
+				if !ok {
+					return
+				}
 				submitter.currentBlockTime = blockEvent.BlockTime
 			}
 		}
@@ -174,24 +211,15 @@ func (submitter *RingSubmitter) listenNewRings() {
 			log.Debugf("received ringstates length:%d", len(ringInfos))
 			//ringSubmitInfoChan <- e
 			if nil != ringInfos {
-				for _, ringState := range ringInfos {
-					txHash, status, err1 := submitter.submitRing(ringState)
-					ringState.SubmitTxHash = txHash
-
-					daoInfo := &dao.RingSubmitInfo{}
-					daoInfo.ConvertDown(ringState, err1)
-					if err := submitter.dbService.Add(daoInfo); nil != err {
-						log.Errorf("Miner submitter,insert new ring err:%s", err.Error())
-					} else {
-						for _, filledOrder := range ringState.RawRing.Orders {
-							daoOrder := &dao.FilledOrder{}
-							daoOrder.ConvertDown(filledOrder, ringState.Ringhash)
-							if err1 := submitter.dbService.Add(daoOrder); nil != err1 {
-								log.Errorf("Miner submitter,insert filled Order err:%s", err1.Error())
-							}
-						}
+				if nil != submitter.batcher {
+					for _, batch := range submitter.batcher.Pack(ringInfos) {
+						submitter.submitBatch(batch)
+					}
+				} else {
+					for _, ringState := range ringInfos {
+						txHash, status, err1 := submitter.submitRing(ringState)
+						submitter.saveSubmitInfo(ringState, txHash, status, 1, err1)
 					}
-					submitter.submitResult(ringState.Ringhash, ringState.RawRing.GenerateUniqueId(), txHash, status, big.NewInt(0), big.NewInt(0), big.NewInt(0), err1)
 				}
 			}
 			return nil
@@ -204,33 +232,174 @@ func (submitter *RingSubmitter) listenNewRings() {
 	})
 }
 
-//todo: 不在submit中的才会提交
+func (submitter *RingSubmitter) SetGasModel(gasModel *GasModel) {
+	submitter.gasModel = gasModel
+	if nil != submitter.batcher {
+		submitter.batcher.SetGasModel(gasModel)
+	}
+}
+
+func (submitter *RingSubmitter) SetLedger(ledger *ProfitLedger) {
+	submitter.ledger = ledger
+}
+
+func (submitter *RingSubmitter) SetBalanceGuard(balanceGuard *BalanceGuard) {
+	submitter.balanceGuard = balanceGuard
+}
+
+// todo: 不在submit中的才会提交
 func (submitter *RingSubmitter) canSubmit(ringState *types.RingSubmitInfo) error {
 	return errors.New("had been processed")
 }
 
+func (submitter *RingSubmitter) Batcher() *RingBatcher {
+	return submitter.batcher
+}
+
+func (submitter *RingSubmitter) saveSubmitInfo(ringState *types.RingSubmitInfo, txHash common.Hash, status types.TxStatus, batchSize int, err1 error) {
+	ringState.SubmitTxHash = txHash
+
+	daoInfo := &dao.RingSubmitInfo{}
+	daoInfo.ConvertDown(ringState, err1)
+	daoInfo.BatchSize = batchSize
+	if err := submitter.dbService.Add(daoInfo); nil != err {
+		log.Errorf("Miner submitter,insert new ring err:%s", err.Error())
+	} else {
+		for _, filledOrder := range ringState.RawRing.Orders {
+			daoOrder := &dao.FilledOrder{}
+			daoOrder.ConvertDown(filledOrder, ringState.Ringhash)
+			if err1 := submitter.dbService.Add(daoOrder); nil != err1 {
+				log.Errorf("Miner submitter,insert filled Order err:%s", err1.Error())
+			}
+		}
+	}
+	if nil != submitter.ledger && types.TX_STATUS_PENDING == status {
+		submitter.ledger.Record(ringState, txHash)
+	}
+	switch status {
+	case types.TX_STATUS_PENDING:
+		metrics.Counter("relay_miner_ring_submits_total", "result", "sent", "reason", "").Inc(1)
+	case types.TX_STATUS_FAILED:
+		metrics.Counter("relay_miner_ring_submits_total", "result", "failed", "reason", "send_error").Inc(1)
+	}
+	submitter.submitResult(ringState.Ringhash, ringState.RawRing.GenerateUniqueId(), txHash, status, big.NewInt(0), big.NewInt(0), big.NewInt(0), err1)
+}
+
 func (submitter *RingSubmitter) submitRing(ringSubmitInfo *types.RingSubmitInfo) (common.Hash, types.TxStatus, error) {
-	status := types.TX_STATUS_PENDING
 	ordersStr, _ := json.Marshal(ringSubmitInfo.RawRing.Orders)
 	log.Debugf("submitring hash:%s, orders:%s", ringSubmitInfo.Ringhash.Hex(), string(ordersStr))
 
-	txHash := types.NilHash
-	var err error
+	if err := submitter.simulate(ringSubmitInfo); nil != err {
+		return types.NilHash, types.TX_STATUS_REJECTED, err
+	}
+	return submitter.sendRing(ringSubmitInfo)
+}
+
+// the ring isn't sent if it would revert, and it isn't counted as failed to execute since no gas is burnt
+func (submitter *RingSubmitter) simulate(ringSubmitInfo *types.RingSubmitInfo) error {
+	if nil != submitter.simulator {
+		if result := submitter.simulator.Simulate(ringSubmitInfo); !result.Success {
+			err := result.Error()
+			log.Errorf("submitring hash:%s, simulation err:%s", ringSubmitInfo.Ringhash.Hex(), err.Error())
+			metrics.Counter("relay_miner_ring_submits_total", "result", "failed", "reason", "simulation_"+result.ReasonCode).Inc(1)
+			return err
+		}
+	}
+	return nil
+}
+
+func (submitter *RingSubmitter) sendRing(ringSubmitInfo *types.RingSubmitInfo) (common.Hash, types.TxStatus, error) {
+	status := types.TX_STATUS_PENDING
+	txHashStr, err := ethaccessor.SignAndSendTransaction(ringSubmitInfo.Miner, ringSubmitInfo.ProtocolAddress, ringSubmitInfo.ProtocolGas, ringSubmitInfo.ProtocolGasPrice, nil, ringSubmitInfo.ProtocolData, false)
+	if nil != err {
+		log.Errorf("submitring hash:%s, err:%s", ringSubmitInfo.Ringhash.Hex(), err.Error())
+		status = types.TX_STATUS_FAILED
+	}
+	return common.HexToHash(txHashStr), status, err
+}
+
+// submitBatch sends the rings that pass the simulation by one tx to the helper contract,
+// the result of each ring is resolved by the receipt in listenBatchReceipts.
+func (submitter *RingSubmitter) submitBatch(batch *RingBatch) {
+	infos := []*types.RingSubmitInfo{}
+	for _, ringState := range batch.Infos {
+		if err := submitter.simulate(ringState); nil != err {
+			submitter.saveSubmitInfo(ringState, types.NilHash, types.TX_STATUS_REJECTED, 1, err)
+		} else {
+			infos = append(infos, ringState)
+		}
+	}
+	if len(infos) == 0 {
+		return
+	} else if len(infos) == 1 {
+		txHash, status, err := submitter.sendRing(infos[0])
+		submitter.saveSubmitInfo(infos[0], txHash, status, 1, err)
+		return
+	}
+	batch.Infos = infos
 
+	status := types.TX_STATUS_PENDING
+	txHash := types.NilHash
+	callData, err := submitter.batcher.CallData(batch)
 	if nil == err {
+		gas, gasPrice := submitter.batcher.Gas(batch)
 		txHashStr := "0x"
-		txHashStr, err = ethaccessor.SignAndSendTransaction(ringSubmitInfo.Miner, ringSubmitInfo.ProtocolAddress, ringSubmitInfo.ProtocolGas, ringSubmitInfo.ProtocolGasPrice, nil, ringSubmitInfo.ProtocolData, false)
-		if nil != err {
-			log.Errorf("submitring hash:%s, err:%s", ringSubmitInfo.Ringhash.Hex(), err.Error())
-			status = types.TX_STATUS_FAILED
-		}
+		txHashStr, err = ethaccessor.SendTransactionWithGas(batch.Miner, submitter.batcher.HelperAddress(), gas, gasPrice, nil, callData)
 		txHash = common.HexToHash(txHashStr)
-	} else {
-		log.Errorf("submitring hash:%s, protocol:%s, err:%s", ringSubmitInfo.Ringhash.Hex(), ringSubmitInfo.ProtocolAddress.Hex(), err.Error())
+	}
+	if nil != err {
+		log.Errorf("submit batch of %d rings, err:%s", len(infos), err.Error())
 		status = types.TX_STATUS_FAILED
+	} else {
+		log.Infof("submit batch of %d rings, txhash:%s", len(infos), txHash.Hex())
+	}
+	for _, ringState := range infos {
+		submitter.saveSubmitInfo(ringState, txHash, status, len(infos), err)
 	}
+}
 
-	return txHash, status, err
+func (submitter *RingSubmitter) listenBatchReceipts() {
+	processBatchReceipts := func() {
+		txHashes, err := submitter.dbService.GetPendingBatchTxHashes(100)
+		if nil != err {
+			log.Errorf("err:%s", err.Error())
+			return
+		}
+		for _, txHashStr := range txHashes {
+			var receipt ethaccessor.TransactionReceipt
+			if err := ethaccessor.GetTransactionReceipt(&receipt, txHashStr, "latest"); nil != err {
+				log.Errorf("err:%s", err.Error())
+				continue
+			}
+			// not mined yet
+			if "" == receipt.TransactionHash || "" == receipt.BlockHash {
+				continue
+			}
+			txHash := common.HexToHash(txHashStr)
+			infos, err := submitter.dbService.GetRingHashesByTxHash(txHash)
+			if nil != err {
+				log.Errorf("err:%s", err.Error())
+				continue
+			}
+			for _, result := range submitter.batcher.Resolve(&receipt, infos) {
+				submitter.submitResult(result.RingHash, result.UniqueId, txHash, result.Status, result.RingIndex, receipt.BlockNumber.BigInt(), result.UsedGas, result.Err)
+			}
+		}
+	}
+	stopChan := make(chan bool)
+	go func() {
+		for {
+			select {
+			case <-time.After(5 * time.Second):
+				processBatchReceipts()
+			case <-stopChan:
+				return
+			}
+		}
+	}()
+	submitter.stopFuncs = append(submitter.stopFuncs, func() {
+		close(stopChan)
+	})
 }
 
 func (submitter *RingSubmitter) listenSubmitRingMethodEventFromMysql() {
@@ -256,10 +425,16 @@ func (submitter *RingSubmitter) listenSubmitRingMethodEventFromMysql() {
 				if lastId < daoEvt.ID {
 					lastId = daoEvt.ID
 				}
+				if nil != submitter.gasModel {
+					submitter.gasModel.Learn(&daoEvt)
+				}
 				evt := &types.RingMinedEvent{}
 				if err3 := daoEvt.ConvertUp(evt); nil == err3 {
 					if infos, err := submitter.dbService.GetRingHashesByTxHash(evt.TxHash); nil != err {
 						log.Errorf("err:%s", err.Error())
+					} else if len(infos) > 1 || (len(infos) == 1 && infos[0].BatchSize > 1) {
+						// rings of a batch are resolved by the receipt in listenBatchReceipts
+						continue
 					} else {
 						var err1 error
 						if nil != evt.Err {
@@ -285,15 +460,21 @@ func (submitter *RingSubmitter) listenSubmitRingMethodEventFromMysql() {
 
 		cache.Set(SubmitRingMethod_LastId, []byte(strconv.Itoa(lastId)), int64(0))
 	}
+	stopChan := make(chan bool)
 	go func() {
 		processSubmitRingMethod()
 		for {
 			select {
 			case <-time.After(5 * time.Second):
 				processSubmitRingMethod()
+			case <-stopChan:
+				return
 			}
 		}
 	}()
+	submitter.stopFuncs = append(submitter.stopFuncs, func() {
+		close(stopChan)
+	})
 
 }
 
@@ -355,6 +536,14 @@ func (submitter *RingSubmitter) submitResult(ringhash, uniqeId, txhash common.Ha
 	if err := submitter.dbService.UpdateRingSubmitInfoResult(resultEvt); nil != err {
 		log.Errorf("err:%s", err.Error())
 	}
+	if nil != blockNumber && blockNumber.Sign() > 0 {
+		switch status {
+		case types.TX_STATUS_SUCCESS:
+			metrics.Counter("relay_miner_ring_mined_total", "result", "success").Inc(1)
+		case types.TX_STATUS_FAILED:
+			metrics.Counter("relay_miner_ring_mined_total", "result", "failed").Inc(1)
+		}
+	}
 	eventemitter.Emit(eventemitter.Miner_RingSubmitResult, resultEvt)
 }
 
@@ -388,12 +577,6 @@ func (submitter *RingSubmitter) GenerateRingSubmitInfo(ringState *types.Ring) (*
 	ringSubmitInfo.Ringhash = ringState.Hash
 
 	protocolAbi := ethaccessor.ProtocolImplAbi()
-	if senderAddress, err := submitter.selectSenderAddress(); nil != err {
-		return ringSubmitInfo, err
-	} else {
-		ringSubmitInfo.Miner = senderAddress
-	}
-	//submitter.computeReceivedAndSelectMiner(ringSubmitInfo)
 	if protocolData, err := ethaccessor.GenerateSubmitRingMethodInputsData(ringState, submitter.feeReceipt, protocolAbi); nil != err {
 		return nil, err
 	} else {
@@ -402,9 +585,27 @@ func (submitter *RingSubmitter) GenerateRingSubmitInfo(ringState *types.Ring) (*
 	//if nil != err {
 	//	return nil, err
 	//}
+	if nil != submitter.gasModel {
+		submitter.gasModel.Predict(ringState)
+	}
 	//预先判断是否会提交成功
 	lastTime := ringSubmitInfo.RawRing.ValidSinceTime()
-	if submitter.currentBlockTime > 0 && lastTime <= submitter.currentBlockTime {
+	if submitter.estimatePending {
+		gas, _, err := ethaccessor.EstimateGas(ringSubmitInfo.ProtocolData, ringSubmitInfo.ProtocolAddress, "pending")
+		if nil != submitter.gasModel {
+			submitter.gasModel.PendingChecked(ringState, gas, err)
+		}
+		if nil != err {
+			log.Errorf("can't generate ring:%s, estimateGas on pending err:%s", ringState.Hash.Hex(), err.Error())
+			return nil, err
+		}
+		gasWithMargin := new(big.Rat).SetInt(gas)
+		gasWithMargin.Mul(gasWithMargin, submitter.estimateMargin)
+		minGas, _ := new(big.Int).SetString(gasWithMargin.FloatString(0), 10)
+		if nil == ringSubmitInfo.ProtocolGas || ringSubmitInfo.ProtocolGas.Cmp(minGas) < 0 {
+			ringSubmitInfo.ProtocolGas = minGas
+		}
+	} else if submitter.currentBlockTime > 0 && lastTime <= submitter.currentBlockTime {
 		var err error
 		_, _, err = ethaccessor.EstimateGas(ringSubmitInfo.ProtocolData, ringSubmitInfo.ProtocolAddress, "latest")
 		//ringSubmitInfo.ProtocolGas, ringSubmitInfo.ProtocolGasPrice, err = ethaccessor.EstimateGas(ringSubmitInfo.ProtocolData, protocolAddress, "latest")
@@ -417,11 +618,18 @@ func (submitter *RingSubmitter) GenerateRingSubmitInfo(ringState *types.Ring) (*
 	//if nil != err {
 	//	return nil, err
 	//}
-	if submitter.maxGasLimit.Sign() > 0 && ringSubmitInfo.ProtocolGas.Cmp(submitter.maxGasLimit) > 0 {
-		ringSubmitInfo.ProtocolGas.Set(submitter.maxGasLimit)
+	submitter.gasLimitMtx.RLock()
+	minGasLimit, maxGasLimit := submitter.minGasLimit, submitter.maxGasLimit
+	submitter.gasLimitMtx.RUnlock()
+	if maxGasLimit.Sign() > 0 && ringSubmitInfo.ProtocolGas.Cmp(maxGasLimit) > 0 {
+		ringSubmitInfo.ProtocolGas.Set(maxGasLimit)
+	}
+	if minGasLimit.Sign() > 0 && ringSubmitInfo.ProtocolGas.Cmp(minGasLimit) < 0 {
+		ringSubmitInfo.ProtocolGas.Set(minGasLimit)
 	}
-	if submitter.minGasLimit.Sign() > 0 && ringSubmitInfo.ProtocolGas.Cmp(submitter.minGasLimit) < 0 {
-		ringSubmitInfo.ProtocolGas.Set(submitter.minGasLimit)
+	// the gas price of percent miners depends on the gas limit
+	if err := submitter.selectSender(ringSubmitInfo); nil != err {
+		return ringSubmitInfo, err
 	}
 	return ringSubmitInfo, nil
 }
@@ -430,25 +638,42 @@ func (submitter *RingSubmitter) stop() {
 	for _, stop := range submitter.stopFuncs {
 		stop()
 	}
+	if nil != submitter.ledger {
+		submitter.ledger.stop()
+	}
+	if nil != submitter.balanceGuard {
+		submitter.balanceGuard.stop()
+	}
 }
 
 func (submitter *RingSubmitter) start() {
 	submitter.listenNewRings()
 	submitter.listenSubmitRingMethodEventFromMysql()
 	submitter.listenBlockNew()
+	if nil != submitter.batcher {
+		submitter.listenBatchReceipts()
+	}
+	if nil != submitter.ledger {
+		submitter.ledger.start()
+	}
+	if nil != submitter.balanceGuard {
+		submitter.balanceGuard.start()
+	}
 	//submitter.listenSubmitRingMethodEvent()
 }
 
 func (submitter *RingSubmitter) availableSenderAddresses() []*NormalSenderAddress {
 	senderAddresses := []*NormalSenderAddress{}
 	for _, minerAddress := range submitter.normalMinerAddresses {
+		if submitter.senderUnavailable(minerAddress.Address) {
+			continue
+		}
 		var blockedTxCount, txCount types.Big
 		//todo:change it by event
 		ethaccessor.GetTransactionCount(&blockedTxCount, minerAddress.Address, "latest")
 		ethaccessor.GetTransactionCount(&txCount, minerAddress.Address, "pending")
 		//submitter.Accessor.Call("latest", &blockedTxCount, "eth_getTransactionCount", minerAddress.Address.Hex(), "latest")
 		//submitter.Accessor.Call("latest", &txCount, "eth_getTransactionCount", minerAddress.Address.Hex(), "pending")
-		//todo:check ethbalance
 		pendingCount := big.NewInt(int64(0))
 		pendingCount.Sub(txCount.BigInt(), blockedTxCount.BigInt())
 		if pendingCount.Int64() <= minerAddress.MaxPendingCount {
@@ -457,108 +682,71 @@ func (submitter *RingSubmitter) availableSenderAddresses() []*NormalSenderAddres
 	}
 
 	if len(senderAddresses) <= 0 {
-		senderAddresses = append(senderAddresses, submitter.normalMinerAddresses[0])
+		for _, minerAddress := range submitter.normalMinerAddresses {
+			if !submitter.senderUnavailable(minerAddress.Address) {
+				senderAddresses = append(senderAddresses, minerAddress)
+				break
+			}
+		}
 	}
 	return senderAddresses
 }
 
-func (submitter *RingSubmitter) selectSenderAddress() (common.Address, error) {
-	senderAddresses := submitter.availableSenderAddresses()
-	if len(senderAddresses) <= 0 {
-		return types.NilAddress, errors.New("there isn't an available sender address")
-	} else {
-		return senderAddresses[0].Address, nil
+// senderUnavailable returns true if the balance of sender is low or it's locked and can't sign
+func (submitter *RingSubmitter) senderUnavailable(address common.Address) bool {
+	return (nil != submitter.balanceGuard && submitter.balanceGuard.SenderLow(address)) || !crypto.CanSign(address)
+}
+
+// Paused returns true when all the sender addresses are excluded for the low balances or being locked, the matcher should stop matching
+func (submitter *RingSubmitter) Paused() bool {
+	for _, minerAddress := range submitter.normalMinerAddresses {
+		if !submitter.senderUnavailable(minerAddress.Address) {
+			return false
+		}
 	}
+	return len(submitter.availablePercentMiners()) == 0
 }
 
-//func (submitter *RingSubmitter) computeReceivedAndSelectMiner(ringSubmitInfo *types.RingSubmitInfo) error {
-//	ringState := ringSubmitInfo.RawRing
-//	ringState.LegalFee = new(big.Rat).SetInt(big.NewInt(int64(0)))
-//	ethPrice, _ := submitter.marketCapProvider.GetEthCap()
-//	ethPrice = ethPrice.Quo(ethPrice, new(big.Rat).SetInt(util.AllTokens["WETH"].Decimals))
-//	lrcAddress := ethaccessor.ProtocolAddresses()[ringSubmitInfo.ProtocolAddress].LrcTokenAddress
-//	spenderAddress := ethaccessor.ProtocolAddresses()[ringSubmitInfo.ProtocolAddress].DelegateAddress
-//	useSplit := false
-//	//for _,splitMiner := range submitter.splitMinerAddresses {
-//	//	//todo:optimize it
-//	//	if lrcFee > splitMiner.StartFee || splitFee > splitMiner.StartFee || len(submitter.normalMinerAddresses) <= 0  {
-//	//		useSplit = true
-//	//		ringState.Miner = splitMiner.Address
-//	//		minerLrcBalance, _ := submitter.matcher.GetAccountAvailableAmount(splitMiner.Address, lrcAddress)
-//	//		//the lrcreward should be send to order.owner when miner selects MarginSplit as the selection of fee
-//	//		//be careful！！！ miner will received nothing, if miner set FeeSelection=1 and he doesn't have enough lrc
-//	//
-//	//
-//	//		if ringState.LrcLegalFee.Cmp(ringState.SplitLegalFee) < 0 && minerLrcBalance.Cmp(filledOrder.LrcFee) > 0 {
-//	//			filledOrder.FeeSelection = 1
-//	//			splitPer := new(big.Rat).SetInt64(int64(filledOrder.OrderState.RawOrder.MarginSplitPercentage))
-//	//			legalAmountOfSaving.Mul(legalAmountOfSaving, splitPer)
-//	//			filledOrder.LrcReward = legalAmountOfLrc
-//	//			legalAmountOfSaving.Sub(legalAmountOfSaving, legalAmountOfLrc)
-//	//			filledOrder.LegalFee = legalAmountOfSaving
-//	//
-//	//			minerLrcBalance.Sub(minerLrcBalance, filledOrder.LrcFee)
-//	//			//log.Debugf("Miner,lrcReward:%s  legalFee:%s", lrcReward.FloatString(10), filledOrder.LegalFee.FloatString(10))
-//	//		} else {
-//	//			filledOrder.FeeSelection = 0
-//	//			filledOrder.LegalFee = legalAmountOfLrc
-//	//		}
-//	//
-//	//		ringState.LegalFee.Add(ringState.LegalFee, filledOrder.LegalFee)
-//	//	}
-//	//}
-//	minerAddresses := submitter.availableSenderAddresses()
-//	if !useSplit {
-//		for _, normalMinerAddress := range minerAddresses {
-//			minerLrcBalance, _ := submitter.matcher.GetAccountAvailableAmount(normalMinerAddress.Address, lrcAddress, spenderAddress)
-//			legalFee := new(big.Rat).SetInt(big.NewInt(int64(0)))
-//			feeSelections := []uint8{}
-//			legalFees := []*big.Rat{}
-//			lrcRewards := []*big.Rat{}
-//			for _, filledOrder := range ringState.Orders {
-//				lrcFee := new(big.Rat).SetInt(big.NewInt(int64(2)))
-//				lrcFee.Mul(lrcFee, filledOrder.LegalLrcFee)
-//				log.Debugf("lrcFee:%s, filledOrder.LegalFeeS:%s, minerLrcBalance:%s, filledOrder.LrcFee:%s", lrcFee.FloatString(3), filledOrder.LegalFeeS.FloatString(3), minerLrcBalance.FloatString(3), filledOrder.LrcFee.FloatString(3))
-//				if lrcFee.Cmp(filledOrder.LegalFeeS) < 0 && minerLrcBalance.Cmp(filledOrder.LrcFee) > 0 {
-//					feeSelections = append(feeSelections, 1)
-//					fee := new(big.Rat).Set(filledOrder.LegalFeeS)
-//					fee.Sub(fee, filledOrder.LegalLrcFee)
-//					legalFees = append(legalFees, fee)
-//					lrcRewards = append(lrcRewards, filledOrder.LegalLrcFee)
-//					legalFee.Add(legalFee, fee)
-//
-//					minerLrcBalance.Sub(minerLrcBalance, filledOrder.LrcFee)
-//					//log.Debugf("Miner,lrcReward:%s  legalFee:%s", lrcReward.FloatString(10), filledOrder.LegalFee.FloatString(10))
-//				} else {
-//					feeSelections = append(feeSelections, 0)
-//					legalFees = append(legalFees, filledOrder.LegalLrcFee)
-//					lrcRewards = append(lrcRewards, new(big.Rat).SetInt(big.NewInt(int64(0))))
-//					legalFee.Add(legalFee, filledOrder.LegalLrcFee)
-//				}
-//			}
-//
-//			if ringState.LegalFee.Sign() == 0 || ringState.LegalFee.Cmp(legalFee) < 0 {
-//				ringState.LegalFee = legalFee
-//				ringSubmitInfo.Miner = normalMinerAddress.Address
-//				for idx, filledOrder := range ringState.Orders {
-//					filledOrder.FeeSelection = feeSelections[idx]
-//					filledOrder.LegalFee = legalFees[idx]
-//					filledOrder.LrcReward = lrcRewards[idx]
-//				}
-//
-//				if nil == ringSubmitInfo.ProtocolGasPrice || ringSubmitInfo.ProtocolGasPrice.Cmp(normalMinerAddress.GasPriceLimit) > 0 {
-//					ringSubmitInfo.ProtocolGasPrice = normalMinerAddress.GasPriceLimit
-//				}
-//			}
-//		}
-//	}
-//	//protocolCost := new(big.Int).Mul(ringSubmitInfo.ProtocolGas, ringSubmitInfo.ProtocolGasPrice)
-//	//
-//	//costEth := new(big.Rat).SetInt(protocolCost)
-//	//costLegal, _ := submitter.marketCapProvider.LegalCurrencyValueOfEth(costEth)
-//	//ringSubmitInfo.LegalCost = costLegal
-//	//received := new(big.Rat).Sub(ringState.LegalFee, costLegal)
-//	//ringSubmitInfo.Received = received
-//
-//	return nil
-//}
+func (submitter *RingSubmitter) availablePercentMiners() []*SplitMinerAddress {
+	miners := []*SplitMinerAddress{}
+	for _, minerAddress := range submitter.percentMinerAddresses {
+		if !submitter.senderUnavailable(minerAddress.Address) {
+			miners = append(miners, minerAddress)
+		}
+	}
+	return miners
+}
+
+// selectSender sends rings of high fee by percent miners with the gas price of FeePercent of the legal fee,
+// others are sent by normal miners with the gas price not higher than GasPriceLimit.
+func (submitter *RingSubmitter) selectSender(ringSubmitInfo *types.RingSubmitInfo) error {
+	ringState := ringSubmitInfo.RawRing
+	normalMiners := submitter.availableSenderAddresses()
+	if percentMiner := SelectPercentMiner(submitter.availablePercentMiners(), ratToFloat(ringState.LegalFee), len(normalMiners) == 0); nil != percentMiner {
+		if ethPrice, err := submitter.marketCapProvider.GetEthCap(); nil != err {
+			log.Errorf("submitring hash:%s, get eth price err:%s", ringState.Hash.Hex(), err.Error())
+		} else {
+			gasPrice := PercentGasPrice(percentMiner.FeePercent, ringState.LegalFee, ethPrice, ringSubmitInfo.ProtocolGas)
+			// a percent miner is only used when it pays more than the normal gas price
+			if len(normalMiners) == 0 || nil == ringSubmitInfo.ProtocolGasPrice || gasPrice.Cmp(ringSubmitInfo.ProtocolGasPrice) > 0 {
+				ringSubmitInfo.Miner = percentMiner.Address
+				if nil == ringSubmitInfo.ProtocolGasPrice || gasPrice.Cmp(ringSubmitInfo.ProtocolGasPrice) > 0 {
+					ringSubmitInfo.ProtocolGasPrice = gasPrice
+				}
+				log.Debugf("submitring hash:%s, percent miner:%s, legalFee:%f, gasPrice:%s", ringState.Hash.Hex(), percentMiner.Address.Hex(), ratToFloat(ringState.LegalFee), ringSubmitInfo.ProtocolGasPrice.String())
+				return nil
+			}
+		}
+	}
+
+	if len(normalMiners) <= 0 {
+		return errors.New("there isn't an available sender address")
+	}
+	normalMiner := normalMiners[0]
+	ringSubmitInfo.Miner = normalMiner.Address
+	if nil != normalMiner.GasPriceLimit && normalMiner.GasPriceLimit.Sign() > 0 &&
+		(nil == ringSubmitInfo.ProtocolGasPrice || ringSubmitInfo.ProtocolGasPrice.Cmp(normalMiner.GasPriceLimit) > 0) {
+		ringSubmitInfo.ProtocolGasPrice = new(big.Int).Set(normalMiner.GasPriceLimit)
+	}
+	return nil
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/Loopring/relay/ethaccessor"
 	"github.com/Loopring/relay/eventemiter"
 	"github.com/Loopring/relay/log"
+	"github.com/Loopring/relay/metrics"
 	"github.com/Loopring/relay/types"
 	"math/big"
 	"sync"
@@ -86,7 +87,10 @@ func (matcher *TimingMatcher) listenOrderReady() {
 			var block *dao.Block
 			if block, err = matcher.db.FindLatestBlock(); nil == err {
 				log.Debugf("listenOrderReadylistenOrderReadylistenOrderReady, %t, %d, %d", matcher.isOrdersReady, block.BlockNumber, ethBlockNumber.Int64())
-				if ethBlockNumber.Int64() > (block.BlockNumber + matcher.lagBlocks) {
+				matcher.mtx.RLock()
+				lagBlocks := matcher.lagBlocks
+				matcher.mtx.RUnlock()
+				if ethBlockNumber.Int64() > (block.BlockNumber + lagBlocks) {
 					matcher.isOrdersReady = false
 				} else {
 					matcher.isOrdersReady = true
@@ -118,6 +122,12 @@ func (matcher *TimingMatcher) listenOrderReady() {
 	})
 }
 
+func (matcher *TimingMatcher) roundDuration() time.Duration {
+	matcher.mtx.RLock()
+	defer matcher.mtx.RUnlock()
+	return time.Duration(matcher.duration.Int64()) * time.Millisecond
+}
+
 func (matcher *TimingMatcher) listenTimingRound() {
 	stopChan := make(chan bool)
 
@@ -125,8 +135,25 @@ func (matcher *TimingMatcher) listenTimingRound() {
 		if !matcher.isOrdersReady {
 			return
 		}
+		matcher.mtx.RLock()
+		defer matcher.mtx.RUnlock()
+		defer metrics.Timer("relay_matcher_round_duration_seconds").UpdateSince(time.Now())
 		//if ethaccessor.Synced() {
+		matcher.roundMtx.Lock()
 		matcher.lastRoundNumber = big.NewInt(time.Now().UnixNano() / 1e6)
+		roundNumber := matcher.lastRoundNumber.Int64()
+		matcher.roundMtx.Unlock()
+		if paused := matcher.submitter.Paused(); paused != matcher.paused {
+			matcher.paused = paused
+			if paused {
+				log.Warnf("matching is paused, there isn't a sender address with enough balance")
+			} else {
+				log.Infof("matching is resumed")
+			}
+		}
+		if matcher.paused {
+			return
+		}
 		//matcher.rounds.appendNewRoundState(matcher.lastRoundNumber)
 		var wg sync.WaitGroup
 		for _, market := range matcher.markets {
@@ -139,13 +166,14 @@ func (matcher *TimingMatcher) listenTimingRound() {
 			}(market)
 		}
 		wg.Wait()
+		matcher.markEvaluatedOrders(roundNumber)
 		//}
 	}
 	go func() {
 		matchFunc()
 		for {
 			select {
-			case <-time.After(time.Duration(matcher.duration.Int64()) * time.Millisecond):
+			case <-time.After(matcher.roundDuration()):
 				matchFunc()
 			case <-stopChan:
 				return
@@ -164,8 +192,11 @@ func (matcher *TimingMatcher) listenSubmitEvent() {
 	go func() {
 		for {
 			select {
-			case minedEvent := <-submitEventChan:
-				if minedEvent.Status == types.TX_STATUS_FAILED || minedEvent.Status == types.TX_STATUS_SUCCESS || minedEvent.Status == types.TX_STATUS_UNKNOWN {
+			case minedEvent, ok := <-submitEventChan:
+				if !ok {
+					return
+				}
+				if minedEvent.Status == types.TX_STATUS_FAILED || minedEvent.Status == types.TX_STATUS_SUCCESS || minedEvent.Status == types.TX_STATUS_REJECTED || minedEvent.Status == types.TX_STATUS_UNKNOWN {
 					log.Debugf("received mined event, this round the related cache will be removed, ringhash:%s, status:%d", minedEvent.RingHash.Hex(), uint8(minedEvent.Status))
 					//matcher.rounds.RemoveMinedRing(minedEvent.RingHash)
 					if orderhashes, err := RemoveMinedRingAndReturnOrderhashes(minedEvent.RingHash); nil != err {
@@ -23,18 +23,19 @@ import (
 	"github.com/Loopring/relay/ethaccessor"
 	"github.com/Loopring/relay/eventemiter"
 	"github.com/Loopring/relay/log"
+	"github.com/Loopring/relay/metrics"
 	"github.com/Loopring/relay/miner"
 	"github.com/Loopring/relay/ordermanager"
 	"github.com/Loopring/relay/types"
 	"github.com/ethereum/go-ethereum/common"
 	"math/big"
-	"sort"
 )
 
 type Market struct {
 	matcher      *TimingMatcher
 	om           ordermanager.OrderManager
 	protocolImpl *ethaccessor.ProtocolAddress
+	selector     RingSelector
 
 	TokenA     common.Address
 	TokenB     common.Address
@@ -49,30 +50,73 @@ func (market *Market) match() {
 	market.getOrdersForMatching(market.protocolImpl.DelegateAddress)
 	matchedOrderHashes := make(map[common.Hash]bool) //true:fullfilled, false:partfilled
 	ringSubmitInfos := []*types.RingSubmitInfo{}
+	totalReceived := new(big.Rat)
 	candidateRingList := CandidateRingList{}
+	recorder := newMatchingStatusRecorder(market)
+	for orderHash := range market.AtoBOrders {
+		if len(market.BtoAOrders) == 0 {
+			recorder.skip(orderHash, matchingStageFilter, ordermanager.MATCHING_SKIP_NO_COUNTER_ORDER, "")
+		}
+	}
+	for orderHash := range market.BtoAOrders {
+		if len(market.AtoBOrders) == 0 {
+			recorder.skip(orderHash, matchingStageFilter, ordermanager.MATCHING_SKIP_NO_COUNTER_ORDER, "")
+		}
+	}
+
+	cancelledOrders := make(map[common.Hash]bool)
 
 	//step 1: evaluate received
 	for _, a2BOrder := range market.AtoBOrders {
+		if cancelledOrders[a2BOrder.RawOrder.Hash] {
+			continue
+		}
 		if failedCount, err1 := OrderExecuteFailedCount(a2BOrder.RawOrder.Hash); nil == err1 && failedCount > market.matcher.maxFailedCount {
 			log.Debugf("orderhash:%s has been failed to submit %d times", a2BOrder.RawOrder.Hash.Hex(), failedCount)
-
+			recorder.skip(a2BOrder.RawOrder.Hash, matchingStageFilter, ordermanager.MATCHING_SKIP_FAILED_COUNT, fmt.Sprintf("failed %d times", failedCount))
 			continue
 		}
 		for _, b2AOrder := range market.BtoAOrders {
+			if cancelledOrders[a2BOrder.RawOrder.Hash] {
+				break
+			}
+			if cancelledOrders[b2AOrder.RawOrder.Hash] {
+				continue
+			}
 			if failedCount, err1 := OrderExecuteFailedCount(b2AOrder.RawOrder.Hash); nil == err1 && failedCount > market.matcher.maxFailedCount {
 				log.Debugf("orderhash:%s has been failed to submit %d times", b2AOrder.RawOrder.Hash.Hex(), failedCount)
+				recorder.skip(b2AOrder.RawOrder.Hash, matchingStageFilter, ordermanager.MATCHING_SKIP_FAILED_COUNT, fmt.Sprintf("failed %d times", failedCount))
 				continue
 			}
-			//todo:move a2BOrder.RawOrder.Owner != b2AOrder.RawOrder.Owner after contract fix bug
-			if miner.PriceValid(a2BOrder, b2AOrder) && a2BOrder.RawOrder.Owner != b2AOrder.RawOrder.Owner {
+			orderHashes := []common.Hash{a2BOrder.RawOrder.Hash, b2AOrder.RawOrder.Hash}
+			if !miner.PriceValid(a2BOrder, b2AOrder) {
+				recorder.skip(a2BOrder.RawOrder.Hash, matchingStagePair, ordermanager.MATCHING_SKIP_PRICE_MISMATCH, "counter order:"+b2AOrder.RawOrder.Hash.Hex())
+				recorder.skip(b2AOrder.RawOrder.Hash, matchingStagePair, ordermanager.MATCHING_SKIP_PRICE_MISMATCH, "counter order:"+a2BOrder.RawOrder.Hash.Hex())
+			} else if rule, _, _ := market.matcher.selfTradePreventer.Check([]*types.OrderState{a2BOrder, b2AOrder}); rule != "" {
+				cancelled := market.matcher.selfTradePreventer.Prevent(recorder.market, rule, a2BOrder, b2AOrder)
+				if !types.IsZeroHash(cancelled) {
+					cancelledOrders[cancelled] = true
+				}
+				recorder.skip(a2BOrder.RawOrder.Hash, matchingStagePair, ordermanager.MATCHING_SKIP_SELF_TRADE, rule+", counter order:"+b2AOrder.RawOrder.Hash.Hex())
+				recorder.skip(b2AOrder.RawOrder.Hash, matchingStagePair, ordermanager.MATCHING_SKIP_SELF_TRADE, rule+", counter order:"+a2BOrder.RawOrder.Hash.Hex())
+			} else {
 				if candidateRing, err := market.GenerateCandidateRing(a2BOrder, b2AOrder); nil != err {
 					log.Errorf("err:%s", err.Error())
+					for _, orderHash := range orderHashes {
+						recorder.skip(orderHash, matchingStageCandidate, evaluateSkipReason(err), err.Error())
+					}
 					continue
 				} else {
 					if candidateRing.received.Sign() > 0 {
 						candidateRingList = append(candidateRingList, *candidateRing)
+						for _, orderHash := range orderHashes {
+							recorder.skip(orderHash, matchingStageSelect, ordermanager.MATCHING_SKIP_NOT_SELECTED, "")
+						}
 					} else {
 						log.Debugf("timing_matchher, market ringForSubmit received not enough, received:%s, cost:%s ", candidateRing.received.FloatString(0), candidateRing.cost.FloatString(0))
+						for _, orderHash := range orderHashes {
+							recorder.skip(orderHash, matchingStageCandidate, ordermanager.MATCHING_SKIP_RECEIVED, fmt.Sprintf("received:%s, cost:%s", candidateRing.received.FloatString(2), candidateRing.cost.FloatString(2)))
+						}
 					}
 				}
 			}
@@ -80,17 +124,22 @@ func (market *Market) match() {
 	}
 
 	log.Debugf("match round:%s, market: %s -> %s , candidateRingList.length:%d", market.matcher.lastRoundNumber, market.TokenA.Hex(), market.TokenB.Hex(), len(candidateRingList))
-	//the ring that can get max received
+	metrics.Counter("relay_matcher_candidate_rings_total").Inc(int64(len(candidateRingList)))
 	list := candidateRingList
 	for {
 		if len(list) <= 0 {
 			break
 		}
 
-		sort.Sort(list)
+		list = market.selector.Select(list)
 		candidateRing := list[0]
 		list = list[1:]
 		orders := []*types.OrderState{}
+		skip := func(reason, detail string) {
+			for hash := range candidateRing.filledOrders {
+				recorder.skip(hash, matchingStageSubmit, reason, detail)
+			}
+		}
 		for hash, _ := range candidateRing.filledOrders {
 			if o, exists := market.AtoBOrders[hash]; exists {
 				orders = append(orders, o)
@@ -100,6 +149,7 @@ func (market *Market) match() {
 		}
 		if ringForSubmit, err := market.generateRingSubmitInfo(orders...); nil != err {
 			log.Debugf("generate RingSubmitInfo err:%s", err.Error())
+			skip(evaluateSkipReason(err), err.Error())
 			continue
 		} else {
 
@@ -108,6 +158,7 @@ func (market *Market) match() {
 					log.Error(err.Error())
 				} else {
 					log.Errorf("ringhash:%s has been submitted", ringForSubmit.Ringhash.Hex())
+					skip(ordermanager.MATCHING_SKIP_RING_SUBMITTED, "ringhash:"+ringForSubmit.Ringhash.Hex())
 				}
 				continue
 			}
@@ -115,6 +166,12 @@ func (market *Market) match() {
 			uniqueId := ringForSubmit.RawRing.GenerateUniqueId()
 			if failedCount, err := RingExecuteFailedCount(uniqueId); nil == err && failedCount > market.matcher.maxFailedCount {
 				log.Debugf("ringSubmitInfo.UniqueId:%s , ringhash: %s , has been failed to submit %d times", uniqueId.Hex(), ringForSubmit.Ringhash.Hex(), failedCount)
+				skip(ordermanager.MATCHING_SKIP_RING_FAILED_COUNT, fmt.Sprintf("ringhash:%s failed %d times", ringForSubmit.Ringhash.Hex(), failedCount))
+				continue
+			}
+			if reasonCode, err := miner.RingQuarantined(uniqueId); nil == err && "" != reasonCode {
+				log.Debugf("ringSubmitInfo.UniqueId:%s , ringhash: %s , is quarantined since simulation failed:%s", uniqueId.Hex(), ringForSubmit.Ringhash.Hex(), reasonCode)
+				skip(ordermanager.MATCHING_SKIP_RING_QUARANTINED, "simulation failed:"+reasonCode)
 				continue
 			}
 
@@ -124,14 +181,18 @@ func (market *Market) match() {
 					orderState := market.reduceAmountAfterFilled(filledOrder)
 					isFullFilled := market.om.IsOrderFullFinished(orderState)
 					matchedOrderHashes[filledOrder.OrderState.RawOrder.Hash] = isFullFilled
+					recorder.skip(filledOrder.OrderState.RawOrder.Hash, matchingStageMatched, ordermanager.MATCHING_MATCHED, "ringhash:"+ringForSubmit.Ringhash.Hex())
 					//market.matcher.rounds.AppendFilledOrderToCurrent(filledOrder, ringForSubmit.RawRing.Hash)
 
 					list = market.reduceReceivedOfCandidateRing(list, filledOrder, isFullFilled)
 				}
 				AddMinedRing(ringForSubmit)
 				ringSubmitInfos = append(ringSubmitInfos, ringForSubmit)
+				metrics.Counter("relay_matcher_matched_rings_total").Inc(1)
+				totalReceived.Add(totalReceived, ringForSubmit.RawRing.Received)
 			} else {
 				log.Debugf("ring:%s will not be submitted,because of received:%s", ringForSubmit.RawRing.Hash.Hex(), ringForSubmit.RawRing.Received.String())
+				skip(ordermanager.MATCHING_SKIP_RECEIVED, "received:"+ringForSubmit.RawRing.Received.FloatString(2))
 			}
 		}
 	}
@@ -154,6 +215,17 @@ func (market *Market) match() {
 		}
 	}
 
+	delayedToNumber := market.matcher.lastRoundNumber.Int64() + market.matcher.delayedNumber
+	for _, orderHash := range market.AtoBOrderHashesExcludeNextRound {
+		recorder.delay(orderHash, delayedToNumber)
+	}
+	for _, orderHash := range market.BtoAOrderHashesExcludeNextRound {
+		recorder.delay(orderHash, delayedToNumber)
+	}
+	recorder.flush()
+
+	log.Infof("match round:%s, market: %s -> %s , selector:%s, candidates:%d, rings:%d, received:%s", market.matcher.lastRoundNumber, market.TokenA.Hex(), market.TokenB.Hex(),
+		market.selector.Name(), len(candidateRingList), len(ringSubmitInfos), totalReceived.FloatString(2))
 	if len(ringSubmitInfos) > 0 {
 		eventemitter.Emit(eventemitter.Miner_NewRing, ringSubmitInfos)
 	}
@@ -205,23 +277,8 @@ func (market *Market) getOrdersForMatching(delegateAddress common.Address) {
 	market.BtoAOrders = make(map[common.Hash]*types.OrderState)
 
 	// log.Debugf("timing matcher,market tokenA:%s, tokenB:%s, atob hash length:%d, btoa hash length:%d", market.TokenA.Hex(), market.TokenB.Hex(), len(market.AtoBOrderHashesExcludeNextRound), len(market.BtoAOrderHashesExcludeNextRound))
-	currentRoundNumber := market.matcher.lastRoundNumber.Int64()
-	deleyedNumber := market.matcher.delayedNumber + currentRoundNumber
-
-	atoBOrders := market.om.MinerOrders(delegateAddress, market.TokenA, market.TokenB, market.matcher.roundOrderCount, market.matcher.reservedTime, int64(0), currentRoundNumber, &types.OrderDelayList{OrderHash: market.AtoBOrderHashesExcludeNextRound, DelayedCount: deleyedNumber})
-
-	if len(atoBOrders) < market.matcher.roundOrderCount {
-		orderCount := market.matcher.roundOrderCount - len(atoBOrders)
-		orders := market.om.MinerOrders(delegateAddress, market.TokenA, market.TokenB, orderCount, market.matcher.reservedTime, currentRoundNumber+1, currentRoundNumber+market.matcher.delayedNumber)
-		atoBOrders = append(atoBOrders, orders...)
-	}
-
-	btoAOrders := market.om.MinerOrders(delegateAddress, market.TokenB, market.TokenA, market.matcher.roundOrderCount, market.matcher.reservedTime, int64(0), currentRoundNumber, &types.OrderDelayList{OrderHash: market.BtoAOrderHashesExcludeNextRound, DelayedCount: deleyedNumber})
-	if len(btoAOrders) < market.matcher.roundOrderCount {
-		orderCount := market.matcher.roundOrderCount - len(btoAOrders)
-		orders := market.om.MinerOrders(delegateAddress, market.TokenB, market.TokenA, orderCount, market.matcher.reservedTime, currentRoundNumber+1, currentRoundNumber+market.matcher.delayedNumber)
-		btoAOrders = append(btoAOrders, orders...)
-	}
+	atoBOrders := market.minerOrders(delegateAddress, market.TokenA, market.TokenB, market.AtoBOrderHashesExcludeNextRound)
+	btoAOrders := market.minerOrders(delegateAddress, market.TokenB, market.TokenA, market.BtoAOrderHashesExcludeNextRound)
 
 	//log.Debugf("#### %s,%s %d,%d %d",market.TokenA.Hex(),market.TokenB.Hex(), len(atoBOrders), len(btoAOrders),market.matcher.roundOrderCount)
 	market.AtoBOrderHashesExcludeNextRound = []common.Hash{}
@@ -248,6 +305,61 @@ func (market *Market) getOrdersForMatching(delegateAddress common.Address) {
 	}
 }
 
+// minerOrders returns the orders of tokenS -> tokenB in this round.
+// The orders not evaluated in the last maxUnevaluatedRounds rounds go first, at most starvedOrdersRatio of the round,
+// then the ones ranked by ordermanager, and the delayed ones fill the rest.
+// The starved orders are queried after excludeHashes are delayed by the ranked query, so they are skipped as well.
+func (market *Market) minerOrders(delegateAddress, tokenS, tokenB common.Address, excludeHashes []common.Hash) []*types.OrderState {
+	currentRoundNumber := market.matcher.lastRoundNumber.Int64()
+	deleyedNumber := market.matcher.delayedNumber + currentRoundNumber
+	roundOrderCount := market.matcher.roundOrderCount
+
+	orders := []*types.OrderState{}
+	selected := make(map[common.Hash]bool)
+	appendOrders := func(list []*types.OrderState) {
+		for _, order := range list {
+			if len(orders) >= roundOrderCount {
+				return
+			}
+			if !selected[order.RawOrder.Hash] {
+				selected[order.RawOrder.Hash] = true
+				orders = append(orders, order)
+			}
+		}
+	}
+
+	ranked := market.om.MinerOrders(delegateAddress, tokenS, tokenB, roundOrderCount, market.matcher.reservedTime, int64(0), currentRoundNumber, &types.OrderDelayList{OrderHash: excludeHashes, DelayedCount: deleyedNumber})
+	if market.matcher.maxUnevaluatedRounds > 0 {
+		evaluatedBefore := currentRoundNumber - market.matcher.maxUnevaluatedRounds*market.matcher.duration.Int64()
+		appendOrders(market.om.StarvedMinerOrders(delegateAddress, tokenS, tokenB, starvedOrdersCount(roundOrderCount, market.matcher.starvedOrdersRatio), market.matcher.reservedTime, int64(0), currentRoundNumber, evaluatedBefore))
+	}
+	appendOrders(ranked)
+
+	if len(orders) < roundOrderCount {
+		orderCount := roundOrderCount - len(orders)
+		appendOrders(market.om.MinerOrders(delegateAddress, tokenS, tokenB, orderCount, market.matcher.reservedTime, currentRoundNumber+1, currentRoundNumber+market.matcher.delayedNumber))
+	}
+
+	if market.matcher.maxUnevaluatedRounds > 0 && len(orders) > 0 {
+		orderHashes := []common.Hash{}
+		for _, order := range orders {
+			orderHashes = append(orderHashes, order.RawOrder.Hash)
+		}
+		market.matcher.addEvaluatedOrders(orderHashes)
+	}
+
+	return orders
+}
+
+// starvedOrdersCount is at least 1, the starved orders can't be evaluated otherwise
+func starvedOrdersCount(roundOrderCount int, ratio float64) int {
+	count := int(float64(roundOrderCount) * ratio)
+	if count < 1 {
+		count = 1
+	}
+	return count
+}
+
 //sub the matched amount in new round.
 func (market *Market) reduceRemainedAmountBeforeMatch(orderState *types.OrderState) {
 	orderHash := orderState.RawOrder.Hash
@@ -301,6 +413,14 @@ func (market *Market) GenerateCandidateRing(orders ...*types.OrderState) (*Candi
 		for _, filledOrder := range ringTmp.Orders {
 			log.Debugf("match, orderhash:%s, filledOrder.FilledAmountS:%s", filledOrder.OrderState.RawOrder.Hash.Hex(), filledOrder.FillAmountS.FloatString(3))
 			candidateRing.filledOrders[filledOrder.OrderState.RawOrder.Hash] = filledOrder.FillAmountS
+			candidateRing.orderHashes = append(candidateRing.orderHashes, filledOrder.OrderState.RawOrder.Hash)
+			createTime := filledOrder.OrderState.RawOrder.CreateTime
+			if createTime <= 0 && nil != filledOrder.OrderState.RawOrder.ValidSince {
+				createTime = filledOrder.OrderState.RawOrder.ValidSince.Int64()
+			}
+			if candidateRing.createTime <= 0 || createTime < candidateRing.createTime {
+				candidateRing.createTime = createTime
+			}
 		}
 		return candidateRing, nil
 	}
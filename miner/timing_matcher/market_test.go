@@ -0,0 +1,139 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package timing_matcher
+
+import (
+	"github.com/Loopring/relay/ordermanager"
+	"github.com/Loopring/relay/types"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"testing"
+)
+
+// minerOrdersManager only serves the orders of a round, the other methods of OrderManager aren't called
+type minerOrdersManager struct {
+	ordermanager.OrderManager
+	starved      []*types.OrderState
+	ranked       []*types.OrderState
+	starvedLimit int
+	starvedRange [2]int64
+	delayed      bool
+	marked       [][]common.Hash
+}
+
+func (m *minerOrdersManager) StarvedMinerOrders(protocol, tokenS, tokenB common.Address, length int, reservedTime, startBlockNumber, endBlockNumber, evaluatedBefore int64) []*types.OrderState {
+	if !m.delayed {
+		panic("starved orders should be queried after the excluded orders are delayed")
+	}
+	m.starvedLimit = length
+	m.starvedRange = [2]int64{startBlockNumber, endBlockNumber}
+	if len(m.starved) > length {
+		return m.starved[:length]
+	}
+	return m.starved
+}
+
+func (m *minerOrdersManager) MinerOrders(protocol, tokenS, tokenB common.Address, length int, reservedTime, startBlockNumber, endBlockNumber int64, filterOrderHashLists ...*types.OrderDelayList) []*types.OrderState {
+	if startBlockNumber > 0 {
+		return []*types.OrderState{}
+	}
+	m.delayed = true
+	return m.ranked
+}
+
+func (m *minerOrdersManager) MarkMinerEvaluatedOrders(orderHashes []common.Hash, roundNumber int64) error {
+	m.marked = append(m.marked, orderHashes)
+	return nil
+}
+
+func minerOrderStates(hashes ...int64) []*types.OrderState {
+	list := []*types.OrderState{}
+	for _, hash := range hashes {
+		state := &types.OrderState{}
+		state.RawOrder.Hash = common.BigToHash(big.NewInt(hash))
+		list = append(list, state)
+	}
+	return list
+}
+
+func TestStarvedOrdersCount(t *testing.T) {
+	cases := []struct {
+		roundOrderCount int
+		ratio           float64
+		count           int
+	}{
+		{10, 0.5, 5},
+		{10, 1, 10},
+		{2, 0.3, 1},
+		{1, 0.5, 1},
+	}
+	for _, c := range cases {
+		if count := starvedOrdersCount(c.roundOrderCount, c.ratio); c.count != count {
+			t.Errorf("count of %d*%v is %d, expect %d", c.roundOrderCount, c.ratio, count, c.count)
+		}
+	}
+}
+
+func TestMarket_MinerOrders(t *testing.T) {
+	om := &minerOrdersManager{
+		starved: minerOrderStates(1, 2, 3, 4),
+		ranked:  minerOrderStates(2, 5, 6, 7),
+	}
+	matcher := &TimingMatcher{
+		om:                   om,
+		lastRoundNumber:      big.NewInt(100000),
+		duration:             big.NewInt(1000),
+		roundOrderCount:      4,
+		maxUnevaluatedRounds: 30,
+		starvedOrdersRatio:   0.5,
+	}
+	market := &Market{matcher: matcher, om: om}
+
+	// the starved orders take at most half of the round, the ranked ones fill the rest without duplicates
+	orders := market.minerOrders(common.Address{}, common.Address{}, common.Address{}, nil)
+	if om.starvedLimit != 2 {
+		t.Errorf("starved orders should be limited to 2, got %d", om.starvedLimit)
+	}
+	if om.starvedRange != [2]int64{0, 100000} {
+		t.Errorf("delayed orders shouldn't be starved orders, got range %v", om.starvedRange)
+	}
+	expects := []int64{1, 2, 5, 6}
+	if len(orders) != len(expects) {
+		t.Fatalf("should be %d orders, got %d", len(expects), len(orders))
+	}
+	for i, expect := range expects {
+		if hash := orders[i].RawOrder.Hash.Big().Int64(); expect != hash {
+			t.Errorf("order %d is %d, expect %d", i, hash, expect)
+		}
+	}
+
+	// the orders of both directions are marked in one update after the round
+	market.minerOrders(common.Address{}, common.Address{}, common.Address{}, nil)
+	if len(om.marked) != 0 {
+		t.Fatalf("orders shouldn't be marked before the round ends")
+	}
+	matcher.markEvaluatedOrders(100000)
+	if len(om.marked) != 1 || len(om.marked[0]) != 8 {
+		t.Fatalf("orders should be marked once, got %v", om.marked)
+	}
+	matcher.markEvaluatedOrders(101000)
+	if len(om.marked) != 1 {
+		t.Errorf("nothing should be marked without evaluated orders")
+	}
+}
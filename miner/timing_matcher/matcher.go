@@ -21,6 +21,7 @@ package timing_matcher
 import (
 	"github.com/Loopring/relay/miner"
 	"github.com/Loopring/relay/ordermanager"
+	"github.com/Loopring/relay/usermanager"
 	"github.com/ethereum/go-ethereum/common"
 	"math/big"
 
@@ -31,8 +32,11 @@ import (
 	marketLib "github.com/Loopring/relay/market"
 	marketUtilLib "github.com/Loopring/relay/market/util"
 	"strings"
+	"sync"
 )
 
+const defaultStarvedOrdersRatio = 0.5
+
 /**
 定时从ordermanager中拉取n条order数据进行匹配成环，如果成环则通过调用evaluator进行费用估计，然后提交到submitter进行提交到以太坊
 */
@@ -51,27 +55,69 @@ type TimingMatcher struct {
 
 	maxCacheRoundsLength int
 	delayedNumber        int64
+	maxUnevaluatedRounds int64
+	starvedOrdersRatio   float64
 	accountManager       *marketLib.AccountManager
 	isOrdersReady        bool
-	db                   dao.RdsService
+	// whether the last round was paused, only used by the rounds
+	paused             bool
+	db                 dao.RdsService
+	selfTradePreventer *SelfTradePreventer
+	om                 ordermanager.OrderManager
+
+	// held by the rounds for reading, Reload waits the running round
+	mtx sync.RWMutex
+	// guards lastRoundNumber for the readers out of the rounds
+	roundMtx sync.RWMutex
+	// orders evaluated by the markets in the running round, they are marked in one update after the round
+	evaluatedOrders    []common.Hash
+	evaluatedOrdersMtx sync.Mutex
 
 	stopFuncs []func()
 }
 
-func NewTimingMatcher(matcherOptions *config.TimingMatcher, submitter *miner.RingSubmitter, evaluator *miner.Evaluator, om ordermanager.OrderManager, accountManager *marketLib.AccountManager, rds dao.RdsService) *TimingMatcher {
+func NewTimingMatcher(matcherOptions *config.TimingMatcher, submitter *miner.RingSubmitter, evaluator *miner.Evaluator, om ordermanager.OrderManager, accountManager *marketLib.AccountManager, rds dao.RdsService) (*TimingMatcher, error) {
 	matcher := &TimingMatcher{}
 	matcher.submitter = submitter
 	matcher.evaluator = evaluator
 	matcher.accountManager = accountManager
-	matcher.roundOrderCount = matcherOptions.RoundOrdersCount
 	//matcher.rounds = NewRoundStates(matcherOptions.MaxCacheRoundsLength)
 	matcher.isOrdersReady = false
 	matcher.db = rds
+	matcher.om = om
+	selfTradePreventer, err := NewSelfTradePreventer(matcherOptions.SelfTradePrevention, om, rds)
+	if nil != err {
+		return nil, err
+	}
+	matcher.selfTradePreventer = selfTradePreventer
+
+	matcher.lastRoundNumber = big.NewInt(0)
+	matcher.stopFuncs = []func(){}
+
+	matcher.markets = []*Market{}
+	matcher.applyOptions(matcherOptions)
+	return matcher, nil
+}
+
+// Reload applies matcherOptions and rebuilds the markets from the token pairs of market/util,
+// it waits for the running round and the markets kept are reused
+func (matcher *TimingMatcher) Reload(matcherOptions *config.TimingMatcher) {
+	matcher.mtx.Lock()
+	defer matcher.mtx.Unlock()
+	matcher.applyOptions(matcherOptions)
+	if err := matcher.selfTradePreventer.SetOptions(matcherOptions.SelfTradePrevention); nil != err {
+		log.Errorf("timing matcher reload, self trade prevention isn't changed, err:%s", err.Error())
+	}
+	log.Infof("timing matcher reloaded, markets:%d, duration:%d", len(matcher.markets), matcher.duration.Int64())
+}
+
+func (matcher *TimingMatcher) applyOptions(matcherOptions *config.TimingMatcher) {
+	matcher.roundOrderCount = matcherOptions.RoundOrdersCount
 	matcher.lagBlocks = matcherOptions.LagForCleanSubmitCacheBlocks
 	if matcherOptions.ReservedSubmitTime > 0 {
 		matcher.reservedTime = matcherOptions.ReservedSubmitTime
 	} else {
-		matcherOptions.ReservedSubmitTime = 45
+		matcher.reservedTime = 45
 	}
 	if matcherOptions.MaxSumitFailedCount > 0 {
 		matcher.maxFailedCount = matcherOptions.MaxSumitFailedCount
@@ -79,16 +125,20 @@ func NewTimingMatcher(matcherOptions *config.TimingMatcher, submitter *miner.Rin
 		matcher.maxFailedCount = 3
 	}
 
-	matcher.markets = []*Market{}
 	matcher.duration = big.NewInt(matcherOptions.Duration)
 	matcher.delayedNumber = matcherOptions.DelayedNumber
+	matcher.maxUnevaluatedRounds = matcherOptions.MaxUnevaluatedRounds
+	if matcherOptions.StarvedOrdersRatio > 0 {
+		matcher.starvedOrdersRatio = matcherOptions.StarvedOrdersRatio
+	} else {
+		matcher.starvedOrdersRatio = defaultStarvedOrdersRatio
+	}
+	ordermanager.SetOrderMatchingStatusTtl(matcherOptions.MatchingStatusTtl)
 
-	matcher.lastRoundNumber = big.NewInt(0)
-	matcher.stopFuncs = []func(){}
-
-	for _, pair := range marketUtilLib.AllTokenPairs {
+	markets := []*Market{}
+	for _, pair := range marketUtilLib.AllTokenPairs() {
 		inited := false
-		for _, market := range matcher.markets {
+		for _, market := range markets {
 			if (market.TokenB == pair.TokenB && market.TokenA == pair.TokenS) ||
 				(market.TokenA == pair.TokenB && market.TokenB == pair.TokenS) {
 				inited = true
@@ -96,20 +146,75 @@ func NewTimingMatcher(matcherOptions *config.TimingMatcher, submitter *miner.Rin
 			}
 		}
 		if !inited {
+			selector := matcher.ringSelector(matcherOptions, pair.TokenS, pair.TokenB)
 			for _, protocolAddress := range ethaccessor.ProtocolAddresses() {
-				m := &Market{}
-				m.protocolImpl = protocolAddress
-				m.om = om
-				m.matcher = matcher
-				m.TokenA = pair.TokenS
-				m.TokenB = pair.TokenB
-				m.AtoBOrderHashesExcludeNextRound = []common.Hash{}
-				m.BtoAOrderHashesExcludeNextRound = []common.Hash{}
-				matcher.markets = append(matcher.markets, m)
+				m := matcher.market(protocolAddress, pair.TokenS, pair.TokenB)
+				if nil == m {
+					m = &Market{}
+					m.protocolImpl = protocolAddress
+					m.om = matcher.om
+					m.matcher = matcher
+					m.TokenA = pair.TokenS
+					m.TokenB = pair.TokenB
+					m.AtoBOrderHashesExcludeNextRound = []common.Hash{}
+					m.BtoAOrderHashesExcludeNextRound = []common.Hash{}
+				}
+				m.selector = selector
+				markets = append(markets, m)
 			}
 		}
 	}
-	return matcher
+	matcher.markets = markets
+}
+
+// market returns the existing market of the pair
+func (matcher *TimingMatcher) market(protocolAddress *ethaccessor.ProtocolAddress, tokenS, tokenB common.Address) *Market {
+	for _, m := range matcher.markets {
+		if m.protocolImpl == protocolAddress && m.TokenA == tokenS && m.TokenB == tokenB {
+			return m
+		}
+	}
+	return nil
+}
+
+// ringSelector returns the selector configured for the market, or the default one
+func (matcher *TimingMatcher) ringSelector(matcherOptions *config.TimingMatcher, tokenS, tokenB common.Address) RingSelector {
+	name := matcherOptions.RingSelector
+	if market, err := marketUtilLib.WrapMarketByAddress(tokenS.Hex(), tokenB.Hex()); nil == err {
+		if marketSelector, exists := matcherOptions.MarketRingSelectors[market]; exists {
+			name = marketSelector
+		}
+	}
+	selector, err := NewRingSelector(name)
+	if nil != err {
+		log.Errorf("err:%s, use %s instead", err.Error(), RING_SELECTOR_GREEDY)
+		selector = &GreedyRingSelector{}
+	}
+	return selector
+}
+
+func (matcher *TimingMatcher) addEvaluatedOrders(orderHashes []common.Hash) {
+	matcher.evaluatedOrdersMtx.Lock()
+	defer matcher.evaluatedOrdersMtx.Unlock()
+	matcher.evaluatedOrders = append(matcher.evaluatedOrders, orderHashes...)
+}
+
+// markEvaluatedOrders saves the round number of the orders evaluated in the round
+func (matcher *TimingMatcher) markEvaluatedOrders(roundNumber int64) {
+	matcher.evaluatedOrdersMtx.Lock()
+	orderHashes := matcher.evaluatedOrders
+	matcher.evaluatedOrders = nil
+	matcher.evaluatedOrdersMtx.Unlock()
+	if len(orderHashes) == 0 {
+		return
+	}
+	if err := matcher.om.MarkMinerEvaluatedOrders(orderHashes, roundNumber); nil != err {
+		log.Errorf("mark evaluated orders err:%s", err.Error())
+	}
+}
+
+func (matcher *TimingMatcher) SetUserManager(um usermanager.UserManager) {
+	matcher.selfTradePreventer.SetUserManager(um)
 }
 
 func (matcher *TimingMatcher) cleanMissedCache() {
@@ -151,9 +256,11 @@ func (matcher *TimingMatcher) Start() {
 	//})
 }
 
+// Stop stops in the reverse order of Start, the running round including the submission of its rings
+// is finished before the listener of submit results is removed
 func (matcher *TimingMatcher) Stop() {
-	for _, stop := range matcher.stopFuncs {
-		stop()
+	for i := len(matcher.stopFuncs) - 1; i >= 0; i-- {
+		matcher.stopFuncs[i]()
 	}
 }
 
@@ -175,3 +282,10 @@ func (matcher *TimingMatcher) GetAccountAvailableAmount(address, tokenAddress, s
 		return availableAmount, nil
 	}
 }
+
+// LastRoundNumber returns the number of the running or the last finished round, it's zero before the first round
+func (matcher *TimingMatcher) LastRoundNumber() int64 {
+	matcher.roundMtx.RLock()
+	defer matcher.roundMtx.RUnlock()
+	return matcher.lastRoundNumber.Int64()
+}
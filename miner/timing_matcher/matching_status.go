@@ -0,0 +1,103 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package timing_matcher
+
+import (
+	"github.com/Loopring/relay/log"
+	marketUtilLib "github.com/Loopring/relay/market/util"
+	"github.com/Loopring/relay/ordermanager"
+	"github.com/ethereum/go-ethereum/common"
+	"strings"
+)
+
+// the reason of a later stage overrides the earlier ones of an order in a round
+const (
+	matchingStageFilter = iota
+	matchingStagePair
+	matchingStageCandidate
+	matchingStageSelect
+	matchingStageSubmit
+	matchingStageMatched
+)
+
+type orderSkip struct {
+	stage  int
+	reason string
+	detail string
+}
+
+// matchingStatusRecorder collects the skip reason of each order in a round of a market,
+// they are saved by ordermanager after the round.
+type matchingStatusRecorder struct {
+	market      string
+	roundNumber int64
+	skips       map[common.Hash]orderSkip
+	delayed     map[common.Hash]int64
+}
+
+func newMatchingStatusRecorder(market *Market) *matchingStatusRecorder {
+	r := &matchingStatusRecorder{}
+	if m, err := marketUtilLib.WrapMarketByAddress(market.TokenA.Hex(), market.TokenB.Hex()); nil == err {
+		r.market = m
+	} else {
+		r.market = market.TokenA.Hex() + "-" + market.TokenB.Hex()
+	}
+	r.roundNumber = market.matcher.lastRoundNumber.Int64()
+	r.skips = make(map[common.Hash]orderSkip)
+	r.delayed = make(map[common.Hash]int64)
+	return r
+}
+
+func (r *matchingStatusRecorder) skip(orderHash common.Hash, stage int, reason, detail string) {
+	if s, exists := r.skips[orderHash]; exists && s.stage > stage {
+		return
+	}
+	r.skips[orderHash] = orderSkip{stage: stage, reason: reason, detail: detail}
+}
+
+func (r *matchingStatusRecorder) delay(orderHash common.Hash, toNumber int64) {
+	r.delayed[orderHash] = toNumber
+}
+
+func (r *matchingStatusRecorder) flush() {
+	for orderHash, s := range r.skips {
+		status := &ordermanager.OrderMatchingStatus{
+			OrderHash:       orderHash.Hex(),
+			Reason:          s.reason,
+			Detail:          s.detail,
+			Market:          r.market,
+			RoundNumber:     r.roundNumber,
+			DelayedToNumber: r.delayed[orderHash],
+		}
+		if err := ordermanager.SetOrderMatchingStatus(status); nil != err {
+			log.Errorf("save matching status of order:%s err:%s", orderHash.Hex(), err.Error())
+		}
+	}
+}
+
+// evaluateSkipReason classifies the error of generating a ring
+func evaluateSkipReason(err error) string {
+	msg := strings.ToLower(err.Error())
+	if strings.Contains(msg, "balance or allowance") {
+		return ordermanager.MATCHING_SKIP_BALANCE
+	} else if strings.Contains(msg, "cvs") {
+		return ordermanager.MATCHING_SKIP_CVS_THRESHOLD
+	}
+	return ordermanager.MATCHING_SKIP_EVALUATE_FAILED
+}
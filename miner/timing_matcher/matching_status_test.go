@@ -0,0 +1,123 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package timing_matcher
+
+import (
+	"errors"
+	"github.com/Loopring/relay/cache"
+	"github.com/Loopring/relay/ordermanager"
+	"github.com/ethereum/go-ethereum/common"
+	"testing"
+)
+
+type statusCache struct {
+	cache.Cache
+	values map[string][]byte
+}
+
+func (c *statusCache) Set(key string, value []byte, ttl int64) error {
+	c.values[key] = value
+	return nil
+}
+
+func (c *statusCache) Get(key string) ([]byte, error) {
+	return c.values[key], nil
+}
+
+func (c *statusCache) Exists(key string) (bool, error) {
+	_, ok := c.values[key]
+	return ok, nil
+}
+
+func newTestRecorder() *matchingStatusRecorder {
+	return &matchingStatusRecorder{
+		market:      "LRC-WETH",
+		roundNumber: 100,
+		skips:       make(map[common.Hash]orderSkip),
+		delayed:     make(map[common.Hash]int64),
+	}
+}
+
+func TestMatchingStatusRecorder_Skip(t *testing.T) {
+	r := newTestRecorder()
+	filtered := common.HexToHash("0x1")
+	selected := common.HexToHash("0x2")
+	matched := common.HexToHash("0x3")
+
+	r.skip(filtered, matchingStageFilter, ordermanager.MATCHING_SKIP_NO_COUNTER_ORDER, "")
+	r.skip(filtered, matchingStageFilter, ordermanager.MATCHING_SKIP_FAILED_COUNT, "failed 3 times")
+
+	// an earlier stage can't override the later one
+	r.skip(selected, matchingStagePair, ordermanager.MATCHING_SKIP_PRICE_MISMATCH, "")
+	r.skip(selected, matchingStageSelect, ordermanager.MATCHING_SKIP_NOT_SELECTED, "")
+	r.skip(selected, matchingStageCandidate, ordermanager.MATCHING_SKIP_RECEIVED, "")
+
+	r.skip(matched, matchingStageCandidate, ordermanager.MATCHING_SKIP_BALANCE, "")
+	r.skip(matched, matchingStageMatched, ordermanager.MATCHING_MATCHED, "ringhash:0x10")
+	r.skip(matched, matchingStageSubmit, ordermanager.MATCHING_SKIP_RING_SUBMITTED, "")
+
+	expects := map[common.Hash]string{
+		filtered: ordermanager.MATCHING_SKIP_FAILED_COUNT,
+		selected: ordermanager.MATCHING_SKIP_NOT_SELECTED,
+		matched:  ordermanager.MATCHING_MATCHED,
+	}
+	for hash, reason := range expects {
+		if s := r.skips[hash]; reason != s.reason {
+			t.Errorf("reason of %s is %s, expect %s", hash.Hex(), s.reason, reason)
+		}
+	}
+	if "ringhash:0x10" != r.skips[matched].detail {
+		t.Errorf("detail should be the one of the kept reason, got %s", r.skips[matched].detail)
+	}
+}
+
+func TestMatchingStatusRecorder_Flush(t *testing.T) {
+	cache.SetCache(&statusCache{values: make(map[string][]byte)})
+
+	r := newTestRecorder()
+	delayed := common.HexToHash("0x1")
+	r.skip(delayed, matchingStageCandidate, ordermanager.MATCHING_SKIP_BALANCE, "insufficient balance")
+	r.skip(delayed, matchingStageSubmit, ordermanager.MATCHING_SKIP_RING_FAILED_COUNT, "")
+	r.delay(delayed, 110)
+	r.flush()
+
+	status, err := ordermanager.GetOrderMatchingStatus(delayed)
+	if nil != err || nil == status {
+		t.Fatalf("status should be saved, err:%v", err)
+	}
+	if ordermanager.MATCHING_SKIP_RING_FAILED_COUNT != status.Reason || "LRC-WETH" != status.Market || 100 != status.RoundNumber || 110 != status.DelayedToNumber {
+		t.Errorf("unexpected status %+v", status)
+	}
+	if status, _ := ordermanager.GetOrderMatchingStatus(common.HexToHash("0x2")); nil != status {
+		t.Errorf("status of an order not recorded should be nil, got %+v", status)
+	}
+}
+
+func TestEvaluateSkipReason(t *testing.T) {
+	cases := map[string]string{
+		"owner:0x1 token:0x2 balance or allowance is zero": ordermanager.MATCHING_SKIP_BALANCE,
+		"Miner,cvs must less than RateRatioCVSThreshold":   ordermanager.MATCHING_SKIP_CVS_THRESHOLD,
+		"price can't be calculated":                        ordermanager.MATCHING_SKIP_EVALUATE_FAILED,
+	}
+	for msg, reason := range cases {
+		if r := evaluateSkipReason(errors.New(msg)); reason != r {
+			t.Errorf("reason of %s is %s, expect %s", msg, r, reason)
+		}
+	}
+}
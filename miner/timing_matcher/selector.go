@@ -0,0 +1,234 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package timing_matcher
+
+import (
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"math"
+	"sort"
+)
+
+const (
+	RING_SELECTOR_GREEDY    = "greedy"
+	RING_SELECTOR_MAX_TOTAL = "max_total"
+	RING_SELECTOR_FAIRNESS  = "fairness"
+)
+
+// RingSelector decides which candidate ring of a round is submitted next.
+// The first ring of the result is submitted, the others are reduced by its fill and passed to Select again.
+type RingSelector interface {
+	Name() string
+	Select(list CandidateRingList) CandidateRingList
+}
+
+func NewRingSelector(name string) (RingSelector, error) {
+	switch name {
+	case "", RING_SELECTOR_GREEDY:
+		return &GreedyRingSelector{}, nil
+	case RING_SELECTOR_MAX_TOTAL:
+		return &MaxTotalRingSelector{}, nil
+	case RING_SELECTOR_FAIRNESS:
+		return &FairnessRingSelector{}, nil
+	}
+	return nil, fmt.Errorf("unsupported ring selector:%s", name)
+}
+
+// GreedyRingSelector takes the ring that can get max received
+type GreedyRingSelector struct{}
+
+func (s *GreedyRingSelector) Name() string {
+	return RING_SELECTOR_GREEDY
+}
+
+func (s *GreedyRingSelector) Select(list CandidateRingList) CandidateRingList {
+	sort.Sort(list)
+	return list
+}
+
+// FairnessRingSelector takes the ring with the oldest order first, rings with orders of the same age are taken by received
+type FairnessRingSelector struct{}
+
+func (s *FairnessRingSelector) Name() string {
+	return RING_SELECTOR_FAIRNESS
+}
+
+func (s *FairnessRingSelector) Select(list CandidateRingList) CandidateRingList {
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].createTime != list[j].createTime {
+			return list[i].createTime < list[j].createTime
+		}
+		return list[i].received.Cmp(list[j].received) > 0
+	})
+	return list
+}
+
+// MaxTotalRingSelector selects the rings that maximise the total received of a round,
+// two rings sharing an order can't be selected both. The rings of a market are made of an
+// order of each side, so the selection is the max weight matching between the two sides.
+// Rings not selected are kept after the selected ones, they are still submitted if the
+// orders aren't fullfilled by the selected rings.
+type MaxTotalRingSelector struct{}
+
+func (s *MaxTotalRingSelector) Name() string {
+	return RING_SELECTOR_MAX_TOTAL
+}
+
+func (s *MaxTotalRingSelector) Select(list CandidateRingList) CandidateRingList {
+	sort.Sort(list)
+	if len(list) <= 1 {
+		return list
+	}
+
+	left := make(map[common.Hash]int)
+	right := make(map[common.Hash]int)
+	for _, ring := range list {
+		if len(ring.orderHashes) != 2 {
+			// only rings of two orders are matched by the timing matcher
+			return list
+		}
+		if _, exists := left[ring.orderHashes[0]]; !exists {
+			left[ring.orderHashes[0]] = len(left)
+		}
+		if _, exists := right[ring.orderHashes[1]]; !exists {
+			right[ring.orderHashes[1]] = len(right)
+		}
+	}
+
+	weights := make([][]float64, len(left))
+	rings := make([][]int, len(left))
+	for i := range weights {
+		weights[i] = make([]float64, len(right))
+		rings[i] = make([]int, len(right))
+		for j := range rings[i] {
+			rings[i][j] = -1
+		}
+	}
+	for idx, ring := range list {
+		i, j := left[ring.orderHashes[0]], right[ring.orderHashes[1]]
+		weights[i][j], _ = ring.received.Float64()
+		rings[i][j] = idx
+	}
+
+	selected := make(map[int]bool)
+	for i, j := range maxWeightMatching(weights) {
+		if j >= 0 && rings[i][j] >= 0 && weights[i][j] > 0 {
+			selected[rings[i][j]] = true
+		}
+	}
+
+	res := CandidateRingList{}
+	for idx, ring := range list {
+		if selected[idx] {
+			res = append(res, ring)
+		}
+	}
+	for idx, ring := range list {
+		if !selected[idx] {
+			res = append(res, ring)
+		}
+	}
+	return res
+}
+
+// maxWeightMatching returns the column matched to each row that maximises the total weight, -1 if not matched.
+// It is the hungarian algorithm on the negative weights.
+func maxWeightMatching(weights [][]float64) []int {
+	rows := len(weights)
+	if rows == 0 {
+		return []int{}
+	}
+	cols := len(weights[0])
+	transposed := rows > cols
+	n, m := rows, cols
+	if transposed {
+		n, m = cols, rows
+	}
+	cost := func(i, j int) float64 {
+		if transposed {
+			return -weights[j][i]
+		}
+		return -weights[i][j]
+	}
+
+	// 1-indexed, p[j] is the row matched to column j
+	u := make([]float64, n+1)
+	v := make([]float64, m+1)
+	p := make([]int, m+1)
+	way := make([]int, m+1)
+	for i := 1; i <= n; i++ {
+		p[0] = i
+		j0 := 0
+		minv := make([]float64, m+1)
+		used := make([]bool, m+1)
+		for j := range minv {
+			minv[j] = math.Inf(1)
+		}
+		for {
+			used[j0] = true
+			i0, delta, j1 := p[j0], math.Inf(1), 0
+			for j := 1; j <= m; j++ {
+				if used[j] {
+					continue
+				}
+				if cur := cost(i0-1, j-1) - u[i0] - v[j]; cur < minv[j] {
+					minv[j] = cur
+					way[j] = j0
+				}
+				if minv[j] < delta {
+					delta = minv[j]
+					j1 = j
+				}
+			}
+			for j := 0; j <= m; j++ {
+				if used[j] {
+					u[p[j]] += delta
+					v[j] -= delta
+				} else {
+					minv[j] -= delta
+				}
+			}
+			j0 = j1
+			if p[j0] == 0 {
+				break
+			}
+		}
+		for j0 != 0 {
+			j1 := way[j0]
+			p[j0] = p[j1]
+			j0 = j1
+		}
+	}
+
+	res := make([]int, rows)
+	for i := range res {
+		res[i] = -1
+	}
+	for j := 1; j <= m; j++ {
+		if p[j] == 0 {
+			continue
+		}
+		if transposed {
+			res[j-1] = p[j] - 1
+		} else {
+			res[p[j]-1] = j - 1
+		}
+	}
+	return res
+}
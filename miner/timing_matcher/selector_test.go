@@ -0,0 +1,98 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package timing_matcher
+
+import (
+	"github.com/Loopring/relay/config"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func candidate(a2B, b2A string, received, createTime int64) CandidateRing {
+	return CandidateRing{
+		orderHashes: []common.Hash{common.HexToHash(a2B), common.HexToHash(b2A)},
+		received:    big.NewRat(received, 1),
+		cost:        new(big.Rat),
+		createTime:  createTime,
+	}
+}
+
+func TestRingSelectors(t *testing.T) {
+	// greedy takes a1-b1 and then can't take a2 with any b, max total takes a1-b2 and a2-b1
+	list := CandidateRingList{
+		candidate("0xa1", "0xb1", 10, 300),
+		candidate("0xa1", "0xb2", 9, 300),
+		candidate("0xa2", "0xb1", 8, 100),
+	}
+
+	greedy := (&GreedyRingSelector{}).Select(append(CandidateRingList{}, list...))
+	if greedy[0].received.Cmp(big.NewRat(10, 1)) != 0 {
+		t.Errorf("greedy should select the max received first, got:%s", greedy[0].received.String())
+	}
+
+	maxTotal := (&MaxTotalRingSelector{}).Select(append(CandidateRingList{}, list...))
+	if maxTotal[0].received.Cmp(big.NewRat(9, 1)) != 0 || maxTotal[1].received.Cmp(big.NewRat(8, 1)) != 0 {
+		t.Errorf("max total should select a1-b2 and a2-b1 first, got:%s, %s", maxTotal[0].received.String(), maxTotal[1].received.String())
+	}
+
+	fairness := (&FairnessRingSelector{}).Select(append(CandidateRingList{}, list...))
+	if fairness[0].createTime != 100 {
+		t.Errorf("fairness should select the oldest order first, got:%d", fairness[0].createTime)
+	}
+}
+
+func TestMaxWeightMatching(t *testing.T) {
+	weights := [][]float64{
+		{10, 9},
+		{8, 0},
+		{7, 1},
+	}
+	res := maxWeightMatching(weights)
+	total := float64(0)
+	for i, j := range res {
+		if j >= 0 {
+			total += weights[i][j]
+		}
+	}
+	if total != 17 {
+		t.Errorf("total weight should be 17, got:%f, matching:%v", total, res)
+	}
+}
+
+// config validates the names by its own lists, they should be the same as the supported ones
+func TestConfigNames(t *testing.T) {
+	if selectors := []string{RING_SELECTOR_GREEDY, RING_SELECTOR_MAX_TOTAL, RING_SELECTOR_FAIRNESS}; !reflect.DeepEqual(selectors, config.RingSelectors) {
+		t.Errorf("ring selectors of config are %v, expect %v", config.RingSelectors, selectors)
+	}
+	for _, name := range config.RingSelectors {
+		if _, err := NewRingSelector(name); nil != err {
+			t.Errorf("%s should be supported, err:%s", name, err.Error())
+		}
+	}
+	if modes := []string{SELF_TRADE_MODE_SKIP, SELF_TRADE_MODE_CANCEL_NEWER}; !reflect.DeepEqual(modes, config.SelfTradeModes) {
+		t.Errorf("self trade modes of config are %v, expect %v", config.SelfTradeModes, modes)
+	}
+	for _, mode := range config.SelfTradeModes {
+		if err := ValidateSelfTradeMode(mode); nil != err {
+			t.Errorf("%s should be supported, err:%s", mode, err.Error())
+		}
+	}
+}
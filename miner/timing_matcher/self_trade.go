@@ -0,0 +1,165 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package timing_matcher
+
+import (
+	"fmt"
+	"github.com/Loopring/relay/config"
+	"github.com/Loopring/relay/dao"
+	"github.com/Loopring/relay/log"
+	"github.com/Loopring/relay/ordermanager"
+	"github.com/Loopring/relay/types"
+	"github.com/Loopring/relay/usermanager"
+	"github.com/ethereum/go-ethereum/common"
+	gocache "github.com/patrickmn/go-cache"
+	"time"
+)
+
+const (
+	SELF_TRADE_MODE_SKIP         = "skip"
+	SELF_TRADE_MODE_CANCEL_NEWER = "cancel_newer"
+
+	SELF_TRADE_RULE_SAME_OWNER  = "same_owner"
+	SELF_TRADE_RULE_SAME_AUTH   = "same_auth"
+	SELF_TRADE_RULE_OWNER_GROUP = "owner_group"
+)
+
+// SelfTradePreventer prevents orders of the same party from matching each other
+type SelfTradePreventer struct {
+	options  config.SelfTradePreventionOptions
+	um       usermanager.UserManager
+	om       ordermanager.OrderManager
+	rds      dao.RdsService
+	recorded *gocache.Cache
+}
+
+func NewSelfTradePreventer(options config.SelfTradePreventionOptions, om ordermanager.OrderManager, rds dao.RdsService) (*SelfTradePreventer, error) {
+	p := &SelfTradePreventer{}
+	if err := p.SetOptions(options); nil != err {
+		return nil, err
+	}
+	p.om = om
+	p.rds = rds
+	//a pair skipped every round is recorded once an hour
+	p.recorded = gocache.New(time.Hour, 10*time.Minute)
+	return p, nil
+}
+
+// ValidateSelfTradeMode returns an error if the mode isn't supported, empty is the same as skip
+func ValidateSelfTradeMode(mode string) error {
+	switch mode {
+	case "", SELF_TRADE_MODE_SKIP, SELF_TRADE_MODE_CANCEL_NEWER:
+		return nil
+	}
+	return fmt.Errorf("unsupported self trade prevention mode:%s", mode)
+}
+
+// SetOptions applies the options, they are kept unchanged if the mode isn't supported
+func (p *SelfTradePreventer) SetOptions(options config.SelfTradePreventionOptions) error {
+	if err := ValidateSelfTradeMode(options.Mode); nil != err {
+		return err
+	}
+	p.options = options
+	if "" == p.options.Mode {
+		p.options.Mode = SELF_TRADE_MODE_SKIP
+	}
+	return nil
+}
+
+func (p *SelfTradePreventer) SetUserManager(um usermanager.UserManager) {
+	p.um = um
+}
+
+// Rule returns the rule that prevents the two orders from matching, empty if they can match
+func (p *SelfTradePreventer) Rule(a, b *types.Order) string {
+	if a.Owner == b.Owner {
+		return SELF_TRADE_RULE_SAME_OWNER
+	}
+	if p.options.SameAuth && a.AuthAddr == b.AuthAddr && !types.IsZeroAddress(a.AuthAddr) {
+		return SELF_TRADE_RULE_SAME_AUTH
+	}
+	if p.options.OwnerGroup && nil != p.um {
+		groupA, okA := p.um.OwnerGroup(a.Owner)
+		groupB, okB := p.um.OwnerGroup(b.Owner)
+		if okA && okB && groupA == groupB {
+			return SELF_TRADE_RULE_OWNER_GROUP
+		}
+	}
+	return ""
+}
+
+// Check returns the rule and the first pair of orders that can't be in a ring together
+func (p *SelfTradePreventer) Check(orders []*types.OrderState) (rule string, a, b *types.OrderState) {
+	for i := 0; i < len(orders); i++ {
+		for j := i + 1; j < len(orders); j++ {
+			if rule = p.Rule(&orders[i].RawOrder, &orders[j].RawOrder); rule != "" {
+				return rule, orders[i], orders[j]
+			}
+		}
+	}
+	return "", nil, nil
+}
+
+// Prevent records the prevented match, and cancels the newer order in mode cancel_newer.
+// It returns the hash of the cancelled order.
+func (p *SelfTradePreventer) Prevent(market, rule string, a, b *types.OrderState) (cancelled common.Hash) {
+	if p.options.Mode == SELF_TRADE_MODE_CANCEL_NEWER {
+		newer := a
+		if isNewerOrder(&b.RawOrder, &a.RawOrder) {
+			newer = b
+		}
+		if err := p.om.CancelOrderOffChain(newer.RawOrder.Hash); nil != err {
+			log.Errorf("self trade prevention, cancel order:%s err:%s", newer.RawOrder.Hash.Hex(), err.Error())
+		} else {
+			cancelled = newer.RawOrder.Hash
+		}
+	}
+
+	key := fmt.Sprintf("%s-%s", a.RawOrder.Hash.Hex(), b.RawOrder.Hash.Hex())
+	if _, exists := p.recorded.Get(key); exists && types.IsZeroHash(cancelled) {
+		return cancelled
+	}
+	p.recorded.Set(key, true, gocache.DefaultExpiration)
+
+	log.Infof("self trade prevention, rule:%s, mode:%s, order:%s and order:%s", rule, p.options.Mode, a.RawOrder.Hash.Hex(), b.RawOrder.Hash.Hex())
+	record := &dao.SelfTradeRecord{
+		Market:     market,
+		Rule:       rule,
+		Mode:       p.options.Mode,
+		OrderHashA: a.RawOrder.Hash.Hex(),
+		OrderHashB: b.RawOrder.Hash.Hex(),
+		OwnerA:     a.RawOrder.Owner.Hex(),
+		OwnerB:     b.RawOrder.Owner.Hex(),
+		CreateTime: time.Now().Unix(),
+	}
+	if !types.IsZeroHash(cancelled) {
+		record.CancelledOrderHash = cancelled.Hex()
+	}
+	if err := p.rds.Add(record); nil != err {
+		log.Errorf("self trade prevention, save record err:%s", err.Error())
+	}
+	return cancelled
+}
+
+func isNewerOrder(x, y *types.Order) bool {
+	if x.CreateTime != y.CreateTime {
+		return x.CreateTime > y.CreateTime
+	}
+	return x.ValidSince.Cmp(y.ValidSince) > 0
+}
@@ -0,0 +1,119 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package timing_matcher
+
+import (
+	"github.com/Loopring/relay/config"
+	"github.com/Loopring/relay/types"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"testing"
+)
+
+type groupUserManager struct {
+	groups map[common.Address]string
+}
+
+func (m *groupUserManager) AddWhiteListUser(user types.WhiteListUser) error { return nil }
+func (m *groupUserManager) DelWhiteListUser(user types.WhiteListUser) error { return nil }
+func (m *groupUserManager) InWhiteList(owner common.Address) bool           { return true }
+func (m *groupUserManager) IsWhiteListOpen() bool                           { return false }
+func (m *groupUserManager) Stop()                                           {}
+func (m *groupUserManager) WhiteListUsers() ([]types.WhiteListUser, error)  { return nil, nil }
+func (m *groupUserManager) OwnerGroup(owner common.Address) (string, bool) {
+	group, ok := m.groups[owner]
+	return group, ok
+}
+func (m *groupUserManager) AddOwnerGroupMember(group string, owner common.Address) error {
+	m.groups[owner] = group
+	return nil
+}
+func (m *groupUserManager) DelOwnerGroupMember(owner common.Address) error {
+	delete(m.groups, owner)
+	return nil
+}
+
+func order(owner, auth string) *types.Order {
+	return &types.Order{Owner: common.HexToAddress(owner), AuthAddr: common.HexToAddress(auth), ValidSince: big.NewInt(0)}
+}
+
+func TestSelfTradePreventerRule(t *testing.T) {
+	options := config.SelfTradePreventionOptions{SameAuth: true, OwnerGroup: true}
+	p, _ := NewSelfTradePreventer(options, nil, nil)
+	p.SetUserManager(&groupUserManager{groups: map[common.Address]string{
+		common.HexToAddress("0xa1"): "g1",
+		common.HexToAddress("0xa2"): "g1",
+		common.HexToAddress("0xa3"): "g2",
+	}})
+
+	cases := []struct {
+		a, b *types.Order
+		rule string
+	}{
+		{order("0xa1", "0xc1"), order("0xa1", "0xc2"), SELF_TRADE_RULE_SAME_OWNER},
+		{order("0xb1", "0xc1"), order("0xb2", "0xc1"), SELF_TRADE_RULE_SAME_AUTH},
+		{order("0xb1", "0x0"), order("0xb2", "0x0"), ""},
+		{order("0xa1", "0xc1"), order("0xa2", "0xc2"), SELF_TRADE_RULE_OWNER_GROUP},
+		{order("0xa1", "0xc1"), order("0xa3", "0xc2"), ""},
+	}
+	for i, c := range cases {
+		if rule := p.Rule(c.a, c.b); rule != c.rule {
+			t.Errorf("case %d, expect rule:%s, got:%s", i, c.rule, rule)
+		}
+	}
+
+	// only the same owner is prevented by default
+	p, _ = NewSelfTradePreventer(config.SelfTradePreventionOptions{}, nil, nil)
+	if rule := p.Rule(order("0xb1", "0xc1"), order("0xb2", "0xc1")); rule != "" {
+		t.Errorf("same auth should not be prevented when disabled, got:%s", rule)
+	}
+	if p.options.Mode != SELF_TRADE_MODE_SKIP {
+		t.Errorf("default mode should be skip, got:%s", p.options.Mode)
+	}
+}
+
+func TestSelfTradePreventerCheck(t *testing.T) {
+	p, _ := NewSelfTradePreventer(config.SelfTradePreventionOptions{}, nil, nil)
+	orders := []*types.OrderState{
+		{RawOrder: *order("0xb1", "0xc1")},
+		{RawOrder: *order("0xb2", "0xc2")},
+		{RawOrder: *order("0xb1", "0xc3")},
+	}
+	rule, a, b := p.Check(orders)
+	if rule != SELF_TRADE_RULE_SAME_OWNER || a != orders[0] || b != orders[2] {
+		t.Errorf("the first and the third order should be prevented, got rule:%s", rule)
+	}
+}
+
+func TestSelfTradePreventerMode(t *testing.T) {
+	if _, err := NewSelfTradePreventer(config.SelfTradePreventionOptions{Mode: "cancel"}, nil, nil); nil == err {
+		t.Errorf("unknown mode should be rejected")
+	}
+
+	p, err := NewSelfTradePreventer(config.SelfTradePreventionOptions{Mode: SELF_TRADE_MODE_CANCEL_NEWER}, nil, nil)
+	if nil != err || p.options.Mode != SELF_TRADE_MODE_CANCEL_NEWER {
+		t.Fatalf("cancel_newer should be supported, err:%v", err)
+	}
+	if err := p.SetOptions(config.SelfTradePreventionOptions{Mode: "skipped", SameAuth: true}); nil == err {
+		t.Errorf("unknown mode should be rejected by SetOptions")
+	}
+	if p.options.Mode != SELF_TRADE_MODE_CANCEL_NEWER || p.options.SameAuth {
+		t.Errorf("options should be kept when the mode is unknown, got %+v", p.options)
+	}
+}
@@ -238,8 +238,10 @@ import (
 
 type CandidateRing struct {
 	filledOrders map[common.Hash]*big.Rat
+	orderHashes  []common.Hash //in the order of ring
 	received     *big.Rat
 	cost         *big.Rat
+	createTime   int64 //create time of the oldest order
 }
 
 type CandidateRingList []CandidateRing
@@ -0,0 +1,73 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package node
+
+import (
+	"github.com/Loopring/relay/crypto"
+	"github.com/Loopring/relay/market/util"
+	"github.com/Loopring/relay/miner"
+	"github.com/Loopring/relay/params"
+	"github.com/ethereum/go-ethereum/common"
+	"time"
+)
+
+type NodeStatus struct {
+	Version   string        `json:"version"`
+	Mode      string        `json:"mode"`
+	StartTime int64         `json:"startTime"`
+	Uptime    int64         `json:"uptime"`
+	Markets   []string      `json:"markets"`
+	Miners    []MinerStatus `json:"miners"`
+}
+
+type MinerStatus struct {
+	Address common.Address `json:"address"`
+	Role    string         `json:"role"`
+	CanSign bool           `json:"canSign"`
+}
+
+// AdminApi is registered under the "node" namespace of the admin endpoint
+type AdminApi struct {
+	n *Node
+}
+
+func (a *AdminApi) Status() (*NodeStatus, error) {
+	c := a.n.config()
+	status := &NodeStatus{Version: params.Version, Mode: c.Mode, Markets: util.AllMarkets(), Miners: []MinerStatus{}}
+	if !a.n.startTime.IsZero() {
+		status.StartTime = a.n.startTime.Unix()
+		status.Uptime = int64(time.Since(a.n.startTime).Seconds())
+	}
+	if MODEL_RELAY != c.Mode {
+		for _, m := range c.Miner.NormalMiners {
+			addr := common.HexToAddress(m.Address)
+			status.Miners = append(status.Miners, MinerStatus{Address: addr, Role: miner.BALANCE_ROLE_NORMAL_MINER, CanSign: crypto.CanSign(addr)})
+		}
+		for _, m := range c.Miner.PercentMiners {
+			addr := common.HexToAddress(m.Address)
+			status.Miners = append(status.Miners, MinerStatus{Address: addr, Role: miner.BALANCE_ROLE_PERCENT_MINER, CanSign: crypto.CanSign(addr)})
+		}
+	}
+	return status, nil
+}
+
+// Reload applies the runtime tunable options of the reloaded config, see Node.Reload
+func (a *AdminApi) Reload() (*ReloadResult, error) {
+	return a.n.Reload()
+}
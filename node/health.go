@@ -0,0 +1,179 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package node
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Loopring/relay/cache"
+	"github.com/Loopring/relay/config"
+	"github.com/Loopring/relay/ethaccessor"
+	"github.com/Loopring/relay/log"
+	"github.com/Loopring/relay/params"
+	"github.com/Loopring/relay/types"
+	"net/http"
+	"time"
+)
+
+const (
+	SUBSYSTEM_OK       = "ok"
+	SUBSYSTEM_DOWN     = "down"
+	SUBSYSTEM_SYNCING  = "syncing"
+	SUBSYSTEM_PAUSED   = "paused"
+	SUBSYSTEM_DISABLED = "disabled"
+	SUBSYSTEM_STOPPING = "stopping"
+)
+
+type SubsystemStatus struct {
+	Name   string `json:"name"`
+	State  string `json:"state"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type RelayStatus struct {
+	Version      string            `json:"version"`
+	Mode         string            `json:"mode"`
+	Ready        bool              `json:"ready"`
+	CurrentBlock uint64            `json:"currentBlock"`
+	HeadBlock    uint64            `json:"headBlock"`
+	SyncLag      uint64            `json:"syncLag"`
+	RoundNumber  int64             `json:"roundNumber"`
+	Subsystems   []SubsystemStatus `json:"subsystems"`
+}
+
+// the eth node state read by Status, they are replaced by tests
+var (
+	healthyEthNodes = ethaccessor.HealthyNodes
+	chainHead       = func() (uint64, error) {
+		var head types.Big
+		if err := ethaccessor.BlockNumber(&head); nil != err {
+			return 0, err
+		}
+		return head.Uint64(), nil
+	}
+)
+
+// RelayApi is registered under the "relay" namespace of the admin endpoint
+type RelayApi struct {
+	n *Node
+}
+
+func (a *RelayApi) Status() (*RelayStatus, error) {
+	return a.n.Status(), nil
+}
+
+// Status checks the subsystems, the relay is ready unless it's stopping or one of them is down or syncing,
+// a paused miner or a disabled extractor doesn't make it unready.
+func (n *Node) Status() *RelayStatus {
+	c := n.config()
+	status := &RelayStatus{Version: params.Version, Mode: c.Mode, Subsystems: []SubsystemStatus{}}
+
+	if n.isStopping() {
+		status.addSubsystem("node", SUBSYSTEM_STOPPING, "")
+	}
+
+	status.addCheck("mysql", n.rdsService.Ping())
+	status.addCheck("redis", cache.Ping())
+
+	if healthyNodes := healthyEthNodes(); healthyNodes > 0 {
+		status.addSubsystem("ethnode", SUBSYSTEM_OK, fmt.Sprintf("%d healthy nodes", healthyNodes))
+	} else {
+		status.addSubsystem("ethnode", SUBSYSTEM_DOWN, "there isn't a healthy eth node")
+	}
+	head, headErr := chainHead()
+	if nil == headErr {
+		status.HeadBlock = head
+	}
+
+	if nil != n.relayNode {
+		if !c.Extractor.Open {
+			status.addSubsystem("extractor", SUBSYSTEM_DISABLED, "")
+		} else {
+			n.addExtractorStatus(status, c.Extractor, headErr)
+		}
+	}
+
+	if nil != n.mineNode {
+		status.RoundNumber = n.mineNode.matcher.LastRoundNumber()
+		if n.mineNode.submitter.Paused() {
+			status.addSubsystem("miner", SUBSYSTEM_PAUSED, "there isn't a miner with enough balance")
+		} else {
+			status.addSubsystem("miner", SUBSYSTEM_OK, "")
+		}
+	}
+
+	status.Ready = true
+	for _, s := range status.Subsystems {
+		if SUBSYSTEM_DOWN == s.State || SUBSYSTEM_SYNCING == s.State || SUBSYSTEM_STOPPING == s.State {
+			status.Ready = false
+		}
+	}
+	return status
+}
+
+// addExtractorStatus compares the block processed by the extractor with the head of the chain, the extractor is down
+// if it has blocks to process but hasn't got one in max_stall_time, the head can't be read only when the eth nodes are down
+func (n *Node) addExtractorStatus(status *RelayStatus, options config.ExtractorOptions, headErr error) {
+	processed, processedTime := n.relayNode.extractorService.Progress()
+	status.CurrentBlock = processed
+	if status.HeadBlock > processed {
+		status.SyncLag = status.HeadBlock - processed
+	}
+	if 0 == processed {
+		status.addSubsystem("extractor", SUBSYSTEM_SYNCING, "no block processed yet")
+		return
+	}
+
+	behind := nil != headErr || status.SyncLag > options.ConfirmBlockNumber
+	if stalled := time.Since(processedTime); behind && stalled > time.Duration(options.MaxStallTime)*time.Second {
+		status.addSubsystem("extractor", SUBSYSTEM_DOWN, fmt.Sprintf("block %d is processed %d seconds ago", processed, int64(stalled.Seconds())))
+	} else if status.SyncLag > options.MaxSyncLag {
+		status.addSubsystem("extractor", SUBSYSTEM_SYNCING, fmt.Sprintf("%d blocks behind the head", status.SyncLag))
+	} else {
+		status.addSubsystem("extractor", SUBSYSTEM_OK, "")
+	}
+}
+
+func (status *RelayStatus) addSubsystem(name, state, detail string) {
+	status.Subsystems = append(status.Subsystems, SubsystemStatus{Name: name, State: state, Detail: detail})
+}
+
+func (status *RelayStatus) addCheck(name string, err error) {
+	if nil != err {
+		status.addSubsystem(name, SUBSYSTEM_DOWN, err.Error())
+	} else {
+		status.addSubsystem(name, SUBSYSTEM_OK, "")
+	}
+}
+
+func (n *Node) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok\n"))
+}
+
+// handleReadyz responds 503 unless the relay is ready, the body is the same as relay_status
+func (n *Node) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	status := n.Status()
+	w.Header().Set("Content-Type", "application/json")
+	if !status.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(status); nil != err {
+		log.Errorf("failed to write readyz, err:%s", err.Error())
+	}
+}
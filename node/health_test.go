@@ -0,0 +1,177 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package node
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/Loopring/relay/cache"
+	"github.com/Loopring/relay/config"
+	"github.com/Loopring/relay/dao"
+	"github.com/Loopring/relay/extractor"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type pingRds struct {
+	dao.RdsService
+	err error
+}
+
+func (r *pingRds) Ping() error {
+	return r.err
+}
+
+type pingCache struct {
+	cache.Cache
+}
+
+func (c *pingCache) Ping() error {
+	return nil
+}
+
+// progressExtractor returns the progress set by tests, the other methods of ExtractorService aren't called
+type progressExtractor struct {
+	extractor.ExtractorService
+	processed     uint64
+	processedTime time.Time
+}
+
+func (e *progressExtractor) Progress() (uint64, time.Time) {
+	return e.processed, e.processedTime
+}
+
+func newHealthNode(rdsErr error) (*Node, *progressExtractor) {
+	cache.SetCache(&pingCache{})
+	c := &config.GlobalConfig{Mode: MODEL_RELAY}
+	c.Extractor = config.ExtractorOptions{Open: true, ConfirmBlockNumber: 2, MaxSyncLag: 10, MaxStallTime: 300}
+	ex := &progressExtractor{}
+	n := &Node{globalConfig: c, rdsService: &pingRds{err: rdsErr}, relayNode: &RelayNode{extractorService: ex}}
+	return n, ex
+}
+
+func setChain(t *testing.T, healthy int, head uint64, headErr error) {
+	oldHealthy, oldHead := healthyEthNodes, chainHead
+	healthyEthNodes = func() int { return healthy }
+	chainHead = func() (uint64, error) { return head, headErr }
+	t.Cleanup(func() {
+		healthyEthNodes, chainHead = oldHealthy, oldHead
+	})
+}
+
+func subsystemState(status *RelayStatus, name string) string {
+	for _, s := range status.Subsystems {
+		if name == s.Name {
+			return s.State
+		}
+	}
+	return ""
+}
+
+func TestNodeStatus(t *testing.T) {
+	n, ex := newHealthNode(nil)
+	now := time.Now()
+	cases := []struct {
+		desc          string
+		head          uint64
+		headErr       error
+		processed     uint64
+		processedTime time.Time
+		state         string
+		lag           uint64
+	}{
+		{"nothing processed", 1000, nil, 0, time.Time{}, SUBSYSTEM_SYNCING, 1000},
+		{"within confirm blocks", 1000, nil, 998, now, SUBSYSTEM_OK, 2},
+		{"within max sync lag", 1000, nil, 995, now, SUBSYSTEM_OK, 5},
+		{"behind max sync lag", 1000, nil, 900, now, SUBSYSTEM_SYNCING, 100},
+		{"stalled behind the live head", 1000, nil, 995, now.Add(-time.Hour), SUBSYSTEM_DOWN, 5},
+		{"stalled far behind the live head", 1000, nil, 900, now.Add(-time.Hour), SUBSYSTEM_DOWN, 100},
+		{"chain doesn't move", 1000, nil, 998, now.Add(-time.Hour), SUBSYSTEM_OK, 2},
+		{"stalled without head", 0, errors.New("no node"), 995, now.Add(-time.Hour), SUBSYSTEM_DOWN, 0},
+	}
+	for _, c := range cases {
+		setChain(t, 1, c.head, c.headErr)
+		ex.processed, ex.processedTime = c.processed, c.processedTime
+		status := n.Status()
+		if state := subsystemState(status, "extractor"); c.state != state {
+			t.Errorf("%s: extractor should be %s, got %s, %+v", c.desc, c.state, state, status.Subsystems)
+		}
+		if c.lag != status.SyncLag || c.head != status.HeadBlock || c.processed != status.CurrentBlock {
+			t.Errorf("%s: unexpected progress %+v", c.desc, status)
+		}
+		if ready := SUBSYSTEM_OK == c.state; ready != status.Ready {
+			t.Errorf("%s: ready should be %v", c.desc, ready)
+		}
+	}
+
+	// the extractor isn't checked when it's closed
+	setChain(t, 1, 1000, nil)
+	n.globalConfig.Extractor.Open = false
+	if status := n.Status(); SUBSYSTEM_DISABLED != subsystemState(status, "extractor") || !status.Ready || 1000 != status.HeadBlock {
+		t.Errorf("disabled extractor shouldn't make relay unready, got %+v", status)
+	}
+
+	setChain(t, 0, 0, errors.New("no node"))
+	if status := n.Status(); SUBSYSTEM_DOWN != subsystemState(status, "ethnode") || status.Ready {
+		t.Errorf("relay shouldn't be ready without eth node, got %+v", status)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	n, ex := newHealthNode(nil)
+	setChain(t, 1, 1000, nil)
+	ex.processed, ex.processedTime = 998, time.Now()
+
+	w := httptest.NewRecorder()
+	n.handleReadyz(w, httptest.NewRequest("GET", "/readyz", nil))
+	status := &RelayStatus{}
+	if http.StatusOK != w.Code {
+		t.Fatalf("ready relay should respond 200, got %d", w.Code)
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), status); nil != err || !status.Ready || 998 != status.CurrentBlock {
+		t.Errorf("body should be the status, got %s", w.Body.String())
+	}
+
+	ex.processedTime = time.Now().Add(-time.Hour)
+	setChain(t, 1, 1100, nil)
+	w = httptest.NewRecorder()
+	n.handleReadyz(w, httptest.NewRequest("GET", "/readyz", nil))
+	if http.StatusServiceUnavailable != w.Code {
+		t.Errorf("stalled extractor should respond 503, got %d", w.Code)
+	}
+
+	down, _ := newHealthNode(errors.New("mysql is down"))
+	w = httptest.NewRecorder()
+	down.handleReadyz(w, httptest.NewRequest("GET", "/readyz", nil))
+	if http.StatusServiceUnavailable != w.Code {
+		t.Errorf("relay without mysql should respond 503, got %d", w.Code)
+	}
+}
+
+func TestHealthz(t *testing.T) {
+	// the relay is alive even if it isn't ready
+	n, _ := newHealthNode(errors.New("mysql is down"))
+	w := httptest.NewRecorder()
+	n.handleHealthz(w, httptest.NewRequest("GET", "/healthz", nil))
+	if http.StatusOK != w.Code || "ok\n" != w.Body.String() {
+		t.Errorf("healthz should respond ok, got %d %s", w.Code, w.Body.String())
+	}
+}
@@ -21,18 +21,21 @@ package node
 import (
 	"sync"
 
-	"fmt"
+	"github.com/Loopring/relay/admin"
 	"github.com/Loopring/relay/cache"
 	"github.com/Loopring/relay/config"
 	"github.com/Loopring/relay/crypto"
+	"github.com/Loopring/relay/crypto/passphrase"
 	"github.com/Loopring/relay/dao"
 	"github.com/Loopring/relay/ethaccessor"
+	"github.com/Loopring/relay/export"
 	"github.com/Loopring/relay/extractor"
 	"github.com/Loopring/relay/gateway"
 	"github.com/Loopring/relay/log"
 	"github.com/Loopring/relay/market"
 	"github.com/Loopring/relay/market/util"
 	"github.com/Loopring/relay/marketcap"
+	"github.com/Loopring/relay/metrics"
 	"github.com/Loopring/relay/miner"
 	"github.com/Loopring/relay/miner/timing_matcher"
 	"github.com/Loopring/relay/ordermanager"
@@ -40,6 +43,8 @@ import (
 	"github.com/Loopring/relay/usermanager"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"go.uber.org/zap"
+	"net/http"
+	"time"
 )
 
 const (
@@ -55,12 +60,19 @@ type Node struct {
 	userManager       usermanager.UserManager
 	marketCapProvider marketcap.MarketCapProvider
 	accountManager    market.AccountManager
+	adminService      *admin.AdminServiceImpl
 	relayNode         *RelayNode
 	mineNode          *MineNode
 
-	stop   chan struct{}
-	lock   sync.RWMutex
-	logger *zap.Logger
+	stop      chan struct{}
+	stopping  bool
+	stopErr   error
+	lock      sync.RWMutex
+	logger    *zap.Logger
+	startTime time.Time
+
+	configLoader func() (*config.GlobalConfig, error)
+	reloadMtx    sync.Mutex
 }
 
 type RelayNode struct {
@@ -68,6 +80,7 @@ type RelayNode struct {
 	trendManager     market.TrendManager
 	tickerCollector  market.CollectorImpl
 	jsonRpcService   gateway.JsonrpcServiceImpl
+	restService      gateway.RestServiceImpl
 	websocketService gateway.WebsocketServiceImpl
 	socketIOService  gateway.SocketIOServiceImpl
 	walletService    gateway.WalletServiceImpl
@@ -79,9 +92,9 @@ func (n *RelayNode) Start() {
 	n.extractorService.Start()
 
 	//gateway.NewJsonrpcService("8080").Start()
-	fmt.Println("step in relay node start")
 	n.tickerCollector.Start()
 	go n.jsonRpcService.Start()
+	go n.restService.Start()
 	//n.websocketService.Start()
 	go n.socketIOService.Start()
 
@@ -89,15 +102,21 @@ func (n *RelayNode) Start() {
 
 func (n *RelayNode) Stop() {
 	n.txManager.Stop()
+	n.restService.Stop()
 }
 
 type MineNode struct {
-	miner *miner.Miner
+	miner     *miner.Miner
+	submitter *miner.RingSubmitter
+	evaluator *miner.Evaluator
+	matcher   *timing_matcher.TimingMatcher
 }
 
 func (n *MineNode) Start() {
 	n.miner.Start()
 }
+
+// Stop returns after the running round, the rings of it have been submitted and saved
 func (n *MineNode) Stop() {
 	n.miner.Stop()
 }
@@ -106,6 +125,7 @@ func NewNode(logger *zap.Logger, globalConfig *config.GlobalConfig) *Node {
 	n := &Node{}
 	n.logger = logger
 	n.globalConfig = globalConfig
+	n.stop = make(chan struct{})
 
 	// register
 	n.registerMysql()
@@ -119,6 +139,7 @@ func NewNode(logger *zap.Logger, globalConfig *config.GlobalConfig) *Node {
 	n.registerAccountManager()
 	n.registerGateway()
 	n.registerCrypto(nil)
+	n.registerAdmin()
 
 	if "relay" == globalConfig.Mode {
 		n.registerRelayNode()
@@ -140,6 +161,7 @@ func (n *Node) registerRelayNode() {
 	n.registerTickerCollector()
 	n.registerWalletService()
 	n.registerJsonRpcService()
+	n.registerRestService()
 	n.registerWebsocketService()
 	n.registerSocketIOService()
 	txmanager.NewTxView(n.rdsService)
@@ -147,14 +169,20 @@ func (n *Node) registerRelayNode() {
 
 func (n *Node) registerMineNode() {
 	n.mineNode = &MineNode{}
-	ks := keystore.NewKeyStore(n.globalConfig.Keystore.Keydir, keystore.StandardScryptN, keystore.StandardScryptP)
-	n.registerCrypto(ks)
+	if crypto.SIGNER_REMOTE == n.globalConfig.Signer.Type {
+		n.registerRemoteCrypto()
+	} else {
+		ks := keystore.NewKeyStore(n.globalConfig.Keystore.Keydir, keystore.StandardScryptN, keystore.StandardScryptP)
+		n.registerCrypto(ks)
+	}
 	n.registerMiner()
 }
 
 func (n *Node) Start() {
+	n.startTime = time.Now()
 	n.orderManager.Start()
 	n.marketCapProvider.Start()
+	n.adminService.Start()
 
 	if n.globalConfig.Mode != MODEL_MINER {
 		n.accountManager.Start()
@@ -167,29 +195,10 @@ func (n *Node) Start() {
 	}
 }
 
-func (n *Node) Wait() {
-	n.lock.RLock()
-
-	// TODO(fk): states should be judged
-
-	stop := n.stop
-	n.lock.RUnlock()
-
-	<-stop
-}
-
-func (n *Node) Stop() {
-	n.lock.RLock()
-	n.mineNode.Stop()
-	//
-	//n.p2pListener.Stop()
-	//n.chainListener.Stop()
-	//n.orderbook.Stop()
-	//n.miner.Stop()
-
-	//close(n.stop)
-
-	n.lock.RUnlock()
+// Wait blocks until the node is stopped, it returns the error of Shutdown
+func (n *Node) Wait() error {
+	<-n.stop
+	return n.stopErr
 }
 
 func (n *Node) registerCrypto(ks *keystore.KeyStore) {
@@ -197,6 +206,14 @@ func (n *Node) registerCrypto(ks *keystore.KeyStore) {
 	crypto.Initialize(c)
 }
 
+func (n *Node) registerRemoteCrypto() {
+	c, err := crypto.NewRemoteCrypto(true, n.globalConfig.Signer.Endpoint)
+	if nil != err {
+		log.Fatalf("failed to connect remote signer, err:%s", err.Error())
+	}
+	crypto.Initialize(c)
+}
+
 func (n *Node) registerMysql() {
 	n.rdsService = dao.NewRdsService(n.globalConfig.Mysql)
 	n.rdsService.Prepare()
@@ -218,6 +235,9 @@ func (n *Node) registerIPFSSubService() {
 }
 
 func (n *Node) registerOrderManager() {
+	if priority := n.globalConfig.OrderManager.MinerOrderPriority; "" != priority && !ordermanager.IsMinerOrderPriority(priority) {
+		log.Fatalf("unsupported order_manager.miner_order_priority:%s", priority)
+	}
 	n.orderManager = ordermanager.NewOrderManager(&n.globalConfig.OrderManager, n.rdsService, n.userManager, n.marketCapProvider)
 }
 
@@ -246,6 +266,11 @@ func (n *Node) registerJsonRpcService() {
 	n.relayNode.jsonRpcService = *gateway.NewJsonrpcService(n.globalConfig.Jsonrpc.Port, &n.relayNode.walletService)
 }
 
+func (n *Node) registerRestService() {
+	n.relayNode.restService = *gateway.NewRestService(n.globalConfig.Rest.Port, n.globalConfig.Rest.CacheMaxAge, &n.relayNode.walletService,
+		export.NewExporter(n.rdsService, n.marketCapProvider))
+}
+
 func (n *Node) registerWebsocketService() {
 	n.relayNode.websocketService = *gateway.NewWebsocketService(n.globalConfig.Websocket.Port, n.relayNode.trendManager, n.accountManager, n.marketCapProvider)
 }
@@ -261,11 +286,53 @@ func (n *Node) registerMiner() {
 		log.Fatalf("failed to init submitter, error:%s", err.Error())
 	}
 	evaluator := miner.NewEvaluator(n.marketCapProvider, n.globalConfig.Miner)
-	matcher := timing_matcher.NewTimingMatcher(n.globalConfig.Miner.TimingMatcher, submitter, evaluator, n.orderManager, &n.accountManager, n.rdsService)
+	gasModel := miner.NewGasModel(n.globalConfig.Miner.GasModel, n.rdsService)
+	submitter.SetGasModel(gasModel)
+	evaluator.SetGasModel(gasModel)
+	ledger := miner.NewProfitLedger(n.rdsService, n.marketCapProvider)
+	submitter.SetLedger(ledger)
+	balanceGuard := miner.NewBalanceGuard(n.globalConfig.Miner.BalanceGuard, n.globalConfig.Miner)
+	submitter.SetBalanceGuard(balanceGuard)
+	evaluator.SetBalanceGuard(balanceGuard)
+	matcher, err := timing_matcher.NewTimingMatcher(n.globalConfig.Miner.TimingMatcher, submitter, evaluator, n.orderManager, &n.accountManager, n.rdsService)
+	if nil != err {
+		log.Fatalf("failed to init timing matcher, error:%s", err.Error())
+	}
+	matcher.SetUserManager(n.userManager)
 	evaluator.SetMatcher(matcher)
+	if err := n.adminService.RegisterName("miner", miner.NewAdminApi(gasModel, submitter.Batcher(), ledger, balanceGuard, n.rdsService)); nil != err {
+		log.Errorf("failed to register miner admin api, err:%s", err.Error())
+	}
+	n.mineNode.submitter = submitter
+	n.mineNode.evaluator = evaluator
+	n.mineNode.matcher = matcher
 	n.mineNode.miner = miner.NewMiner(submitter, matcher, evaluator, n.marketCapProvider)
 }
 
+// RegisterAccountAdmin registers the "account" namespace to lock and unlock keystore accounts at runtime
+func (n *Node) RegisterAccountAdmin(source passphrase.Source) {
+	relockTimeout := time.Duration(n.globalConfig.Keystore.RelockTimeout) * time.Second
+	if err := n.adminService.RegisterName("account", crypto.NewAccountAdminApi(source, relockTimeout)); nil != err {
+		log.Errorf("failed to register account admin api, err:%s", err.Error())
+	}
+}
+
+func (n *Node) registerAdmin() {
+	n.adminService = admin.NewAdminService(n.globalConfig.Admin)
+	if err := n.adminService.RegisterName("node", &AdminApi{n: n}); nil != err {
+		log.Errorf("failed to register node admin api, err:%s", err.Error())
+	}
+	if err := n.adminService.RegisterName("whitelist", usermanager.NewAdminApi(n.userManager)); nil != err {
+		log.Errorf("failed to register whitelist admin api, err:%s", err.Error())
+	}
+	if err := n.adminService.RegisterName("relay", &RelayApi{n: n}); nil != err {
+		log.Errorf("failed to register relay admin api, err:%s", err.Error())
+	}
+	n.adminService.Handle("/metrics", metrics.Handler())
+	n.adminService.Handle("/healthz", http.HandlerFunc(n.handleHealthz))
+	n.adminService.Handle("/readyz", http.HandlerFunc(n.handleReadyz))
+}
+
 func (n *Node) registerGateway() {
 	gateway.Initialize(&n.globalConfig.GatewayFilters, &n.globalConfig.Gateway, &n.globalConfig.Ipfs, n.orderManager, n.marketCapProvider, n.accountManager)
 }
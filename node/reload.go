@@ -0,0 +1,132 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package node
+
+import (
+	"errors"
+	"github.com/Loopring/relay/config"
+	"github.com/Loopring/relay/gateway"
+	"github.com/Loopring/relay/log"
+	"github.com/Loopring/relay/market/util"
+	"strings"
+)
+
+// the options applied to the running node by Reload, the ones end with "." are prefixes
+var reloadableOptions = []string{
+	"gateway_filters.",
+	"miner.rate_ratio_cvs_threshold",
+	"miner.subsidy",
+	"miner.wallet_split",
+	"miner.min_gas_limit",
+	"miner.max_gas_limit",
+	"miner.timing_matcher.",
+	"market.token_file",
+}
+
+type ReloadResult struct {
+	Applied         []string `json:"applied"`
+	RestartRequired []string `json:"restartRequired"`
+}
+
+func isReloadable(key string) bool {
+	for _, option := range reloadableOptions {
+		if key == option || (strings.HasSuffix(option, ".") && strings.HasPrefix(key, option)) {
+			return true
+		}
+	}
+	return false
+}
+
+// config returns the running config, it's replaced as a whole by Reload and shouldn't be modified
+func (n *Node) config() *config.GlobalConfig {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+	return n.globalConfig
+}
+
+// SetConfigLoader sets how Reload loads the config, it should load the same layers as the node started with
+func (n *Node) SetConfigLoader(loader func() (*config.GlobalConfig, error)) {
+	n.configLoader = loader
+}
+
+// Reload loads the config again and swaps the gateway filters, the miner thresholds, the timing matcher
+// options and the token tables into the running node, the token file is read again even if unchanged.
+// Nothing is applied if the new config is invalid, the other changed options require restart.
+func (n *Node) Reload() (*ReloadResult, error) {
+	n.reloadMtx.Lock()
+	defer n.reloadMtx.Unlock()
+
+	if nil == n.configLoader {
+		return nil, errors.New("config loader of node hasn't been set")
+	}
+	newConfig, err := n.configLoader()
+	if nil != err {
+		return nil, err
+	}
+	if err := validateReloadConfig(newConfig); nil != err {
+		return nil, err
+	}
+
+	result := &ReloadResult{Applied: []string{}, RestartRequired: []string{}}
+	for _, key := range config.Diff(n.config(), newConfig) {
+		if isReloadable(key) {
+			result.Applied = append(result.Applied, key)
+		} else {
+			result.RestartRequired = append(result.RestartRequired, key)
+		}
+	}
+
+	if err := util.Reload(newConfig.Market); nil != err {
+		return nil, err
+	}
+
+	// the running config is replaced by a copy rather than modified, the options kept by the subsystems aren't changed under them
+	c := *n.config()
+	c.GatewayFilters = newConfig.GatewayFilters
+	c.Miner.RateRatioCVSThreshold = newConfig.Miner.RateRatioCVSThreshold
+	c.Miner.Subsidy = newConfig.Miner.Subsidy
+	c.Miner.WalletSplit = newConfig.Miner.WalletSplit
+	c.Miner.MinGasLimit = newConfig.Miner.MinGasLimit
+	c.Miner.MaxGasLimit = newConfig.Miner.MaxGasLimit
+	c.Miner.TimingMatcher = newConfig.Miner.TimingMatcher
+	c.Market.TokenFile = newConfig.Market.TokenFile
+	n.lock.Lock()
+	n.globalConfig = &c
+	n.lock.Unlock()
+
+	gateway.Reload(&c.GatewayFilters)
+	if nil != n.mineNode && nil != n.mineNode.evaluator {
+		n.mineNode.evaluator.Reload(c.Miner)
+		n.mineNode.submitter.Reload(c.Miner)
+		n.mineNode.matcher.Reload(c.Miner.TimingMatcher)
+	}
+
+	log.Infof("config reloaded, applied:%s, restart required:%s", strings.Join(result.Applied, ","), strings.Join(result.RestartRequired, ","))
+	return result, nil
+}
+
+func validateReloadConfig(c *config.GlobalConfig) error {
+	if err := config.Validate(c); nil != err {
+		return err
+	}
+	if nil == c.Miner.TimingMatcher {
+		return errors.New("miner.timing_matcher must be set")
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Loopring/relay/cache"
+	"github.com/Loopring/relay/gateway"
+	"github.com/Loopring/relay/log"
+	"strings"
+	"time"
+)
+
+// Shutdown stops the subsystems in order, the steps waiting for running work give up when ctx is done:
+// new orders are rejected, the rpc and socket.io servers are drained, the block being extracted and
+// the running matching round are finished, then the other services are stopped and redis and mysql are closed.
+// If a step gives up, its work may still be running and use redis and mysql, so they are left to the process exit.
+func (n *Node) Shutdown(ctx context.Context) error {
+	n.lock.Lock()
+	if n.stopping {
+		n.lock.Unlock()
+		return errors.New("node is already shutting down")
+	}
+	n.stopping = true
+	n.lock.Unlock()
+
+	failed := []string{}
+	unfinished := []string{}
+	step := func(name string, stop func() error) {
+		start := time.Now()
+		if err := stop(); nil != err {
+			log.Errorf("shutdown, failed to stop %s, err:%s", name, err.Error())
+			failed = append(failed, name)
+			if err == ctx.Err() {
+				unfinished = append(unfinished, name)
+			}
+		} else {
+			log.Infof("shutdown, %s stopped in %s", name, time.Since(start).String())
+		}
+	}
+
+	gateway.Stop()
+	if nil != n.relayNode {
+		step("jsonrpc", func() error { return n.relayNode.jsonRpcService.Shutdown(ctx) })
+		step("rest", func() error { return n.relayNode.restService.Shutdown(ctx) })
+		step("socketio", func() error { return n.relayNode.socketIOService.Shutdown(ctx) })
+		step("extractor", func() error { return waitUntilDone(ctx, n.relayNode.extractorService.Drain) })
+	}
+	if nil != n.mineNode {
+		step("miner", func() error { return waitUntilDone(ctx, n.mineNode.Stop) })
+	}
+	if nil != n.relayNode {
+		step("relay services", func() error { return waitUntilDone(ctx, n.relayNode.Stop) })
+	}
+	step("order manager", func() error { return waitUntilDone(ctx, n.orderManager.Stop) })
+	step("user manager", func() error { return waitUntilDone(ctx, n.userManager.Stop) })
+	step("market cap", func() error { return waitUntilDone(ctx, n.marketCapProvider.Stop) })
+	step("admin", func() error { return n.adminService.Shutdown(ctx) })
+	if len(unfinished) > 0 {
+		log.Warnf("shutdown forced, redis and mysql aren't closed since %s may be still running", strings.Join(unfinished, ", "))
+	} else {
+		step("redis", cache.Close)
+		step("mysql", n.rdsService.Close)
+	}
+
+	if len(failed) > 0 {
+		n.stopErr = fmt.Errorf("failed to stop %s", strings.Join(failed, ", "))
+	}
+	close(n.stop)
+	return n.stopErr
+}
+
+func (n *Node) isStopping() bool {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+	return n.stopping
+}
+
+// waitUntilDone runs stop and waits it to return or ctx to be done
+func waitUntilDone(ctx context.Context, stop func()) error {
+	done := make(chan struct{})
+	go func() {
+		stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,119 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package node
+
+import (
+	"context"
+	"github.com/Loopring/relay/admin"
+	"github.com/Loopring/relay/cache"
+	"github.com/Loopring/relay/config"
+	"github.com/Loopring/relay/log"
+	"github.com/Loopring/relay/marketcap"
+	"github.com/Loopring/relay/ordermanager"
+	"github.com/Loopring/relay/usermanager"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func init() {
+	log.Initialize(config.LogOptions{ZapOpts: zap.NewDevelopmentConfig()})
+}
+
+type closedCache struct {
+	cache.Cache
+	closed bool
+}
+
+func (c *closedCache) Close() error {
+	c.closed = true
+	return nil
+}
+
+type closedRds struct {
+	pingRds
+	closed bool
+}
+
+func (r *closedRds) Close() error {
+	r.closed = true
+	return nil
+}
+
+// blockedOrderManager doesn't stop until it's released
+type blockedOrderManager struct {
+	ordermanager.OrderManager
+	release chan struct{}
+}
+
+func (om *blockedOrderManager) Stop() {
+	<-om.release
+}
+
+type stoppedUserManager struct {
+	usermanager.UserManager
+}
+
+func (m *stoppedUserManager) Stop() {}
+
+type stoppedCapProvider struct {
+	marketcap.MarketCapProvider
+}
+
+func (p *stoppedCapProvider) Stop() {}
+
+func newShutdownNode() (*Node, *blockedOrderManager, *closedCache, *closedRds) {
+	c := &closedCache{}
+	cache.SetCache(c)
+	rds := &closedRds{}
+	om := &blockedOrderManager{release: make(chan struct{})}
+	n := &Node{
+		rdsService:        rds,
+		orderManager:      om,
+		userManager:       &stoppedUserManager{},
+		marketCapProvider: &stoppedCapProvider{},
+		adminService:      &admin.AdminServiceImpl{},
+		stop:              make(chan struct{}),
+	}
+	return n, om, c, rds
+}
+
+func TestShutdown(t *testing.T) {
+	n, om, c, rds := newShutdownNode()
+	close(om.release)
+	if err := n.Shutdown(context.Background()); nil != err {
+		t.Fatalf("shutdown should succeed, err:%s", err.Error())
+	}
+	if !c.closed || !rds.closed {
+		t.Errorf("redis and mysql should be closed, redis:%v, mysql:%v", c.closed, rds.closed)
+	}
+}
+
+func TestShutdown_Timeout(t *testing.T) {
+	n, om, c, rds := newShutdownNode()
+	defer close(om.release)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := n.Shutdown(ctx); nil == err {
+		t.Fatal("shutdown should fail while order manager is still stopping")
+	}
+	if c.closed || rds.closed {
+		t.Errorf("redis and mysql shouldn't be closed while order manager is still stopping, redis:%v, mysql:%v", c.closed, rds.closed)
+	}
+}
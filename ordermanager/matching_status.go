@@ -0,0 +1,94 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package ordermanager
+
+import (
+	"encoding/json"
+	"github.com/Loopring/relay/cache"
+	"github.com/ethereum/go-ethereum/common"
+	"strings"
+	"time"
+)
+
+const (
+	OrderMatchingStatusPrefix     = "order_matching_status_"
+	defaultOrderMatchingStatusTtl = 3600
+
+	MATCHING_SKIP_NOT_IN_WHITELIST  = "not_in_white_list"
+	MATCHING_SKIP_NO_COUNTER_ORDER  = "no_counter_order"
+	MATCHING_SKIP_FAILED_COUNT      = "failed_count_exceeded"
+	MATCHING_SKIP_SELF_TRADE        = "self_trade"
+	MATCHING_SKIP_PRICE_MISMATCH    = "price_mismatch"
+	MATCHING_SKIP_BALANCE           = "insufficient_balance_or_allowance"
+	MATCHING_SKIP_CVS_THRESHOLD     = "cvs_threshold"
+	MATCHING_SKIP_EVALUATE_FAILED   = "evaluate_failed"
+	MATCHING_SKIP_RECEIVED          = "received_not_enough"
+	MATCHING_SKIP_RING_FAILED_COUNT = "ring_failed_count_exceeded"
+	MATCHING_SKIP_RING_QUARANTINED  = "ring_quarantined"
+	MATCHING_SKIP_RING_SUBMITTED    = "ring_submitted"
+	MATCHING_SKIP_NOT_SELECTED      = "not_selected"
+	MATCHING_MATCHED                = "matched"
+)
+
+var orderMatchingStatusTtl int64 = defaultOrderMatchingStatusTtl
+
+// OrderMatchingStatus is the latest reason that an order is skipped by the matcher, or matched
+type OrderMatchingStatus struct {
+	OrderHash       string `json:"orderHash"`
+	Reason          string `json:"reason"`
+	Detail          string `json:"detail"`
+	Market          string `json:"market"`
+	RoundNumber     int64  `json:"roundNumber"`
+	DelayedToNumber int64  `json:"delayedToNumber"`
+	UpdateTime      int64  `json:"updateTime"`
+}
+
+func SetOrderMatchingStatusTtl(ttl int64) {
+	if ttl > 0 {
+		orderMatchingStatusTtl = ttl
+	}
+}
+
+func SetOrderMatchingStatus(status *OrderMatchingStatus) error {
+	status.UpdateTime = time.Now().Unix()
+	data, err := json.Marshal(status)
+	if nil != err {
+		return err
+	}
+	return cache.Set(orderMatchingStatusKey(common.HexToHash(status.OrderHash)), data, orderMatchingStatusTtl)
+}
+
+// GetOrderMatchingStatus returns nil if the order hasn't been considered by the matcher in ttl
+func GetOrderMatchingStatus(orderHash common.Hash) (*OrderMatchingStatus, error) {
+	key := orderMatchingStatusKey(orderHash)
+	if exists, err := cache.Exists(key); nil != err || !exists {
+		return nil, err
+	}
+	data, err := cache.Get(key)
+	if nil != err {
+		return nil, err
+	}
+	status := &OrderMatchingStatus{}
+	err = json.Unmarshal(data, status)
+	return status, err
+}
+
+func orderMatchingStatusKey(orderHash common.Hash) string {
+	return OrderMatchingStatusPrefix + strings.ToLower(orderHash.Hex())
+}
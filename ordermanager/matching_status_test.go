@@ -0,0 +1,104 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package ordermanager_test
+
+import (
+	"github.com/Loopring/relay/cache"
+	"github.com/Loopring/relay/ordermanager"
+	"github.com/ethereum/go-ethereum/common"
+	"testing"
+)
+
+// statusCache keeps the values and ttl in memory, only the methods used by matching status are implemented
+type statusCache struct {
+	cache.Cache
+	values map[string][]byte
+	ttls   map[string]int64
+}
+
+func newStatusCache() *statusCache {
+	return &statusCache{values: make(map[string][]byte), ttls: make(map[string]int64)}
+}
+
+func (c *statusCache) Set(key string, value []byte, ttl int64) error {
+	c.values[key] = value
+	c.ttls[key] = ttl
+	return nil
+}
+
+func (c *statusCache) Get(key string) ([]byte, error) {
+	return c.values[key], nil
+}
+
+func (c *statusCache) Exists(key string) (bool, error) {
+	_, ok := c.values[key]
+	return ok, nil
+}
+
+func TestGetOrderMatchingStatus(t *testing.T) {
+	c := newStatusCache()
+	cache.SetCache(c)
+
+	orderHash := common.HexToHash("0xAB")
+	if status, err := ordermanager.GetOrderMatchingStatus(orderHash); nil != err || nil != status {
+		t.Fatalf("status should be nil before the order is considered, got %+v err:%v", status, err)
+	}
+
+	status := &ordermanager.OrderMatchingStatus{
+		OrderHash:       orderHash.Hex(),
+		Reason:          ordermanager.MATCHING_SKIP_PRICE_MISMATCH,
+		Detail:          "counter order:0x1",
+		Market:          "LRC-WETH",
+		RoundNumber:     1000,
+		DelayedToNumber: 1005,
+	}
+	if err := ordermanager.SetOrderMatchingStatus(status); nil != err {
+		t.Fatalf("set status err:%s", err.Error())
+	}
+	got, err := ordermanager.GetOrderMatchingStatus(orderHash)
+	if nil != err || nil == got {
+		t.Fatalf("status should be read back, err:%v", err)
+	}
+	if *got != *status || got.UpdateTime <= 0 {
+		t.Errorf("read back %+v, expect %+v", got, status)
+	}
+
+	// the key is lower case, the ttl can be configured
+	key := ordermanager.OrderMatchingStatusPrefix + "0x00000000000000000000000000000000000000000000000000000000000000ab"
+	if ttl, ok := c.ttls[key]; !ok || 3600 != ttl {
+		t.Errorf("status should be saved with the default ttl, got %d", ttl)
+	}
+	ordermanager.SetOrderMatchingStatusTtl(0)
+	ordermanager.SetOrderMatchingStatusTtl(60)
+	defer ordermanager.SetOrderMatchingStatusTtl(3600)
+	status.Reason = ordermanager.MATCHING_MATCHED
+	ordermanager.SetOrderMatchingStatus(status)
+	if 60 != c.ttls[key] {
+		t.Errorf("ttl should be 60, got %d", c.ttls[key])
+	}
+	if got, _ := ordermanager.GetOrderMatchingStatus(orderHash); nil == got || ordermanager.MATCHING_MATCHED != got.Reason {
+		t.Errorf("the latest status should be read back, got %+v", got)
+	}
+
+	// expired by redis
+	delete(c.values, key)
+	if got, err := ordermanager.GetOrderMatchingStatus(orderHash); nil != err || nil != got {
+		t.Errorf("expired status should be nil, got %+v", got)
+	}
+}
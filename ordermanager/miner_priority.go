@@ -0,0 +1,81 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package ordermanager
+
+import (
+	"github.com/Loopring/relay/types"
+	"math/big"
+	"sort"
+)
+
+// priorities of orders provided to miner, orders are loaded by price and then ranked by the priority
+const (
+	MINER_ORDER_PRIORITY_PRICE        = "price"
+	MINER_ORDER_PRIORITY_LRC_FEE      = "lrc_fee"
+	MINER_ORDER_PRIORITY_MARGIN_SPLIT = "margin_split"
+	MINER_ORDER_PRIORITY_AGE          = "age"
+)
+
+func IsMinerOrderPriority(priority string) bool {
+	switch priority {
+	case MINER_ORDER_PRIORITY_PRICE, MINER_ORDER_PRIORITY_LRC_FEE, MINER_ORDER_PRIORITY_MARGIN_SPLIT, MINER_ORDER_PRIORITY_AGE:
+		return true
+	}
+	return false
+}
+
+// SortMinerOrders sorts orders of the same tokenS and tokenB by the priority, the order of ties is kept
+func SortMinerOrders(priority string, list []*types.OrderState) {
+	var less func(a, b *types.Order) bool
+	switch priority {
+	case MINER_ORDER_PRIORITY_LRC_FEE:
+		less = func(a, b *types.Order) bool {
+			return lrcFeePerAmountS(a).Cmp(lrcFeePerAmountS(b)) > 0
+		}
+	case MINER_ORDER_PRIORITY_MARGIN_SPLIT:
+		less = func(a, b *types.Order) bool {
+			return marginSplit(a) > marginSplit(b)
+		}
+	case MINER_ORDER_PRIORITY_AGE:
+		less = func(a, b *types.Order) bool {
+			return a.CreateTime < b.CreateTime
+		}
+	default:
+		return
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return less(&list[i].RawOrder, &list[j].RawOrder)
+	})
+}
+
+func lrcFeePerAmountS(order *types.Order) *big.Rat {
+	if nil == order.LrcFee || nil == order.AmountS || order.AmountS.Sign() <= 0 {
+		return new(big.Rat)
+	}
+	return new(big.Rat).SetFrac(order.LrcFee, order.AmountS)
+}
+
+// the margin split of 0 means 100 percent
+func marginSplit(order *types.Order) uint8 {
+	if order.MarginSplitPercentage == 0 {
+		return 100
+	}
+	return order.MarginSplitPercentage
+}
@@ -0,0 +1,77 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package ordermanager_test
+
+import (
+	"github.com/Loopring/relay/config"
+	"github.com/Loopring/relay/ordermanager"
+	"github.com/Loopring/relay/types"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func minerOrder(hash string, lrcFee, amountS int64, marginSplit uint8, createTime int64) *types.OrderState {
+	state := &types.OrderState{}
+	state.RawOrder.Hash = common.HexToHash(hash)
+	state.RawOrder.LrcFee = big.NewInt(lrcFee)
+	state.RawOrder.AmountS = big.NewInt(amountS)
+	state.RawOrder.MarginSplitPercentage = marginSplit
+	state.RawOrder.CreateTime = createTime
+	return state
+}
+
+func TestSortMinerOrders(t *testing.T) {
+	list := func() []*types.OrderState {
+		return []*types.OrderState{
+			minerOrder("0x1", 10, 100, 50, 300),
+			minerOrder("0x2", 10, 50, 0, 200),
+			minerOrder("0x3", 1, 100, 60, 100),
+		}
+	}
+	cases := map[string][]string{
+		ordermanager.MINER_ORDER_PRIORITY_PRICE:        {"0x1", "0x2", "0x3"},
+		ordermanager.MINER_ORDER_PRIORITY_LRC_FEE:      {"0x2", "0x1", "0x3"},
+		ordermanager.MINER_ORDER_PRIORITY_MARGIN_SPLIT: {"0x2", "0x3", "0x1"},
+		ordermanager.MINER_ORDER_PRIORITY_AGE:          {"0x3", "0x2", "0x1"},
+	}
+	for priority, expected := range cases {
+		orders := list()
+		ordermanager.SortMinerOrders(priority, orders)
+		for i, hash := range expected {
+			if orders[i].RawOrder.Hash != common.HexToHash(hash) {
+				t.Errorf("priority:%s, expect %s at %d, got %s", priority, hash, i, orders[i].RawOrder.Hash.Hex())
+			}
+		}
+	}
+}
+
+// config validates the priority by its own list, it should be the same as the supported ones
+func TestMinerOrderPriorities(t *testing.T) {
+	priorities := []string{ordermanager.MINER_ORDER_PRIORITY_PRICE, ordermanager.MINER_ORDER_PRIORITY_LRC_FEE, ordermanager.MINER_ORDER_PRIORITY_MARGIN_SPLIT, ordermanager.MINER_ORDER_PRIORITY_AGE}
+	if !reflect.DeepEqual(priorities, config.MinerOrderPriorities) {
+		t.Errorf("priorities of config are %v, expect %v", config.MinerOrderPriorities, priorities)
+	}
+	for _, priority := range config.MinerOrderPriorities {
+		if !ordermanager.IsMinerOrderPriority(priority) {
+			t.Errorf("%s should be supported", priority)
+		}
+	}
+}
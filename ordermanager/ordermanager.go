@@ -36,19 +36,25 @@ type OrderManager interface {
 	Start()
 	Stop()
 	MinerOrders(protocol, tokenS, tokenB common.Address, length int, reservedTime, startBlockNumber, endBlockNumber int64, filterOrderHashLists ...*types.OrderDelayList) []*types.OrderState
+	StarvedMinerOrders(protocol, tokenS, tokenB common.Address, length int, reservedTime, startBlockNumber, endBlockNumber, evaluatedBefore int64) []*types.OrderState
+	MarkMinerEvaluatedOrders(orderHashes []common.Hash, roundNumber int64) error
 	GetOrderBook(protocol, tokenS, tokenB common.Address, length int) ([]types.OrderState, error)
 	GetOrders(query map[string]interface{}, statusList []types.OrderStatus, pageIndex, pageSize int) (dao.PageResult, error)
+	GetOrdersByCursor(query map[string]interface{}, statusList []types.OrderStatus, cursor string, pageSize int) (dao.PageResult, error)
 	GetOrderByHash(hash common.Hash) (*types.OrderState, error)
 	UpdateBroadcastTimeByHash(hash common.Hash, bt int) error
 	FillsPageQuery(query map[string]interface{}, pageIndex, pageSize int) (dao.PageResult, error)
+	FillsCursorQuery(query map[string]interface{}, cursor string, pageSize int) (dao.PageResult, error)
 	GetLatestFills(query map[string]interface{}, limit int) ([]dao.FillEvent, error)
 	FindFillsByRingHash(ringHash common.Hash) (result []dao.FillEvent, err error)
 	RingMinedPageQuery(query map[string]interface{}, pageIndex, pageSize int) (dao.PageResult, error)
+	RingMinedCursorQuery(query map[string]interface{}, cursor string, pageSize int) (dao.PageResult, error)
 	IsOrderCutoff(protocol, owner, token1, token2 common.Address, validsince *big.Int) bool
 	IsOrderFullFinished(state *types.OrderState) bool
 	IsValueDusted(tokenAddress common.Address, value *big.Rat) bool
 	GetFrozenAmount(owner common.Address, token common.Address, statusSet []types.OrderStatus, delegateAddress common.Address) (*big.Int, error)
 	GetFrozenLRCFee(owner common.Address, statusSet []types.OrderStatus) (*big.Int, error)
+	CancelOrderOffChain(orderhash common.Hash) error
 }
 
 type OrderManagerImpl struct {
@@ -462,12 +468,50 @@ func (om *OrderManagerImpl) MinerOrders(protocol, tokenS, tokenB common.Address,
 		}
 	}
 
-	// 从数据库获取订单
-	if modelList, err = om.rds.GetOrdersForMiner(protocol.Hex(), tokenS.Hex(), tokenB.Hex(), length, filterStatus, reservedTime, startBlockNumber, endBlockNumber); err != nil {
+	// 从数据库获取订单, 按价格取出poolSize个订单后再按优先级排序
+	poolSize := length
+	if om.options.MinerOrderPriority != "" && om.options.MinerOrderPriority != MINER_ORDER_PRIORITY_PRICE {
+		if om.options.MinerOrderPoolSize > length {
+			poolSize = om.options.MinerOrderPoolSize
+		} else {
+			poolSize = length * 4
+		}
+	}
+	if modelList, err = om.rds.GetOrdersForMiner(protocol.Hex(), tokenS.Hex(), tokenB.Hex(), poolSize, filterStatus, reservedTime, startBlockNumber, endBlockNumber); err != nil {
 		log.Errorf("err:%s", err.Error())
 		return list
 	}
 
+	list = om.minerOrderStates(modelList)
+	SortMinerOrders(om.options.MinerOrderPriority, list)
+	if len(list) > length {
+		list = list[:length]
+	}
+
+	return list
+}
+
+// StarvedMinerOrders returns orders that have not been evaluated by miner since evaluatedBefore, the delayed ones are skipped
+func (om *OrderManagerImpl) StarvedMinerOrders(protocol, tokenS, tokenB common.Address, length int, reservedTime, startBlockNumber, endBlockNumber, evaluatedBefore int64) []*types.OrderState {
+	filterStatus := []types.OrderStatus{types.ORDER_FINISHED, types.ORDER_CUTOFF, types.ORDER_CANCEL}
+	modelList, err := om.rds.GetStarvedOrdersForMiner(protocol.Hex(), tokenS.Hex(), tokenB.Hex(), length, filterStatus, reservedTime, startBlockNumber, endBlockNumber, evaluatedBefore)
+	if err != nil {
+		log.Errorf("err:%s", err.Error())
+		return []*types.OrderState{}
+	}
+	return om.minerOrderStates(modelList)
+}
+
+func (om *OrderManagerImpl) MarkMinerEvaluatedOrders(orderHashes []common.Hash, roundNumber int64) error {
+	hashes := []string{}
+	for _, hash := range orderHashes {
+		hashes = append(hashes, hash.Hex())
+	}
+	return om.rds.MarkMinerEvaluatedOrders(hashes, roundNumber)
+}
+
+func (om *OrderManagerImpl) minerOrderStates(modelList []*dao.Order) []*types.OrderState {
+	var list []*types.OrderState
 	for _, v := range modelList {
 		state := &types.OrderState{}
 		v.ConvertUp(state)
@@ -475,9 +519,9 @@ func (om *OrderManagerImpl) MinerOrders(protocol, tokenS, tokenB common.Address,
 			list = append(list, state)
 		} else {
 			log.Debugf("order manager,owner:%s not in white list", state.RawOrder.Owner.Hex())
+			SetOrderMatchingStatus(&OrderMatchingStatus{OrderHash: state.RawOrder.Hash.Hex(), Reason: MATCHING_SKIP_NOT_IN_WHITELIST, Detail: "owner:" + state.RawOrder.Owner.Hex()})
 		}
 	}
-
 	return list
 }
 
@@ -500,6 +544,18 @@ func (om *OrderManagerImpl) GetOrderBook(protocol, tokenS, tokenB common.Address
 }
 
 func (om *OrderManagerImpl) GetOrders(query map[string]interface{}, statusList []types.OrderStatus, pageIndex, pageSize int) (dao.PageResult, error) {
+	return om.orderPageQuery(query, statusList, nil, pageIndex, pageSize)
+}
+
+func (om *OrderManagerImpl) GetOrdersByCursor(query map[string]interface{}, statusList []types.OrderStatus, cursor string, pageSize int) (dao.PageResult, error) {
+	c, err := dao.DecodeCursor(cursor)
+	if nil != err {
+		return dao.PageResult{}, err
+	}
+	return om.orderPageQuery(query, statusList, c, 1, pageSize)
+}
+
+func (om *OrderManagerImpl) orderPageQuery(query map[string]interface{}, statusList []types.OrderStatus, cursor *dao.Cursor, pageIndex, pageSize int) (dao.PageResult, error) {
 	var (
 		pageRes dao.PageResult
 	)
@@ -507,7 +563,7 @@ func (om *OrderManagerImpl) GetOrders(query map[string]interface{}, statusList [
 	for _, s := range statusList {
 		sL = append(sL, int(s))
 	}
-	tmp, err := om.rds.OrderPageQuery(query, sL, pageIndex, pageSize)
+	tmp, err := om.rds.OrderPageQuery(query, sL, cursor, pageIndex, pageSize)
 
 	if err != nil {
 		return pageRes, err
@@ -515,6 +571,7 @@ func (om *OrderManagerImpl) GetOrders(query map[string]interface{}, statusList [
 	pageRes.PageIndex = tmp.PageIndex
 	pageRes.PageSize = tmp.PageSize
 	pageRes.Total = tmp.Total
+	pageRes.NextCursor = tmp.NextCursor
 
 	for _, v := range tmp.Data {
 		var state types.OrderState
@@ -547,7 +604,15 @@ func (om *OrderManagerImpl) UpdateBroadcastTimeByHash(hash common.Hash, bt int)
 }
 
 func (om *OrderManagerImpl) FillsPageQuery(query map[string]interface{}, pageIndex, pageSize int) (result dao.PageResult, err error) {
-	return om.rds.FillsPageQuery(query, pageIndex, pageSize)
+	return om.rds.FillsPageQuery(query, nil, pageIndex, pageSize)
+}
+
+func (om *OrderManagerImpl) FillsCursorQuery(query map[string]interface{}, cursor string, pageSize int) (result dao.PageResult, err error) {
+	c, err := dao.DecodeCursor(cursor)
+	if nil != err {
+		return result, err
+	}
+	return om.rds.FillsPageQuery(query, c, 1, pageSize)
 }
 
 func (om *OrderManagerImpl) GetLatestFills(query map[string]interface{}, limit int) (result []dao.FillEvent, err error) {
@@ -559,7 +624,15 @@ func (om *OrderManagerImpl) FindFillsByRingHash(ringHash common.Hash) (result []
 }
 
 func (om *OrderManagerImpl) RingMinedPageQuery(query map[string]interface{}, pageIndex, pageSize int) (result dao.PageResult, err error) {
-	return om.rds.RingMinedPageQuery(query, pageIndex, pageSize)
+	return om.rds.RingMinedPageQuery(query, nil, pageIndex, pageSize)
+}
+
+func (om *OrderManagerImpl) RingMinedCursorQuery(query map[string]interface{}, cursor string, pageSize int) (result dao.PageResult, err error) {
+	c, err := dao.DecodeCursor(cursor)
+	if nil != err {
+		return result, err
+	}
+	return om.rds.RingMinedPageQuery(query, c, 1, pageSize)
 }
 
 func (om *OrderManagerImpl) IsOrderCutoff(protocol, owner, token1, token2 common.Address, validsince *big.Int) bool {
@@ -609,3 +682,15 @@ func (om *OrderManagerImpl) GetFrozenLRCFee(owner common.Address, statusSet []ty
 
 	return totalAmount, nil
 }
+
+// CancelOrderOffChain cancels the order in relay only, it will not be matched any more
+func (om *OrderManagerImpl) CancelOrderOffChain(orderhash common.Hash) error {
+	state, err := om.GetOrderByHash(orderhash)
+	if err != nil {
+		return err
+	}
+	if types.InUnchangeableStatus(state.Status) {
+		return fmt.Errorf("order:%s can't be cancelled in status:%d", orderhash.Hex(), state.Status)
+	}
+	return om.rds.UpdateOrderStatus(orderhash, types.ORDER_CANCEL)
+}
@@ -0,0 +1,92 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package signer
+
+import (
+	"errors"
+	"github.com/Loopring/relay/crypto"
+	"github.com/Loopring/relay/log"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// SignerApi is registered as namespace "account", the methods are
+// account_list, account_signData and account_signTransaction.
+type SignerApi struct {
+	ks     *keystore.KeyStore
+	policy *Policy
+}
+
+func NewSignerApi(ks *keystore.KeyStore, policy *Policy) *SignerApi {
+	return &SignerApi{ks: ks, policy: policy}
+}
+
+func (api *SignerApi) List() []common.Address {
+	addresses := []common.Address{}
+	for _, acc := range api.ks.Accounts() {
+		if nil == api.policy.checkAccount(acc.Address) {
+			addresses = append(addresses, acc.Address)
+		}
+	}
+	return addresses
+}
+
+func (api *SignerApi) SignData(contentType string, addr common.Address, data hexutil.Bytes) (hexutil.Bytes, error) {
+	if err := api.policy.CheckData(contentType, addr, data); nil != err {
+		log.Errorf("reject to sign data by:%s, err:%s", addr.Hex(), err.Error())
+		return nil, err
+	}
+	hash := ethCrypto.Keccak256([]byte("\x19Ethereum Signed Message:\n32"), data)
+	sig, err := api.ks.SignHash(accounts.Account{Address: addr}, hash)
+	if nil != err {
+		return nil, err
+	}
+	sig[64] += 27
+	log.Infof("signed data:%s by:%s", common.ToHex(data), addr.Hex())
+	return sig, nil
+}
+
+func (api *SignerApi) SignTransaction(args crypto.SendTxArgs) (*crypto.SignTxResult, error) {
+	if nil == args.Gas || nil == args.GasPrice {
+		return nil, errors.New("gas and gasPrice are required")
+	}
+	if nil == args.Value {
+		args.Value = new(hexutil.Big)
+	}
+	if err := api.policy.CheckTx(&args); nil != err {
+		log.Errorf("reject to sign tx by:%s, err:%s", args.From.Hex(), err.Error())
+		return nil, err
+	}
+
+	var chainId = args.ChainId.ToInt()
+	signedTx, err := api.ks.SignTx(accounts.Account{Address: args.From}, args.ToTransaction(), chainId)
+	if nil != err {
+		return nil, err
+	}
+	raw, err := rlp.EncodeToBytes(signedTx)
+	if nil != err {
+		return nil, err
+	}
+	log.Infof("signed tx:%s by:%s, to:%s, nonce:%d, gasPrice:%s", signedTx.Hash().Hex(), args.From.Hex(), args.To.Hex(), uint64(args.Nonce), args.GasPrice.ToInt().String())
+	return &crypto.SignTxResult{Raw: raw, Tx: signedTx}, nil
+}
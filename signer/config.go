@@ -0,0 +1,57 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package signer
+
+import (
+	"github.com/Loopring/relay/config"
+	"github.com/naoina/toml"
+	"os"
+)
+
+type PolicyOptions struct {
+	Accounts     []string //accounts allowed to sign, all unlocked accounts if empty
+	Destinations []string //contracts allowed to send to, e.g. the protocol address
+	MaxGasPrice  string   //wei
+	MaxValue     string   //wei, default:0
+	ChainId      int64    //required chain id of tx if it's greater than 0
+}
+
+type SignerConfig struct {
+	Keydir   string
+	Unlocks  []string
+	Ipc      string //unix socket path
+	HttpHost string
+	HttpPort string
+	Policy   PolicyOptions
+	Log      config.LogOptions
+}
+
+func LoadConfig(file string) (*SignerConfig, error) {
+	io, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer io.Close()
+
+	c := &SignerConfig{}
+	if err := toml.NewDecoder(io).Decode(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
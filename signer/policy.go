@@ -0,0 +1,118 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package signer
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Loopring/relay/crypto"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+)
+
+// Policy is checked before signing, a request not allowed is rejected.
+type Policy struct {
+	accounts     map[common.Address]bool
+	destinations map[common.Address]bool
+	maxGasPrice  *big.Int
+	maxValue     *big.Int
+	chainId      *big.Int
+}
+
+func NewPolicy(options PolicyOptions) (*Policy, error) {
+	p := &Policy{}
+	p.accounts = make(map[common.Address]bool)
+	p.destinations = make(map[common.Address]bool)
+
+	for _, addr := range options.Accounts {
+		if !common.IsHexAddress(addr) {
+			return nil, fmt.Errorf("account:%s is not a HexAddress", addr)
+		}
+		p.accounts[common.HexToAddress(addr)] = true
+	}
+	for _, addr := range options.Destinations {
+		if !common.IsHexAddress(addr) {
+			return nil, fmt.Errorf("destination:%s is not a HexAddress", addr)
+		}
+		p.destinations[common.HexToAddress(addr)] = true
+	}
+	if len(p.destinations) == 0 {
+		return nil, errors.New("destinations can't be empty, it should contain the protocol address at least")
+	}
+
+	var ok bool
+	if "" == options.MaxGasPrice {
+		return nil, errors.New("max_gas_price can't be empty")
+	} else if p.maxGasPrice, ok = new(big.Int).SetString(options.MaxGasPrice, 0); !ok {
+		return nil, fmt.Errorf("invalid max_gas_price:%s", options.MaxGasPrice)
+	}
+	p.maxValue = big.NewInt(0)
+	if "" != options.MaxValue {
+		if p.maxValue, ok = new(big.Int).SetString(options.MaxValue, 0); !ok {
+			return nil, fmt.Errorf("invalid max_value:%s", options.MaxValue)
+		}
+	}
+	if options.ChainId > 0 {
+		p.chainId = big.NewInt(options.ChainId)
+	}
+	return p, nil
+}
+
+func (p *Policy) checkAccount(addr common.Address) error {
+	if len(p.accounts) > 0 && !p.accounts[addr] {
+		return fmt.Errorf("account:%s is not allowed", addr.Hex())
+	}
+	return nil
+}
+
+func (p *Policy) CheckTx(args *crypto.SendTxArgs) error {
+	if err := p.checkAccount(args.From); nil != err {
+		return err
+	}
+	if nil == args.To {
+		return errors.New("contract creation is not allowed")
+	}
+	if !p.destinations[*args.To] {
+		return fmt.Errorf("destination:%s is not allowed", args.To.Hex())
+	}
+	if nil == args.GasPrice || args.GasPrice.ToInt().Cmp(p.maxGasPrice) > 0 {
+		return fmt.Errorf("gas price exceeds the cap:%s", p.maxGasPrice.String())
+	}
+	if nil != args.Value && args.Value.ToInt().Cmp(p.maxValue) > 0 {
+		return fmt.Errorf("value exceeds the cap:%s", p.maxValue.String())
+	}
+	if nil != p.chainId && (nil == args.ChainId || args.ChainId.ToInt().Cmp(p.chainId) != 0) {
+		return fmt.Errorf("chain id should be:%s", p.chainId.String())
+	}
+	return nil
+}
+
+// CheckData only allows to sign 32 bytes hashes, such as ringhash
+func (p *Policy) CheckData(contentType string, addr common.Address, data []byte) error {
+	if err := p.checkAccount(addr); nil != err {
+		return err
+	}
+	if crypto.SIGN_DATA_TEXT_PLAIN != contentType {
+		return fmt.Errorf("content type:%s is not allowed", contentType)
+	}
+	if len(data) != 32 {
+		return fmt.Errorf("only hash of 32 bytes can be signed, got %d bytes", len(data))
+	}
+	return nil
+}
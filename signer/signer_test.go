@@ -0,0 +1,137 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package signer_test
+
+import (
+	"github.com/Loopring/relay/config"
+	"github.com/Loopring/relay/crypto"
+	"github.com/Loopring/relay/log"
+	"github.com/Loopring/relay/signer"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func init() {
+	log.Initialize(config.LogOptions{ZapOpts: zap.NewDevelopmentConfig()})
+}
+
+func startSigner(t *testing.T, options signer.PolicyOptions) (*crypto.EthRemoteCrypto, common.Address, func()) {
+	dir, err := ioutil.TempDir("", "relay-signer")
+	if nil != err {
+		t.Fatal(err)
+	}
+	ks := keystore.NewKeyStore(filepath.Join(dir, "ks"), keystore.LightScryptN, keystore.LightScryptP)
+	acc, err := ks.NewAccount("pass")
+	if nil != err {
+		t.Fatal(err)
+	}
+	if err := ks.Unlock(acc, "pass"); nil != err {
+		t.Fatal(err)
+	}
+
+	policy, err := signer.NewPolicy(options)
+	if nil != err {
+		t.Fatal(err)
+	}
+	server := rpc.NewServer()
+	server.RegisterName("account", signer.NewSignerApi(ks, policy))
+	endpoint := filepath.Join(dir, "signer.ipc")
+	listener, err := rpc.CreateIPCListener(endpoint)
+	if nil != err {
+		t.Fatal(err)
+	}
+	go server.ServeListener(listener)
+
+	c, err := crypto.NewRemoteCrypto(true, endpoint)
+	if nil != err {
+		t.Fatal(err)
+	}
+	return c, acc.Address, func() {
+		listener.Close()
+		server.Stop()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestRemoteCrypto(t *testing.T) {
+	protocol := common.HexToAddress("0x456044789a41b277f033e4d79fab2139d69cd154")
+	c, miner, stop := startSigner(t, signer.PolicyOptions{
+		Destinations: []string{protocol.Hex()},
+		MaxGasPrice:  "50000000000",
+		ChainId:      1,
+	})
+	defer stop()
+
+	if accounts, err := c.Accounts(); nil != err || len(accounts) != 1 || accounts[0] != miner {
+		t.Fatalf("account_list should return the miner, got:%v, err:%v", accounts, err)
+	}
+
+	ringhash := crypto.GenerateHash([]byte("ring"))
+	sig, err := c.Sign(ringhash, miner)
+	if nil != err {
+		t.Fatalf("sign ringhash err:%s", err.Error())
+	}
+	if addr, err := c.SigToAddress(ringhash, sig); nil != err || common.BytesToAddress(addr) != miner {
+		t.Errorf("the signature should be recovered to the miner")
+	}
+	if _, err := c.Sign([]byte("not a hash"), miner); nil == err {
+		t.Errorf("data that isn't a hash should be rejected")
+	}
+
+	chainId := big.NewInt(1)
+	tx := ethTypes.NewTransaction(1, protocol, big.NewInt(0), big.NewInt(500000), big.NewInt(1000000000), []byte{0x01})
+	signedTx, err := c.SignTx(miner, tx, chainId)
+	if nil != err {
+		t.Fatalf("sign tx err:%s", err.Error())
+	}
+	if sender, err := ethTypes.Sender(ethTypes.NewEIP155Signer(chainId), signedTx); nil != err || sender != miner {
+		t.Errorf("the tx should be signed by the miner")
+	}
+
+	rejected := map[string]*ethTypes.Transaction{
+		"destination": ethTypes.NewTransaction(1, common.HexToAddress("0x1"), big.NewInt(0), big.NewInt(500000), big.NewInt(1000000000), nil),
+		"gas price":   ethTypes.NewTransaction(1, protocol, big.NewInt(0), big.NewInt(500000), big.NewInt(60000000000), nil),
+		"value":       ethTypes.NewTransaction(1, protocol, big.NewInt(1), big.NewInt(500000), big.NewInt(1000000000), nil),
+	}
+	for name, tx := range rejected {
+		if _, err := c.SignTx(miner, tx, chainId); nil == err {
+			t.Errorf("tx exceeding %s should be rejected", name)
+		}
+	}
+	if _, err := c.SignTx(miner, tx, big.NewInt(3)); nil == err {
+		t.Errorf("tx of other chain should be rejected")
+	}
+}
+
+func TestNewPolicy(t *testing.T) {
+	if _, err := signer.NewPolicy(signer.PolicyOptions{MaxGasPrice: "1"}); nil == err {
+		t.Errorf("policy without destinations should be invalid")
+	}
+	if _, err := signer.NewPolicy(signer.PolicyOptions{Destinations: []string{"0x456044789a41b277f033e4d79fab2139d69cd154"}}); nil == err {
+		t.Errorf("policy without max gas price should be invalid")
+	}
+}
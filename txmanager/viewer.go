@@ -41,11 +41,15 @@ func GetAllTransactionCount(ownerStr, symbol, status, typ string) (int, error) {
 func GetAllTransactions(owner, symbol, status, typ string, limit, offset int) ([]txtyp.TransactionJsonResult, error) {
 	return impl.GetAllTransactions(owner, symbol, status, typ, limit, offset)
 }
+func GetAllTransactionsByCursor(owner, symbol, status, typ, cursor string, limit, offset int) ([]txtyp.TransactionJsonResult, string, error) {
+	return impl.GetAllTransactionsByCursor(owner, symbol, status, typ, cursor, limit, offset)
+}
 
 type TransactionViewer interface {
 	GetPendingTransactions(owner string) ([]txtyp.TransactionJsonResult, error)
 	GetAllTransactionCount(owner, symbol, status, typ string) (int, error)
 	GetAllTransactions(owner, symbol, status, typ string, limit, offset int) ([]txtyp.TransactionJsonResult, error)
+	GetAllTransactionsByCursor(owner, symbol, status, typ, cursor string, limit, offset int) ([]txtyp.TransactionJsonResult, string, error)
 	GetTransactionsByHash(owner string, hashList []string) ([]txtyp.TransactionJsonResult, error)
 }
 
@@ -129,10 +133,21 @@ func (impl *TransactionViewerImpl) GetAllTransactionCount(ownerStr, symbolStr, s
 }
 
 func (impl *TransactionViewerImpl) GetAllTransactions(ownerStr, symbolStr, statusStr, typStr string, limit, offset int) ([]txtyp.TransactionJsonResult, error) {
+	list, _, err := impl.GetAllTransactionsByCursor(ownerStr, symbolStr, statusStr, typStr, "", limit, offset)
+	return list, err
+}
+
+// offset is ignored if cursor isn't empty
+func (impl *TransactionViewerImpl) GetAllTransactionsByCursor(ownerStr, symbolStr, statusStr, typStr, cursorStr string, limit, offset int) ([]txtyp.TransactionJsonResult, string, error) {
 	list := make([]txtyp.TransactionJsonResult, 0)
 
 	if !validateOwner(ownerStr) {
-		return list, ErrOwnerAddressInvalid
+		return list, "", ErrOwnerAddressInvalid
+	}
+
+	cursor, err := dao.DecodeCursor(cursorStr)
+	if err != nil {
+		return list, "", err
 	}
 
 	owner := safeOwner(ownerStr)
@@ -140,14 +155,14 @@ func (impl *TransactionViewerImpl) GetAllTransactions(ownerStr, symbolStr, statu
 	status := safeStatus(statusStr)
 	typ := safeType(typStr)
 
-	views, err := impl.db.GetTxViewByOwner(owner, symbol, status, typ, limit, offset)
+	views, nextCursor, err := impl.db.GetTxViewByOwner(owner, symbol, status, typ, cursor, limit, offset)
 	if err != nil {
-		return list, ErrNonTransaction
+		return list, "", ErrNonTransaction
 	}
 
 	list = impl.assemble(views)
 
-	return list, nil
+	return list, nextCursor, nil
 }
 
 // 如果transaction包含多条记录,则将protocol不同的记录放到content里
@@ -30,6 +30,8 @@ const (
 	TX_STATUS_PENDING TxStatus = 1
 	TX_STATUS_SUCCESS TxStatus = 2
 	TX_STATUS_FAILED  TxStatus = 3
+	// only used by ring submit, the ring isn't sent since it would revert in the simulation
+	TX_STATUS_REJECTED TxStatus = 4
 )
 
 func StatusStr(status TxStatus) string {
@@ -41,6 +43,8 @@ func StatusStr(status TxStatus) string {
 		ret = "success"
 	case TX_STATUS_FAILED:
 		ret = "failed"
+	case TX_STATUS_REJECTED:
+		ret = "rejected"
 	default:
 		ret = "unknown"
 	}
@@ -57,6 +61,8 @@ func StrToTxStatus(txType string) TxStatus {
 		ret = TX_STATUS_SUCCESS
 	case "failed":
 		ret = TX_STATUS_FAILED
+	case "rejected":
+		ret = TX_STATUS_REJECTED
 	default:
 		ret = TX_STATUS_UNKNOWN
 	}
@@ -208,6 +214,16 @@ type RingSubmitResultEvent struct {
 	Err          error
 }
 
+// MinerBalanceLowEvent is emitted when the balance of a miner address or the fee recipient falls below the threshold
+type MinerBalanceLowEvent struct {
+	Address   common.Address
+	Role      string
+	Token     common.Address
+	Balance   *big.Int
+	Threshold *big.Int
+	Time      int64
+}
+
 type ForkedEvent struct {
 	DetectedBlock *big.Int
 	DetectedHash  common.Hash
@@ -147,6 +147,11 @@ func (o *Order) GenerateHash() common.Hash {
 	return *h
 }
 
+// CancelOrderHash is signed by the owner to cancel the order in relay without sending tx
+func CancelOrderHash(orderHash common.Hash, timestamp int64) []byte {
+	return crypto.GenerateHash(orderHash.Bytes(), common.LeftPadBytes(big.NewInt(timestamp).Bytes(), 32))
+}
+
 func (o *Order) GenerateAndSetSignature(singerAddr common.Address) error {
 	if IsZeroHash(o.Hash) {
 		o.Hash = o.GenerateHash()
@@ -416,3 +421,29 @@ func ToOrder(request *OrderJsonRequest) *Order {
 	order.OrderType = request.OrderType
 	return order
 }
+
+func ToOrderJsonRequest(order *Order) *OrderJsonRequest {
+	request := &OrderJsonRequest{}
+	request.Protocol = order.Protocol
+	request.DelegateAddress = order.DelegateAddress
+	request.TokenS = order.TokenS
+	request.TokenB = order.TokenB
+	request.AmountS = order.AmountS
+	request.AmountB = order.AmountB
+	request.ValidSince = order.ValidSince
+	request.ValidUntil = order.ValidUntil
+	request.AuthAddr = order.AuthAddr
+	request.AuthPrivateKey = order.AuthPrivateKey
+	request.LrcFee = order.LrcFee
+	request.BuyNoMoreThanAmountB = order.BuyNoMoreThanAmountB
+	request.MarginSplitPercentage = order.MarginSplitPercentage
+	request.V = order.V
+	request.R = order.R
+	request.S = order.S
+	request.Owner = order.Owner
+	request.WalletAddress = order.WalletAddress
+	request.PowNonce = order.PowNonce
+	request.OrderType = order.OrderType
+	request.Hash = order.Hash
+	return request
+}
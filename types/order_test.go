@@ -19,9 +19,12 @@
 package types_test
 
 import (
+	"bytes"
 	"encoding/json"
 	"github.com/Loopring/relay/crypto"
 	"github.com/Loopring/relay/types"
+	"github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
 	"math/big"
 	"testing"
 )
@@ -58,3 +61,35 @@ func TestOrder_GenerateHash(t *testing.T) {
 		t.Log(o.GenerateHash().Hex())
 	}
 }
+
+func TestCancelOrderHash(t *testing.T) {
+	c, err := crypto.NewPrivateKeyCrypto(true, "acfe437a8e0f65124c44647737c0471b8adc9a0763f139df76766f46d6af8e15")
+	if nil != err {
+		t.Fatal(err.Error())
+	}
+	crypto.Initialize(c)
+
+	orderHash := common.HexToHash("0x6a2e1bd6e6b0d1b0e3ce1b7ac2a6e6b6c9e0e3a3c0c72e1e8f6cd7e0ce6c9b51")
+	hash := types.CancelOrderHash(orderHash, 1520000000)
+
+	// keccak256 of the order hash and the timestamp padded to 32 bytes
+	data := append(orderHash.Bytes(), common.LeftPadBytes(big.NewInt(1520000000).Bytes(), 32)...)
+	if !bytes.Equal(ethCrypto.Keccak256(data), hash) {
+		t.Fatalf("hash should be keccak256 of the order hash and the timestamp, got %s", common.ToHex(hash))
+	}
+	if bytes.Equal(types.CancelOrderHash(orderHash, 1520000001), hash) {
+		t.Errorf("hash should change with the timestamp")
+	}
+
+	// the signature of owner is recovered by the relay
+	sig, err := crypto.Sign(hash, c.Address())
+	if nil != err {
+		t.Fatal(err.Error())
+	}
+	v, r, s := crypto.SigToVRS(sig)
+	sig, _ = crypto.VRSToSig(v, r, s)
+	signer, err := crypto.SigToAddress(types.CancelOrderHash(orderHash, 1520000000), sig)
+	if nil != err || common.BytesToAddress(signer) != c.Address() {
+		t.Errorf("signer should be recovered as %s, got %s", c.Address().Hex(), common.BytesToAddress(signer).Hex())
+	}
+}
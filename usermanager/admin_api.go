@@ -0,0 +1,55 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package usermanager
+
+import (
+	"github.com/Loopring/relay/log"
+	"github.com/Loopring/relay/types"
+	"github.com/ethereum/go-ethereum/common"
+	"time"
+)
+
+// AdminApi is registered under the "whitelist" namespace of the admin endpoint
+type AdminApi struct {
+	um UserManager
+}
+
+func NewAdminApi(um UserManager) *AdminApi {
+	return &AdminApi{um: um}
+}
+
+func (a *AdminApi) Add(owner common.Address) error {
+	if err := a.um.AddWhiteListUser(types.WhiteListUser{Owner: owner, CreateTime: time.Now().Unix()}); err != nil {
+		return err
+	}
+	log.Infof("white list user:%s added through admin api", owner.Hex())
+	return nil
+}
+
+func (a *AdminApi) Remove(owner common.Address) error {
+	if err := a.um.DelWhiteListUser(types.WhiteListUser{Owner: owner}); err != nil {
+		return err
+	}
+	log.Infof("white list user:%s removed through admin api", owner.Hex())
+	return nil
+}
+
+func (a *AdminApi) List() ([]types.WhiteListUser, error) {
+	return a.um.WhiteListUsers()
+}
@@ -0,0 +1,127 @@
+/*
+
+  Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+package usermanager_test
+
+import (
+	"github.com/Loopring/relay/config"
+	"github.com/Loopring/relay/dao"
+	"github.com/Loopring/relay/log"
+	"github.com/Loopring/relay/usermanager"
+	"github.com/ethereum/go-ethereum/common"
+	"go.uber.org/zap"
+	"testing"
+)
+
+func init() {
+	log.Initialize(config.LogOptions{ZapOpts: zap.NewDevelopmentConfig()})
+}
+
+// whiteListRds keeps the white list in memory, only the methods used by usermanager are implemented
+type whiteListRds struct {
+	dao.RdsService
+	users map[string]dao.WhiteList
+}
+
+func (r *whiteListRds) GetWhiteList() ([]dao.WhiteList, error) {
+	list := []dao.WhiteList{}
+	for _, user := range r.users {
+		list = append(list, user)
+	}
+	return list, nil
+}
+
+func (r *whiteListRds) Add(item interface{}) error {
+	user := item.(dao.WhiteList)
+	r.users[user.Owner] = user
+	return nil
+}
+
+func (r *whiteListRds) DelWhiteListUser(address common.Address) error {
+	delete(r.users, address.Hex())
+	return nil
+}
+
+func (r *whiteListRds) GetOwnerGroups() ([]dao.OwnerGroup, error) {
+	return []dao.OwnerGroup{}, nil
+}
+
+func newAdminApi(open bool) (*usermanager.AdminApi, *usermanager.UserManagerImpl, *whiteListRds) {
+	rds := &whiteListRds{users: make(map[string]dao.WhiteList)}
+	options := &config.UserManagerOptions{WhiteListOpen: open, WhiteListCacheExpireTime: 3600, WhiteListCacheCleanTime: 3600}
+	um := usermanager.NewUserManager(options, rds)
+	return usermanager.NewAdminApi(um), um, rds
+}
+
+func TestAdminApi_WhiteList(t *testing.T) {
+	api, um, rds := newAdminApi(true)
+	defer um.Stop()
+
+	owner := common.HexToAddress("0x48ff2269e58a373120ffdbbdee3fbcea854ac30a")
+	if um.InWhiteList(owner) {
+		t.Fatalf("owner shouldn't be in white list before added")
+	}
+	if err := api.Add(owner); nil != err {
+		t.Fatalf("add err:%s", err.Error())
+	}
+	if !um.InWhiteList(owner) {
+		t.Errorf("owner should be in white list after added")
+	}
+	if _, ok := rds.users[owner.Hex()]; !ok {
+		t.Errorf("owner should be saved")
+	}
+
+	// adding twice keeps one user
+	api.Add(owner)
+	users, err := api.List()
+	if nil != err || len(users) != 1 || users[0].Owner != owner || users[0].CreateTime <= 0 {
+		t.Errorf("list should return the added user, got %+v err:%v", users, err)
+	}
+
+	if err := api.Remove(owner); nil != err {
+		t.Fatalf("remove err:%s", err.Error())
+	}
+	if um.InWhiteList(owner) {
+		t.Errorf("owner shouldn't be in white list after removed")
+	}
+	if users, _ := api.List(); len(users) != 0 {
+		t.Errorf("list should be empty after removed, got %+v", users)
+	}
+}
+
+func TestAdminApi_WhiteListClosed(t *testing.T) {
+	api, um, rds := newAdminApi(false)
+	defer um.Stop()
+
+	owner := common.HexToAddress("0x48ff2269e58a373120ffdbbdee3fbcea854ac30a")
+	if err := api.Add(owner); nil == err {
+		t.Errorf("add should fail when the white list is closed")
+	}
+	if err := api.Remove(owner); nil == err {
+		t.Errorf("remove should fail when the white list is closed")
+	}
+	if _, err := api.List(); nil == err {
+		t.Errorf("list should fail when the white list is closed")
+	}
+	if len(rds.users) != 0 {
+		t.Errorf("nothing should be saved, got %+v", rds.users)
+	}
+	if !um.InWhiteList(owner) {
+		t.Errorf("every owner is allowed when the white list is closed")
+	}
+}
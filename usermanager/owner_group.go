@@ -0,0 +1,124 @@
+/*
+
+ Copyright 2017 Loopring Project Ltd (Loopring Foundation).
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package usermanager
+
+import (
+	"github.com/Loopring/relay/dao"
+	"github.com/Loopring/relay/log"
+	"github.com/ethereum/go-ethereum/common"
+	"sync"
+	"time"
+)
+
+// OwnerGroupCache holds owners linked to the same party, which are configured in the owner_group table
+type OwnerGroupCache struct {
+	rds    dao.RdsService
+	groups map[common.Address]string
+	mtx    sync.RWMutex
+
+	stopChan chan bool
+}
+
+func newOwnerGroupCache(rds dao.RdsService) *OwnerGroupCache {
+	c := &OwnerGroupCache{}
+	c.rds = rds
+	c.groups = make(map[common.Address]string)
+	c.stopChan = make(chan bool)
+
+	c.refreshOwnerGroups()
+
+	return c
+}
+
+func (c *OwnerGroupCache) syncOwnerGroups() {
+	list, err := c.rds.GetOwnerGroups()
+	if err != nil {
+		log.Errorf("sync owner groups error:%s", err.Error())
+		return
+	}
+
+	groups := make(map[common.Address]string)
+	for _, v := range list {
+		groups[common.HexToAddress(v.Owner)] = v.GroupName
+	}
+
+	c.mtx.Lock()
+	c.groups = groups
+	c.mtx.Unlock()
+}
+
+func (c *OwnerGroupCache) refreshOwnerGroups() {
+	c.syncOwnerGroups()
+	go func() {
+		for {
+			select {
+			case <-time.After(time.Second * 60):
+				c.syncOwnerGroups()
+			case <-c.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+func (c *OwnerGroupCache) stop() {
+	close(c.stopChan)
+}
+
+func (c *OwnerGroupCache) OwnerGroup(owner common.Address) (string, bool) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	group, ok := c.groups[owner]
+	return group, ok
+}
+
+func (c *OwnerGroupCache) AddOwnerGroupMember(group string, owner common.Address) error {
+	model, err := c.rds.FindOwnerGroupByAddress(owner)
+	if err != nil {
+		model = &dao.OwnerGroup{Owner: owner.Hex(), CreateTime: time.Now().Unix()}
+	}
+	model.GroupName = group
+	model.IsDeleted = false
+	if err := c.rds.Save(model); err != nil {
+		return err
+	}
+
+	c.mtx.Lock()
+	c.groups[owner] = group
+	c.mtx.Unlock()
+	return nil
+}
+
+func (c *OwnerGroupCache) DelOwnerGroupMember(owner common.Address) error {
+	model, err := c.rds.FindOwnerGroupByAddress(owner)
+	if err != nil {
+		log.Debugf("owner group member:%s not exists", owner.Hex())
+		return nil
+	}
+	model.IsDeleted = true
+	if err := c.rds.Save(model); err != nil {
+		return err
+	}
+
+	c.mtx.Lock()
+	delete(c.groups, owner)
+	c.mtx.Unlock()
+	return nil
+}
@@ -30,13 +30,19 @@ type UserManager interface {
 	AddWhiteListUser(user types.WhiteListUser) error
 	DelWhiteListUser(user types.WhiteListUser) error
 	InWhiteList(owner common.Address) bool
+	WhiteListUsers() ([]types.WhiteListUser, error)
 	IsWhiteListOpen() bool
+	OwnerGroup(owner common.Address) (string, bool)
+	AddOwnerGroupMember(group string, owner common.Address) error
+	DelOwnerGroupMember(owner common.Address) error
+	Stop()
 }
 
 type UserManagerImpl struct {
 	rds       dao.RdsService
 	options   *config.UserManagerOptions
 	whiteList *WhiteListCache
+	groups    *OwnerGroupCache
 }
 
 func NewUserManager(options *config.UserManagerOptions, rds dao.RdsService) *UserManagerImpl {
@@ -47,10 +53,16 @@ func NewUserManager(options *config.UserManagerOptions, rds dao.RdsService) *Use
 	if options.WhiteListOpen {
 		impl.whiteList = newWhiteListCache(impl.options, impl.rds)
 	}
+	impl.groups = newOwnerGroupCache(impl.rds)
 
 	return impl
 }
 
+// Stop stops refreshing the owner groups
+func (m *UserManagerImpl) Stop() {
+	m.groups.stop()
+}
+
 func (m *UserManagerImpl) InWhiteList(owner common.Address) bool {
 	if !m.options.WhiteListOpen {
 		return true
@@ -71,6 +83,24 @@ func (m *UserManagerImpl) DelWhiteListUser(user types.WhiteListUser) error {
 	}
 	return m.whiteList.DelWhiteListUser(user)
 }
+func (m *UserManagerImpl) WhiteListUsers() ([]types.WhiteListUser, error) {
+	if !m.options.WhiteListOpen {
+		return nil, fmt.Errorf("wihte list is closed")
+	}
+	return m.whiteList.WhiteListUsers()
+}
 func (m *UserManagerImpl) IsWhiteListOpen() bool {
 	return m.options.WhiteListOpen
 }
+
+func (m *UserManagerImpl) OwnerGroup(owner common.Address) (string, bool) {
+	return m.groups.OwnerGroup(owner)
+}
+
+func (m *UserManagerImpl) AddOwnerGroupMember(group string, owner common.Address) error {
+	return m.groups.AddOwnerGroupMember(group, owner)
+}
+
+func (m *UserManagerImpl) DelOwnerGroupMember(owner common.Address) error {
+	return m.groups.DelOwnerGroupMember(owner)
+}
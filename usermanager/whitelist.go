@@ -94,12 +94,22 @@ func (c *WhiteListCache) DelWhiteListUser(user types.WhiteListUser) error {
 	}
 
 	c.del(user.Owner)
-	model := dao.WhiteList{}
-	if err := model.ConvertDown(&user); err != nil {
-		return err
-	}
+	return c.rds.DelWhiteListUser(user.Owner)
+}
 
-	return c.rds.Del(model)
+func (c *WhiteListCache) WhiteListUsers() ([]types.WhiteListUser, error) {
+	list, err := c.rds.GetWhiteList()
+	if err != nil {
+		return nil, err
+	}
+	users := []types.WhiteListUser{}
+	for _, v := range list {
+		var user types.WhiteListUser
+		if err := v.ConvertUp(&user); err == nil {
+			users = append(users, user)
+		}
+	}
+	return users, nil
 }
 
 func (c *WhiteListCache) InWhiteList(address common.Address) bool {